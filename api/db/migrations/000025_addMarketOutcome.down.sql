@@ -0,0 +1,3 @@
+-- remove the outcome column from the markets table
+ALTER TABLE markets
+DROP COLUMN outcome;
@@ -0,0 +1,3 @@
+-- add an outcome column to the markets table (NULL until resolved, TRUE => YES, FALSE => NO)
+ALTER TABLE markets
+ADD COLUMN outcome BOOLEAN DEFAULT NULL;
@@ -0,0 +1,3 @@
+-- remove the closed_at column from the markets table
+ALTER TABLE markets
+DROP COLUMN IF EXISTS closed_at;
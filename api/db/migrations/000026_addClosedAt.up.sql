@@ -0,0 +1,3 @@
+-- add a closed_at column to the markets table (set by the cron job once closes_at has passed)
+ALTER TABLE markets
+ADD COLUMN closed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
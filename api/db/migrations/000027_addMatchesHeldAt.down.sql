@@ -0,0 +1,3 @@
+-- remove the held_at column from the matches table
+ALTER TABLE matches
+DROP COLUMN IF EXISTS held_at;
@@ -0,0 +1,3 @@
+-- add a held_at column to the matches table (settlement deferred while the market is paused)
+ALTER TABLE matches
+ADD COLUMN held_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS markets_closes_at_idx;
+DROP INDEX IF EXISTS markets_search_idx;
@@ -0,0 +1,3 @@
+-- full-text search over market statement + description (expression must match the GetMarkets/CountMarkets queries)
+CREATE INDEX IF NOT EXISTS markets_search_idx ON markets USING GIN (to_tsvector('english', statement || ' ' || COALESCE(description, '')));
+CREATE INDEX IF NOT EXISTS markets_closes_at_idx ON markets (closes_at);
@@ -0,0 +1,15 @@
+-- market creation saga: one row per CreateMarket request, written before any step runs
+-- step: pending -> contract_created -> clob_created -> completed
+CREATE TABLE IF NOT EXISTS market_creations (
+  market_id UUID PRIMARY KEY NOT NULL,
+  net TEXT NOT NULL CHECK (net IN ('testnet', 'mainnet', 'previewnet')),
+  smart_contract_id TEXT NOT NULL,
+  request JSONB NOT NULL, -- the original CreateMarketRequest (protojson) so that a retry or the reconciler can replay it
+  step TEXT NOT NULL DEFAULT 'pending' CHECK (step IN ('pending', 'contract_created', 'clob_created', 'completed')),
+  remaining_allowance BIGINT DEFAULT NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT DEFAULT NULL,
+  flagged_at TIMESTAMP WITH TIME ZONE DEFAULT NULL, -- given up on by the reconciler - needs a human
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
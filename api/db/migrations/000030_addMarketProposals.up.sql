@@ -0,0 +1,18 @@
+-- market proposals: markets only go live once an admin approves them
+-- the proposer pays no fee - the market creation fee is paid by the operator account on approval (fee_charged)
+CREATE TABLE IF NOT EXISTS market_proposals (
+  market_id UUID PRIMARY KEY NOT NULL, -- becomes the market_id of the market on approval
+  net TEXT NOT NULL CHECK (net IN ('testnet', 'mainnet', 'previewnet')),
+  account_id TEXT NOT NULL CHECK (LENGTH(account_id) >= 5),
+  request JSONB NOT NULL, -- the proposed CreateMarketRequest (protojson)
+  sig TEXT NOT NULL,
+  public_key_hex TEXT NOT NULL,
+  key_type INTEGER NOT NULL CHECK (key_type IN (1, 2)),
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+  reject_reason TEXT DEFAULT NULL,
+  fee_charged BOOLEAN NOT NULL DEFAULT FALSE,
+  reviewed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_market_proposals_status ON market_proposals (status, created_at);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_markets_group_id;
+
+ALTER TABLE markets
+DROP COLUMN IF EXISTS group_id;
+
+DROP TABLE IF EXISTS market_groups;
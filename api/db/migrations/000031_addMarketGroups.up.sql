@@ -0,0 +1,18 @@
+-- multi-outcome market groups: every leg is a regular binary market (markets.group_id)
+CREATE TABLE IF NOT EXISTS market_groups (
+  group_id UUID PRIMARY KEY NOT NULL,
+  net TEXT NOT NULL CHECK (net IN ('testnet', 'mainnet', 'previewnet')),
+  statement TEXT NOT NULL,
+  description TEXT DEFAULT NULL,
+  image_url TEXT DEFAULT NULL,
+  closes_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+  winning_market_id UUID DEFAULT NULL, -- the one leg that resolves YES (all other legs resolve NO)
+  resolved_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE markets
+ADD COLUMN group_id UUID DEFAULT NULL REFERENCES market_groups(group_id);
+
+CREATE INDEX IF NOT EXISTS idx_markets_group_id ON markets (group_id);
@@ -0,0 +1,6 @@
+ALTER TABLE positions
+DROP COLUMN IF EXISTS refund;
+
+ALTER TABLE markets
+DROP COLUMN IF EXISTS void_reason,
+DROP COLUMN IF EXISTS voided_at;
@@ -0,0 +1,8 @@
+-- voided (annulled) markets: every YES and NO token is redeemable on-chain for 50% of the collateral
+ALTER TABLE markets
+ADD COLUMN voided_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+ADD COLUMN void_reason TEXT DEFAULT NULL;
+
+-- refund owed to the position on a voided market (same scale as n_yes/n_no)
+ALTER TABLE positions
+ADD COLUMN refund BIGINT DEFAULT NULL;
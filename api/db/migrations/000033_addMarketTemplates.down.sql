@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS market_template_runs;
+
+DROP TABLE IF EXISTS market_templates;
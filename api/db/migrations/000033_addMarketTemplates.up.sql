@@ -0,0 +1,25 @@
+-- recurring market templates: CronService instantiates a market through MarketsService.CreateMarket every time the schedule fires
+CREATE TABLE IF NOT EXISTS market_templates (
+  template_id UUID PRIMARY KEY NOT NULL,
+  name TEXT NOT NULL UNIQUE,
+  net TEXT NOT NULL CHECK (net IN ('testnet', 'mainnet', 'previewnet')),
+  statement_pattern TEXT NOT NULL, -- Go text/template, e.g. 'Will BTC close above $100k on {{.ClosesAt.Format "Mon Jan 2"}}?'
+  description TEXT DEFAULT NULL,
+  image_url TEXT DEFAULT NULL,
+  category_id INTEGER DEFAULT NULL REFERENCES categories(id),
+  closes_after_seconds BIGINT NOT NULL CHECK (closes_after_seconds > 0), -- closes_at = scheduled time + offset
+  schedule TEXT NOT NULL, -- standard 5-field cron expression, evaluated in UTC
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  last_run_at TIMESTAMP WITH TIME ZONE DEFAULT NULL, -- the last scheduled time a market was created for
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- one row per scheduled time: pins the marketId so a retried run resumes the same market creation instead of creating a duplicate
+CREATE TABLE IF NOT EXISTS market_template_runs (
+  template_id UUID NOT NULL REFERENCES market_templates(template_id),
+  scheduled_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  market_id UUID NOT NULL UNIQUE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (template_id, scheduled_at)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE prediction_intents
+DROP COLUMN IF EXISTS replaces_tx_id;
@@ -0,0 +1,4 @@
+-- cancel-replace (amend): the new prediction intent points at the one it replaced (audit trail)
+-- UNIQUE => an order can only be replaced once
+ALTER TABLE prediction_intents
+ADD COLUMN replaces_tx_id UUID DEFAULT NULL UNIQUE REFERENCES prediction_intents(tx_id);
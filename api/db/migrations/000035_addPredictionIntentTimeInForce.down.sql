@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_prediction_intents_expires_at;
+
+ALTER TABLE prediction_intents
+DROP CONSTRAINT IF EXISTS prediction_intents_expires_at_check,
+DROP COLUMN IF EXISTS expired_at,
+DROP COLUMN IF EXISTS expires_at,
+DROP COLUMN IF EXISTS time_in_force;
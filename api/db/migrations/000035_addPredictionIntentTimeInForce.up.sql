@@ -0,0 +1,10 @@
+-- time-in-force: gtc (rests until matched/cancelled), gtd (rests until expires_at), ioc (remainder cancelled on arrival), fok (fully filled on arrival or not at all)
+-- expires_at is part of the signed payload - required for gtd, NULL otherwise
+ALTER TABLE prediction_intents
+ADD COLUMN time_in_force TEXT NOT NULL DEFAULT 'gtc' CHECK (time_in_force IN ('gtc', 'gtd', 'ioc', 'fok')),
+ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+ADD COLUMN expired_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+ADD CONSTRAINT prediction_intents_expires_at_check CHECK ((time_in_force = 'gtd') = (expires_at IS NOT NULL));
+
+-- the cron job scans for open gtd intents past their expires_at
+CREATE INDEX idx_prediction_intents_expires_at ON prediction_intents (expires_at) WHERE expires_at IS NOT NULL;
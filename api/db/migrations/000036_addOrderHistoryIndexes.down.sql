@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_matches_tx_id2;
+DROP INDEX IF EXISTS idx_matches_tx_id1;
+DROP INDEX IF EXISTS idx_prediction_intents_account_id;
@@ -0,0 +1,4 @@
+-- order status/fill history: ListPredictionIntents filters by account, GetMatchesByTxIds looks matches up by either side's txId
+CREATE INDEX IF NOT EXISTS idx_prediction_intents_account_id ON prediction_intents (account_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_matches_tx_id1 ON matches (tx_id1);
+CREATE INDEX IF NOT EXISTS idx_matches_tx_id2 ON matches (tx_id2);
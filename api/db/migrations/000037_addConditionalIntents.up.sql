@@ -0,0 +1,19 @@
+-- conditional (stop/take-profit) intents: a signed prediction intent held server-side until the market's last traded price crosses the trigger
+-- when it fires, the intent goes through the regular CreatePredictionIntent validation and is placed with its own tx_id
+CREATE TABLE IF NOT EXISTS conditional_intents (
+  tx_id UUID PRIMARY KEY NOT NULL, -- tx_id of the wrapped prediction intent
+  market_id UUID NOT NULL REFERENCES markets(market_id),
+  account_id TEXT NOT NULL CHECK (LENGTH(account_id) >= 5),
+  trigger_condition TEXT NOT NULL CHECK (trigger_condition IN ('above', 'below')), -- above => last price >= trigger_price_usd, below => last price <= trigger_price_usd
+  trigger_price_usd DOUBLE PRECISION NOT NULL CHECK (trigger_price_usd > 0.0 AND trigger_price_usd < 1.0),
+  intent JSONB NOT NULL, -- the signed PredictionIntentRequest (protojson)
+  triggered_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+  cancelled_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+  failed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL, -- triggered, but the intent was rejected
+  failure_reason TEXT DEFAULT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- every price tick looks up the pending triggers of its market
+CREATE INDEX IF NOT EXISTS idx_conditional_intents_pending ON conditional_intents (market_id) WHERE triggered_at IS NULL AND cancelled_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_conditional_intents_account_id ON conditional_intents (account_id);
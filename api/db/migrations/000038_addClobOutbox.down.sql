@@ -0,0 +1 @@
+DROP TABLE IF EXISTS clob_outbox;
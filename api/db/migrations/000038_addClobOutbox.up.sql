@@ -0,0 +1,14 @@
+-- transactional outbox for the CLOB: written in the same db transaction as the prediction intent it publishes
+-- a relay (NatsService.RelayClobOutbox) publishes the undelivered rows to NATS, in id order, and marks them as delivered
+CREATE TABLE IF NOT EXISTS clob_outbox (
+  id SERIAL PRIMARY KEY,
+  tx_id UUID NOT NULL REFERENCES prediction_intents(tx_id),
+  subject TEXT NOT NULL, -- NATS subject, e.g. clob.orders
+  attempts INTEGER NOT NULL DEFAULT 0, -- failed publish attempts
+  last_error TEXT DEFAULT NULL,
+  delivered_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- the relay only ever scans the undelivered rows
+CREATE INDEX IF NOT EXISTS idx_clob_outbox_undelivered ON clob_outbox (id) WHERE delivered_at IS NULL;
@@ -0,0 +1,19 @@
+-- configurable pre-trade risk limits (see: RiskService) - a NULL limit is not enforced
+-- one network-wide row per net (market_id IS NULL), optionally overridden per market (market_id set)
+CREATE TABLE IF NOT EXISTS risk_limits (
+  id SERIAL PRIMARY KEY,
+  net TEXT NOT NULL CHECK (net IN ('testnet', 'mainnet', 'previewnet')),
+  market_id UUID DEFAULT NULL REFERENCES markets(market_id),
+  max_open_intents_per_account INTEGER DEFAULT NULL CHECK (max_open_intents_per_account > 0), -- per account per market
+  max_position_qty DOUBLE PRECISION DEFAULT NULL CHECK (max_position_qty > 0.0), -- per account per market and side: position + open intents + the new intent
+  min_price_usd DOUBLE PRECISION DEFAULT NULL CHECK (min_price_usd >= 0.0 AND min_price_usd < 1.0), -- price band on |price_usd|
+  max_price_usd DOUBLE PRECISION DEFAULT NULL CHECK (max_price_usd > 0.0 AND max_price_usd <= 1.0),
+  max_notional_usd_per_account DOUBLE PRECISION DEFAULT NULL CHECK (max_notional_usd_per_account > 0.0), -- network-wide only: the account's open intents across every market
+  max_open_notional_usd DOUBLE PRECISION DEFAULT NULL CHECK (max_open_notional_usd > 0.0), -- network-wide only: every account's open intents
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CHECK (min_price_usd IS NULL OR max_price_usd IS NULL OR min_price_usd <= max_price_usd),
+  CHECK (market_id IS NULL OR (max_notional_usd_per_account IS NULL AND max_open_notional_usd IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_risk_limits_net ON risk_limits (net) WHERE market_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_risk_limits_net_market_id ON risk_limits (net, market_id) WHERE market_id IS NOT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE matches DROP COLUMN IF EXISTS fee_usd2;
+ALTER TABLE matches DROP COLUMN IF EXISTS fee_usd1;
+ALTER TABLE matches DROP COLUMN IF EXISTS maker_tx_id;
+
+DROP TABLE IF EXISTS fee_schedules;
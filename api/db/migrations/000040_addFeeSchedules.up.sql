@@ -0,0 +1,18 @@
+-- maker/taker trading fees (see: FeesService) in basis points of each side's matched notional
+-- one network-wide row per net (market_id IS NULL), optionally overridden per market (market_id set) - no row => no fee
+CREATE TABLE IF NOT EXISTS fee_schedules (
+  id SERIAL PRIMARY KEY,
+  net TEXT NOT NULL CHECK (net IN ('testnet', 'mainnet', 'previewnet')),
+  market_id UUID DEFAULT NULL REFERENCES markets(market_id),
+  maker_fee_bps INTEGER NOT NULL DEFAULT 0 CHECK (maker_fee_bps >= 0 AND maker_fee_bps <= 1000), -- the side that was resting on the book
+  taker_fee_bps INTEGER NOT NULL DEFAULT 0 CHECK (taker_fee_bps >= 0 AND taker_fee_bps <= 1000), -- the side that crossed the book
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fee_schedules_net ON fee_schedules (net) WHERE market_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fee_schedules_net_market_id ON fee_schedules (net, market_id) WHERE market_id IS NOT NULL;
+
+-- the fees charged on each match (settled on-chain together with the match)
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS maker_tx_id UUID DEFAULT NULL; -- NULL for matches recorded before fees
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS fee_usd1 DOUBLE PRECISION NOT NULL DEFAULT 0.0; -- paid by tx_id1 (YES side)
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS fee_usd2 DOUBLE PRECISION NOT NULL DEFAULT 0.0; -- paid by tx_id2 (NO side)
@@ -0,0 +1,29 @@
+-- settlement state of every match (see: HederaService.BuyPositionTokens) - replaces the settlements table dropped in 000015
+-- pending (recorded, or held while the market is paused) -> submitted (sent to the smart contract) -> confirmed | failed
+CREATE TABLE IF NOT EXISTS settlements (
+  id SERIAL PRIMARY KEY,
+  match_id INTEGER NOT NULL UNIQUE REFERENCES matches(id),
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'submitted', 'confirmed', 'failed')),
+  hedera_tx_id TEXT DEFAULT NULL, -- Hedera transaction ID of the (last) buyPositionTokensOnBehalfAtomic call
+  receipt_status TEXT DEFAULT NULL, -- e.g. SUCCESS, CONTRACT_REVERT_EXECUTED
+  gas_used BIGINT DEFAULT NULL,
+  error TEXT DEFAULT NULL, -- why the (last) attempt failed
+  attempts INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_settlements_status ON settlements (status) WHERE status IN ('pending', 'submitted', 'failed');
+
+-- backfill: the matches settled so far have their transaction ID in tx_hash.
+-- N.B. a match still at notYetAvailable either failed to settle, or settled and then failed to record its transaction -
+-- never pending (it would be submitted again): failed with an unknown outcome, dead-lettered for an admin by 000042
+INSERT INTO settlements (match_id, status, hedera_tx_id, error, created_at, updated_at)
+SELECT id,
+  CASE WHEN tx_hash = 'notYetAvailable' THEN 'failed' ELSE 'confirmed' END,
+  CASE WHEN tx_hash = 'notYetAvailable' THEN NULL ELSE tx_hash END,
+  CASE WHEN tx_hash = 'notYetAvailable' THEN 'pre-migration, outcome unknown' ELSE NULL END,
+  created_at,
+  created_at
+FROM matches
+ON CONFLICT (match_id) DO NOTHING;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_settlements_next_attempt_at;
+DROP INDEX IF EXISTS idx_settlements_status;
+CREATE INDEX IF NOT EXISTS idx_settlements_status ON settlements (status) WHERE status IN ('pending', 'submitted', 'failed');
+
+ALTER TABLE settlements DROP COLUMN IF EXISTS abandoned_at;
+ALTER TABLE settlements DROP COLUMN IF EXISTS dead_lettered_at;
+ALTER TABLE settlements DROP COLUMN IF EXISTS next_attempt_at;
+
+UPDATE settlements SET status = 'failed' WHERE status IN ('dead_lettered', 'abandoned');
+ALTER TABLE settlements DROP CONSTRAINT IF EXISTS settlements_status_check;
+ALTER TABLE settlements ADD CONSTRAINT settlements_status_check CHECK (status IN ('pending', 'submitted', 'confirmed', 'failed'));
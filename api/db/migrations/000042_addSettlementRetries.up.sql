@@ -0,0 +1,17 @@
+-- settlement retry queue: transient failures are retried with exponential backoff (next_attempt_at),
+-- permanent failures (and retries exhausted) are dead-lettered until an admin retries or abandons them
+ALTER TABLE settlements DROP CONSTRAINT IF EXISTS settlements_status_check;
+ALTER TABLE settlements ADD CONSTRAINT settlements_status_check CHECK (status IN ('pending', 'submitted', 'confirmed', 'failed', 'dead_lettered', 'abandoned'));
+
+ALTER TABLE settlements ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP WITH TIME ZONE DEFAULT NULL; -- failed only
+ALTER TABLE settlements ADD COLUMN IF NOT EXISTS dead_lettered_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+ALTER TABLE settlements ADD COLUMN IF NOT EXISTS abandoned_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+DROP INDEX IF EXISTS idx_settlements_status;
+CREATE INDEX IF NOT EXISTS idx_settlements_status ON settlements (status) WHERE status IN ('pending', 'submitted', 'failed', 'dead_lettered');
+CREATE INDEX IF NOT EXISTS idx_settlements_next_attempt_at ON settlements (next_attempt_at) WHERE status = 'failed';
+
+-- the matches backfilled by 000041 with an unknown outcome wait for an admin - they are never retried automatically
+UPDATE settlements
+SET status = 'dead_lettered', dead_lettered_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE status = 'failed' AND hedera_tx_id IS NULL AND error = 'pre-migration, outcome unknown';
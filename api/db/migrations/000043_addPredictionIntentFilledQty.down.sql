@@ -0,0 +1,3 @@
+ALTER TABLE prediction_intents DROP COLUMN IF EXISTS remaining_qty;
+ALTER TABLE prediction_intents DROP COLUMN IF EXISTS filled_qty;
+ALTER TABLE matches DROP COLUMN IF EXISTS matched_qty;
@@ -0,0 +1,64 @@
+-- per-intent fill accounting: filled_qty is updated in the same transaction as each match (see: MatchesRepository.CreateMatch),
+-- remaining_qty is derived from it so there is only one value to read
+ALTER TABLE prediction_intents ADD COLUMN IF NOT EXISTS filled_qty DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (filled_qty >= 0);
+ALTER TABLE prediction_intents ADD COLUMN IF NOT EXISTS remaining_qty DOUBLE PRECISION GENERATED ALWAYS AS (GREATEST(qty - filled_qty, 0)) STORED NOT NULL;
+
+-- the qty filled on both sides by a match, recorded with it (LEAST(qty1, qty2): the CLOB publishes both sides with their qty before the match)
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS matched_qty DOUBLE PRECISION;
+UPDATE matches SET matched_qty = LEAST(qty1, qty2) WHERE matched_qty IS NULL;
+
+-- until now the CLOB published the incoming side of a PARTIAL match with its qty AFTER the match (10 against a resting 8 was recorded as 2/8):
+-- the matched qty of those rows is the resting side's qty, not LEAST(qty1, qty2). qty1/qty2 are kept as they were published - only matched_qty is corrected.
+-- The incoming side is the taker (maker_tx_id), else the later intent (as ComputeMatchFees). An incoming intent's matches on arrival are recorded
+-- in order: a row is a partial match if its incoming qty + resting qty is the incoming qty of the previous row (of the intent's qty for the first one).
+-- N.B. settlements are unaffected - the smart contract is sent each side's original (signed) qty, not the matched qty
+WITH sides AS (
+  SELECT matches.id,
+    matches.qty1,
+    matches.qty2,
+    CASE WHEN matches.maker_tx_id IS NOT NULL THEN matches.maker_tx_id = matches.tx_id2
+      ELSE (intent1.created_at, intent1.tx_id) > (intent2.created_at, intent2.tx_id)
+    END AS is_incoming1
+  FROM matches
+  JOIN prediction_intents AS intent1 ON intent1.tx_id = matches.tx_id1
+  JOIN prediction_intents AS intent2 ON intent2.tx_id = matches.tx_id2
+),
+incoming AS (
+  SELECT sides.id,
+    CASE WHEN sides.is_incoming1 THEN sides.qty1 ELSE sides.qty2 END AS incoming_qty,
+    CASE WHEN sides.is_incoming1 THEN sides.qty2 ELSE sides.qty1 END AS resting_qty,
+    COALESCE(
+      LAG(CASE WHEN sides.is_incoming1 THEN sides.qty1 ELSE sides.qty2 END) OVER (PARTITION BY prediction_intents.tx_id ORDER BY sides.id),
+      prediction_intents.qty
+    ) AS previous_incoming_qty
+  FROM sides
+  JOIN matches ON matches.id = sides.id
+  JOIN prediction_intents ON prediction_intents.tx_id = CASE WHEN sides.is_incoming1 THEN matches.tx_id1 ELSE matches.tx_id2 END
+)
+UPDATE matches
+SET matched_qty = incoming.resting_qty
+FROM incoming
+WHERE matches.id = incoming.id
+AND ABS(incoming.previous_incoming_qty - incoming.resting_qty - incoming.incoming_qty) < 1e-9;
+
+ALTER TABLE matches ALTER COLUMN matched_qty SET NOT NULL;
+
+-- backfill from the recorded matches (abandoned settlements did not fill anything) - a match fills its matched_qty on both sides
+UPDATE prediction_intents
+SET filled_qty = LEAST(prediction_intents.qty, fills.filled_qty)
+FROM (
+  SELECT tx_id, SUM(matched_qty) AS filled_qty
+  FROM (
+    SELECT matches.tx_id1 AS tx_id, matches.matched_qty
+    FROM matches
+    LEFT JOIN settlements ON settlements.match_id = matches.id
+    WHERE settlements.status IS DISTINCT FROM 'abandoned'
+    UNION ALL
+    SELECT matches.tx_id2 AS tx_id, matches.matched_qty
+    FROM matches
+    LEFT JOIN settlements ON settlements.match_id = matches.id
+    WHERE settlements.status IS DISTINCT FROM 'abandoned'
+  ) AS matched
+  GROUP BY tx_id
+) AS fills
+WHERE prediction_intents.tx_id = fills.tx_id;
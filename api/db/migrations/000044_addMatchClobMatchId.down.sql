@@ -0,0 +1,2 @@
+ALTER TABLE matches DROP CONSTRAINT IF EXISTS matches_clob_match_id_key;
+ALTER TABLE matches DROP COLUMN IF EXISTS clob_match_id;
@@ -0,0 +1,4 @@
+-- idempotent match ingestion: the CLOB publishes every match with a deterministic match ID ("<yes txId>:<no txId>:<match seq>")
+-- and a match is recorded (and settled) once per match ID - NULL for the matches recorded before
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS clob_match_id TEXT DEFAULT NULL;
+ALTER TABLE matches ADD CONSTRAINT matches_clob_match_id_key UNIQUE (clob_match_id);
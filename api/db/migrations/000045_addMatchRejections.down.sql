@@ -0,0 +1,3 @@
+ALTER TABLE prediction_intents DROP COLUMN IF EXISTS eviction_reason;
+
+DROP TABLE IF EXISTS match_rejections;
@@ -0,0 +1,17 @@
+-- pre-settlement funds re-check (see: NatsService.HandleOrderMatches): a match with an underfunded side is not recorded nor settled,
+-- the underfunded intent is evicted (eviction_reason) and the CLOB reinstates the other side's qty - once per CLOB match ID
+CREATE TABLE IF NOT EXISTS match_rejections (
+  id SERIAL PRIMARY KEY,
+  clob_match_id TEXT UNIQUE DEFAULT NULL, -- NULL for matches published without a match ID
+  market_id UUID NOT NULL,
+  tx_id1 UUID NOT NULL, -- YES side
+  tx_id2 UUID NOT NULL, -- NO side
+  qty1 DOUBLE PRECISION NOT NULL,
+  qty2 DOUBLE PRECISION NOT NULL,
+  evicted_tx_ids UUID[] NOT NULL,
+  reinstated_tx_ids UUID[] NOT NULL DEFAULT '{}',
+  reason TEXT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE prediction_intents ADD COLUMN IF NOT EXISTS eviction_reason TEXT DEFAULT NULL;
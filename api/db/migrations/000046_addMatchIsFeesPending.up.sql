@@ -0,0 +1,3 @@
+-- a match whose fees could not be computed when it was recorded (e.g. db) is not settled with zero fees:
+-- its settlement is failed (transient) and the fees are computed again before it is retried
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS is_fees_pending BOOLEAN NOT NULL DEFAULT FALSE;
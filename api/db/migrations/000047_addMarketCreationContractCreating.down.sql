@@ -0,0 +1,4 @@
+ALTER TABLE market_creations DROP COLUMN IF EXISTS claimed_at;
+UPDATE market_creations SET step = 'pending' WHERE step = 'contract_creating';
+ALTER TABLE market_creations DROP CONSTRAINT IF EXISTS market_creations_step_check;
+ALTER TABLE market_creations ADD CONSTRAINT market_creations_step_check CHECK (step IN ('pending', 'contract_created', 'clob_created', 'completed'));
@@ -0,0 +1,6 @@
+-- the smart contract step of a market creation is claimed (pending -> contract_creating) before createNewMarket is called,
+-- so two concurrent attempts can't both create the market on-chain (and pay the creation fee twice).
+-- claimed_at: when it was claimed - a claim older than MARKET_CREATION_CLAIM_STALE_SECONDS was interrupted and can be claimed again
+ALTER TABLE market_creations DROP CONSTRAINT IF EXISTS market_creations_step_check;
+ALTER TABLE market_creations ADD CONSTRAINT market_creations_step_check CHECK (step IN ('pending', 'contract_creating', 'contract_created', 'clob_created', 'completed'));
+ALTER TABLE market_creations ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
@@ -1,3 +1,14 @@
+-- CREATE
+
+-- name: AddMarketCategory :exec
+INSERT INTO market_categories (market_id, category_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+
+
+
+
 -- READ
 
 -- name: GetCategories :many
@@ -10,3 +21,13 @@ SELECT *
 FROM categories
 WHERE is_active = TRUE
 ORDER BY sort_order, name;
+
+
+
+
+
+-- DELETE
+
+-- name: DeleteMarketCategories :exec
+DELETE FROM market_categories
+WHERE market_id = $1;
@@ -0,0 +1,39 @@
+-- CREATE
+
+-- name: CreateClobOutboxMessage :exec
+INSERT INTO clob_outbox (tx_id, subject)
+VALUES ($1, $2);
+
+
+
+
+
+
+
+
+-- READ
+
+-- name: GetUndeliveredClobOutboxMessages :many
+SELECT * FROM clob_outbox
+WHERE delivered_at IS NULL
+ORDER BY id ASC
+LIMIT $1;
+
+
+
+
+
+
+
+
+-- UPDATE
+
+-- name: MarkClobOutboxMessageAsDelivered :exec
+UPDATE clob_outbox
+SET delivered_at = CURRENT_TIMESTAMP, last_error = NULL
+WHERE id = $1;
+
+-- name: SetClobOutboxMessageError :exec
+UPDATE clob_outbox
+SET attempts = attempts + 1, last_error = $2
+WHERE id = $1;
@@ -0,0 +1,63 @@
+-- CREATE
+
+-- name: CreateConditionalIntent :one
+INSERT INTO conditional_intents (tx_id, market_id, account_id, trigger_condition, trigger_price_usd, intent)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+
+
+
+
+
+
+
+-- READ
+
+-- name: GetConditionalIntent :one
+SELECT * FROM conditional_intents
+WHERE tx_id = $1;
+
+-- name: GetPendingConditionalIntentsByMarketId :many
+SELECT * FROM conditional_intents
+WHERE market_id = $1 AND triggered_at IS NULL AND cancelled_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: GetPendingConditionalIntentsByAccountId :many
+SELECT * FROM conditional_intents
+WHERE account_id = sqlc.arg('account_id')
+AND (sqlc.narg('market_id')::UUID IS NULL OR market_id = sqlc.narg('market_id')::UUID)
+AND triggered_at IS NULL AND cancelled_at IS NULL
+ORDER BY created_at DESC;
+
+
+
+
+
+
+
+
+
+
+-- UPDATE
+
+-- name: MarkConditionalIntentAsTriggered :execrows
+-- claims the trigger - only one price tick can fire it
+UPDATE conditional_intents
+SET triggered_at = CURRENT_TIMESTAMP
+WHERE tx_id = $1 AND triggered_at IS NULL AND cancelled_at IS NULL;
+
+-- name: MarkConditionalIntentAsFailed :exec
+UPDATE conditional_intents
+SET failed_at = CURRENT_TIMESTAMP, failure_reason = $2
+WHERE tx_id = $1;
+
+-- name: CancelConditionalIntent :execrows
+UPDATE conditional_intents
+SET cancelled_at = CURRENT_TIMESTAMP
+WHERE tx_id = $1 AND triggered_at IS NULL AND cancelled_at IS NULL;
+
+-- name: CancelPendingConditionalIntentsByMarketId :execrows
+UPDATE conditional_intents
+SET cancelled_at = CURRENT_TIMESTAMP
+WHERE market_id = $1 AND triggered_at IS NULL AND cancelled_at IS NULL;
@@ -0,0 +1,43 @@
+-- CREATE
+
+-- name: CreateFeeSchedule :one
+INSERT INTO fee_schedules (net, market_id, maker_fee_bps, taker_fee_bps)
+VALUES (
+  sqlc.arg('net'),
+  sqlc.narg('market_id')::UUID,
+  sqlc.arg('maker_fee_bps'),
+  sqlc.arg('taker_fee_bps')
+)
+RETURNING *;
+
+
+
+
+
+
+
+
+-- READ
+
+-- name: GetFeeSchedules :many
+SELECT * FROM fee_schedules
+ORDER BY net ASC, market_id ASC NULLS FIRST;
+
+-- name: GetFeeSchedulesForMarket :many
+-- the network-wide row (market_id IS NULL) first, then the market's override
+SELECT * FROM fee_schedules
+WHERE net = $1 AND (market_id IS NULL OR market_id = $2)
+ORDER BY market_id ASC NULLS FIRST;
+
+
+
+
+
+
+
+
+-- DELETE
+
+-- name: DeleteFeeSchedule :execrows
+DELETE FROM fee_schedules
+WHERE net = sqlc.arg('net') AND market_id IS NOT DISTINCT FROM sqlc.narg('market_id')::UUID;
@@ -0,0 +1,64 @@
+-- CREATE
+
+-- name: CreateMarketCreation :exec
+INSERT INTO market_creations (market_id, net, smart_contract_id, request)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (market_id) DO NOTHING;
+
+
+
+
+
+
+
+
+-- READ
+
+-- name: GetMarketCreation :one
+SELECT * FROM market_creations
+WHERE market_id = $1;
+
+-- name: GetStalledMarketCreations :many
+SELECT * FROM market_creations
+WHERE step <> 'completed' AND flagged_at IS NULL AND updated_at <= CURRENT_TIMESTAMP - INTERVAL '5 minutes'
+ORDER BY created_at ASC;
+
+
+
+
+
+
+
+
+-- UPDATE
+
+-- name: StartMarketCreationAttempt :one
+UPDATE market_creations
+SET attempts = attempts + 1, updated_at = CURRENT_TIMESTAMP
+WHERE market_id = $1
+RETURNING *;
+
+-- name: ClaimMarketCreationContractStep :execrows
+-- 0 rows => another attempt is creating the market on the smart contract (or already did)
+UPDATE market_creations
+SET step = 'contract_creating', claimed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE market_id = sqlc.arg('market_id')
+AND (step = 'pending' OR (step = 'contract_creating' AND claimed_at < CURRENT_TIMESTAMP - make_interval(secs => sqlc.arg('stale_seconds')::INTEGER)));
+
+-- name: SetMarketCreationStep :exec
+UPDATE market_creations
+SET step = sqlc.arg('step'),
+    remaining_allowance = COALESCE(sqlc.narg('remaining_allowance')::BIGINT, remaining_allowance),
+    last_error = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE market_id = sqlc.arg('market_id');
+
+-- name: SetMarketCreationError :exec
+UPDATE market_creations
+SET last_error = $2, updated_at = CURRENT_TIMESTAMP
+WHERE market_id = $1;
+
+-- name: FlagMarketCreation :exec
+UPDATE market_creations
+SET flagged_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE market_id = $1;
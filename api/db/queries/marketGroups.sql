@@ -0,0 +1,40 @@
+-- CREATE
+
+-- name: CreateMarketGroup :exec
+INSERT INTO market_groups (group_id, net, statement, description, image_url, closes_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (group_id) DO NOTHING;
+
+
+
+
+
+
+
+
+-- READ
+
+-- name: GetMarketGroup :one
+SELECT * FROM market_groups
+WHERE group_id = $1;
+
+-- name: GetMarketsByGroupId :many
+SELECT * FROM markets
+WHERE group_id = $1
+ORDER BY created_at ASC;
+
+
+
+
+
+
+
+
+-- UPDATE
+
+-- name: SetMarketGroupWinner :one
+-- the winner can only be set once - re-running with the same winner is a no-op
+UPDATE market_groups
+SET winning_market_id = $2, resolved_at = COALESCE(resolved_at, CURRENT_TIMESTAMP), updated_at = CURRENT_TIMESTAMP
+WHERE group_id = $1 AND (winning_market_id IS NULL OR winning_market_id = $2)
+RETURNING *;
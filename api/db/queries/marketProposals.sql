@@ -0,0 +1,48 @@
+-- CREATE
+
+-- name: CreateMarketProposal :one
+INSERT INTO market_proposals (market_id, net, account_id, request, sig, public_key_hex, key_type)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+
+
+
+
+
+
+
+-- READ
+
+-- name: GetMarketProposal :one
+SELECT * FROM market_proposals
+WHERE market_id = $1;
+
+-- name: GetMarketProposals :many
+SELECT * FROM market_proposals
+WHERE (sqlc.narg('status')::TEXT IS NULL OR status = sqlc.narg('status')::TEXT)
+ORDER BY created_at ASC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+
+
+
+
+
+
+
+-- UPDATE
+
+-- name: ApproveMarketProposal :one
+UPDATE market_proposals
+SET status = 'approved', fee_charged = TRUE, reviewed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE market_id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: RejectMarketProposal :one
+-- a proposal whose market creation has already started can't be rejected any more
+UPDATE market_proposals
+SET status = 'rejected', reject_reason = $2, reviewed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE market_id = $1 AND status = 'pending'
+AND NOT EXISTS (SELECT 1 FROM market_creations WHERE market_creations.market_id = market_proposals.market_id)
+RETURNING *;
@@ -0,0 +1,59 @@
+-- CREATE
+
+-- name: CreateMarketTemplate :one
+INSERT INTO market_templates (template_id, name, net, statement_pattern, description, image_url, category_id, closes_after_seconds, schedule, is_active)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: CreateMarketTemplateRun :exec
+-- a retried run keeps the marketId picked the first time
+INSERT INTO market_template_runs (template_id, scheduled_at, market_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (template_id, scheduled_at) DO NOTHING;
+
+
+
+
+
+
+
+
+-- READ
+
+-- name: GetMarketTemplate :one
+SELECT * FROM market_templates
+WHERE template_id = $1;
+
+-- name: GetMarketTemplates :many
+SELECT * FROM market_templates
+ORDER BY name ASC;
+
+-- name: GetActiveMarketTemplates :many
+SELECT * FROM market_templates
+WHERE is_active = TRUE
+ORDER BY created_at ASC;
+
+-- name: GetMarketTemplateRun :one
+SELECT * FROM market_template_runs
+WHERE template_id = $1 AND scheduled_at = $2;
+
+
+
+
+
+
+
+
+-- UPDATE
+
+-- name: UpdateMarketTemplate :one
+UPDATE market_templates
+SET name = $2, net = $3, statement_pattern = $4, description = $5, image_url = $6, category_id = $7, closes_after_seconds = $8, schedule = $9, is_active = $10, updated_at = CURRENT_TIMESTAMP
+WHERE template_id = $1
+RETURNING *;
+
+-- name: SetMarketTemplateLastRunAt :exec
+-- never moves backwards
+UPDATE market_templates
+SET last_run_at = $2, updated_at = CURRENT_TIMESTAMP
+WHERE template_id = $1 AND (last_run_at IS NULL OR last_run_at < $2);
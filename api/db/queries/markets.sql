@@ -1,8 +1,8 @@
 -- CREATE
 
 -- name: CreateMarket :one
-INSERT INTO markets (market_id, net, statement, image_url, smart_contract_id, closes_at, description, is_paused, created_at, resolved_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, FALSE, CURRENT_TIMESTAMP, NULL)
+INSERT INTO markets (market_id, net, statement, image_url, smart_contract_id, closes_at, description, group_id, is_paused, created_at, resolved_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, FALSE, CURRENT_TIMESTAMP, NULL)
 RETURNING *;
 
 
@@ -18,21 +18,76 @@ RETURNING *;
 SELECT * FROM markets
 WHERE market_id = $1 AND is_suspended = FALSE;
 
--- name: GetMarkets :many
+-- name: GetMarketIncludingSuspended :one
+-- admin and settlement paths - a suspended market must still be resolved, voided and have its matches settled
 SELECT * FROM markets
-WHERE is_suspended = FALSE
-ORDER BY created_at DESC
-LIMIT $1 OFFSET $2;
+WHERE market_id = $1;
+
+-- name: GetMarkets :many
+-- keep the WHERE clause in sync with CountMarkets
+SELECT markets.* FROM markets
+WHERE markets.is_suspended = FALSE
+AND (sqlc.narg('category_id')::INTEGER IS NULL OR markets.market_id IN (
+  SELECT market_id FROM market_categories WHERE category_id = sqlc.narg('category_id')::INTEGER
+))
+AND (sqlc.narg('net')::TEXT IS NULL OR markets.net = sqlc.narg('net')::TEXT)
+AND (sqlc.narg('search')::TEXT IS NULL OR to_tsvector('english', markets.statement || ' ' || COALESCE(markets.description, '')) @@ websearch_to_tsquery('english', sqlc.narg('search')::TEXT)) -- uses markets_search_idx
+AND (sqlc.narg('closes_after')::TIMESTAMPTZ IS NULL OR markets.closes_at >= sqlc.narg('closes_after')::TIMESTAMPTZ)
+AND (sqlc.narg('closes_before')::TIMESTAMPTZ IS NULL OR markets.closes_at < sqlc.narg('closes_before')::TIMESTAMPTZ)
+AND (
+  sqlc.narg('status')::TEXT IS NULL
+  OR (sqlc.narg('status')::TEXT = 'open' AND markets.resolved_at IS NULL AND markets.voided_at IS NULL AND markets.closed_at IS NULL AND markets.closes_at > CURRENT_TIMESTAMP AND markets.is_paused = FALSE)
+  OR (sqlc.narg('status')::TEXT = 'paused' AND markets.resolved_at IS NULL AND markets.voided_at IS NULL AND markets.closed_at IS NULL AND markets.is_paused = TRUE)
+  OR (sqlc.narg('status')::TEXT = 'closed' AND markets.resolved_at IS NULL AND markets.voided_at IS NULL AND (markets.closed_at IS NOT NULL OR markets.closes_at <= CURRENT_TIMESTAMP))
+  OR (sqlc.narg('status')::TEXT = 'resolved' AND markets.resolved_at IS NOT NULL)
+  OR (sqlc.narg('status')::TEXT = 'voided' AND markets.voided_at IS NOT NULL)
+)
+ORDER BY
+  CASE WHEN sqlc.arg('sort')::TEXT = 'closing_soonest' THEN markets.closes_at END ASC,
+  CASE WHEN sqlc.arg('sort')::TEXT = 'volume_24h' THEN ( -- every matched YES/NO pair is backed by $1
+    SELECT COALESCE(SUM(matches.matched_qty), 0) FROM matches
+    WHERE matches.market_id = markets.market_id AND matches.created_at >= CURRENT_TIMESTAMP - INTERVAL '24 hours'
+  ) END DESC,
+  CASE WHEN sqlc.arg('sort')::TEXT = 'price_move_24h' THEN ABS(
+    COALESCE((SELECT price FROM price_history WHERE price_history.market_id = markets.market_id ORDER BY ts DESC LIMIT 1), 0.5)
+    - COALESCE((SELECT price FROM price_history WHERE price_history.market_id = markets.market_id AND ts <= CURRENT_TIMESTAMP - INTERVAL '24 hours' ORDER BY ts DESC LIMIT 1), 0.5)
+  ) END DESC,
+  markets.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountMarkets :one
+SELECT COUNT(*) FROM markets
+WHERE markets.is_suspended = FALSE
+AND (sqlc.narg('category_id')::INTEGER IS NULL OR markets.market_id IN (
+  SELECT market_id FROM market_categories WHERE category_id = sqlc.narg('category_id')::INTEGER
+))
+AND (sqlc.narg('net')::TEXT IS NULL OR markets.net = sqlc.narg('net')::TEXT)
+AND (sqlc.narg('search')::TEXT IS NULL OR to_tsvector('english', markets.statement || ' ' || COALESCE(markets.description, '')) @@ websearch_to_tsquery('english', sqlc.narg('search')::TEXT)) -- uses markets_search_idx
+AND (sqlc.narg('closes_after')::TIMESTAMPTZ IS NULL OR markets.closes_at >= sqlc.narg('closes_after')::TIMESTAMPTZ)
+AND (sqlc.narg('closes_before')::TIMESTAMPTZ IS NULL OR markets.closes_at < sqlc.narg('closes_before')::TIMESTAMPTZ)
+AND (
+  sqlc.narg('status')::TEXT IS NULL
+  OR (sqlc.narg('status')::TEXT = 'open' AND markets.resolved_at IS NULL AND markets.voided_at IS NULL AND markets.closed_at IS NULL AND markets.closes_at > CURRENT_TIMESTAMP AND markets.is_paused = FALSE)
+  OR (sqlc.narg('status')::TEXT = 'paused' AND markets.resolved_at IS NULL AND markets.voided_at IS NULL AND markets.closed_at IS NULL AND markets.is_paused = TRUE)
+  OR (sqlc.narg('status')::TEXT = 'closed' AND markets.resolved_at IS NULL AND markets.voided_at IS NULL AND (markets.closed_at IS NOT NULL OR markets.closes_at <= CURRENT_TIMESTAMP))
+  OR (sqlc.narg('status')::TEXT = 'resolved' AND markets.resolved_at IS NOT NULL)
+  OR (sqlc.narg('status')::TEXT = 'voided' AND markets.voided_at IS NOT NULL)
+);
 
 -- name: GetAllUnresolvedMarkets :many
 SELECT * FROM markets
-WHERE resolved_at IS NULL AND closes_at > CURRENT_TIMESTAMP AND is_suspended = FALSE
+WHERE resolved_at IS NULL AND voided_at IS NULL AND closes_at > CURRENT_TIMESTAMP AND is_suspended = FALSE
 ORDER BY created_at ASC;
 -- LIMIT $1 OFFSET $2;
 
 -- name: CountUnresolvedMarkets :one
 SELECT COUNT(*) FROM markets
-WHERE resolved_at IS NULL AND closes_at > CURRENT_TIMESTAMP AND is_suspended = FALSE;
+WHERE resolved_at IS NULL AND voided_at IS NULL AND closes_at > CURRENT_TIMESTAMP AND is_suspended = FALSE;
+
+-- name: GetMarketsPastClosesAt :many
+SELECT * FROM markets
+WHERE closed_at IS NULL AND resolved_at IS NULL AND voided_at IS NULL AND closes_at <= CURRENT_TIMESTAMP
+ORDER BY closes_at ASC;
 
 
 
@@ -40,6 +95,37 @@ WHERE resolved_at IS NULL AND closes_at > CURRENT_TIMESTAMP AND is_suspended = F
 
 -- UPDATE
 
+-- name: ResolveMarket :one
+UPDATE markets
+SET resolved_at = CURRENT_TIMESTAMP, outcome = $2, updated_at = CURRENT_TIMESTAMP
+WHERE market_id = $1 AND resolved_at IS NULL AND voided_at IS NULL
+RETURNING *;
+
+-- name: VoidMarket :one
+UPDATE markets
+SET voided_at = CURRENT_TIMESTAMP, void_reason = $2, updated_at = CURRENT_TIMESTAMP
+WHERE market_id = $1 AND resolved_at IS NULL AND voided_at IS NULL
+RETURNING *;
+
+-- name: SetMarketPaused :one
+UPDATE markets
+SET is_paused = $2, updated_at = CURRENT_TIMESTAMP
+WHERE market_id = $1 AND is_suspended = FALSE
+RETURNING *;
+
+-- name: SuspendMarket :one
+-- a suspended market can't be resumed - it is no longer paused (its held settlements are released)
+UPDATE markets
+SET is_suspended = TRUE, is_paused = FALSE, updated_at = CURRENT_TIMESTAMP
+WHERE market_id = $1
+RETURNING *;
+
+-- name: CloseMarket :one
+UPDATE markets
+SET closed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE market_id = $1 AND closed_at IS NULL
+RETURNING *;
+
 
 
 
@@ -2,21 +2,59 @@
 
 
 -- name: CreateMatch :one
-INSERT INTO matches (market_id, tx_id1, tx_id2, qty1, qty2, tx_hash)
-VALUES ($1, $2, $3, $4, $5, $6)
+-- no row => the CLOB match ID was already recorded (a redelivered match)
+-- both sides are published with their qty before the match - the matched qty is the smaller one
+INSERT INTO matches (market_id, tx_id1, tx_id2, qty1, qty2, tx_hash, maker_tx_id, fee_usd1, fee_usd2, clob_match_id, is_fees_pending, matched_qty)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, LEAST($4, $5))
+ON CONFLICT (clob_match_id) DO NOTHING
 RETURNING *;
 
+-- name: CreateMatchRejection :execrows
+-- 0 rows => the CLOB match ID was already rejected (a redelivered match)
+INSERT INTO match_rejections (clob_match_id, market_id, tx_id1, tx_id2, qty1, qty2, evicted_tx_ids, reinstated_tx_ids, reason)
+VALUES (sqlc.narg('clob_match_id'), sqlc.arg('market_id'), sqlc.arg('tx_id1'), sqlc.arg('tx_id2'), sqlc.arg('qty1'), sqlc.arg('qty2'), sqlc.arg('evicted_tx_ids')::UUID[], sqlc.arg('reinstated_tx_ids')::UUID[], sqlc.arg('reason'))
+ON CONFLICT (clob_match_id) DO NOTHING;
+
 
 
 
 
 -- READ
 
--- name: GetAllMatchesForMarketIdTxId :many
+-- name: IsClobMatchIdProcessed :one
+-- recorded (and settled) or rejected already
+SELECT (EXISTS (SELECT 1 FROM matches WHERE clob_match_id = sqlc.arg('clob_match_id')::TEXT)
+  OR EXISTS (SELECT 1 FROM match_rejections WHERE clob_match_id = sqlc.arg('clob_match_id')::TEXT))::BOOLEAN AS is_processed;
+
+-- name: GetMatchById :one
+SELECT *
+FROM matches
+WHERE id = $1;
+
+-- name: GetMatchesByTxIds :many
 SELECT *
 FROM matches
-WHERE market_id = $1 AND (tx_id1 = $2 OR tx_id2 = $2)
-ORDER BY created_at DESC;
+WHERE tx_id1 = ANY(sqlc.arg('tx_ids')::uuid[]) OR tx_id2 = ANY(sqlc.arg('tx_ids')::uuid[])
+ORDER BY created_at ASC;
+
+-- name: GetTotalFeesUsdSince :one
+SELECT COALESCE(SUM(fee_usd1 + fee_usd2), 0.0)::DOUBLE PRECISION AS total_fees_usd
+FROM matches
+WHERE created_at >= sqlc.arg('since')::TIMESTAMPTZ;
+
+-- name: GetHeldMatchesByMarketId :many
+SELECT *
+FROM matches
+WHERE market_id = $1 AND held_at IS NOT NULL
+ORDER BY created_at ASC;
+
+-- name: GetMatchesDueForSettlementRetry :many
+SELECT *
+FROM matches
+WHERE held_at IS NULL
+AND id IN (SELECT match_id FROM settlements WHERE status = 'failed' AND next_attempt_at <= CURRENT_TIMESTAMP)
+ORDER BY created_at ASC
+LIMIT $1;
 
 
 
@@ -32,4 +70,20 @@ ORDER BY created_at DESC;
 -- name: UpdateMatchTxHash :exec
 UPDATE matches
 SET tx_hash = $4
-WHERE (market_id = $1 AND tx_id1 = $2 AND tx_id2 = $3) OR (market_id = $1 AND tx_id1 = $3 AND tx_id2 = $2);
\ No newline at end of file
+WHERE (market_id = $1 AND tx_id1 = $2 AND tx_id2 = $3) OR (market_id = $1 AND tx_id1 = $3 AND tx_id2 = $2);
+
+-- name: SetMatchFees :exec
+-- the fees of a match recorded with is_fees_pending (see: NatsService.settleMatch)
+UPDATE matches
+SET maker_tx_id = $2, fee_usd1 = $3, fee_usd2 = $4, is_fees_pending = FALSE
+WHERE id = $1;
+
+-- name: HoldMatch :exec
+UPDATE matches
+SET held_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: ReleaseHeldMatch :exec
+UPDATE matches
+SET held_at = NULL
+WHERE id = $1;
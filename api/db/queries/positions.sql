@@ -24,6 +24,7 @@ SELECT
   evm_address,
   n_yes,
   n_no,
+  refund,
   updated_at
 FROM positions
 WHERE evm_address = $1;
@@ -34,6 +35,7 @@ SELECT
   evm_address,
   n_yes,
   n_no,
+  refund,
   updated_at
 FROM positions
 WHERE evm_address = $1 AND market_id = $2;
@@ -46,3 +48,8 @@ WHERE updated_at >= NOW() - INTERVAL '30 days';
 
 -- UPDATE
 
+-- name: SetPositionRefundsByMarketId :execrows
+-- voided market: every YES and NO token is refunded at 50% (same scale as n_yes/n_no)
+UPDATE positions
+SET refund = (n_yes + n_no) / 2, updated_at = CURRENT_TIMESTAMP
+WHERE market_id = $1;
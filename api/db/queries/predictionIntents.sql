@@ -1,8 +1,8 @@
 -- CREATE
 
 -- name: CreatePredictionIntent :one
-INSERT INTO prediction_intents (tx_id, net, market_id, account_id, market_limit, price_usd, qty, sig, public_key_hex, evmaddress, keytype, generated_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+INSERT INTO prediction_intents (tx_id, net, market_id, account_id, market_limit, price_usd, qty, sig, public_key_hex, evmaddress, keytype, generated_at, replaces_tx_id, time_in_force, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 RETURNING *;
 
 
@@ -15,29 +15,75 @@ RETURNING *;
 SELECT *
 FROM prediction_intents
 WHERE market_id = $1 
-AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL;
+AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL;
 
 -- name: GetAllOpenPredictionIntentsByMarketIdAndAccountId :many
 SELECT *
 FROM prediction_intents
 WHERE market_id = $1 AND account_id = $2 
-AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL
+AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL
 ORDER BY account_id;
 
 -- name: GetAllAccountIdsForMarketId :many
 SELECT DISTINCT account_id
 FROM prediction_intents
 WHERE market_id = $1 
-AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL;
+AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL;
 
 -- name: GetAllOpenPredictionIntentsByEvmAddress :many
 SELECT *
 FROM prediction_intents
 WHERE evmaddress = $1 
-AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL;
+AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL;
 
 
 
+-- name: GetExpiredPredictionIntents :many
+SELECT *
+FROM prediction_intents
+WHERE expires_at IS NOT NULL AND expires_at <= CURRENT_TIMESTAMP
+AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL
+ORDER BY expires_at;
+
+-- name: GetPredictionIntents :many
+-- state: open | filled | evicted | expired | cancelled (NULL => every state)
+-- keep the state precedence in sync with getPredictionIntentState (an evicted intent is also cancelled)
+SELECT * FROM prediction_intents
+WHERE (sqlc.narg('account_id')::TEXT IS NULL OR account_id = sqlc.narg('account_id')::TEXT)
+AND (sqlc.narg('market_id')::UUID IS NULL OR market_id = sqlc.narg('market_id')::UUID)
+AND (
+  sqlc.narg('state')::TEXT IS NULL
+  OR (sqlc.narg('state')::TEXT = 'open' AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL)
+  OR (sqlc.narg('state')::TEXT = 'filled' AND fully_matched_at IS NOT NULL)
+  OR (sqlc.narg('state')::TEXT = 'evicted' AND fully_matched_at IS NULL AND evicted_at IS NOT NULL)
+  OR (sqlc.narg('state')::TEXT = 'expired' AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NOT NULL)
+  OR (sqlc.narg('state')::TEXT = 'cancelled' AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL AND cancelled_at IS NOT NULL)
+)
+ORDER BY created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetPredictionIntentByTxId :one
+SELECT *
+FROM prediction_intents
+WHERE tx_id = $1;
+
+-- name: GetOpenPredictionIntentExposure :one
+-- the account's open intents on the network, for the pre-trade risk checks (see: RiskService)
+SELECT
+  (COUNT(*) FILTER (WHERE market_id = sqlc.arg('market_id')::UUID))::INTEGER AS n_open_in_market,
+  (COALESCE(SUM(remaining_qty) FILTER (WHERE market_id = sqlc.arg('market_id')::UUID AND price_usd >= 0), 0))::DOUBLE PRECISION AS qty_yes_in_market,
+  (COALESCE(SUM(remaining_qty) FILTER (WHERE market_id = sqlc.arg('market_id')::UUID AND price_usd < 0), 0))::DOUBLE PRECISION AS qty_no_in_market,
+  (COALESCE(SUM(ABS(price_usd) * remaining_qty), 0))::DOUBLE PRECISION AS notional_usd
+FROM prediction_intents
+WHERE account_id = sqlc.arg('account_id') AND net = sqlc.arg('net')
+AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL;
+
+-- name: GetOpenNotionalUsdByNet :one
+SELECT (COALESCE(SUM(ABS(price_usd) * remaining_qty), 0))::DOUBLE PRECISION AS notional_usd
+FROM prediction_intents
+WHERE net = $1
+AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL;
+
 -- name: IsDuplicateTxId :one
 SELECT COUNT(*) > 0 AS exists
 FROM prediction_intents
@@ -54,22 +100,57 @@ UPDATE prediction_intents
 SET regenerated_at = CURRENT_TIMESTAMP
 WHERE tx_id = $1;
 
--- name: MarkPredictionIntentAsFullyMatched :one
+-- name: AddPredictionIntentFill :one
+-- called in the same transaction as CreateMatch - the intent is fully matched once nothing remains
 UPDATE prediction_intents
-SET fully_matched_at = CURRENT_TIMESTAMP
-WHERE market_id = $1 AND tx_id = $2
+SET filled_qty = LEAST(qty, filled_qty + sqlc.arg('matched_qty')::DOUBLE PRECISION),
+    fully_matched_at = CASE
+      WHEN fully_matched_at IS NULL AND filled_qty + sqlc.arg('matched_qty')::DOUBLE PRECISION >= qty THEN CURRENT_TIMESTAMP
+      ELSE fully_matched_at
+    END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE tx_id = sqlc.arg('tx_id')
 RETURNING *;
 
+-- name: ReleasePredictionIntentFill :one
+-- the match was abandoned - give the qty back, and reopen the intent only if nothing else closed it
+UPDATE prediction_intents
+SET filled_qty = GREATEST(filled_qty - sqlc.arg('matched_qty')::DOUBLE PRECISION, 0),
+    fully_matched_at = CASE
+      WHEN cancelled_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL THEN NULL
+      ELSE fully_matched_at
+    END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE tx_id = sqlc.arg('tx_id')
+RETURNING *;
+
+-- name: MarkPredictionIntentAsExpired :execrows
+UPDATE prediction_intents
+SET expired_at = CURRENT_TIMESTAMP
+WHERE tx_id = $1 AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL;
+
 -- name: MarkPredictionIntentAsEvicted :exec
 UPDATE prediction_intents
-SET evicted_at = CURRENT_TIMESTAMP
-WHERE tx_id = $1;
+SET evicted_at = CURRENT_TIMESTAMP, eviction_reason = sqlc.arg('eviction_reason')::TEXT
+WHERE tx_id = sqlc.arg('tx_id');
 
 
 
 -- DELETE
 
--- name: CancelPredictionIntent :exec
+-- name: CancelPredictionIntent :execrows
 UPDATE prediction_intents
 SET cancelled_at = CURRENT_TIMESTAMP
-WHERE tx_id = $1 AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL;
+WHERE tx_id = $1 AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL;
+
+-- name: CancelPredictionIntents :many
+UPDATE prediction_intents
+SET cancelled_at = CURRENT_TIMESTAMP
+WHERE tx_id = ANY($1::uuid[]) AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL
+RETURNING tx_id;
+
+-- name: CancelAllOpenPredictionIntentsByMarketId :many
+UPDATE prediction_intents
+SET cancelled_at = CURRENT_TIMESTAMP
+WHERE market_id = $1 AND cancelled_at IS NULL AND fully_matched_at IS NULL AND evicted_at IS NULL AND expired_at IS NULL
+RETURNING *;
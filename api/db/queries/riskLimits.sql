@@ -0,0 +1,47 @@
+-- CREATE
+
+-- name: CreateRiskLimits :one
+INSERT INTO risk_limits (net, market_id, max_open_intents_per_account, max_position_qty, min_price_usd, max_price_usd, max_notional_usd_per_account, max_open_notional_usd)
+VALUES (
+  sqlc.arg('net'),
+  sqlc.narg('market_id')::UUID,
+  sqlc.narg('max_open_intents_per_account')::INTEGER,
+  sqlc.narg('max_position_qty')::DOUBLE PRECISION,
+  sqlc.narg('min_price_usd')::DOUBLE PRECISION,
+  sqlc.narg('max_price_usd')::DOUBLE PRECISION,
+  sqlc.narg('max_notional_usd_per_account')::DOUBLE PRECISION,
+  sqlc.narg('max_open_notional_usd')::DOUBLE PRECISION
+)
+RETURNING *;
+
+
+
+
+
+
+
+
+-- READ
+
+-- name: GetRiskLimits :many
+SELECT * FROM risk_limits
+ORDER BY net ASC, market_id ASC NULLS FIRST;
+
+-- name: GetRiskLimitsForMarket :many
+-- the network-wide row (market_id IS NULL) first, then the market's override
+SELECT * FROM risk_limits
+WHERE net = $1 AND (market_id IS NULL OR market_id = $2)
+ORDER BY market_id ASC NULLS FIRST;
+
+
+
+
+
+
+
+
+-- DELETE
+
+-- name: DeleteRiskLimits :execrows
+DELETE FROM risk_limits
+WHERE net = sqlc.arg('net') AND market_id IS NOT DISTINCT FROM sqlc.narg('market_id')::UUID;
@@ -0,0 +1,104 @@
+-- CREATE
+
+-- name: CreateSettlement :exec
+INSERT INTO settlements (match_id)
+VALUES ($1)
+ON CONFLICT (match_id) DO NOTHING;
+
+
+
+
+
+
+
+
+-- READ
+
+-- name: GetSettlements :many
+-- by match, by intent (either side of the match) and/or by status
+SELECT settlements.*, matches.market_id, matches.tx_id1, matches.tx_id2, matches.held_at
+FROM settlements
+JOIN matches ON matches.id = settlements.match_id
+WHERE (sqlc.narg('match_id')::INTEGER IS NULL OR settlements.match_id = sqlc.narg('match_id')::INTEGER)
+AND (sqlc.narg('tx_id')::UUID IS NULL OR matches.tx_id1 = sqlc.narg('tx_id')::UUID OR matches.tx_id2 = sqlc.narg('tx_id')::UUID)
+AND (sqlc.narg('status')::TEXT IS NULL OR settlements.status = sqlc.narg('status')::TEXT)
+ORDER BY settlements.created_at ASC;
+
+-- name: GetSettlementsByMatchIds :many
+SELECT *
+FROM settlements
+WHERE match_id = ANY(sqlc.arg('match_ids')::INTEGER[]);
+
+
+
+
+
+
+
+
+-- UPDATE
+
+-- name: MarkSettlementAsSubmitted :exec
+UPDATE settlements
+SET status = 'submitted', hedera_tx_id = $2, attempts = attempts + 1, error = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE match_id = $1;
+
+-- name: MarkSettlementAsConfirmed :exec
+UPDATE settlements
+SET status = 'confirmed', receipt_status = $2, gas_used = $3, error = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE match_id = $1;
+
+-- name: MarkSettlementAsFailed :one
+-- a failure before submission (status pending) counts as an attempt too
+-- transient failures are retried after backoff_seconds * 2^(attempts - 1) (capped) - permanent failures and the last attempt are dead-lettered
+-- receipt_status is kept if the failure happened before a receipt was available
+UPDATE settlements
+SET attempts = attempts + (CASE WHEN status = 'pending' THEN 1 ELSE 0 END),
+    status = CASE
+      WHEN sqlc.arg('is_permanent')::BOOLEAN OR attempts + (CASE WHEN status = 'pending' THEN 1 ELSE 0 END) >= sqlc.arg('max_attempts')::INTEGER THEN 'dead_lettered'
+      ELSE 'failed'
+    END,
+    next_attempt_at = CASE
+      WHEN sqlc.arg('is_permanent')::BOOLEAN OR attempts + (CASE WHEN status = 'pending' THEN 1 ELSE 0 END) >= sqlc.arg('max_attempts')::INTEGER THEN NULL
+      ELSE CURRENT_TIMESTAMP + make_interval(secs => LEAST(sqlc.arg('backoff_seconds')::INTEGER * power(2, GREATEST(attempts + (CASE WHEN status = 'pending' THEN 1 ELSE 0 END) - 1, 0)), sqlc.arg('max_backoff_seconds')::INTEGER))
+    END,
+    dead_lettered_at = CASE
+      WHEN sqlc.arg('is_permanent')::BOOLEAN OR attempts + (CASE WHEN status = 'pending' THEN 1 ELSE 0 END) >= sqlc.arg('max_attempts')::INTEGER THEN CURRENT_TIMESTAMP
+      ELSE NULL
+    END,
+    receipt_status = COALESCE(sqlc.narg('receipt_status')::TEXT, receipt_status),
+    error = sqlc.arg('error')::TEXT,
+    updated_at = CURRENT_TIMESTAMP
+WHERE match_id = sqlc.arg('match_id')
+RETURNING *;
+
+-- name: ClaimSettlementForRetry :execrows
+-- back to pending - only one of the retry worker / an admin retry gets the claim
+UPDATE settlements
+SET status = 'pending', next_attempt_at = NULL, dead_lettered_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE match_id = $1 AND status IN ('failed', 'dead_lettered');
+
+-- name: FailStaleSettlements :many
+-- pending (and not held) or submitted for longer than stale_seconds: the API stopped mid-settlement (e.g. a restart) -
+-- hand them to the retry worker, which looks up the outcome of a submitted transaction before it resubmits anything.
+-- Only matches with a CLOB match ID: never a row backfilled from before settlements were tracked (see: 000041)
+UPDATE settlements
+SET status = 'failed',
+    next_attempt_at = CURRENT_TIMESTAMP,
+    error = 'interrupted while ' || status || ' (stale for over ' || sqlc.arg('stale_seconds')::INTEGER || 's)',
+    updated_at = CURRENT_TIMESTAMP
+WHERE status IN ('pending', 'submitted')
+AND updated_at < CURRENT_TIMESTAMP - make_interval(secs => sqlc.arg('stale_seconds')::INTEGER)
+AND match_id IN (SELECT id FROM matches WHERE held_at IS NULL AND clob_match_id IS NOT NULL)
+RETURNING match_id;
+
+-- name: PostponeSettlementRetry :exec
+-- the outcome of its last transaction is not known yet - look again later, without counting an attempt
+UPDATE settlements
+SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => sqlc.arg('delay_seconds')::INTEGER), updated_at = CURRENT_TIMESTAMP
+WHERE match_id = sqlc.arg('match_id') AND status = 'failed';
+
+-- name: MarkSettlementAsAbandoned :execrows
+UPDATE settlements
+SET status = 'abandoned', abandoned_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE match_id = $1 AND status = 'dead_lettered';
@@ -114,6 +114,45 @@ ALTER SEQUENCE public.categories_id_seq OWNER TO your_db_user;
 ALTER SEQUENCE public.categories_id_seq OWNED BY public.categories.id;
 
 
+--
+-- Name: clob_outbox; Type: TABLE; Schema: public; Owner: your_db_user
+--
+
+CREATE TABLE public.clob_outbox (
+    id integer NOT NULL,
+    tx_id uuid NOT NULL,
+    subject text NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    last_error text,
+    delivered_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+ALTER TABLE public.clob_outbox OWNER TO your_db_user;
+
+--
+-- Name: clob_outbox_id_seq; Type: SEQUENCE; Schema: public; Owner: your_db_user
+--
+
+CREATE SEQUENCE public.clob_outbox_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+ALTER SEQUENCE public.clob_outbox_id_seq OWNER TO your_db_user;
+
+--
+-- Name: clob_outbox_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: your_db_user
+--
+
+ALTER SEQUENCE public.clob_outbox_id_seq OWNED BY public.clob_outbox.id;
+
+
 --
 -- Name: comments; Type: TABLE; Schema: public; Owner: your_db_user
 --
@@ -154,6 +193,71 @@ ALTER SEQUENCE public.comments_comment_id_seq OWNER TO your_db_user;
 ALTER SEQUENCE public.comments_comment_id_seq OWNED BY public.comments.comment_id;
 
 
+--
+-- Name: conditional_intents; Type: TABLE; Schema: public; Owner: your_db_user
+--
+
+CREATE TABLE public.conditional_intents (
+    tx_id uuid NOT NULL,
+    market_id uuid NOT NULL,
+    account_id text NOT NULL,
+    trigger_condition text NOT NULL,
+    trigger_price_usd double precision NOT NULL,
+    intent jsonb NOT NULL,
+    triggered_at timestamp with time zone,
+    cancelled_at timestamp with time zone,
+    failed_at timestamp with time zone,
+    failure_reason text,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT conditional_intents_account_id_check CHECK ((length(account_id) >= 5)),
+    CONSTRAINT conditional_intents_trigger_condition_check CHECK ((trigger_condition = ANY (ARRAY['above'::text, 'below'::text]))),
+    CONSTRAINT conditional_intents_trigger_price_usd_check CHECK (((trigger_price_usd > (0.0)::double precision) AND (trigger_price_usd < (1.0)::double precision)))
+);
+
+
+ALTER TABLE public.conditional_intents OWNER TO your_db_user;
+
+--
+-- Name: fee_schedules; Type: TABLE; Schema: public; Owner: your_db_user
+--
+
+CREATE TABLE public.fee_schedules (
+    id integer NOT NULL,
+    net text NOT NULL,
+    market_id uuid,
+    maker_fee_bps integer DEFAULT 0 NOT NULL,
+    taker_fee_bps integer DEFAULT 0 NOT NULL,
+    updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fee_schedules_maker_fee_bps_check CHECK (((maker_fee_bps >= 0) AND (maker_fee_bps <= 1000))),
+    CONSTRAINT fee_schedules_net_check CHECK ((net = ANY (ARRAY['testnet'::text, 'mainnet'::text, 'previewnet'::text]))),
+    CONSTRAINT fee_schedules_taker_fee_bps_check CHECK (((taker_fee_bps >= 0) AND (taker_fee_bps <= 1000)))
+);
+
+
+ALTER TABLE public.fee_schedules OWNER TO your_db_user;
+
+--
+-- Name: fee_schedules_id_seq; Type: SEQUENCE; Schema: public; Owner: your_db_user
+--
+
+CREATE SEQUENCE public.fee_schedules_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+ALTER SEQUENCE public.fee_schedules_id_seq OWNER TO your_db_user;
+
+--
+-- Name: fee_schedules_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: your_db_user
+--
+
+ALTER SEQUENCE public.fee_schedules_id_seq OWNED BY public.fee_schedules.id;
+
+
 --
 -- Name: market_categories; Type: TABLE; Schema: public; Owner: your_db_user
 --
@@ -166,6 +270,117 @@ CREATE TABLE public.market_categories (
 
 ALTER TABLE public.market_categories OWNER TO your_db_user;
 
+--
+-- Name: market_creations; Type: TABLE; Schema: public; Owner: your_db_user
+--
+
+CREATE TABLE public.market_creations (
+    market_id uuid NOT NULL,
+    net text NOT NULL,
+    smart_contract_id text NOT NULL,
+    request jsonb NOT NULL,
+    step text DEFAULT 'pending'::text NOT NULL,
+    remaining_allowance bigint,
+    attempts integer DEFAULT 0 NOT NULL,
+    last_error text,
+    flagged_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    claimed_at timestamp with time zone,
+    CONSTRAINT market_creations_net_check CHECK ((net = ANY (ARRAY['testnet'::text, 'mainnet'::text, 'previewnet'::text]))),
+    CONSTRAINT market_creations_step_check CHECK ((step = ANY (ARRAY['pending'::text, 'contract_creating'::text, 'contract_created'::text, 'clob_created'::text, 'completed'::text])))
+);
+
+
+ALTER TABLE public.market_creations OWNER TO your_db_user;
+
+--
+-- Name: market_groups; Type: TABLE; Schema: public; Owner: your_db_user
+--
+
+CREATE TABLE public.market_groups (
+    group_id uuid NOT NULL,
+    net text NOT NULL,
+    statement text NOT NULL,
+    description text,
+    image_url text,
+    closes_at timestamp with time zone,
+    winning_market_id uuid,
+    resolved_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT market_groups_net_check CHECK ((net = ANY (ARRAY['testnet'::text, 'mainnet'::text, 'previewnet'::text])))
+);
+
+
+ALTER TABLE public.market_groups OWNER TO your_db_user;
+
+--
+-- Name: market_proposals; Type: TABLE; Schema: public; Owner: your_db_user
+--
+
+CREATE TABLE public.market_proposals (
+    market_id uuid NOT NULL,
+    net text NOT NULL,
+    account_id text NOT NULL,
+    request jsonb NOT NULL,
+    sig text NOT NULL,
+    public_key_hex text NOT NULL,
+    key_type integer NOT NULL,
+    status text DEFAULT 'pending'::text NOT NULL,
+    reject_reason text,
+    fee_charged boolean DEFAULT false NOT NULL,
+    reviewed_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT market_proposals_account_id_check CHECK ((length(account_id) >= 5)),
+    CONSTRAINT market_proposals_key_type_check CHECK ((key_type = ANY (ARRAY[1, 2]))),
+    CONSTRAINT market_proposals_net_check CHECK ((net = ANY (ARRAY['testnet'::text, 'mainnet'::text, 'previewnet'::text]))),
+    CONSTRAINT market_proposals_status_check CHECK ((status = ANY (ARRAY['pending'::text, 'approved'::text, 'rejected'::text])))
+);
+
+
+ALTER TABLE public.market_proposals OWNER TO your_db_user;
+
+--
+-- Name: market_template_runs; Type: TABLE; Schema: public; Owner: your_db_user
+--
+
+CREATE TABLE public.market_template_runs (
+    template_id uuid NOT NULL,
+    scheduled_at timestamp with time zone NOT NULL,
+    market_id uuid NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+ALTER TABLE public.market_template_runs OWNER TO your_db_user;
+
+--
+-- Name: market_templates; Type: TABLE; Schema: public; Owner: your_db_user
+--
+
+CREATE TABLE public.market_templates (
+    template_id uuid NOT NULL,
+    name text NOT NULL,
+    net text NOT NULL,
+    statement_pattern text NOT NULL,
+    description text,
+    image_url text,
+    category_id integer,
+    closes_after_seconds bigint NOT NULL,
+    schedule text NOT NULL,
+    is_active boolean DEFAULT true NOT NULL,
+    last_run_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT market_templates_closes_after_seconds_check CHECK ((closes_after_seconds > 0)),
+    CONSTRAINT market_templates_net_check CHECK ((net = ANY (ARRAY['testnet'::text, 'mainnet'::text, 'previewnet'::text])))
+);
+
+
+ALTER TABLE public.market_templates OWNER TO your_db_user;
+
 --
 -- Name: markets; Type: TABLE; Schema: public; Owner: your_db_user
 --
@@ -183,12 +398,60 @@ CREATE TABLE public.markets (
     closes_at timestamp with time zone DEFAULT (now() + '30 days'::interval) NOT NULL,
     description text,
     is_suspended boolean DEFAULT false NOT NULL,
+    outcome boolean,
+    closed_at timestamp with time zone,
+    group_id uuid,
+    voided_at timestamp with time zone,
+    void_reason text,
     CONSTRAINT smart_contract_id_check CHECK (((length((smart_contract_id)::text) >= 5) AND ((smart_contract_id)::text ~~ '%.%.%'::text)))
 );
 
 
 ALTER TABLE public.markets OWNER TO your_db_user;
 
+--
+-- Name: match_rejections; Type: TABLE; Schema: public; Owner: your_db_user
+--
+
+CREATE TABLE public.match_rejections (
+    id integer NOT NULL,
+    clob_match_id text,
+    market_id uuid NOT NULL,
+    tx_id1 uuid NOT NULL,
+    tx_id2 uuid NOT NULL,
+    qty1 double precision NOT NULL,
+    qty2 double precision NOT NULL,
+    evicted_tx_ids uuid[] NOT NULL,
+    reinstated_tx_ids uuid[] DEFAULT '{}'::uuid[] NOT NULL,
+    reason text NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+ALTER TABLE public.match_rejections OWNER TO your_db_user;
+
+--
+-- Name: match_rejections_id_seq; Type: SEQUENCE; Schema: public; Owner: your_db_user
+--
+
+CREATE SEQUENCE public.match_rejections_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+ALTER SEQUENCE public.match_rejections_id_seq OWNER TO your_db_user;
+
+--
+-- Name: match_rejections_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: your_db_user
+--
+
+ALTER SEQUENCE public.match_rejections_id_seq OWNED BY public.match_rejections.id;
+
+
 --
 -- Name: matches; Type: TABLE; Schema: public; Owner: your_db_user
 --
@@ -201,7 +464,14 @@ CREATE TABLE public.matches (
     market_id uuid NOT NULL,
     tx_hash character varying(256) NOT NULL,
     qty1 double precision NOT NULL,
-    qty2 double precision NOT NULL
+    qty2 double precision NOT NULL,
+    held_at timestamp with time zone,
+    maker_tx_id uuid,
+    fee_usd1 double precision DEFAULT 0.0 NOT NULL,
+    fee_usd2 double precision DEFAULT 0.0 NOT NULL,
+    matched_qty double precision NOT NULL,
+    clob_match_id text,
+    is_fees_pending boolean DEFAULT false NOT NULL
 );
 
 
@@ -278,6 +548,7 @@ CREATE TABLE public.positions (
     n_no bigint NOT NULL,
     updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP CONSTRAINT positions_created_at_not_null NOT NULL,
     created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP CONSTRAINT positions_created_at_not_null1 NOT NULL,
+    refund bigint,
     CONSTRAINT positions_account_id_check CHECK ((length(evm_address) >= 5)),
     CONSTRAINT positions_n_no_check CHECK ((n_no >= 0)),
     CONSTRAINT positions_n_yes_check CHECK ((n_yes >= 0))
@@ -331,6 +602,13 @@ CREATE TABLE public.prediction_intents (
     regenerated_at timestamp with time zone,
     fully_matched_at timestamp with time zone,
     evicted_at timestamp with time zone,
+    replaces_tx_id uuid,
+    time_in_force text DEFAULT 'gtc'::text NOT NULL,
+    expires_at timestamp with time zone,
+    expired_at timestamp with time zone,
+    filled_qty double precision DEFAULT 0 NOT NULL,
+    remaining_qty double precision GENERATED ALWAYS AS (GREATEST((qty - filled_qty), (0)::double precision)) STORED NOT NULL,
+    eviction_reason text,
     CONSTRAINT order_requests_account_id_check CHECK ((length(account_id) >= 5)),
     CONSTRAINT order_requests_evmaddress_check CHECK ((length(evmaddress) = 40)),
     CONSTRAINT order_requests_keytype_check CHECK ((keytype = ANY (ARRAY[1, 2, 3]))),
@@ -339,7 +617,10 @@ CREATE TABLE public.prediction_intents (
     CONSTRAINT order_requests_price_usd_check CHECK (((price_usd >= ('-1.0'::numeric)::double precision) AND (price_usd <= (1.0)::double precision))),
     CONSTRAINT order_requests_public_key_hex_check CHECK (((length(public_key_hex) > 10) AND (length(public_key_hex) <= 256))),
     CONSTRAINT order_requests_qty_check CHECK ((qty > (0.0)::double precision)),
-    CONSTRAINT order_requests_sig_check CHECK (((length(sig) > 10) AND (length(sig) < 256)))
+    CONSTRAINT order_requests_sig_check CHECK (((length(sig) > 10) AND (length(sig) < 256))),
+    CONSTRAINT prediction_intents_expires_at_check CHECK (((time_in_force = 'gtd'::text) = (expires_at IS NOT NULL))),
+    CONSTRAINT prediction_intents_filled_qty_check CHECK ((filled_qty >= (0)::double precision)),
+    CONSTRAINT prediction_intents_time_in_force_check CHECK ((time_in_force = ANY (ARRAY['gtc'::text, 'gtd'::text, 'ioc'::text, 'fok'::text])))
 );
 
 
@@ -556,6 +837,57 @@ CREATE TABLE public.price_history_p20260311 (
 
 ALTER TABLE public.price_history_p20260311 OWNER TO your_db_user;
 
+--
+-- Name: risk_limits; Type: TABLE; Schema: public; Owner: your_db_user
+--
+
+CREATE TABLE public.risk_limits (
+    id integer NOT NULL,
+    net text NOT NULL,
+    market_id uuid,
+    max_open_intents_per_account integer,
+    max_position_qty double precision,
+    min_price_usd double precision,
+    max_price_usd double precision,
+    max_notional_usd_per_account double precision,
+    max_open_notional_usd double precision,
+    updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT risk_limits_check CHECK (((min_price_usd IS NULL) OR (max_price_usd IS NULL) OR (min_price_usd <= max_price_usd))),
+    CONSTRAINT risk_limits_check1 CHECK (((market_id IS NULL) OR ((max_notional_usd_per_account IS NULL) AND (max_open_notional_usd IS NULL)))),
+    CONSTRAINT risk_limits_max_notional_usd_per_account_check CHECK ((max_notional_usd_per_account > (0.0)::double precision)),
+    CONSTRAINT risk_limits_max_open_intents_per_account_check CHECK ((max_open_intents_per_account > 0)),
+    CONSTRAINT risk_limits_max_open_notional_usd_check CHECK ((max_open_notional_usd > (0.0)::double precision)),
+    CONSTRAINT risk_limits_max_position_qty_check CHECK ((max_position_qty > (0.0)::double precision)),
+    CONSTRAINT risk_limits_max_price_usd_check CHECK (((max_price_usd > (0.0)::double precision) AND (max_price_usd <= (1.0)::double precision))),
+    CONSTRAINT risk_limits_min_price_usd_check CHECK (((min_price_usd >= (0.0)::double precision) AND (min_price_usd < (1.0)::double precision))),
+    CONSTRAINT risk_limits_net_check CHECK ((net = ANY (ARRAY['testnet'::text, 'mainnet'::text, 'previewnet'::text])))
+);
+
+
+ALTER TABLE public.risk_limits OWNER TO your_db_user;
+
+--
+-- Name: risk_limits_id_seq; Type: SEQUENCE; Schema: public; Owner: your_db_user
+--
+
+CREATE SEQUENCE public.risk_limits_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+ALTER SEQUENCE public.risk_limits_id_seq OWNER TO your_db_user;
+
+--
+-- Name: risk_limits_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: your_db_user
+--
+
+ALTER SEQUENCE public.risk_limits_id_seq OWNED BY public.risk_limits.id;
+
+
 --
 -- Name: schema_migrations; Type: TABLE; Schema: public; Owner: your_db_user
 --
@@ -568,6 +900,52 @@ CREATE TABLE public.schema_migrations (
 
 ALTER TABLE public.schema_migrations OWNER TO your_db_user;
 
+--
+-- Name: settlements; Type: TABLE; Schema: public; Owner: your_db_user
+--
+
+CREATE TABLE public.settlements (
+    id integer NOT NULL,
+    match_id integer NOT NULL,
+    status text DEFAULT 'pending'::text NOT NULL,
+    hedera_tx_id text,
+    receipt_status text,
+    gas_used bigint,
+    error text,
+    attempts integer DEFAULT 0 NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    next_attempt_at timestamp with time zone,
+    dead_lettered_at timestamp with time zone,
+    abandoned_at timestamp with time zone,
+    CONSTRAINT settlements_status_check CHECK ((status = ANY (ARRAY['pending'::text, 'submitted'::text, 'confirmed'::text, 'failed'::text, 'dead_lettered'::text, 'abandoned'::text])))
+);
+
+
+ALTER TABLE public.settlements OWNER TO your_db_user;
+
+--
+-- Name: settlements_id_seq; Type: SEQUENCE; Schema: public; Owner: your_db_user
+--
+
+CREATE SEQUENCE public.settlements_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+ALTER SEQUENCE public.settlements_id_seq OWNER TO your_db_user;
+
+--
+-- Name: settlements_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: your_db_user
+--
+
+ALTER SEQUENCE public.settlements_id_seq OWNED BY public.settlements.id;
+
+
 --
 -- Name: price_history_default; Type: TABLE ATTACH; Schema: public; Owner: your_db_user
 --
@@ -673,6 +1051,13 @@ ALTER TABLE ONLY public.price_history ATTACH PARTITION public.price_history_p202
 ALTER TABLE ONLY public.categories ALTER COLUMN id SET DEFAULT nextval('public.categories_id_seq'::regclass);
 
 
+--
+-- Name: clob_outbox id; Type: DEFAULT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.clob_outbox ALTER COLUMN id SET DEFAULT nextval('public.clob_outbox_id_seq'::regclass);
+
+
 --
 -- Name: comments comment_id; Type: DEFAULT; Schema: public; Owner: your_db_user
 --
@@ -680,6 +1065,20 @@ ALTER TABLE ONLY public.categories ALTER COLUMN id SET DEFAULT nextval('public.c
 ALTER TABLE ONLY public.comments ALTER COLUMN comment_id SET DEFAULT nextval('public.comments_comment_id_seq'::regclass);
 
 
+--
+-- Name: fee_schedules id; Type: DEFAULT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.fee_schedules ALTER COLUMN id SET DEFAULT nextval('public.fee_schedules_id_seq'::regclass);
+
+
+--
+-- Name: match_rejections id; Type: DEFAULT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.match_rejections ALTER COLUMN id SET DEFAULT nextval('public.match_rejections_id_seq'::regclass);
+
+
 --
 -- Name: matches id; Type: DEFAULT; Schema: public; Owner: your_db_user
 --
@@ -701,6 +1100,20 @@ ALTER TABLE ONLY public.newsletter ALTER COLUMN id SET DEFAULT nextval('public.n
 ALTER TABLE ONLY public.positions ALTER COLUMN id SET DEFAULT nextval('public.positions_id_seq'::regclass);
 
 
+--
+-- Name: risk_limits id; Type: DEFAULT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.risk_limits ALTER COLUMN id SET DEFAULT nextval('public.risk_limits_id_seq'::regclass);
+
+
+--
+-- Name: settlements id; Type: DEFAULT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.settlements ALTER COLUMN id SET DEFAULT nextval('public.settlements_id_seq'::regclass);
+
+
 --
 -- Name: categories categories_name_key; Type: CONSTRAINT; Schema: public; Owner: your_db_user
 --
@@ -717,6 +1130,14 @@ ALTER TABLE ONLY public.categories
     ADD CONSTRAINT categories_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: clob_outbox clob_outbox_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.clob_outbox
+    ADD CONSTRAINT clob_outbox_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: comments comments_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
 --
@@ -725,6 +1146,22 @@ ALTER TABLE ONLY public.comments
     ADD CONSTRAINT comments_pkey PRIMARY KEY (comment_id);
 
 
+--
+-- Name: conditional_intents conditional_intents_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.conditional_intents
+    ADD CONSTRAINT conditional_intents_pkey PRIMARY KEY (tx_id);
+
+
+--
+-- Name: fee_schedules fee_schedules_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.fee_schedules
+    ADD CONSTRAINT fee_schedules_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: market_categories market_categories_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
 --
@@ -733,6 +1170,62 @@ ALTER TABLE ONLY public.market_categories
     ADD CONSTRAINT market_categories_pkey PRIMARY KEY (market_id, category_id);
 
 
+--
+-- Name: market_creations market_creations_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.market_creations
+    ADD CONSTRAINT market_creations_pkey PRIMARY KEY (market_id);
+
+
+--
+-- Name: market_groups market_groups_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.market_groups
+    ADD CONSTRAINT market_groups_pkey PRIMARY KEY (group_id);
+
+
+--
+-- Name: market_proposals market_proposals_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.market_proposals
+    ADD CONSTRAINT market_proposals_pkey PRIMARY KEY (market_id);
+
+
+--
+-- Name: market_template_runs market_template_runs_market_id_key; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.market_template_runs
+    ADD CONSTRAINT market_template_runs_market_id_key UNIQUE (market_id);
+
+
+--
+-- Name: market_template_runs market_template_runs_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.market_template_runs
+    ADD CONSTRAINT market_template_runs_pkey PRIMARY KEY (template_id, scheduled_at);
+
+
+--
+-- Name: market_templates market_templates_name_key; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.market_templates
+    ADD CONSTRAINT market_templates_name_key UNIQUE (name);
+
+
+--
+-- Name: market_templates market_templates_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.market_templates
+    ADD CONSTRAINT market_templates_pkey PRIMARY KEY (template_id);
+
+
 --
 -- Name: markets markets_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
 --
@@ -741,6 +1234,30 @@ ALTER TABLE ONLY public.markets
     ADD CONSTRAINT markets_pkey PRIMARY KEY (market_id);
 
 
+--
+-- Name: match_rejections match_rejections_clob_match_id_key; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.match_rejections
+    ADD CONSTRAINT match_rejections_clob_match_id_key UNIQUE (clob_match_id);
+
+
+--
+-- Name: match_rejections match_rejections_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.match_rejections
+    ADD CONSTRAINT match_rejections_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: matches matches_clob_match_id_key; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.matches
+    ADD CONSTRAINT matches_clob_match_id_key UNIQUE (clob_match_id);
+
+
 --
 -- Name: matches matches_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
 --
@@ -773,6 +1290,14 @@ ALTER TABLE ONLY public.prediction_intents
     ADD CONSTRAINT order_requests_pkey PRIMARY KEY (tx_id);
 
 
+--
+-- Name: prediction_intents prediction_intents_replaces_tx_id_key; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.prediction_intents
+    ADD CONSTRAINT prediction_intents_replaces_tx_id_key UNIQUE (replaces_tx_id);
+
+
 --
 -- Name: positions positions_market_id_account_id_key; Type: CONSTRAINT; Schema: public; Owner: your_db_user
 --
@@ -909,6 +1434,14 @@ ALTER TABLE ONLY public.price_history_p20260311
     ADD CONSTRAINT price_history_p20260311_pkey PRIMARY KEY (market_id, ts);
 
 
+--
+-- Name: risk_limits risk_limits_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.risk_limits
+    ADD CONSTRAINT risk_limits_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: schema_migrations schema_migrations_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
 --
@@ -917,6 +1450,22 @@ ALTER TABLE ONLY public.schema_migrations
     ADD CONSTRAINT schema_migrations_pkey PRIMARY KEY (version);
 
 
+--
+-- Name: settlements settlements_match_id_key; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.settlements
+    ADD CONSTRAINT settlements_match_id_key UNIQUE (match_id);
+
+
+--
+-- Name: settlements settlements_pkey; Type: CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.settlements
+    ADD CONSTRAINT settlements_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: positions unique_market_id_evm_address; Type: CONSTRAINT; Schema: public; Owner: your_db_user
 --
@@ -939,6 +1488,125 @@ CREATE INDEX idx_comments_account_id ON public.comments USING btree (account_id)
 CREATE INDEX idx_comments_market_id ON public.comments USING btree (market_id);
 
 
+--
+-- Name: idx_matches_tx_id1; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE INDEX idx_matches_tx_id1 ON public.matches USING btree (tx_id1);
+
+
+--
+-- Name: idx_matches_tx_id2; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE INDEX idx_matches_tx_id2 ON public.matches USING btree (tx_id2);
+
+
+--
+-- Name: idx_clob_outbox_undelivered; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE INDEX idx_clob_outbox_undelivered ON public.clob_outbox USING btree (id) WHERE (delivered_at IS NULL);
+
+
+--
+-- Name: idx_conditional_intents_account_id; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE INDEX idx_conditional_intents_account_id ON public.conditional_intents USING btree (account_id);
+
+
+--
+-- Name: idx_conditional_intents_pending; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE INDEX idx_conditional_intents_pending ON public.conditional_intents USING btree (market_id) WHERE ((triggered_at IS NULL) AND (cancelled_at IS NULL));
+
+
+--
+-- Name: idx_fee_schedules_net; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE UNIQUE INDEX idx_fee_schedules_net ON public.fee_schedules USING btree (net) WHERE (market_id IS NULL);
+
+
+--
+-- Name: idx_fee_schedules_net_market_id; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE UNIQUE INDEX idx_fee_schedules_net_market_id ON public.fee_schedules USING btree (net, market_id) WHERE (market_id IS NOT NULL);
+
+
+--
+-- Name: idx_markets_group_id; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE INDEX idx_markets_group_id ON public.markets USING btree (group_id);
+
+
+--
+-- Name: idx_market_proposals_status; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE INDEX idx_market_proposals_status ON public.market_proposals USING btree (status, created_at);
+
+
+--
+-- Name: idx_prediction_intents_account_id; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE INDEX idx_prediction_intents_account_id ON public.prediction_intents USING btree (account_id, created_at DESC);
+
+
+--
+-- Name: idx_prediction_intents_expires_at; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE INDEX idx_prediction_intents_expires_at ON public.prediction_intents USING btree (expires_at) WHERE (expires_at IS NOT NULL);
+
+
+--
+-- Name: idx_risk_limits_net; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE UNIQUE INDEX idx_risk_limits_net ON public.risk_limits USING btree (net) WHERE (market_id IS NULL);
+
+
+--
+-- Name: idx_risk_limits_net_market_id; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE UNIQUE INDEX idx_risk_limits_net_market_id ON public.risk_limits USING btree (net, market_id) WHERE (market_id IS NOT NULL);
+
+
+--
+-- Name: idx_settlements_next_attempt_at; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE INDEX idx_settlements_next_attempt_at ON public.settlements USING btree (next_attempt_at) WHERE (status = 'failed'::text);
+
+
+--
+-- Name: idx_settlements_status; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE INDEX idx_settlements_status ON public.settlements USING btree (status) WHERE (status = ANY (ARRAY['pending'::text, 'submitted'::text, 'failed'::text, 'dead_lettered'::text]));
+
+
+--
+-- Name: markets_closes_at_idx; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE INDEX markets_closes_at_idx ON public.markets USING btree (closes_at);
+
+
+--
+-- Name: markets_search_idx; Type: INDEX; Schema: public; Owner: your_db_user
+--
+
+CREATE INDEX markets_search_idx ON public.markets USING gin (to_tsvector('english'::regconfig, ((statement || ' '::text) || COALESCE(description, ''::text))));
+
+
 --
 -- Name: price_history_market_id_ts_idx; Type: INDEX; Schema: public; Owner: your_db_user
 --
@@ -1254,6 +1922,14 @@ CREATE TRIGGER update_markets_updated_at BEFORE UPDATE ON public.markets FOR EAC
 CREATE TRIGGER update_order_requests_updated_at BEFORE UPDATE ON public.prediction_intents FOR EACH ROW EXECUTE FUNCTION public.update_updated_at_column();
 
 
+--
+-- Name: clob_outbox clob_outbox_tx_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.clob_outbox
+    ADD CONSTRAINT clob_outbox_tx_id_fkey FOREIGN KEY (tx_id) REFERENCES public.prediction_intents(tx_id);
+
+
 --
 -- Name: comments fk_market; Type: FK CONSTRAINT; Schema: public; Owner: your_db_user
 --
@@ -1262,6 +1938,22 @@ ALTER TABLE ONLY public.comments
     ADD CONSTRAINT fk_market FOREIGN KEY (market_id) REFERENCES public.markets(market_id) ON DELETE CASCADE;
 
 
+--
+-- Name: conditional_intents conditional_intents_market_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.conditional_intents
+    ADD CONSTRAINT conditional_intents_market_id_fkey FOREIGN KEY (market_id) REFERENCES public.markets(market_id);
+
+
+--
+-- Name: fee_schedules fee_schedules_market_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.fee_schedules
+    ADD CONSTRAINT fee_schedules_market_id_fkey FOREIGN KEY (market_id) REFERENCES public.markets(market_id);
+
+
 --
 -- Name: market_categories market_categories_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: your_db_user
 --
@@ -1278,6 +1970,54 @@ ALTER TABLE ONLY public.market_categories
     ADD CONSTRAINT market_categories_market_id_fkey FOREIGN KEY (market_id) REFERENCES public.markets(market_id) ON DELETE CASCADE;
 
 
+--
+-- Name: market_template_runs market_template_runs_template_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.market_template_runs
+    ADD CONSTRAINT market_template_runs_template_id_fkey FOREIGN KEY (template_id) REFERENCES public.market_templates(template_id);
+
+
+--
+-- Name: market_templates market_templates_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.market_templates
+    ADD CONSTRAINT market_templates_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.categories(id);
+
+
+--
+-- Name: markets markets_group_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.markets
+    ADD CONSTRAINT markets_group_id_fkey FOREIGN KEY (group_id) REFERENCES public.market_groups(group_id);
+
+
+--
+-- Name: prediction_intents prediction_intents_replaces_tx_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.prediction_intents
+    ADD CONSTRAINT prediction_intents_replaces_tx_id_fkey FOREIGN KEY (replaces_tx_id) REFERENCES public.prediction_intents(tx_id);
+
+
+--
+-- Name: risk_limits risk_limits_market_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.risk_limits
+    ADD CONSTRAINT risk_limits_market_id_fkey FOREIGN KEY (market_id) REFERENCES public.markets(market_id);
+
+
+--
+-- Name: settlements settlements_match_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: your_db_user
+--
+
+ALTER TABLE ONLY public.settlements
+    ADD CONSTRAINT settlements_match_id_fkey FOREIGN KEY (match_id) REFERENCES public.matches(id);
+
+
 --
 -- PostgreSQL database dump complete
 --
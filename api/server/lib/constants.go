@@ -1,10 +1,73 @@
 package lib
 
 const (
-	MID_MARKET_PRICE           = 0.5
-	SUBJECT_CLOB_ORDERS        = "clob.orders"
-	NATS_CLOB_MATCHES_FULL     = "clob.matches.full"
-	NATS_CLOB_MATCHES_PARTIAL  = "clob.matches.partial"
-	NATS_CLOB_MATCHES_WILDCARD = "clob.matches.*"
-	NATS_CLOB_CANCEL_ORDERS    = "clob.orders.cancel"
+	MID_MARKET_PRICE              = 0.5
+	SUBJECT_CLOB_ORDERS           = "clob.orders"
+	NATS_CLOB_MATCHES_FULL        = "clob.matches.full"
+	NATS_CLOB_MATCHES_PARTIAL     = "clob.matches.partial"
+	NATS_CLOB_MATCHES_WILDCARD    = "clob.matches.*"
+	NATS_CLOB_CANCEL_ORDERS       = "clob.orders.cancel"
+	SUBJECT_CLOB_MATCH_REJECTIONS = "clob.match_rejections" // matches rejected before settlement - not under clob.matches.* (see: HandleOrderMatches)
+	NATS_STREAM_CLOB_ORDERS       = "CLOB_ORDERS"           // JetStream stream over SUBJECT_CLOB_ORDERS (acked publishes)
+
+	CLOB_OUTBOX_RELAY_INTERVAL_MS = 1000 // the outbox relay also runs straight after every committed intent
+	CLOB_OUTBOX_BATCH_SIZE        = 100
+
+	MATCH_TX_HASH_NOT_YET_AVAILABLE = "notYetAvailable" // matches.tx_hash until the settlement is submitted to the smart contract
+
+	// state of a match's settlement (see: settlements table)
+	SETTLEMENT_STATUS_PENDING       = "pending"       // recorded (or held while the market is paused, or claimed for a retry)
+	SETTLEMENT_STATUS_SUBMITTED     = "submitted"     // sent to the smart contract
+	SETTLEMENT_STATUS_CONFIRMED     = "confirmed"     // settled on-chain
+	SETTLEMENT_STATUS_FAILED        = "failed"        // transient failure - retried at next_attempt_at
+	SETTLEMENT_STATUS_DEAD_LETTERED = "dead_lettered" // permanent failure (or retries exhausted) - waits for an admin to retry or abandon it
+	SETTLEMENT_STATUS_ABANDONED     = "abandoned"     // given up by an admin - the intents were re-queued
+
+	SETTLEMENT_MAX_ATTEMPTS              = 6    // dead-lettered after this many failed attempts
+	SETTLEMENT_RETRY_BACKOFF_SECONDS     = 30   // first retry delay - doubled after every failed attempt
+	SETTLEMENT_RETRY_MAX_BACKOFF_SECONDS = 1800 // retry delay cap
+	SETTLEMENT_RETRY_INTERVAL_MS         = 10000
+	SETTLEMENT_RETRY_BATCH_SIZE          = 20
+	SETTLEMENT_STALE_SECONDS             = 300 // a settlement pending (not held) or submitted for longer than this was interrupted (e.g. a restart) - retried
+
+	// outcome of the last transaction submitted for a settlement (see: HederaService.GetSettlementTxOutcome)
+	SETTLEMENT_TX_OUTCOME_SUCCEEDED    = "succeeded"    // settled on-chain - never resubmit it
+	SETTLEMENT_TX_OUTCOME_NOT_EXECUTED = "not_executed" // reverted, or can no longer reach consensus - safe to resubmit
+	SETTLEMENT_TX_OUTCOME_UNKNOWN      = "unknown"      // not (yet) known - neither resubmit nor abandon it
+
+	SETTLEMENT_TX_VALID_DURATION_SECONDS = 180 // the longest a Hedera transaction can wait to reach consensus after its valid start
+	SETTLEMENT_TX_MIRROR_LAG_SECONDS     = 60  // how far the mirror node may lag behind consensus
+	SETTLEMENT_TX_RECHECK_SECONDS        = 60  // a settlement whose last transaction has an unknown outcome is looked at again after this
+
+	FEE_BPS_DENOMINATOR = 10000.0 // fee_schedules.maker_fee_bps/taker_fee_bps are basis points of the matched notional
+
+	MAX_MARKET_CREATION_ATTEMPTS        = 5   // the cron reconciler gives up (and flags the market creation) after this many attempts
+	MARKET_CREATION_CLAIM_STALE_SECONDS = 300 // a smart contract step claimed longer ago than this was interrupted - its transaction can no longer reach consensus (see: SETTLEMENT_TX_VALID_DURATION_SECONDS)
+
+	// time-in-force of a prediction intent (an empty time_in_force is treated as GTC)
+	TIME_IN_FORCE_GTC = "gtc" // good-till-cancelled: rests on the book until matched, cancelled or evicted
+	TIME_IN_FORCE_GTD = "gtd" // good-till-date: as GTC, but expires at expires_at
+	TIME_IN_FORCE_IOC = "ioc" // immediate-or-cancel: matches what it can on arrival, the remainder is cancelled
+	TIME_IN_FORCE_FOK = "fok" // fill-or-kill: fully matched on arrival or not at all
+
+	// lifecycle state of a prediction intent (see: ListPredictionIntents)
+	PREDICTION_INTENT_STATE_OPEN      = "open"
+	PREDICTION_INTENT_STATE_FILLED    = "filled"
+	PREDICTION_INTENT_STATE_CANCELLED = "cancelled"
+	PREDICTION_INTENT_STATE_EVICTED   = "evicted" // cancelled by the cron job (insufficient allowance/balance)
+	PREDICTION_INTENT_STATE_EXPIRED   = "expired" // gtd intent past its expires_at
+
+	// trigger condition of a conditional (stop/take-profit) intent, against the market's last traded (YES) price
+	TRIGGER_CONDITION_ABOVE = "above" // fires when the last traded price >= trigger price
+	TRIGGER_CONDITION_BELOW = "below" // fires when the last traded price <= trigger price
+
+	// rule code of a pre-trade risk check rejection (see: RiskService) - returned to the client in PredictionIntentResponse.ruleCode
+	RISK_RULE_TIMESTAMP_WINDOW = "timestamp_window" // generatedAt outside [now - TIMESTAMP_ALLOWED_PAST_SECONDS, now + TIMESTAMP_ALLOWED_FUTURE_SECONDS]
+	RISK_RULE_PRICE_BAND       = "price_band"       // |priceUsd| outside the market's [min_price_usd, max_price_usd]
+	RISK_RULE_MAX_OPEN_INTENTS = "max_open_intents" // too many open intents for the account in the market
+	RISK_RULE_MAX_POSITION     = "max_position"     // position + open intents + the new intent would exceed max_position_qty (per side)
+	RISK_RULE_MAX_NOTIONAL     = "max_notional"     // the account's open notional on the network would exceed max_notional_usd_per_account
+	RISK_RULE_NETWORK_LIMIT    = "network_limit"    // every account's open notional on the network would exceed max_open_notional_usd
+	RISK_RULE_ALLOWANCE        = "allowance"        // USDC allowance to the market's smart contract too low
+	RISK_RULE_BALANCE          = "balance"          // USDC balance too low
 )
@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"database/sql"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"math"
 	"math/big"
 	"strings"
+	"time"
 
 	"github.com/hiero-ledger/hiero-sdk-go/v2/proto/services"
 	hiero "github.com/hiero-ledger/hiero-sdk-go/v2/sdk"
@@ -27,28 +29,19 @@ import (
 * @returns a long string conforming to the format
  */
 func AssemblePayloadHexForSigning(req *pb_api.PredictionIntentRequest, usdcDecimals uint64) (string, error) {
-	collateralUsdAbs := math.Abs(req.PriceUsd * req.Qty)
-	collateralUsdAbsScaled, err := FloatToBigIntScaledDecimals(collateralUsdAbs, int(usdcDecimals))
+	buySell, collateralUsdAbsScaled, evmAddressBigInt, marketIdBigInt, txIdBigInt, err := payloadFieldsForSigning(req, usdcDecimals)
 	if err != nil {
-		return "", fmt.Errorf("failed to scale collateralUsdAbs: %v", err)
+		return "", err
 	}
 
-	marketIdBigInt, err := Uuid7_to_bigint(req.MarketId)
+	timeInForceCode, err := TimeInForceCode(req.TimeInForce)
 	if err != nil {
-		return "", fmt.Errorf("failed to convert MarketId: %v", err)
+		return "", err
 	}
 
-	txIdBigInt, err := Uuid7_to_bigint(req.TxId)
+	expiresAtUnix, err := ExpiresAtUnix(req.ExpiresAt)
 	if err != nil {
-		return "", fmt.Errorf("failed to convert TxId: %v", err)
-	}
-
-	evmAddressBigInt := new(big.Int)
-	evmAddressBigInt.SetString(strings.TrimPrefix(req.EvmAddress, "0x"), 16)
-
-	buySell := 0xf0 // buy
-	if req.PriceUsd < 0 {
-		buySell = 0xf1 // sell
+		return "", err
 	}
 
 	// The format specifier "%002x" is used to ensure that the value of `buySell`
@@ -64,17 +57,290 @@ func AssemblePayloadHexForSigning(req *pb_api.PredictionIntentRequest, usdcDecim
 	// avoids odd-length hex strings, which could cause issues in contexts where
 	// fixed-length formatting is required.
 	payloadHex := fmt.Sprintf( // beautiful :)   example: 0100000000000000000000000000004e20000000000000000000000000440a1d7af93b92920bce50b4c0d2a8e6dcfebfd60189c0a87e807e808000000000000003019b45b837017342a16c7fb8a8023f17
-		"%02x%064x%040x%032x%032x",
+		"%02x%064x%040x%032x%032x%02x%016x",
 
 		buySell,                // note: 8 bits. The hex len is 2 chars (padded left with '0') to avoid odd length hex strings. 0xf0 = buy, 0xf1 = sell
 		collateralUsdAbsScaled, // yes, uint256
 		evmAddressBigInt,       // note: an evm address is exactly 20 bytes = 40 hex chars
 		marketIdBigInt,         // uint128
 		txIdBigInt,             // uint128
+		timeInForceCode,        // uint8: 0 = GTC, 1 = GTD, 2 = IOC, 3 = FOK
+		expiresAtUnix,          // uint64: unix seconds (0 unless GTD)
 	)
 	return payloadHex, nil
 }
 
+/**
+* Assembles the payload hex string signed for a market on a legacy Prism smart contract (PRISM_CONTRACT_VERSION_LEGACY),
+* which predates time-in-force and expiry: the same fields as AssemblePayloadHexForSigning without the last two
+* See: prism/README.md for format definition details
+* Also see: ./web.eng/lib/utils.ts
+ */
+func AssembleLegacyPayloadHexForSigning(req *pb_api.PredictionIntentRequest, usdcDecimals uint64) (string, error) {
+	if timeInForceCode, err := TimeInForceCode(req.TimeInForce); err != nil || timeInForceCode != 0 || req.ExpiresAt != "" {
+		return "", fmt.Errorf("a legacy smart contract only settles GTC orders without an expiry (timeInForce=%s, expiresAt=%s)", req.TimeInForce, req.ExpiresAt)
+	}
+
+	buySell, collateralUsdAbsScaled, evmAddressBigInt, marketIdBigInt, txIdBigInt, err := payloadFieldsForSigning(req, usdcDecimals)
+	if err != nil {
+		return "", err
+	}
+
+	payloadHex := fmt.Sprintf(
+		"%02x%064x%040x%032x%032x",
+
+		buySell,                // uint8: 0xf0 = buy, 0xf1 = sell
+		collateralUsdAbsScaled, // uint256
+		evmAddressBigInt,       // 20 bytes
+		marketIdBigInt,         // uint128
+		txIdBigInt,             // uint128
+	)
+	return payloadHex, nil
+}
+
+/**
+* Assembles the payload hex string signed for a prediction intent in the format of the given Prism smart contract version
+ */
+func AssemblePayloadHexForSigningForContractVersion(req *pb_api.PredictionIntentRequest, usdcDecimals uint64, contractVersion PrismContractVersion) (string, error) {
+	switch contractVersion {
+	case PRISM_CONTRACT_VERSION_LEGACY:
+		return AssembleLegacyPayloadHexForSigning(req, usdcDecimals)
+	case PRISM_CONTRACT_VERSION_CURRENT:
+		return AssemblePayloadHexForSigning(req, usdcDecimals)
+	default:
+		return "", fmt.Errorf("unknown smart contract version: %d", contractVersion)
+	}
+}
+
+// payloadFieldsForSigning converts the fields common to every version of the signed payload
+func payloadFieldsForSigning(req *pb_api.PredictionIntentRequest, usdcDecimals uint64) (buySell int, collateralUsdAbsScaled *big.Int, evmAddressBigInt *big.Int, marketIdBigInt *big.Int, txIdBigInt *big.Int, err error) {
+	collateralUsdAbs := math.Abs(req.PriceUsd * req.Qty)
+	collateralUsdAbsScaled, err = FloatToBigIntScaledDecimals(collateralUsdAbs, int(usdcDecimals))
+	if err != nil {
+		return 0, nil, nil, nil, nil, fmt.Errorf("failed to scale collateralUsdAbs: %v", err)
+	}
+
+	marketIdBigInt, err = Uuid7_to_bigint(req.MarketId)
+	if err != nil {
+		return 0, nil, nil, nil, nil, fmt.Errorf("failed to convert MarketId: %v", err)
+	}
+
+	txIdBigInt, err = Uuid7_to_bigint(req.TxId)
+	if err != nil {
+		return 0, nil, nil, nil, nil, fmt.Errorf("failed to convert TxId: %v", err)
+	}
+
+	evmAddressBigInt = new(big.Int)
+	evmAddressBigInt.SetString(strings.TrimPrefix(req.EvmAddress, "0x"), 16)
+
+	buySell = 0xf0 // buy
+	if req.PriceUsd < 0 {
+		buySell = 0xf1 // sell
+	}
+
+	return buySell, collateralUsdAbsScaled, evmAddressBigInt, marketIdBigInt, txIdBigInt, nil
+}
+
+/**
+* Maps a time-in-force to the code signed in the payload (an empty time-in-force is GTC)
+* Must match the codes checked by Prism.sol
+ */
+func TimeInForceCode(timeInForce string) (uint8, error) {
+	switch strings.ToLower(timeInForce) {
+	case "", TIME_IN_FORCE_GTC:
+		return 0, nil
+	case TIME_IN_FORCE_GTD:
+		return 1, nil
+	case TIME_IN_FORCE_IOC:
+		return 2, nil
+	case TIME_IN_FORCE_FOK:
+		return 3, nil
+	default:
+		return 0, fmt.Errorf("invalid timeInForce: %s", timeInForce)
+	}
+}
+
+/**
+* Formats a stored expires_at the way it is sent to the CLOB (an empty string if the intent never expires)
+ */
+func FormatExpiresAt(expiresAt sql.NullTime) string {
+	if !expiresAt.Valid {
+		return ""
+	}
+	return expiresAt.Time.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+/**
+* Converts expiresAt (UTC ISO 8601) to the unix seconds signed in the payload (an empty expiresAt is 0 = never expires)
+ */
+func ExpiresAtUnix(expiresAt string) (uint64, error) {
+	if expiresAt == "" {
+		return 0, nil
+	}
+	expiresAtTime, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return 0, fmt.Errorf("invalid expiresAt timestamp: %v", err)
+	}
+	if expiresAtTime.Unix() <= 0 {
+		return 0, fmt.Errorf("invalid expiresAt timestamp: %s", expiresAt)
+	}
+	return uint64(expiresAtTime.Unix()), nil
+}
+
+/**
+* Assembles a payload hex string for signing a cancellation of a prediction intent
+* See: prism/README.md for format definition details
+* Also see: ./web.eng/lib/utils.ts
+* @param req CancelOrderRequest object from front-end
+* @param evmAddress the evm address stored with the prediction intent being cancelled
+* @returns a string conforming to the format
+ */
+func AssembleCancelPayloadHexForSigning(req *pb_api.CancelOrderRequest, evmAddress string) (string, error) {
+	marketIdBigInt, err := Uuid7_to_bigint(req.MarketId)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert MarketId: %v", err)
+	}
+
+	txIdBigInt, err := Uuid7_to_bigint(req.TxId)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert TxId: %v", err)
+	}
+
+	evmAddressBigInt := new(big.Int)
+	evmAddressBigInt.SetString(strings.TrimPrefix(evmAddress, "0x"), 16)
+
+	payloadHex := fmt.Sprintf(
+		"%02x%040x%032x%032x",
+
+		0xfc,             // cancel (8 bits) - never collides with the buy/sell (0xf0/0xf1) payloads, so a signed order can't be replayed as a cancel
+		evmAddressBigInt, // note: an evm address is exactly 20 bytes = 40 hex chars
+		marketIdBigInt,   // uint128
+		txIdBigInt,       // uint128
+	)
+	return payloadHex, nil
+}
+
+/**
+* Assembles a payload hex string for signing the trigger of a conditional intent (signed with the same key as the intent)
+* See: prism/README.md for format definition details
+* Also see: ./web.eng/lib/utils.ts
+* @param req ConditionalIntentRequest object from front-end
+* @param usdcDecimals number of decimals for USDC
+* @returns a string conforming to the format
+ */
+func AssembleTriggerPayloadHexForSigning(req *pb_api.ConditionalIntentRequest, usdcDecimals uint64) (string, error) {
+	marketIdBigInt, err := Uuid7_to_bigint(req.Intent.MarketId)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert MarketId: %v", err)
+	}
+
+	txIdBigInt, err := Uuid7_to_bigint(req.Intent.TxId)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert TxId: %v", err)
+	}
+
+	var triggerCondition uint8
+	switch strings.ToLower(req.TriggerCondition) {
+	case TRIGGER_CONDITION_ABOVE:
+		triggerCondition = 1
+	case TRIGGER_CONDITION_BELOW:
+		triggerCondition = 2
+	default:
+		return "", fmt.Errorf("invalid trigger condition: %s", req.TriggerCondition)
+	}
+
+	triggerPriceUsdScaled, err := FloatToBigIntScaledDecimals(req.TriggerPriceUsd, int(usdcDecimals))
+	if err != nil {
+		return "", fmt.Errorf("failed to scale triggerPriceUsd: %v", err)
+	}
+
+	evmAddressBigInt := new(big.Int)
+	evmAddressBigInt.SetString(strings.TrimPrefix(req.Intent.EvmAddress, "0x"), 16)
+
+	payloadHex := fmt.Sprintf(
+		"%02x%040x%032x%032x%02x%064x",
+
+		0xfd,                  // trigger (8 bits) - distinct from the buy/sell (0xf0/0xf1), cancel (0xfc) and cancel all (0xfa) payloads
+		evmAddressBigInt,      // note: an evm address is exactly 20 bytes = 40 hex chars
+		marketIdBigInt,        // uint128
+		txIdBigInt,            // uint128 - binds the trigger to the intent it fires
+		triggerCondition,      // uint8: 1 = above, 2 = below
+		triggerPriceUsdScaled, // uint256
+	)
+	return payloadHex, nil
+}
+
+/**
+* Assembles a payload hex string for signing a bulk cancellation of prediction intents
+* See: prism/README.md for format definition details
+* Also see: ./web.eng/lib/utils.ts
+* @param req CancelAllPredictionIntentsRequest object from front-end
+* @returns a string conforming to the format
+ */
+func AssembleCancelAllPayloadHexForSigning(req *pb_api.CancelAllPredictionIntentsRequest) (string, error) {
+	marketIdBigInt := big.NewInt(0) // 0 = every market
+	if req.MarketId != "" {
+		var err error
+		marketIdBigInt, err = Uuid7_to_bigint(req.MarketId)
+		if err != nil {
+			return "", fmt.Errorf("failed to convert MarketId: %v", err)
+		}
+	}
+
+	var side uint8 // 0 = both sides
+	switch req.Side {
+	case "buy":
+		side = 0xf0
+	case "sell":
+		side = 0xf1
+	}
+
+	generatedAt, err := time.Parse(time.RFC3339, req.GeneratedAt)
+	if err != nil {
+		return "", fmt.Errorf("invalid generatedAt timestamp: %v", err)
+	}
+
+	evmAddressBigInt := new(big.Int)
+	evmAddressBigInt.SetString(strings.TrimPrefix(req.EvmAddress, "0x"), 16)
+
+	payloadHex := fmt.Sprintf(
+		"%02x%040x%032x%02x%016x",
+
+		0xfa,                    // cancel all (8 bits) - distinct from the buy/sell (0xf0/0xf1) and single cancel (0xfc) payloads
+		evmAddressBigInt,        // note: an evm address is exactly 20 bytes = 40 hex chars
+		marketIdBigInt,          // uint128, 0 = every market
+		side,                    // 8 bits: 0 = both sides, 0xf0 = buy, 0xf1 = sell
+		generatedAt.UnixMilli(), // uint64 - bounds the window in which the signed request can be replayed
+	)
+	return payloadHex, nil
+}
+
+/**
+* Assembles a payload hex string for signing a market proposal (ProposeMarket)
+* Every free-text field is length-prefixed, so no two different proposals share a payload (e.g. a "|" inside the statement)
+* See: prism/README.md for format definition details
+* Also see: ./web.eng/lib/utils.ts
+* @param req the proposed CreateMarketRequest
+* @returns a string conforming to the format
+ */
+func AssembleMarketProposalPayloadHexForSigning(req *pb_api.CreateMarketRequest) (string, error) {
+	marketIdBigInt, err := Uuid7_to_bigint(req.MarketId)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert MarketId: %v", err)
+	}
+
+	var payloadHex strings.Builder
+	fmt.Fprintf(&payloadHex, "%02x%032x", 0xfb, marketIdBigInt) // 0xfb = market proposal, uint128
+	for _, field := range []string{req.Net, req.Statement, req.ImageUrl, req.GetClosesAt(), req.Description} {
+		fmt.Fprintf(&payloadHex, "%08x%x", len(field), field) // uint32 byte length, then the utf8 bytes (closesAt is empty if unset)
+	}
+	fmt.Fprintf(&payloadHex, "%02x", len(req.CategoryIds)) // uint8 count (at most 20), then a uint32 per category
+	for _, categoryId := range req.CategoryIds {
+		fmt.Fprintf(&payloadHex, "%08x", uint32(categoryId))
+	}
+	return payloadHex.String(), nil
+}
+
 func Uuid7_to_bigint(uuid7 string) (*big.Int, error) {
 	// Remove all hyphens from the UUID7 string
 	uuid7Cleaned := strings.ReplaceAll(uuid7, "-", "")
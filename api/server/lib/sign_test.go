@@ -0,0 +1,147 @@
+package lib
+
+import (
+	"testing"
+
+	pb_api "api/gen"
+)
+
+// fixed payload vectors - assemblePayloadHexForSigning(...) in ./web.eng/lib/utils.ts must produce the same hex strings
+const (
+	testMarketId   = "01989c0a-87e8-77e8-8000-000000000003"
+	testTxId       = "019b45b8-3701-7342-a16c-7fb8a8023f17"
+	testEvmAddress = "440a1d7af93b92920bce50b4c0d2a8e6dcfebfd6"
+)
+
+func TestAssemblePayloadHexForSigning(t *testing.T) {
+	tests := []struct {
+		name        string
+		priceUsd    float64
+		qty         float64
+		timeInForce string
+		expiresAt   string
+		want        string
+	}{
+		{
+			name:     "buy, empty time-in-force (gtc)",
+			priceUsd: 0.5,
+			qty:      10,
+			want:     "f000000000000000000000000000000000000000000000000000000000004c4b40440a1d7af93b92920bce50b4c0d2a8e6dcfebfd601989c0a87e877e88000000000000003019b45b837017342a16c7fb8a8023f17000000000000000000",
+		},
+		{
+			name:        "sell, gtd",
+			priceUsd:    -0.25,
+			qty:         8,
+			timeInForce: TIME_IN_FORCE_GTD,
+			expiresAt:   "2026-01-01T00:00:00.000Z",
+			want:        "f100000000000000000000000000000000000000000000000000000000001e8480440a1d7af93b92920bce50b4c0d2a8e6dcfebfd601989c0a87e877e88000000000000003019b45b837017342a16c7fb8a8023f1701000000006955b900",
+		},
+		{
+			name:        "buy, fok",
+			priceUsd:    0.75,
+			qty:         4,
+			timeInForce: TIME_IN_FORCE_FOK,
+			want:        "f000000000000000000000000000000000000000000000000000000000002dc6c0440a1d7af93b92920bce50b4c0d2a8e6dcfebfd601989c0a87e877e88000000000000003019b45b837017342a16c7fb8a8023f17030000000000000000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AssemblePayloadHexForSigning(&pb_api.PredictionIntentRequest{
+				PriceUsd:    tt.priceUsd,
+				Qty:         tt.qty,
+				MarketId:    testMarketId,
+				EvmAddress:  testEvmAddress,
+				TxId:        testTxId,
+				TimeInForce: tt.timeInForce,
+				ExpiresAt:   tt.expiresAt,
+			}, 6)
+			if err != nil {
+				t.Fatalf("AssemblePayloadHexForSigning() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("AssemblePayloadHexForSigning() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssemblePayloadHexForSigningForContractVersion(t *testing.T) {
+	req := &pb_api.PredictionIntentRequest{
+		PriceUsd:   0.5,
+		Qty:        10,
+		MarketId:   testMarketId,
+		EvmAddress: testEvmAddress,
+		TxId:       testTxId,
+	}
+
+	// a legacy smart contract signs the same fields without the time-in-force and expiry
+	got, err := AssemblePayloadHexForSigningForContractVersion(req, 6, PRISM_CONTRACT_VERSION_LEGACY)
+	if err != nil {
+		t.Fatalf("legacy payload error = %v", err)
+	}
+	want := "f000000000000000000000000000000000000000000000000000000000004c4b40440a1d7af93b92920bce50b4c0d2a8e6dcfebfd601989c0a87e877e88000000000000003019b45b837017342a16c7fb8a8023f17"
+	if got != want {
+		t.Errorf("legacy payload =\n%s\nwant\n%s", got, want)
+	}
+
+	req.TimeInForce = TIME_IN_FORCE_IOC
+	if _, err := AssemblePayloadHexForSigningForContractVersion(req, 6, PRISM_CONTRACT_VERSION_LEGACY); err == nil {
+		t.Errorf("legacy payload of an ioc intent: expected an error")
+	}
+	if _, err := AssemblePayloadHexForSigningForContractVersion(req, 6, PRISM_CONTRACT_VERSION_UNKNOWN); err == nil {
+		t.Errorf("payload for an unknown contract version: expected an error")
+	}
+}
+
+func TestTimeInForceCode(t *testing.T) {
+	tests := []struct {
+		timeInForce string
+		want        uint8
+		wantErr     bool
+	}{
+		{"", 0, false},
+		{"gtc", 0, false},
+		{"GTC", 0, false},
+		{"gtd", 1, false},
+		{"ioc", 2, false},
+		{"fok", 3, false},
+		{"day", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := TimeInForceCode(tt.timeInForce)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("TimeInForceCode(%q) error = %v, wantErr %t", tt.timeInForce, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("TimeInForceCode(%q) = %d, want %d", tt.timeInForce, got, tt.want)
+		}
+	}
+}
+
+func TestExpiresAtUnix(t *testing.T) {
+	tests := []struct {
+		expiresAt string
+		want      uint64
+		wantErr   bool
+	}{
+		{"", 0, false},
+		{"2026-01-01T00:00:00.000Z", 1767225600, false},
+		{"2026-01-01T00:00:00.999Z", 1767225600, false}, // unix seconds - the milliseconds are dropped
+		{"1970-01-01T00:00:00.000Z", 0, true},
+		{"2026-01-01 00:00:00", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ExpiresAtUnix(tt.expiresAt)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ExpiresAtUnix(%q) error = %v, wantErr %t", tt.expiresAt, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ExpiresAtUnix(%q) = %d, want %d", tt.expiresAt, got, tt.want)
+		}
+	}
+}
@@ -17,3 +17,22 @@ const (
 	KEY_TYPE_ECDSA   HederaKeyType = 2
 	// Future key types
 )
+
+type MarketCreationStep string
+
+const (
+	MARKET_CREATION_PENDING           MarketCreationStep = "pending"
+	MARKET_CREATION_CONTRACT_CREATING MarketCreationStep = "contract_creating" // claimed by an attempt that is creating the market on the smart contract
+	MARKET_CREATION_CONTRACT_CREATED  MarketCreationStep = "contract_created"
+	MARKET_CREATION_CLOB_CREATED      MarketCreationStep = "clob_created"
+	MARKET_CREATION_COMPLETED         MarketCreationStep = "completed"
+)
+
+type PrismContractVersion uint8
+
+const (
+	PRISM_CONTRACT_VERSION_UNKNOWN PrismContractVersion = 0
+	PRISM_CONTRACT_VERSION_LEGACY  PrismContractVersion = 1 // signed payload without time-in-force/expiry, no trading fees, no voidMarket
+	PRISM_CONTRACT_VERSION_CURRENT PrismContractVersion = 2
+	// Future contract versions
+)
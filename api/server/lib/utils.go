@@ -25,7 +25,9 @@ import (
 	"gopkg.in/gomail.v2"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 type HTTPMethod string
@@ -319,9 +321,46 @@ func CreateMarketOnClob(marketId string) error {
 			MarketId: marketId,
 		},
 	)
+	if status.Code(err) == codes.AlreadyExists {
+		// the book is already there (e.g. a retried market creation) - nothing to do
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create a market (marketId=%s) on the CLOB (%s): %w", marketId, clobAddr, err)
 	}
 
 	return nil
 }
+
+/*
+*
+Delete a market (and every order resting on its book) from the clob
+*/
+func DeleteMarketOnClob(marketId string) error {
+	// TODO - use NATS
+
+	clobAddr := os.Getenv("CLOB_HOST") + ":" + os.Getenv("CLOB_PORT")
+
+	conn, err := grpc.NewClient(clobAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to delete market (marketId=%s) - connect to CLOB gRPC server failed: %w", marketId, err)
+	}
+	defer conn.Close()
+
+	clobClient := pb_clob.NewClobInternalClient(conn)
+	_, err = clobClient.DeleteMarket(
+		context.Background(),
+		&pb_clob.MarketIdRequest{
+			MarketId: marketId,
+		},
+	)
+	if status.Code(err) == codes.NotFound {
+		// the book is already gone (e.g. the CLOB restarted) - nothing to do
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete a market (marketId=%s) on the CLOB (%s): %w", marketId, clobAddr, err)
+	}
+
+	return nil
+}
@@ -22,25 +22,40 @@ type server struct {
 	pb_api.UnimplementedApiServiceInternalServer
 	pb_api.UnimplementedApiServicePublicServer
 
-	commentsRepository          repositories.CommentsRepository
-	dbRepository                repositories.DbRepository
-	marketsRepository           repositories.MarketsRepository
-	matchesRepository           repositories.MatchesRepository
-	positionsRepository         repositories.PositionsRepository
-	predictionIntentsRepository repositories.PredictionIntentsRepository
-	priceRepository             repositories.PriceRepository
-
-	commentsService          services.CommentsService
-	cronService              services.CronService
-	hederaService            services.HederaService
-	logService               services.LogService
-	marketsService           services.MarketsService
-	natsService              services.NatsService
-	newsletterService        services.NewsletterService
-	positionsService         services.PositionsService
-	predictionIntentsService services.PredictionIntentsService
-	prismService             services.Prism
-	priceService             services.PriceService
+	categoriesRepository         repositories.CategoriesRepository
+	commentsRepository           repositories.CommentsRepository
+	conditionalIntentsRepository repositories.ConditionalIntentsRepository
+	dbRepository                 repositories.DbRepository
+	feeSchedulesRepository       repositories.FeeSchedulesRepository
+	marketGroupsRepository       repositories.MarketGroupsRepository
+	marketProposalsRepository    repositories.MarketProposalsRepository
+	marketTemplatesRepository    repositories.MarketTemplatesRepository
+	marketsRepository            repositories.MarketsRepository
+	matchesRepository            repositories.MatchesRepository
+	positionsRepository          repositories.PositionsRepository
+	predictionIntentsRepository  repositories.PredictionIntentsRepository
+	priceRepository              repositories.PriceRepository
+	riskLimitsRepository         repositories.RiskLimitsRepository
+	settlementsRepository        repositories.SettlementsRepository
+
+	categoriesService         services.CategoriesService
+	commentsService           services.CommentsService
+	conditionalIntentsService services.ConditionalIntentsService
+	cronService               services.CronService
+	feesService               services.FeesService
+	hederaService             services.HederaService
+	logService                services.LogService
+	marketGroupsService       services.MarketGroupsService
+	marketProposalsService    services.MarketProposalsService
+	marketTemplatesService    services.MarketTemplatesService
+	marketsService            services.MarketsService
+	natsService               services.NatsService
+	newsletterService         services.NewsletterService
+	positionsService          services.PositionsService
+	predictionIntentsService  services.PredictionIntentsService
+	prismService              services.Prism
+	priceService              services.PriceService
+	riskService               services.RiskService
 
 	// don't forget to register in RegisterApiServiceServer grpc call in main()
 }
@@ -51,12 +66,29 @@ func (s *server) Health(ctx context.Context, req *pb_api.Empty) (*pb_api.StdResp
 	}, nil
 }
 
-func (s *server) CreatePredictionIntent(ctx context.Context, req *pb_api.PredictionIntentRequest) (*pb_api.StdResponse, error) {
+func (s *server) CreatePredictionIntent(ctx context.Context, req *pb_api.PredictionIntentRequest) (*pb_api.PredictionIntentResponse, error) {
 	if err := req.ValidateAll(); err != nil { // PGV validation
-		return &pb_api.StdResponse{Message: fmt.Sprintf("Invalid request: %v", err)}, err
+		return &pb_api.PredictionIntentResponse{Message: fmt.Sprintf("Invalid request: %v", err)}, err
 	}
 
 	response, err := s.predictionIntentsService.CreatePredictionIntent(req)
+	return response, err
+}
+
+func (s *server) CreatePredictionIntents(ctx context.Context, req *pb_api.PredictionIntentsRequest) (*pb_api.PredictionIntentsResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	return s.predictionIntentsService.CreatePredictionIntents(req)
+}
+
+func (s *server) ReplacePredictionIntent(ctx context.Context, req *pb_api.ReplacePredictionIntentRequest) (*pb_api.StdResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return &pb_api.StdResponse{Message: fmt.Sprintf("Invalid request: %v", err)}, err
+	}
+
+	response, err := s.predictionIntentsService.ReplacePredictionIntent(req)
 
 	return &pb_api.StdResponse{
 		Message: response,
@@ -68,13 +100,35 @@ func (s *server) GetMarketById(ctx context.Context, req *pb_api.MarketIdRequest)
 	return result, err
 }
 
-func (s *server) GetMarkets(ctx context.Context, req *pb_api.LimitOffsetRequest) (*pb_api.MarketsResponse, error) {
-	result, err := s.marketsService.GetMarkets(req.GetLimit(), req.GetOffset())
+func (s *server) GetMarkets(ctx context.Context, req *pb_api.GetMarketsRequest) (*pb_api.MarketsResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketsService.GetMarkets(req)
 	return result, err
 }
 
-func (s *server) CreateMarket(ctx context.Context, req *pb_api.CreateMarketRequest) (*pb_api.CreateMarketResponse, error) {
-	result, err := s.marketsService.CreateMarket(req)
+func (s *server) GetCategories(ctx context.Context, req *pb_api.Empty) (*pb_api.CategoriesResponse, error) {
+	result, err := s.categoriesService.GetCategories()
+	return result, err
+}
+
+func (s *server) ProposeMarket(ctx context.Context, req *pb_api.ProposeMarketRequest) (*pb_api.MarketProposal, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketProposalsService.ProposeMarket(req)
+	return result, err
+}
+
+func (s *server) GetMarketGroup(ctx context.Context, req *pb_api.MarketGroupIdRequest) (*pb_api.MarketGroupResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketGroupsService.GetMarketGroup(req.GroupId)
 	return result, err
 }
 
@@ -122,11 +176,256 @@ func (s *server) TriggerRecreateClob(ctx context.Context, req *pb_api.Empty) (*p
 	}, err
 }
 
+func (s *server) ResolveMarket(ctx context.Context, req *pb_api.ResolveMarketRequest) (*pb_api.MarketResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketsService.ResolveMarket(req)
+	return result, err
+}
+
+func (s *server) VoidMarket(ctx context.Context, req *pb_api.VoidMarketRequest) (*pb_api.MarketResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketsService.VoidMarket(req)
+	return result, err
+}
+
+func (s *server) PauseMarket(ctx context.Context, req *pb_api.MarketIdRequest) (*pb_api.MarketResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketsService.PauseMarket(req.MarketId)
+	return result, err
+}
+
+func (s *server) ResumeMarket(ctx context.Context, req *pb_api.MarketIdRequest) (*pb_api.MarketResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketsService.ResumeMarket(req.MarketId)
+	return result, err
+}
+
+func (s *server) SuspendMarket(ctx context.Context, req *pb_api.MarketIdRequest) (*pb_api.MarketResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketsService.SuspendMarket(req.MarketId)
+	return result, err
+}
+
+func (s *server) SetMarketCategories(ctx context.Context, req *pb_api.SetMarketCategoriesRequest) (*pb_api.StdResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.categoriesService.SetMarketCategories(req)
+	return result, err
+}
+
+func (s *server) CreateMarket(ctx context.Context, req *pb_api.CreateMarketRequest) (*pb_api.CreateMarketResponse, error) {
+	result, err := s.marketsService.CreateMarket(req)
+	return result, err
+}
+
+func (s *server) GetMarketProposals(ctx context.Context, req *pb_api.GetMarketProposalsRequest) (*pb_api.MarketProposalsResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketProposalsService.GetMarketProposals(req)
+	return result, err
+}
+
+func (s *server) ApproveMarketProposal(ctx context.Context, req *pb_api.MarketIdRequest) (*pb_api.CreateMarketResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketProposalsService.ApproveMarketProposal(req.MarketId)
+	return result, err
+}
+
+func (s *server) RejectMarketProposal(ctx context.Context, req *pb_api.RejectMarketProposalRequest) (*pb_api.MarketProposal, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketProposalsService.RejectMarketProposal(req)
+	return result, err
+}
+
+func (s *server) CreateMarketGroup(ctx context.Context, req *pb_api.CreateMarketGroupRequest) (*pb_api.MarketGroupResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketGroupsService.CreateMarketGroup(req)
+	return result, err
+}
+
+func (s *server) ResolveMarketGroup(ctx context.Context, req *pb_api.ResolveMarketGroupRequest) (*pb_api.MarketGroupResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketGroupsService.ResolveMarketGroup(req)
+	return result, err
+}
+
+func (s *server) CreateMarketTemplate(ctx context.Context, req *pb_api.MarketTemplateRequest) (*pb_api.MarketTemplate, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketTemplatesService.CreateMarketTemplate(req)
+	return result, err
+}
+
+func (s *server) UpdateMarketTemplate(ctx context.Context, req *pb_api.MarketTemplateRequest) (*pb_api.MarketTemplate, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketTemplatesService.UpdateMarketTemplate(req)
+	return result, err
+}
+
+func (s *server) GetMarketTemplates(ctx context.Context, req *pb_api.Empty) (*pb_api.MarketTemplatesResponse, error) {
+	result, err := s.marketTemplatesService.GetMarketTemplates()
+	return result, err
+}
+
+func (s *server) PreviewMarketTemplate(ctx context.Context, req *pb_api.PreviewMarketTemplateRequest) (*pb_api.PreviewMarketTemplateResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.marketTemplatesService.PreviewMarketTemplate(req)
+	return result, err
+}
+
+func (s *server) SetRiskLimits(ctx context.Context, req *pb_api.RiskLimits) (*pb_api.RiskLimits, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.riskService.SetRiskLimits(req)
+	return result, err
+}
+
+func (s *server) GetRiskLimits(ctx context.Context, req *pb_api.Empty) (*pb_api.RiskLimitsResponse, error) {
+	result, err := s.riskService.GetRiskLimits()
+	return result, err
+}
+
+func (s *server) SetFeeSchedule(ctx context.Context, req *pb_api.FeeSchedule) (*pb_api.FeeSchedule, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	result, err := s.feesService.SetFeeSchedule(req)
+	return result, err
+}
+
+func (s *server) GetFeeSchedules(ctx context.Context, req *pb_api.Empty) (*pb_api.FeeSchedulesResponse, error) {
+	result, err := s.feesService.GetFeeSchedules()
+	return result, err
+}
+
+func (s *server) ListDeadLetteredSettlements(ctx context.Context, req *pb_api.Empty) (*pb_api.SettlementsResponse, error) {
+	result, err := s.predictionIntentsService.ListDeadLetteredSettlements()
+	return result, err
+}
+
+func (s *server) RetrySettlement(ctx context.Context, req *pb_api.SettlementRequest) (*pb_api.Settlement, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	return s.predictionIntentsService.RetrySettlement(req)
+}
+
+func (s *server) AbandonSettlement(ctx context.Context, req *pb_api.SettlementRequest) (*pb_api.AbandonSettlementResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	return s.predictionIntentsService.AbandonSettlement(req)
+}
+
 func (s *server) CancelPredictionIntent(ctx context.Context, req *pb_api.CancelOrderRequest) (*pb_api.StdResponse, error) {
-	cancelResp, err := s.predictionIntentsService.CancelPredictionIntent(req.MarketId, req.TxId)
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	cancelResp, err := s.predictionIntentsService.CancelPredictionIntent(req)
 	return cancelResp, err
 }
 
+func (s *server) CancelAllPredictionIntents(ctx context.Context, req *pb_api.CancelAllPredictionIntentsRequest) (*pb_api.CancelAllPredictionIntentsResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	return s.predictionIntentsService.CancelAllPredictionIntents(req)
+}
+
+func (s *server) GetPredictionIntent(ctx context.Context, req *pb_api.GetPredictionIntentRequest) (*pb_api.PredictionIntentStatus, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	return s.predictionIntentsService.GetPredictionIntent(req)
+}
+
+func (s *server) ListPredictionIntents(ctx context.Context, req *pb_api.ListPredictionIntentsRequest) (*pb_api.PredictionIntentStatusesResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	return s.predictionIntentsService.ListPredictionIntents(req)
+}
+
+func (s *server) GetSettlements(ctx context.Context, req *pb_api.GetSettlementsRequest) (*pb_api.SettlementsResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	return s.predictionIntentsService.GetSettlements(req)
+}
+
+func (s *server) CreateConditionalIntent(ctx context.Context, req *pb_api.ConditionalIntentRequest) (*pb_api.ConditionalIntent, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	return s.conditionalIntentsService.CreateConditionalIntent(req)
+}
+
+func (s *server) ListConditionalIntents(ctx context.Context, req *pb_api.ListConditionalIntentsRequest) (*pb_api.ConditionalIntentsResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	return s.conditionalIntentsService.ListConditionalIntents(req)
+}
+
+func (s *server) CancelConditionalIntent(ctx context.Context, req *pb_api.CancelOrderRequest) (*pb_api.StdResponse, error) {
+	if err := req.ValidateAll(); err != nil { // PGV validation
+		return nil, err
+	}
+
+	return s.conditionalIntentsService.CancelConditionalIntent(req)
+}
+
 func main() {
 	// check env vars are available (.config.ENV and .secrets.ENV are loaded):
 	vars := []string{
@@ -199,6 +498,13 @@ func main() {
 	// data layer
 	/////
 	// initialize database
+	categoriesRepository := repositories.CategoriesRepository{}
+	err = categoriesRepository.InitDb()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer categoriesRepository.CloseDb()
+
 	commentsRepository := repositories.CommentsRepository{}
 	err = commentsRepository.InitDb()
 	if err != nil {
@@ -213,6 +519,34 @@ func main() {
 	}
 	defer dbRepository.CloseDb()
 
+	conditionalIntentsRepository := repositories.ConditionalIntentsRepository{}
+	err = conditionalIntentsRepository.InitDb()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer conditionalIntentsRepository.CloseDb()
+
+	marketGroupsRepository := repositories.MarketGroupsRepository{}
+	err = marketGroupsRepository.InitDb()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer marketGroupsRepository.CloseDb()
+
+	marketProposalsRepository := repositories.MarketProposalsRepository{}
+	err = marketProposalsRepository.InitDb()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer marketProposalsRepository.CloseDb()
+
+	marketTemplatesRepository := repositories.MarketTemplatesRepository{}
+	err = marketTemplatesRepository.InitDb()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer marketTemplatesRepository.CloseDb()
+
 	marketsRepository := repositories.MarketsRepository{}
 	err = marketsRepository.InitDb()
 	if err != nil {
@@ -241,6 +575,20 @@ func main() {
 	}
 	defer priceRepository.CloseDb()
 
+	feeSchedulesRepository := repositories.FeeSchedulesRepository{}
+	err = feeSchedulesRepository.InitDb()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer feeSchedulesRepository.CloseDb()
+
+	riskLimitsRepository := repositories.RiskLimitsRepository{}
+	err = riskLimitsRepository.InitDb()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer riskLimitsRepository.CloseDb()
+
 	matchesRepository := repositories.MatchesRepository{}
 	err = matchesRepository.InitDb()
 	if err != nil {
@@ -248,6 +596,13 @@ func main() {
 	}
 	defer matchesRepository.CloseDb()
 
+	settlementsRepository := repositories.SettlementsRepository{}
+	err = settlementsRepository.InitDb()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer settlementsRepository.CloseDb()
+
 	/////
 	// service layer
 	/////
@@ -257,7 +612,7 @@ func main() {
 
 	// initialize Hedera service
 	hederaService := services.HederaService{}
-	err = hederaService.InitHedera(&logService, &dbRepository, &priceRepository, &marketsRepository, &matchesRepository)
+	err = hederaService.InitHedera(&logService, &dbRepository, &priceRepository, &marketsRepository, &matchesRepository, &settlementsRepository)
 	if err != nil {
 		log.Fatalf("Failed to initialize Hedera service: %v", err)
 	}
@@ -270,13 +625,62 @@ func main() {
 		log.Fatalf("Failed to initialize Price service: %v", err)
 	}
 
+	// initialize Fees service (maker/taker fees computed per match)
+	feesService := services.FeesService{}
+	err = feesService.Init(&logService, &hederaService, &feeSchedulesRepository, &predictionIntentsRepository, &marketsRepository)
+	if err != nil {
+		log.Fatalf("Failed to initialize Fees service: %v", err)
+	}
+
+	// initialize NATS
+	natsService := services.NatsService{}
+	err = natsService.InitNATS(&logService, &hederaService, &feesService, &dbRepository, &matchesRepository, &predictionIntentsRepository, &marketsRepository, &settlementsRepository)
+	if err != nil {
+		log.Fatalf("Failed to initialize NATS: %v", err)
+	}
+	defer natsService.CloseNATS()
+	// NATS start listening for matches
+	natsService.HandleOrderMatches()
+	// NATS start relaying the clob outbox (prediction intents committed to the db) to the CLOB
+	natsService.RelayClobOutbox()
+	// NATS start retrying the failed settlements (with backoff)
+	natsService.RetryFailedSettlements()
+
 	// initialize Markets service
 	marketsService := services.MarketsService{}
-	err = marketsService.Init(&logService, &marketsRepository, &hederaService, &priceService)
+	err = marketsService.Init(&logService, &marketsRepository, &hederaService, &priceService, &natsService)
 	if err != nil {
 		log.Fatalf("Failed to initialize Markets service: %v", err)
 	}
 
+	// initialize MarketGroups service
+	marketGroupsService := services.MarketGroupsService{}
+	err = marketGroupsService.Init(&logService, &marketGroupsRepository, &marketsService)
+	if err != nil {
+		log.Fatalf("Failed to initialize MarketGroups service: %v", err)
+	}
+
+	// initialize MarketProposals service
+	marketProposalsService := services.MarketProposalsService{}
+	err = marketProposalsService.Init(&logService, &marketProposalsRepository, &marketsRepository, &marketsService, &hederaService)
+	if err != nil {
+		log.Fatalf("Failed to initialize MarketProposals service: %v", err)
+	}
+
+	// initialize MarketTemplates service
+	marketTemplatesService := services.MarketTemplatesService{}
+	err = marketTemplatesService.Init(&logService, &marketTemplatesRepository, &marketsService)
+	if err != nil {
+		log.Fatalf("Failed to initialize MarketTemplates service: %v", err)
+	}
+
+	// initialize Categories service
+	categoriesService := services.CategoriesService{}
+	err = categoriesService.Init(&logService, &categoriesRepository)
+	if err != nil {
+		log.Fatalf("Failed to initialize Categories service: %v", err)
+	}
+
 	// initialize Comments service
 	commentsService := services.CommentsService{}
 	err = commentsService.Init(&logService, &commentsRepository)
@@ -298,25 +702,29 @@ func main() {
 		log.Fatalf("Failed to initialize Positions service: %v", err)
 	}
 
-	// initialize NATS
-	natsService := services.NatsService{}
-	err = natsService.InitNATS(&logService, &hederaService, &dbRepository, &matchesRepository, &predictionIntentsRepository)
+	// initialize Risk service (pre-trade risk checks)
+	riskService := services.RiskService{}
+	err = riskService.Init(&logService, &riskLimitsRepository, &predictionIntentsRepository, &positionsRepository, &hederaService, &feesService)
 	if err != nil {
-		log.Fatalf("Failed to initialize NATS: %v", err)
+		log.Fatalf("Failed to initialize Risk service: %v", err)
 	}
-	defer natsService.CloseNATS()
-	// NATS start listening for matches
-	natsService.HandleOrderMatches()
 
 	// initialize PredictionIntents service
 	predictionIntentsService := services.PredictionIntentsService{}
-	err = predictionIntentsService.Init(&logService, &dbRepository, &marketsRepository, &natsService, &hederaService, &predictionIntentsRepository)
+	err = predictionIntentsService.Init(&logService, &dbRepository, &marketsRepository, &natsService, &hederaService, &predictionIntentsRepository, &matchesRepository, &settlementsRepository, &riskService)
 	if err != nil {
 		log.Fatalf("Failed to initialize PredictionIntents service: %v", err)
 	}
 
+	// initialize ConditionalIntents service (stop/take-profit triggers evaluated on every settlement price)
+	conditionalIntentsService := services.ConditionalIntentsService{}
+	err = conditionalIntentsService.Init(&logService, &conditionalIntentsRepository, &priceService, &hederaService, &predictionIntentsService)
+	if err != nil {
+		log.Fatalf("Failed to initialize ConditionalIntents service: %v", err)
+	}
+
 	cronService := services.CronService{}
-	err = cronService.Init(&logService, &marketsRepository, &predictionIntentsRepository, &hederaService, &predictionIntentsService)
+	err = cronService.Init(&logService, &marketsRepository, &marketTemplatesRepository, &predictionIntentsRepository, &hederaService, &predictionIntentsService, &marketsService, &marketTemplatesService)
 	if err != nil {
 		log.Fatalf("Failed to initialize Cron service: %v", err)
 	}
@@ -341,25 +749,40 @@ func main() {
 
 	grpcServer := grpc.NewServer()
 	sharedServer := &server{
-		commentsRepository:          commentsRepository,
-		dbRepository:                dbRepository,
-		marketsRepository:           marketsRepository,
-		matchesRepository:           matchesRepository,
-		positionsRepository:         positionsRepository,
-		predictionIntentsRepository: predictionIntentsRepository,
-		priceRepository:             priceRepository,
-
-		commentsService:          commentsService,
-		cronService:              cronService,
-		hederaService:            hederaService,
-		logService:               logService,
-		marketsService:           marketsService,
-		natsService:              natsService,
-		newsletterService:        newsletterService,
-		positionsService:         positionsService,
-		predictionIntentsService: predictionIntentsService,
-		priceService:             priceService,
-		prismService:             prismService,
+		categoriesRepository:         categoriesRepository,
+		commentsRepository:           commentsRepository,
+		conditionalIntentsRepository: conditionalIntentsRepository,
+		dbRepository:                 dbRepository,
+		feeSchedulesRepository:       feeSchedulesRepository,
+		marketGroupsRepository:       marketGroupsRepository,
+		marketProposalsRepository:    marketProposalsRepository,
+		marketTemplatesRepository:    marketTemplatesRepository,
+		marketsRepository:            marketsRepository,
+		matchesRepository:            matchesRepository,
+		positionsRepository:          positionsRepository,
+		predictionIntentsRepository:  predictionIntentsRepository,
+		priceRepository:              priceRepository,
+		riskLimitsRepository:         riskLimitsRepository,
+		settlementsRepository:        settlementsRepository,
+
+		categoriesService:         categoriesService,
+		commentsService:           commentsService,
+		conditionalIntentsService: conditionalIntentsService,
+		cronService:               cronService,
+		feesService:               feesService,
+		hederaService:             hederaService,
+		logService:                logService,
+		marketGroupsService:       marketGroupsService,
+		marketProposalsService:    marketProposalsService,
+		marketTemplatesService:    marketTemplatesService,
+		marketsService:            marketsService,
+		natsService:               natsService,
+		newsletterService:         newsletterService,
+		positionsService:          positionsService,
+		predictionIntentsService:  predictionIntentsService,
+		priceService:              priceService,
+		riskService:               riskService,
+		prismService:              prismService,
 	}
 	// must pass the grpc server to bother internal and the public servers!
 	pb_api.RegisterApiServiceInternalServer(grpcServer, sharedServer)
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	sqlc "api/gen/sqlc"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+)
+
+type CategoriesRepository struct {
+	db *sql.DB
+}
+
+func (categoriesRepository *CategoriesRepository) CloseDb() error {
+	var err = categoriesRepository.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close database: %v", err)
+	}
+	return nil
+}
+
+func (categoriesRepository *CategoriesRepository) InitDb() error {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_UNAME"), os.Getenv("DB_PWORD"), os.Getenv("DB_NAME"))
+
+	var db, err = sql.Open("postgres", connStr)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	categoriesRepository.db = db
+
+	// Verify connection
+	if err = db.Ping(); err != nil {
+		return fmt.Errorf("failed to ping database: %v", err)
+	}
+
+	log.Println("DB: CategoriesRepository connected successfully")
+	return nil
+}
+
+func (categoriesRepository *CategoriesRepository) GetActiveCategories() ([]sqlc.Category, error) {
+	if categoriesRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(categoriesRepository.db)
+	categories, err := q.GetActiveCategories(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("GetActiveCategories failed: %v", err)
+	}
+
+	return categories, nil
+}
+
+// SetMarketCategories replaces all the categories attached to a market
+func (categoriesRepository *CategoriesRepository) SetMarketCategories(marketId string, categoryIds []int32) error {
+	if categoriesRepository.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.Parse(marketId)
+	if err != nil {
+		return fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	// OK
+	// Start a transaction
+	tx, err := categoriesRepository.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	q := sqlc.New(tx)
+	err = q.DeleteMarketCategories(context.Background(), marketUUID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("DeleteMarketCategories failed: %v", err)
+	}
+
+	for _, categoryId := range categoryIds {
+		err = q.AddMarketCategory(context.Background(), sqlc.AddMarketCategoryParams{
+			MarketID:   marketUUID,
+			CategoryID: categoryId,
+		})
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("AddMarketCategory (categoryId=%d) failed: %v", categoryId, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("Set %d categories on market in database: %s", len(categoryIds), marketId)
+	return nil
+}
@@ -0,0 +1,190 @@
+package repositories
+
+import (
+	sqlc "api/gen/sqlc"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb_api "api/gen"
+)
+
+type ConditionalIntentsRepository struct {
+	db *sql.DB
+}
+
+func (conditionalIntentsRepository *ConditionalIntentsRepository) CloseDb() error {
+	var err = conditionalIntentsRepository.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close database: %v", err)
+	}
+	return nil
+}
+
+func (conditionalIntentsRepository *ConditionalIntentsRepository) InitDb() error {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_UNAME"), os.Getenv("DB_PWORD"), os.Getenv("DB_NAME"))
+
+	var db, err = sql.Open("postgres", connStr)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	conditionalIntentsRepository.db = db
+
+	// Verify connection
+	if err = db.Ping(); err != nil {
+		return fmt.Errorf("failed to ping database: %v", err)
+	}
+
+	log.Println("DB: ConditionalIntentsRepository connected successfully")
+	return nil
+}
+
+func (conditionalIntentsRepository *ConditionalIntentsRepository) CreateConditionalIntent(req *pb_api.ConditionalIntentRequest) (*sqlc.ConditionalIntent, error) {
+	if conditionalIntentsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	txUUID, err := uuid.Parse(req.Intent.TxId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid txId uuid: %v", err)
+	}
+
+	marketUUID, err := uuid.Parse(req.Intent.MarketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	intent, err := protojson.Marshal(req.Intent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal PredictionIntentRequest: %v", err)
+	}
+
+	q := sqlc.New(conditionalIntentsRepository.db)
+	conditionalIntent, err := q.CreateConditionalIntent(context.Background(), sqlc.CreateConditionalIntentParams{
+		TxID:             txUUID,
+		MarketID:         marketUUID,
+		AccountID:        req.Intent.AccountId,
+		TriggerCondition: strings.ToLower(req.TriggerCondition),
+		TriggerPriceUsd:  req.TriggerPriceUsd,
+		Intent:           intent,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CreateConditionalIntent failed: %v", err)
+	}
+
+	log.Printf("Created conditional intent in database for txId: %s (%s %f)", req.Intent.TxId, req.TriggerCondition, req.TriggerPriceUsd)
+	return &conditionalIntent, nil
+}
+
+func (conditionalIntentsRepository *ConditionalIntentsRepository) GetConditionalIntent(txId uuid.UUID) (*sqlc.ConditionalIntent, error) {
+	if conditionalIntentsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(conditionalIntentsRepository.db)
+	conditionalIntent, err := q.GetConditionalIntent(context.Background(), txId)
+	if err != nil {
+		return nil, fmt.Errorf("GetConditionalIntent failed: %v", err)
+	}
+
+	return &conditionalIntent, nil
+}
+
+func (conditionalIntentsRepository *ConditionalIntentsRepository) GetPendingConditionalIntentsByMarketId(marketId string) ([]sqlc.ConditionalIntent, error) {
+	if conditionalIntentsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.Parse(marketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	q := sqlc.New(conditionalIntentsRepository.db)
+	conditionalIntents, err := q.GetPendingConditionalIntentsByMarketId(context.Background(), marketUUID)
+	if err != nil {
+		return nil, fmt.Errorf("GetPendingConditionalIntentsByMarketId failed: %v", err)
+	}
+
+	return conditionalIntents, nil
+}
+
+func (conditionalIntentsRepository *ConditionalIntentsRepository) GetPendingConditionalIntentsByAccountId(req *pb_api.ListConditionalIntentsRequest) ([]sqlc.ConditionalIntent, error) {
+	if conditionalIntentsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	params := sqlc.GetPendingConditionalIntentsByAccountIdParams{
+		AccountID: req.AccountId,
+	}
+	if req.MarketId != nil {
+		marketUUID, err := uuid.Parse(*req.MarketId)
+		if err != nil {
+			return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+		}
+		params.MarketID = uuid.NullUUID{UUID: marketUUID, Valid: true}
+	}
+
+	q := sqlc.New(conditionalIntentsRepository.db)
+	conditionalIntents, err := q.GetPendingConditionalIntentsByAccountId(context.Background(), params)
+	if err != nil {
+		return nil, fmt.Errorf("GetPendingConditionalIntentsByAccountId failed: %v", err)
+	}
+
+	return conditionalIntents, nil
+}
+
+// MarkConditionalIntentAsTriggered claims a pending trigger - returns false if it already fired or was cancelled
+func (conditionalIntentsRepository *ConditionalIntentsRepository) MarkConditionalIntentAsTriggered(txId uuid.UUID) (bool, error) {
+	if conditionalIntentsRepository.db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(conditionalIntentsRepository.db)
+	rows, err := q.MarkConditionalIntentAsTriggered(context.Background(), txId)
+	if err != nil {
+		return false, fmt.Errorf("MarkConditionalIntentAsTriggered failed: %v", err)
+	}
+
+	return rows == 1, nil
+}
+
+func (conditionalIntentsRepository *ConditionalIntentsRepository) MarkConditionalIntentAsFailed(txId uuid.UUID, reason string) error {
+	if conditionalIntentsRepository.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(conditionalIntentsRepository.db)
+	err := q.MarkConditionalIntentAsFailed(context.Background(), sqlc.MarkConditionalIntentAsFailedParams{
+		TxID:          txId,
+		FailureReason: sql.NullString{String: reason, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("MarkConditionalIntentAsFailed failed: %v", err)
+	}
+
+	log.Printf("Marked conditional intent as failed in database for txId: %s (%s)", txId.String(), reason)
+	return nil
+}
+
+// CancelConditionalIntent cancels a pending trigger - returns false if it already fired or was cancelled
+func (conditionalIntentsRepository *ConditionalIntentsRepository) CancelConditionalIntent(txId uuid.UUID) (bool, error) {
+	if conditionalIntentsRepository.db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(conditionalIntentsRepository.db)
+	rows, err := q.CancelConditionalIntent(context.Background(), txId)
+	if err != nil {
+		return false, fmt.Errorf("CancelConditionalIntent failed: %v", err)
+	}
+
+	log.Printf("Cancelled conditional intent in database for txId: %s", txId.String())
+	return rows == 1, nil
+}
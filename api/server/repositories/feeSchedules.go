@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	sqlc "api/gen/sqlc"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+
+	pb_api "api/gen"
+)
+
+type FeeSchedulesRepository struct {
+	db *sql.DB
+}
+
+func (feeSchedulesRepository *FeeSchedulesRepository) CloseDb() error {
+	var err = feeSchedulesRepository.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close database: %v", err)
+	}
+	return nil
+}
+
+func (feeSchedulesRepository *FeeSchedulesRepository) InitDb() error {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_UNAME"), os.Getenv("DB_PWORD"), os.Getenv("DB_NAME"))
+
+	var db, err = sql.Open("postgres", connStr)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	feeSchedulesRepository.db = db
+
+	// Verify connection
+	if err = db.Ping(); err != nil {
+		return fmt.Errorf("failed to ping database: %v", err)
+	}
+
+	log.Println("DB: FeeSchedulesRepository connected successfully")
+	return nil
+}
+
+// SetFeeSchedule replaces the fee schedule of the scope (net, or net + market) - returns nil if no fee is set (the scope's schedule is removed)
+func (feeSchedulesRepository *FeeSchedulesRepository) SetFeeSchedule(req *pb_api.FeeSchedule) (*sqlc.FeeSchedule, error) {
+	if feeSchedulesRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID := uuid.NullUUID{}
+	if req.MarketId != nil {
+		parsed, err := uuid.Parse(*req.MarketId)
+		if err != nil {
+			return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+		}
+		marketUUID = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+
+	// OK
+	// Start a transaction
+	tx, err := feeSchedulesRepository.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	q := sqlc.New(tx)
+	_, err = q.DeleteFeeSchedule(context.Background(), sqlc.DeleteFeeScheduleParams{
+		Net:      req.Net,
+		MarketID: marketUUID,
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("DeleteFeeSchedule failed: %v", err)
+	}
+
+	var feeSchedule *sqlc.FeeSchedule
+	if req.MakerFeeBps != nil || req.TakerFeeBps != nil {
+		created, err := q.CreateFeeSchedule(context.Background(), sqlc.CreateFeeScheduleParams{
+			Net:         req.Net,
+			MarketID:    marketUUID,
+			MakerFeeBps: int32(req.GetMakerFeeBps()), // unset => 0 (no fee)
+			TakerFeeBps: int32(req.GetTakerFeeBps()),
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("CreateFeeSchedule failed: %v", err)
+		}
+		feeSchedule = &created
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("Set fee schedule in database for net %s (marketId=%s)", req.Net, req.GetMarketId())
+	return feeSchedule, nil
+}
+
+func (feeSchedulesRepository *FeeSchedulesRepository) GetFeeSchedules() ([]sqlc.FeeSchedule, error) {
+	if feeSchedulesRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(feeSchedulesRepository.db)
+	feeSchedules, err := q.GetFeeSchedules(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("GetFeeSchedules failed: %v", err)
+	}
+
+	return feeSchedules, nil
+}
+
+// GetFeeSchedulesForMarket returns the network-wide schedule (if any) followed by the market's override (if any)
+func (feeSchedulesRepository *FeeSchedulesRepository) GetFeeSchedulesForMarket(net string, marketId string) ([]sqlc.FeeSchedule, error) {
+	if feeSchedulesRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.Parse(marketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	q := sqlc.New(feeSchedulesRepository.db)
+	feeSchedules, err := q.GetFeeSchedulesForMarket(context.Background(), sqlc.GetFeeSchedulesForMarketParams{
+		Net:      net,
+		MarketID: uuid.NullUUID{UUID: marketUUID, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetFeeSchedulesForMarket failed: %v", err)
+	}
+
+	return feeSchedules, nil
+}
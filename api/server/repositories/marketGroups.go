@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	sqlc "api/gen/sqlc"
+	"api/server/lib"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	pb_api "api/gen"
+)
+
+type MarketGroupsRepository struct {
+	db *sql.DB
+}
+
+func (marketGroupsRepository *MarketGroupsRepository) CloseDb() error {
+	var err = marketGroupsRepository.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close database: %v", err)
+	}
+	return nil
+}
+
+func (marketGroupsRepository *MarketGroupsRepository) InitDb() error {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_UNAME"), os.Getenv("DB_PWORD"), os.Getenv("DB_NAME"))
+
+	var db, err = sql.Open("postgres", connStr)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	marketGroupsRepository.db = db
+
+	// Verify connection
+	if err = db.Ping(); err != nil {
+		return fmt.Errorf("failed to ping database: %v", err)
+	}
+
+	log.Println("DB: MarketGroupsRepository connected successfully")
+	return nil
+}
+
+// CreateMarketGroup inserts the group row (a retry with the same groupId returns the existing row)
+func (marketGroupsRepository *MarketGroupsRepository) CreateMarketGroup(req *pb_api.CreateMarketGroupRequest) (*sqlc.MarketGroup, error) {
+	if marketGroupsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	groupUUID, err := uuid.Parse(req.GroupId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid groupId uuid: %v", err)
+	}
+
+	net := strings.ToLower(req.Net)
+	if !lib.IsValidNetwork(net) {
+		return nil, fmt.Errorf("invalid network: %s", net)
+	}
+
+	closesAt := sql.NullTime{} // optional: legs default to 30 days from now
+	if req.ClosesAt != nil {
+		closesAtTime, err := time.Parse(time.RFC3339, *req.ClosesAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid closesAt time format: %v", err)
+		}
+		closesAt = sql.NullTime{Time: closesAtTime, Valid: true}
+	}
+
+	description := strings.TrimSpace(req.Description)
+	imageUrl := strings.TrimSpace(req.ImageUrl)
+
+	q := sqlc.New(marketGroupsRepository.db)
+	err = q.CreateMarketGroup(context.Background(), sqlc.CreateMarketGroupParams{
+		GroupID:     groupUUID,
+		Net:         net,
+		Statement:   strings.TrimSpace(req.Statement),
+		Description: sql.NullString{String: description, Valid: description != ""},
+		ImageUrl:    sql.NullString{String: imageUrl, Valid: imageUrl != ""},
+		ClosesAt:    closesAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CreateMarketGroup failed: %v", err)
+	}
+
+	marketGroup, err := q.GetMarketGroup(context.Background(), groupUUID)
+	if err != nil {
+		return nil, fmt.Errorf("GetMarketGroup failed: %v", err)
+	}
+
+	log.Printf("Created market group in database: %s", marketGroup.GroupID.String())
+	return &marketGroup, nil
+}
+
+func (marketGroupsRepository *MarketGroupsRepository) GetMarketGroup(groupId string) (*sqlc.MarketGroup, error) {
+	if marketGroupsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	groupUUID, err := uuid.Parse(groupId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid groupId uuid: %v", err)
+	}
+
+	q := sqlc.New(marketGroupsRepository.db)
+	marketGroup, err := q.GetMarketGroup(context.Background(), groupUUID)
+	if err != nil {
+		return nil, fmt.Errorf("GetMarketGroup failed: %v", err)
+	}
+
+	return &marketGroup, nil
+}
+
+func (marketGroupsRepository *MarketGroupsRepository) GetMarketsByGroupId(groupId uuid.UUID) ([]sqlc.Market, error) {
+	if marketGroupsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(marketGroupsRepository.db)
+	markets, err := q.GetMarketsByGroupId(context.Background(), uuid.NullUUID{UUID: groupId, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("GetMarketsByGroupId failed: %v", err)
+	}
+
+	return markets, nil
+}
+
+// SetMarketGroupWinner records the winning leg - fails if a different winner was already recorded
+func (marketGroupsRepository *MarketGroupsRepository) SetMarketGroupWinner(groupId uuid.UUID, winningMarketId string) (*sqlc.MarketGroup, error) {
+	if marketGroupsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	winningMarketUUID, err := uuid.Parse(winningMarketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid winningMarketId uuid: %v", err)
+	}
+
+	q := sqlc.New(marketGroupsRepository.db)
+	marketGroup, err := q.SetMarketGroupWinner(context.Background(), sqlc.SetMarketGroupWinnerParams{
+		GroupID:         groupId,
+		WinningMarketID: uuid.NullUUID{UUID: winningMarketUUID, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("SetMarketGroupWinner failed (was a different winner already recorded?): %v", err)
+	}
+
+	log.Printf("Set winning market %s on market group in database: %s", winningMarketId, marketGroup.GroupID.String())
+	return &marketGroup, nil
+}
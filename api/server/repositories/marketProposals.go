@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	sqlc "api/gen/sqlc"
+	"api/server/lib"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb_api "api/gen"
+)
+
+type MarketProposalsRepository struct {
+	db *sql.DB
+}
+
+func (marketProposalsRepository *MarketProposalsRepository) CloseDb() error {
+	var err = marketProposalsRepository.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close database: %v", err)
+	}
+	return nil
+}
+
+func (marketProposalsRepository *MarketProposalsRepository) InitDb() error {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_UNAME"), os.Getenv("DB_PWORD"), os.Getenv("DB_NAME"))
+
+	var db, err = sql.Open("postgres", connStr)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	marketProposalsRepository.db = db
+
+	// Verify connection
+	if err = db.Ping(); err != nil {
+		return fmt.Errorf("failed to ping database: %v", err)
+	}
+
+	log.Println("DB: MarketProposalsRepository connected successfully")
+	return nil
+}
+
+func (marketProposalsRepository *MarketProposalsRepository) CreateMarketProposal(req *pb_api.ProposeMarketRequest) (*sqlc.MarketProposal, error) {
+	if marketProposalsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.Parse(req.Market.MarketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	net := strings.ToLower(req.Market.Net)
+	if !lib.IsValidNetwork(net) {
+		return nil, fmt.Errorf("invalid network: %s", net)
+	}
+
+	request, err := protojson.Marshal(req.Market)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal CreateMarketRequest: %v", err)
+	}
+
+	q := sqlc.New(marketProposalsRepository.db)
+	marketProposal, err := q.CreateMarketProposal(context.Background(), sqlc.CreateMarketProposalParams{
+		MarketID:     marketUUID,
+		Net:          net,
+		AccountID:    req.AccountId,
+		Request:      request,
+		Sig:          req.Sig,
+		PublicKeyHex: req.PublicKey,
+		KeyType:      int32(req.KeyType),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CreateMarketProposal failed: %v", err)
+	}
+
+	log.Printf("Created market proposal in database: %s (account %s)", marketProposal.MarketID.String(), marketProposal.AccountID)
+	return &marketProposal, nil
+}
+
+func (marketProposalsRepository *MarketProposalsRepository) GetMarketProposal(marketId string) (*sqlc.MarketProposal, error) {
+	if marketProposalsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.Parse(marketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	q := sqlc.New(marketProposalsRepository.db)
+	marketProposal, err := q.GetMarketProposal(context.Background(), marketUUID)
+	if err != nil {
+		return nil, fmt.Errorf("GetMarketProposal failed: %v", err)
+	}
+
+	return &marketProposal, nil
+}
+
+func (marketProposalsRepository *MarketProposalsRepository) GetMarketProposals(status *string, limit int32, offset int32) ([]sqlc.MarketProposal, error) {
+	if marketProposalsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	statusParam := sql.NullString{} // optional: NULL => all statuses
+	if status != nil {
+		statusParam = sql.NullString{String: *status, Valid: true}
+	}
+
+	q := sqlc.New(marketProposalsRepository.db)
+	marketProposals, err := q.GetMarketProposals(context.Background(), sqlc.GetMarketProposalsParams{
+		Status: statusParam,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetMarketProposals failed: %v", err)
+	}
+
+	return marketProposals, nil
+}
+
+func (marketProposalsRepository *MarketProposalsRepository) ApproveMarketProposal(marketId string) (*sqlc.MarketProposal, error) {
+	if marketProposalsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.Parse(marketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	q := sqlc.New(marketProposalsRepository.db)
+	marketProposal, err := q.ApproveMarketProposal(context.Background(), marketUUID)
+	if err != nil {
+		return nil, fmt.Errorf("ApproveMarketProposal failed (is the proposal still pending?): %v", err)
+	}
+
+	log.Printf("Approved market proposal in database: %s", marketProposal.MarketID.String())
+	return &marketProposal, nil
+}
+
+func (marketProposalsRepository *MarketProposalsRepository) RejectMarketProposal(marketId string, reason string) (*sqlc.MarketProposal, error) {
+	if marketProposalsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.Parse(marketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	q := sqlc.New(marketProposalsRepository.db)
+	marketProposal, err := q.RejectMarketProposal(context.Background(), sqlc.RejectMarketProposalParams{
+		MarketID:     marketUUID,
+		RejectReason: sql.NullString{String: reason, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("RejectMarketProposal failed (is the proposal still pending, with no market creation started?): %v", err)
+	}
+
+	log.Printf("Rejected market proposal in database: %s", marketProposal.MarketID.String())
+	return &marketProposal, nil
+}
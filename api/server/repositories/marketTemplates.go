@@ -0,0 +1,223 @@
+package repositories
+
+import (
+	sqlc "api/gen/sqlc"
+	"api/server/lib"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	pb_api "api/gen"
+)
+
+type MarketTemplatesRepository struct {
+	db *sql.DB
+}
+
+func (marketTemplatesRepository *MarketTemplatesRepository) CloseDb() error {
+	var err = marketTemplatesRepository.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close database: %v", err)
+	}
+	return nil
+}
+
+func (marketTemplatesRepository *MarketTemplatesRepository) InitDb() error {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_UNAME"), os.Getenv("DB_PWORD"), os.Getenv("DB_NAME"))
+
+	var db, err = sql.Open("postgres", connStr)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	marketTemplatesRepository.db = db
+
+	// Verify connection
+	if err = db.Ping(); err != nil {
+		return fmt.Errorf("failed to ping database: %v", err)
+	}
+
+	log.Println("DB: MarketTemplatesRepository connected successfully")
+	return nil
+}
+
+func (marketTemplatesRepository *MarketTemplatesRepository) CreateMarketTemplate(req *pb_api.MarketTemplateRequest) (*sqlc.MarketTemplate, error) {
+	if marketTemplatesRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	params, err := getMarketTemplateParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	q := sqlc.New(marketTemplatesRepository.db)
+	marketTemplate, err := q.CreateMarketTemplate(context.Background(), sqlc.CreateMarketTemplateParams{
+		TemplateID:         params.TemplateID,
+		Name:               params.Name,
+		Net:                params.Net,
+		StatementPattern:   params.StatementPattern,
+		Description:        params.Description,
+		ImageUrl:           params.ImageUrl,
+		CategoryID:         params.CategoryID,
+		ClosesAfterSeconds: params.ClosesAfterSeconds,
+		Schedule:           params.Schedule,
+		IsActive:           params.IsActive,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CreateMarketTemplate failed: %v", err)
+	}
+
+	log.Printf("Created market template in database: %s (%s)", marketTemplate.TemplateID.String(), marketTemplate.Name)
+	return &marketTemplate, nil
+}
+
+func (marketTemplatesRepository *MarketTemplatesRepository) UpdateMarketTemplate(req *pb_api.MarketTemplateRequest) (*sqlc.MarketTemplate, error) {
+	if marketTemplatesRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	params, err := getMarketTemplateParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	q := sqlc.New(marketTemplatesRepository.db)
+	marketTemplate, err := q.UpdateMarketTemplate(context.Background(), params)
+	if err != nil {
+		return nil, fmt.Errorf("UpdateMarketTemplate failed: %v", err)
+	}
+
+	log.Printf("Updated market template in database: %s (%s, isActive=%t)", marketTemplate.TemplateID.String(), marketTemplate.Name, marketTemplate.IsActive)
+	return &marketTemplate, nil
+}
+
+// optional fields => NULL columns
+func getMarketTemplateParams(req *pb_api.MarketTemplateRequest) (sqlc.UpdateMarketTemplateParams, error) {
+	templateUUID, err := uuid.Parse(req.TemplateId)
+	if err != nil {
+		return sqlc.UpdateMarketTemplateParams{}, fmt.Errorf("invalid templateId uuid: %v", err)
+	}
+
+	net := strings.ToLower(req.Net)
+	if !lib.IsValidNetwork(net) {
+		return sqlc.UpdateMarketTemplateParams{}, fmt.Errorf("invalid network: %s", net)
+	}
+
+	description := strings.TrimSpace(req.Description)
+	imageUrl := strings.TrimSpace(req.ImageUrl)
+
+	return sqlc.UpdateMarketTemplateParams{
+		TemplateID:         templateUUID,
+		Name:               strings.TrimSpace(req.Name),
+		Net:                net,
+		StatementPattern:   req.StatementPattern,
+		Description:        sql.NullString{String: description, Valid: description != ""},
+		ImageUrl:           sql.NullString{String: imageUrl, Valid: imageUrl != ""},
+		CategoryID:         sql.NullInt32{Int32: req.CategoryId, Valid: req.CategoryId > 0},
+		ClosesAfterSeconds: req.ClosesAfterSeconds,
+		Schedule:           strings.TrimSpace(req.Schedule),
+		IsActive:           req.IsActive,
+	}, nil
+}
+
+func (marketTemplatesRepository *MarketTemplatesRepository) GetMarketTemplate(templateId string) (*sqlc.MarketTemplate, error) {
+	if marketTemplatesRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	templateUUID, err := uuid.Parse(templateId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid templateId uuid: %v", err)
+	}
+
+	q := sqlc.New(marketTemplatesRepository.db)
+	marketTemplate, err := q.GetMarketTemplate(context.Background(), templateUUID)
+	if err != nil {
+		return nil, fmt.Errorf("GetMarketTemplate failed: %v", err)
+	}
+
+	return &marketTemplate, nil
+}
+
+func (marketTemplatesRepository *MarketTemplatesRepository) GetMarketTemplates() ([]sqlc.MarketTemplate, error) {
+	if marketTemplatesRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(marketTemplatesRepository.db)
+	marketTemplates, err := q.GetMarketTemplates(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("GetMarketTemplates failed: %v", err)
+	}
+
+	return marketTemplates, nil
+}
+
+func (marketTemplatesRepository *MarketTemplatesRepository) GetActiveMarketTemplates() ([]sqlc.MarketTemplate, error) {
+	if marketTemplatesRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(marketTemplatesRepository.db)
+	marketTemplates, err := q.GetActiveMarketTemplates(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("GetActiveMarketTemplates failed: %v", err)
+	}
+
+	return marketTemplates, nil
+}
+
+// CreateMarketTemplateRun picks a new marketId for the scheduled time, or returns the one picked by an earlier attempt
+func (marketTemplatesRepository *MarketTemplatesRepository) CreateMarketTemplateRun(templateId uuid.UUID, scheduledAt time.Time) (*sqlc.MarketTemplateRun, error) {
+	if marketTemplatesRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate marketId: %v", err)
+	}
+
+	q := sqlc.New(marketTemplatesRepository.db)
+	err = q.CreateMarketTemplateRun(context.Background(), sqlc.CreateMarketTemplateRunParams{
+		TemplateID:  templateId,
+		ScheduledAt: scheduledAt,
+		MarketID:    marketUUID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CreateMarketTemplateRun failed: %v", err)
+	}
+
+	marketTemplateRun, err := q.GetMarketTemplateRun(context.Background(), sqlc.GetMarketTemplateRunParams{
+		TemplateID:  templateId,
+		ScheduledAt: scheduledAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetMarketTemplateRun failed: %v", err)
+	}
+
+	return &marketTemplateRun, nil
+}
+
+func (marketTemplatesRepository *MarketTemplatesRepository) SetMarketTemplateLastRunAt(templateId uuid.UUID, lastRunAt time.Time) error {
+	if marketTemplatesRepository.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(marketTemplatesRepository.db)
+	err := q.SetMarketTemplateLastRunAt(context.Background(), sqlc.SetMarketTemplateLastRunAtParams{
+		TemplateID: templateId,
+		LastRunAt:  sql.NullTime{Time: lastRunAt, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("SetMarketTemplateLastRunAt failed: %v", err)
+	}
+
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
 
 	pb_api "api/gen"
 )
@@ -66,15 +67,52 @@ func (marketsRepository *MarketsRepository) GetMarketById(marketId string) (*sql
 	return &market, nil
 }
 
-func (marketsRepository *MarketsRepository) GetMarkets(limit int32, offset int32) ([]sqlc.Market, error) {
+// GetMarketByIdIncludingSuspended - GetMarketById for the admin and settlement paths, which must still find a suspended market
+func (marketsRepository *MarketsRepository) GetMarketByIdIncludingSuspended(marketId string) (*sqlc.Market, error) {
 	if marketsRepository.db == nil {
 		return nil, fmt.Errorf("database not initialized")
 	}
 
+	marketUUID, err := uuid.Parse(marketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	q := sqlc.New(marketsRepository.db)
+	market, err := q.GetMarketIncludingSuspended(context.Background(), marketUUID)
+	if err != nil {
+		return nil, fmt.Errorf("GetMarketIncludingSuspended failed: %v", err)
+	}
+
+	return &market, nil
+}
+
+func (marketsRepository *MarketsRepository) GetMarkets(req *pb_api.GetMarketsRequest, limit int32) ([]sqlc.Market, error) {
+	if marketsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	filters, err := getMarketsFilters(req)
+	if err != nil {
+		return nil, err
+	}
+
+	sort := "newest" // default
+	if req.Sort != nil {
+		sort = *req.Sort
+	}
+
 	q := sqlc.New(marketsRepository.db)
 	markets, err := q.GetMarkets(context.Background(), sqlc.GetMarketsParams{
-		Limit:  limit,
-		Offset: offset,
+		CategoryID:   filters.CategoryID,
+		Net:          filters.Net,
+		Search:       filters.Search,
+		ClosesAfter:  filters.ClosesAfter,
+		ClosesBefore: filters.ClosesBefore,
+		Status:       filters.Status,
+		Sort:         sort,
+		Limit:        limit,
+		Offset:       req.Offset,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("GetMarkets failed: %v", err)
@@ -84,6 +122,72 @@ func (marketsRepository *MarketsRepository) GetMarkets(limit int32, offset int32
 	return markets, nil
 }
 
+// CountMarkets counts the markets matching the GetMarkets filters (ignores limit/offset/sort)
+func (marketsRepository *MarketsRepository) CountMarkets(req *pb_api.GetMarketsRequest) (int64, error) {
+	if marketsRepository.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	filters, err := getMarketsFilters(req)
+	if err != nil {
+		return 0, err
+	}
+
+	q := sqlc.New(marketsRepository.db)
+	count, err := q.CountMarkets(context.Background(), filters)
+	if err != nil {
+		return 0, fmt.Errorf("CountMarkets failed: %v", err)
+	}
+
+	return count, nil
+}
+
+// optional filters => NULL params (no filtering)
+func getMarketsFilters(req *pb_api.GetMarketsRequest) (sqlc.CountMarketsParams, error) {
+	filters := sqlc.CountMarketsParams{}
+
+	if req.CategoryId != nil {
+		filters.CategoryID = sql.NullInt32{Int32: *req.CategoryId, Valid: true}
+	}
+
+	if req.Net != nil {
+		net := strings.ToLower(*req.Net)
+		if !lib.IsValidNetwork(net) {
+			return filters, fmt.Errorf("invalid network: %s", net)
+		}
+		filters.Net = sql.NullString{String: net, Valid: true}
+	}
+
+	if req.Search != nil {
+		search := strings.TrimSpace(*req.Search)
+		if search != "" {
+			filters.Search = sql.NullString{String: search, Valid: true}
+		}
+	}
+
+	if req.Status != nil {
+		filters.Status = sql.NullString{String: *req.Status, Valid: true}
+	}
+
+	if req.ClosesAfter != nil {
+		closesAfter, err := time.Parse(time.RFC3339, *req.ClosesAfter)
+		if err != nil {
+			return filters, fmt.Errorf("invalid closesAfter time format: %v", err)
+		}
+		filters.ClosesAfter = sql.NullTime{Time: closesAfter, Valid: true}
+	}
+
+	if req.ClosesBefore != nil {
+		closesBefore, err := time.Parse(time.RFC3339, *req.ClosesBefore)
+		if err != nil {
+			return filters, fmt.Errorf("invalid closesBefore time format: %v", err)
+		}
+		filters.ClosesBefore = sql.NullTime{Time: closesBefore, Valid: true}
+	}
+
+	return filters, nil
+}
+
 func (marketsRepository *MarketsRepository) CreateMarket(req *pb_api.CreateMarketRequest, smartContractId string) (*sqlc.Market, error) {
 	if marketsRepository.db == nil {
 		return nil, fmt.Errorf("database not initialized")
@@ -109,6 +213,15 @@ func (marketsRepository *MarketsRepository) CreateMarket(req *pb_api.CreateMarke
 		return nil, fmt.Errorf("invalid smart contract ID: %s", smartContractId)
 	}
 
+	groupIdParam := uuid.NullUUID{} // optional: only legs of a market group have one
+	if req.GroupId != nil {
+		groupUUID, err := uuid.Parse(*req.GroupId)
+		if err != nil {
+			return nil, fmt.Errorf("invalid groupId uuid: %v", err)
+		}
+		groupIdParam = uuid.NullUUID{UUID: groupUUID, Valid: true}
+	}
+
 	closesAt := time.Now().Add(30 * 24 * time.Hour) // default: 30 days from now
 	if req.ClosesAt != nil {                        // the optional param is not set
 		closesAtTime, err := time.Parse(time.RFC3339, *req.ClosesAt)
@@ -134,12 +247,35 @@ func (marketsRepository *MarketsRepository) CreateMarket(req *pb_api.CreateMarke
 		ImageUrl:        sql.NullString{String: imageUrl, Valid: imageUrl != ""},
 		SmartContractID: smartContractId,
 		ClosesAt:        closesAt,
+		GroupID:         groupIdParam,
 	})
 	if err != nil {
 		tx.Rollback() // Rollback the transaction on error
 		return nil, fmt.Errorf("CreateMarket failed: %v", err)
 	}
 
+	// attach the (optional) categories in the same transaction
+	for _, categoryId := range req.CategoryIds {
+		err = q.AddMarketCategory(context.Background(), sqlc.AddMarketCategoryParams{
+			MarketID:   marketUUID,
+			CategoryID: categoryId,
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("AddMarketCategory (categoryId=%d) failed: %v", categoryId, err)
+		}
+	}
+
+	// mark the market creation saga (if any) as completed in the same transaction
+	err = q.SetMarketCreationStep(context.Background(), sqlc.SetMarketCreationStepParams{
+		MarketID: marketUUID,
+		Step:     string(lib.MARKET_CREATION_COMPLETED),
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("SetMarketCreationStep failed: %v", err)
+	}
+
 	// Commit the transaction
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %v", err)
@@ -199,3 +335,366 @@ func (marketsRepository *MarketsRepository) GetAllUnresolvedMarkets() ([]sqlc.Ma
 
 	return markets, nil
 }
+
+func (marketsRepository *MarketsRepository) ResolveMarket(marketId string, outcome bool) (*sqlc.Market, error) {
+	if marketsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.Parse(marketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	// OK
+	// resolve the market and cancel all of its open prediction intents atomically
+	tx, err := marketsRepository.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	q := sqlc.New(tx)
+	market, err := q.ResolveMarket(context.Background(), sqlc.ResolveMarketParams{
+		MarketID: marketUUID,
+		Outcome:  sql.NullBool{Bool: outcome, Valid: true},
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("ResolveMarket failed: %v", err)
+	}
+
+	cancelled, err := q.CancelAllOpenPredictionIntentsByMarketId(context.Background(), marketUUID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CancelAllOpenPredictionIntentsByMarketId failed: %v", err)
+	}
+
+	// pending stop/take-profit triggers can never fire once the market stops trading
+	_, err = q.CancelPendingConditionalIntentsByMarketId(context.Background(), marketUUID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CancelPendingConditionalIntentsByMarketId failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("Resolved market in database: %s (outcome=%t, cancelled %d open prediction intents)", market.MarketID.String(), outcome, len(cancelled))
+	return &market, nil
+}
+
+func (marketsRepository *MarketsRepository) VoidMarket(marketId uuid.UUID, reason string) (*sqlc.Market, error) {
+	if marketsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	// OK
+	// void the market, cancel all of its open prediction intents and record the 50/50 refunds atomically
+	tx, err := marketsRepository.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	q := sqlc.New(tx)
+	market, err := q.VoidMarket(context.Background(), sqlc.VoidMarketParams{
+		MarketID:   marketId,
+		VoidReason: sql.NullString{String: reason, Valid: true},
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("VoidMarket failed: %v", err)
+	}
+
+	cancelled, err := q.CancelAllOpenPredictionIntentsByMarketId(context.Background(), marketId)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CancelAllOpenPredictionIntentsByMarketId failed: %v", err)
+	}
+
+	// pending stop/take-profit triggers can never fire once the market stops trading
+	_, err = q.CancelPendingConditionalIntentsByMarketId(context.Background(), marketId)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CancelPendingConditionalIntentsByMarketId failed: %v", err)
+	}
+
+	refunded, err := q.SetPositionRefundsByMarketId(context.Background(), marketId)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("SetPositionRefundsByMarketId failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("Voided market in database: %s (cancelled %d open prediction intents, refunded %d positions)", market.MarketID.String(), len(cancelled), refunded)
+	return &market, nil
+}
+
+func (marketsRepository *MarketsRepository) GetMarketsPastClosesAt() ([]sqlc.Market, error) {
+	if marketsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(marketsRepository.db)
+	markets, err := q.GetMarketsPastClosesAt(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("GetMarketsPastClosesAt failed: %v", err)
+	}
+
+	return markets, nil
+}
+
+func (marketsRepository *MarketsRepository) CloseMarket(marketId uuid.UUID) (*sqlc.Market, error) {
+	if marketsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	// OK
+	// close the market and cancel all of its open prediction intents atomically
+	tx, err := marketsRepository.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	q := sqlc.New(tx)
+	market, err := q.CloseMarket(context.Background(), marketId)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CloseMarket failed: %v", err)
+	}
+
+	cancelled, err := q.CancelAllOpenPredictionIntentsByMarketId(context.Background(), marketId)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CancelAllOpenPredictionIntentsByMarketId failed: %v", err)
+	}
+
+	// pending stop/take-profit triggers can never fire once the market stops trading
+	_, err = q.CancelPendingConditionalIntentsByMarketId(context.Background(), marketId)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CancelPendingConditionalIntentsByMarketId failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("Closed market in database: %s (cancelled %d open prediction intents)", market.MarketID.String(), len(cancelled))
+	return &market, nil
+}
+
+func (marketsRepository *MarketsRepository) SetMarketPaused(marketId string, isPaused bool) (*sqlc.Market, error) {
+	if marketsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.Parse(marketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	q := sqlc.New(marketsRepository.db)
+	market, err := q.SetMarketPaused(context.Background(), sqlc.SetMarketPausedParams{
+		MarketID: marketUUID,
+		IsPaused: isPaused,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("SetMarketPaused failed: %v", err)
+	}
+
+	log.Printf("Set isPaused=%t on market in database: %s", isPaused, market.MarketID.String())
+	return &market, nil
+}
+
+func (marketsRepository *MarketsRepository) SuspendMarket(marketId string) (*sqlc.Market, error) {
+	if marketsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.Parse(marketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	// OK
+	// suspend the market and cancel all of its open prediction intents atomically
+	tx, err := marketsRepository.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	q := sqlc.New(tx)
+	market, err := q.SuspendMarket(context.Background(), marketUUID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("SuspendMarket failed: %v", err)
+	}
+
+	cancelled, err := q.CancelAllOpenPredictionIntentsByMarketId(context.Background(), marketUUID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CancelAllOpenPredictionIntentsByMarketId failed: %v", err)
+	}
+
+	// pending stop/take-profit triggers can never fire once the market stops trading
+	_, err = q.CancelPendingConditionalIntentsByMarketId(context.Background(), marketUUID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CancelPendingConditionalIntentsByMarketId failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("Suspended market in database: %s (cancelled %d open prediction intents)", market.MarketID.String(), len(cancelled))
+	return &market, nil
+}
+
+/////
+// market creation saga
+/////
+
+// CreateMarketCreation records the intent to create a market before any step runs.
+// Retries with the same marketId return the existing row.
+func (marketsRepository *MarketsRepository) CreateMarketCreation(req *pb_api.CreateMarketRequest, smartContractId string) (*sqlc.MarketCreation, error) {
+	if marketsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.Parse(req.MarketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	request, err := protojson.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal CreateMarketRequest: %v", err)
+	}
+
+	q := sqlc.New(marketsRepository.db)
+	err = q.CreateMarketCreation(context.Background(), sqlc.CreateMarketCreationParams{
+		MarketID:        marketUUID,
+		Net:             strings.ToLower(req.Net),
+		SmartContractID: smartContractId,
+		Request:         request,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CreateMarketCreation failed: %v", err)
+	}
+
+	marketCreation, err := q.GetMarketCreation(context.Background(), marketUUID)
+	if err != nil {
+		return nil, fmt.Errorf("GetMarketCreation failed: %v", err)
+	}
+
+	return &marketCreation, nil
+}
+
+// StartMarketCreationAttempt bumps the attempt counter (and updated_at, so the reconciler leaves in-flight creations alone)
+func (marketsRepository *MarketsRepository) StartMarketCreationAttempt(marketId uuid.UUID) (*sqlc.MarketCreation, error) {
+	if marketsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(marketsRepository.db)
+	marketCreation, err := q.StartMarketCreationAttempt(context.Background(), marketId)
+	if err != nil {
+		return nil, fmt.Errorf("StartMarketCreationAttempt failed: %v", err)
+	}
+
+	return &marketCreation, nil
+}
+
+// ClaimMarketCreationContractStep moves a pending market creation (or one whose claim went stale) to contract_creating - false if another attempt holds it
+func (marketsRepository *MarketsRepository) ClaimMarketCreationContractStep(marketId uuid.UUID, staleSeconds int32) (bool, error) {
+	if marketsRepository.db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(marketsRepository.db)
+	n, err := q.ClaimMarketCreationContractStep(context.Background(), sqlc.ClaimMarketCreationContractStepParams{
+		MarketID:     marketId,
+		StaleSeconds: staleSeconds,
+	})
+	if err != nil {
+		return false, fmt.Errorf("ClaimMarketCreationContractStep failed: %v", err)
+	}
+
+	return n > 0, nil
+}
+
+func (marketsRepository *MarketsRepository) SetMarketCreationStep(marketId uuid.UUID, step lib.MarketCreationStep, remainingAllowance *uint64) error {
+	if marketsRepository.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	remainingAllowanceParam := sql.NullInt64{} // NULL => keep the stored value
+	if remainingAllowance != nil {
+		remainingAllowanceParam = sql.NullInt64{Int64: int64(*remainingAllowance), Valid: true}
+	}
+
+	q := sqlc.New(marketsRepository.db)
+	err := q.SetMarketCreationStep(context.Background(), sqlc.SetMarketCreationStepParams{
+		MarketID:           marketId,
+		Step:               string(step),
+		RemainingAllowance: remainingAllowanceParam,
+	})
+	if err != nil {
+		return fmt.Errorf("SetMarketCreationStep failed: %v", err)
+	}
+
+	log.Printf("Market creation %s reached step: %s", marketId.String(), step)
+	return nil
+}
+
+func (marketsRepository *MarketsRepository) SetMarketCreationError(marketId uuid.UUID, lastError string) error {
+	if marketsRepository.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(marketsRepository.db)
+	err := q.SetMarketCreationError(context.Background(), sqlc.SetMarketCreationErrorParams{
+		MarketID:  marketId,
+		LastError: sql.NullString{String: lastError, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("SetMarketCreationError failed: %v", err)
+	}
+
+	return nil
+}
+
+func (marketsRepository *MarketsRepository) FlagMarketCreation(marketId uuid.UUID) error {
+	if marketsRepository.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(marketsRepository.db)
+	err := q.FlagMarketCreation(context.Background(), marketId)
+	if err != nil {
+		return fmt.Errorf("FlagMarketCreation failed: %v", err)
+	}
+
+	log.Printf("Flagged market creation for manual attention: %s", marketId.String())
+	return nil
+}
+
+// GetStalledMarketCreations returns unfinished, unflagged market creations that nobody has touched for a while
+func (marketsRepository *MarketsRepository) GetStalledMarketCreations() ([]sqlc.MarketCreation, error) {
+	if marketsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(marketsRepository.db)
+	marketCreations, err := q.GetStalledMarketCreations(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("GetStalledMarketCreations failed: %v", err)
+	}
+
+	return marketCreations, nil
+}
@@ -4,9 +4,11 @@ import (
 	sqlc "api/gen/sqlc"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	pb_clob "api/gen/clob"
 
@@ -43,63 +45,183 @@ func (matchesRepository *MatchesRepository) InitDb() error {
 	return nil
 }
 
-// Record the match in the database for auditing
-func (matchesRepository *MatchesRepository) CreateMatch(orderRequestClobTuple [2]*pb_clob.CreateOrderRequestClob, txHash string) (*sqlc.Match, error) {
+// Record the match in the database for auditing - feeUsdTuple is aligned with orderRequestClobTuple (empty makerTxId => unknown).
+// isFeesPending: the fees could not be computed - they are computed (and set) before the match is settled.
+// Idempotent on the CLOB match ID: isDuplicate (and no match) if it was already recorded - nothing is written again.
+func (matchesRepository *MatchesRepository) CreateMatch(orderRequestClobTuple [2]*pb_clob.CreateOrderRequestClob, txHash string, makerTxId string, feeUsdTuple [2]float64, isFeesPending bool) (match *sqlc.Match, isDuplicate bool, err error) {
 	// guards
 	if matchesRepository.db == nil {
-		return nil, fmt.Errorf("database not initialized")
+		return nil, false, fmt.Errorf("database not initialized")
 	}
 
 	// txId1 MUST be the YES side (positive priceUsd)
 	// txId2 MUST be the NO side (negative priceUsd)
 	// the CLOB should already be enforcing this on the way in to this function - if not, error here
 	if orderRequestClobTuple[0].PriceUsd < 0 {
-		return nil, fmt.Errorf("txId1 must be the YES side (positive priceUsd), but got negative priceUsd: %f", orderRequestClobTuple[0].PriceUsd)
+		return nil, false, fmt.Errorf("txId1 must be the YES side (positive priceUsd), but got negative priceUsd: %f", orderRequestClobTuple[0].PriceUsd)
 	}
 	if orderRequestClobTuple[1].PriceUsd > 0 {
-		return nil, fmt.Errorf("txId2 must be the NO side (negative priceUsd), but got positive priceUsd: %f", orderRequestClobTuple[1].PriceUsd)
+		return nil, false, fmt.Errorf("txId2 must be the NO side (negative priceUsd), but got positive priceUsd: %f", orderRequestClobTuple[1].PriceUsd)
 	}
 
 	// marketIds should match
 	if orderRequestClobTuple[0].MarketId != orderRequestClobTuple[1].MarketId {
-		return nil, fmt.Errorf("marketIds do not match: %s vs %s", orderRequestClobTuple[0].MarketId, orderRequestClobTuple[1].MarketId)
+		return nil, false, fmt.Errorf("marketIds do not match: %s vs %s", orderRequestClobTuple[0].MarketId, orderRequestClobTuple[1].MarketId)
 	}
 
 	marketId, err := uuid.Parse(orderRequestClobTuple[0].MarketId)
 	if err != nil {
-		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+		return nil, false, fmt.Errorf("invalid marketId uuid: %v", err)
 	}
 
 	txId1, err := uuid.Parse(orderRequestClobTuple[0].TxId)
 	if err != nil {
-		return nil, fmt.Errorf("invalid txId1 uuid: %v", err)
+		return nil, false, fmt.Errorf("invalid txId1 uuid: %v", err)
 	}
 
 	txId2, err := uuid.Parse(orderRequestClobTuple[1].TxId)
 	if err != nil {
-		return nil, fmt.Errorf("invalid txId2 uuid: %v", err)
+		return nil, false, fmt.Errorf("invalid txId2 uuid: %v", err)
+	}
+
+	makerTxUUID := uuid.NullUUID{}
+	if makerTxId != "" {
+		parsed, err := uuid.Parse(makerTxId)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid makerTxId uuid: %v", err)
+		}
+		makerTxUUID = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+
+	// both sides carry the same CLOB match ID (empty => published by a CLOB without match IDs - not de-duplicated)
+	if orderRequestClobTuple[0].MatchId != orderRequestClobTuple[1].MatchId {
+		return nil, false, fmt.Errorf("match IDs do not match: %s vs %s", orderRequestClobTuple[0].MatchId, orderRequestClobTuple[1].MatchId)
 	}
+	clobMatchId := sql.NullString{String: orderRequestClobTuple[0].MatchId, Valid: orderRequestClobTuple[0].MatchId != ""}
 
 	// OK
 
 	params := sqlc.CreateMatchParams{
-		MarketID: marketId,
-		TxId1:    txId1,
-		TxId2:    txId2,
-		Qty1:     orderRequestClobTuple[0].Qty,
-		Qty2:     orderRequestClobTuple[1].Qty,
-		TxHash:   txHash,
+		MarketID:      marketId,
+		TxId1:         txId1,
+		TxId2:         txId2,
+		Qty1:          orderRequestClobTuple[0].Qty,
+		Qty2:          orderRequestClobTuple[1].Qty,
+		TxHash:        txHash,
+		MakerTxID:     makerTxUUID,
+		FeeUsd1:       feeUsdTuple[0],
+		FeeUsd2:       feeUsdTuple[1],
+		ClobMatchID:   clobMatchId,
+		IsFeesPending: isFeesPending,
+	}
+
+	// Start a transaction - every match starts with a pending settlement
+	tx, err := matchesRepository.db.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	q := sqlc.New(tx)
+	createdMatch, err := q.CreateMatch(context.Background(), params)
+	if errors.Is(err, sql.ErrNoRows) { // the CLOB match ID was already recorded
+		tx.Rollback()
+		log.Printf("Match %s already recorded for txIds: {%s, %s} - ignoring it", clobMatchId.String, orderRequestClobTuple[0].TxId, orderRequestClobTuple[1].TxId)
+		return nil, true, nil
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, false, fmt.Errorf("failed to record match for txIds %s and %s: %v", orderRequestClobTuple[0].TxId, orderRequestClobTuple[1].TxId, err)
+	}
+
+	err = q.CreateSettlement(context.Background(), createdMatch.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, false, fmt.Errorf("CreateSettlement failed: %v", err)
+	}
+
+	// both sides are filled by the matched qty (the CLOB publishes each side's qty before the match) - an intent is fully matched once nothing remains
+	matchedQty := createdMatch.MatchedQty
+	for _, txId := range []uuid.UUID{txId1, txId2} {
+		predictionIntent, err := q.AddPredictionIntentFill(context.Background(), sqlc.AddPredictionIntentFillParams{
+			TxID:       txId,
+			MatchedQty: matchedQty,
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, false, fmt.Errorf("AddPredictionIntentFill failed for txId %s: %v", txId.String(), err)
+		}
+		if predictionIntent.RemainingQty <= 0 {
+			log.Printf("Prediction intent fully matched for txId: %s (filledQty=%f)", txId.String(), predictionIntent.FilledQty)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("Recorded match (settlement pending, matchedQty=%f) on database for txIds: {%s, %s}", matchedQty, orderRequestClobTuple[0].TxId, orderRequestClobTuple[1].TxId)
+
+	return &createdMatch, false, nil
+}
+
+/*
+*
+Record a match rejected before settlement (e.g. an underfunded side) - nothing else of the match is recorded.
+false if the CLOB match ID was already rejected (a redelivered match).
+*/
+func (matchesRepository *MatchesRepository) CreateMatchRejection(orderRequestClobTuple [2]*pb_clob.CreateOrderRequestClob, evictedTxIds []uuid.UUID, reinstatedTxIds []uuid.UUID, reason string) (bool, error) {
+	if matchesRepository.db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	marketId, err := uuid.Parse(orderRequestClobTuple[0].MarketId)
+	if err != nil {
+		return false, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+	txId1, err := uuid.Parse(orderRequestClobTuple[0].TxId)
+	if err != nil {
+		return false, fmt.Errorf("invalid txId1 uuid: %v", err)
+	}
+	txId2, err := uuid.Parse(orderRequestClobTuple[1].TxId)
+	if err != nil {
+		return false, fmt.Errorf("invalid txId2 uuid: %v", err)
 	}
 
 	q := sqlc.New(matchesRepository.db)
-	match, err := q.CreateMatch(context.Background(), params)
+	n, err := q.CreateMatchRejection(context.Background(), sqlc.CreateMatchRejectionParams{
+		ClobMatchID:     sql.NullString{String: orderRequestClobTuple[0].MatchId, Valid: orderRequestClobTuple[0].MatchId != ""},
+		MarketID:        marketId,
+		TxId1:           txId1,
+		TxId2:           txId2,
+		Qty1:            orderRequestClobTuple[0].Qty,
+		Qty2:            orderRequestClobTuple[1].Qty,
+		EvictedTxIds:    evictedTxIds,
+		ReinstatedTxIds: reinstatedTxIds,
+		Reason:          reason,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to record match for txIds %s and %s: %v", orderRequestClobTuple[0].TxId, orderRequestClobTuple[1].TxId, err)
+		return false, fmt.Errorf("CreateMatchRejection failed: %v", err)
+	}
+
+	if n > 0 {
+		log.Printf("Recorded match rejection on database for txIds: {%s, %s}: %s", orderRequestClobTuple[0].TxId, orderRequestClobTuple[1].TxId, reason)
 	}
+	return n > 0, nil
+}
 
-	log.Printf("Recorded match on database for txIds: {%s, %s}", orderRequestClobTuple[0].TxId, orderRequestClobTuple[1].TxId)
+// IsClobMatchIdProcessed - true if the CLOB match ID was already recorded or rejected
+func (matchesRepository *MatchesRepository) IsClobMatchIdProcessed(clobMatchId string) (bool, error) {
+	if matchesRepository.db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
 
-	return &match, nil
+	q := sqlc.New(matchesRepository.db)
+	isProcessed, err := q.IsClobMatchIdProcessed(context.Background(), clobMatchId)
+	if err != nil {
+		return false, fmt.Errorf("IsClobMatchIdProcessed failed: %v", err)
+	}
+
+	return isProcessed, nil
 }
 
 // func (dbRepository *DbRepository) CreateMatch(sideYes *pb_clob.CreateOrderRequestClob, sideNo *pb_clob.CreateOrderRequestClob, txHash string) error {
@@ -182,18 +304,135 @@ func (matchesRepository *MatchesRepository) UpdateMatchTxHash(marketId string, t
 	return nil
 }
 
-func (matchesRepository *MatchesRepository) GetAllMatchesForMarketIdTxId(marketID uuid.UUID, txId uuid.UUID) ([]sqlc.Match, error) {
+// GetMatchesByTxIds returns every match either side of which is one of txIds (oldest first)
+func (matchesRepository *MatchesRepository) GetMatchesByTxIds(txIds []uuid.UUID) ([]sqlc.Match, error) {
 	if matchesRepository.db == nil {
 		return nil, fmt.Errorf("database not initialized")
 	}
+
+	q := sqlc.New(matchesRepository.db)
+	matches, err := q.GetMatchesByTxIds(context.Background(), txIds)
+	if err != nil {
+		return nil, fmt.Errorf("GetMatchesByTxIds failed: %v", err)
+	}
+
+	return matches, nil
+}
+
+// GetTotalFeesUsdSince sums the trading fees charged on every match recorded since the given time
+func (matchesRepository *MatchesRepository) GetTotalFeesUsdSince(since time.Time) (float64, error) {
+	if matchesRepository.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
 	q := sqlc.New(matchesRepository.db)
-	matches, err := q.GetAllMatchesForMarketIdTxId(context.Background(), sqlc.GetAllMatchesForMarketIdTxIdParams{
-		MarketID: marketID,
-		TxId1:    txId,
+	totalFeesUsd, err := q.GetTotalFeesUsdSince(context.Background(), since)
+	if err != nil {
+		return 0, fmt.Errorf("GetTotalFeesUsdSince failed: %v", err)
+	}
+
+	return totalFeesUsd, nil
+}
+
+// SetMatchFees sets the fees of a match recorded without them (isFeesPending) - empty makerTxId => unknown
+func (matchesRepository *MatchesRepository) SetMatchFees(id int32, makerTxId string, feeUsdTuple [2]float64) error {
+	if matchesRepository.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	makerTxUUID := uuid.NullUUID{}
+	if makerTxId != "" {
+		parsed, err := uuid.Parse(makerTxId)
+		if err != nil {
+			return fmt.Errorf("invalid makerTxId uuid: %v", err)
+		}
+		makerTxUUID = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+
+	q := sqlc.New(matchesRepository.db)
+	err := q.SetMatchFees(context.Background(), sqlc.SetMatchFeesParams{
+		ID:        id,
+		MakerTxID: makerTxUUID,
+		FeeUsd1:   feeUsdTuple[0],
+		FeeUsd2:   feeUsdTuple[1],
 	})
 	if err != nil {
-		return nil, fmt.Errorf("GetAllMatchesForMarketIdTxId failed: %v", err)
+		return fmt.Errorf("SetMatchFees failed: %v", err)
+	}
+
+	log.Printf("Set the fees on database for match id: %d (YES $USD%.6f, NO $USD%.6f)", id, feeUsdTuple[0], feeUsdTuple[1])
+	return nil
+}
+
+func (matchesRepository *MatchesRepository) HoldMatch(id int32) error {
+	if matchesRepository.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(matchesRepository.db)
+	err := q.HoldMatch(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("HoldMatch failed: %v", err)
+	}
+
+	log.Printf("Holding settlement on database for match id: %d", id)
+	return nil
+}
+
+func (matchesRepository *MatchesRepository) GetHeldMatchesByMarketId(marketID uuid.UUID) ([]sqlc.Match, error) {
+	if matchesRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(matchesRepository.db)
+	matches, err := q.GetHeldMatchesByMarketId(context.Background(), marketID)
+	if err != nil {
+		return nil, fmt.Errorf("GetHeldMatchesByMarketId failed: %v", err)
 	}
 
 	return matches, nil
 }
+
+func (matchesRepository *MatchesRepository) GetMatchById(id int32) (*sqlc.Match, error) {
+	if matchesRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(matchesRepository.db)
+	match, err := q.GetMatchById(context.Background(), id)
+	if err != nil {
+		return nil, fmt.Errorf("GetMatchById failed: %v", err)
+	}
+
+	return &match, nil
+}
+
+// GetMatchesDueForSettlementRetry returns (up to limit) matches whose failed settlement is due for a retry - held matches wait for the market to resume
+func (matchesRepository *MatchesRepository) GetMatchesDueForSettlementRetry(limit int32) ([]sqlc.Match, error) {
+	if matchesRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(matchesRepository.db)
+	matches, err := q.GetMatchesDueForSettlementRetry(context.Background(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetMatchesDueForSettlementRetry failed: %v", err)
+	}
+
+	return matches, nil
+}
+
+func (matchesRepository *MatchesRepository) ReleaseHeldMatch(id int32) error {
+	if matchesRepository.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(matchesRepository.db)
+	err := q.ReleaseHeldMatch(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("ReleaseHeldMatch failed: %v", err)
+	}
+
+	log.Printf("Released held settlement on database for match id: %d", id)
+	return nil
+}
@@ -2,11 +2,13 @@ package repositories
 
 import (
 	sqlc "api/gen/sqlc"
+	"api/server/lib"
 	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -51,23 +53,197 @@ func (pir *PredictionIntentsRepository) CreateOrderIntentRequest(req *pb_api.Pre
 		return nil, fmt.Errorf("could not connect to database")
 	}
 
+	params, err := getPredictionIntentParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	q := sqlc.New(pir.db)
+	newPredictionIntent, err := q.CreatePredictionIntent(context.Background(), params)
+	if err != nil {
+		return nil, fmt.Errorf("CreatePredictionIntent failed: %v", err)
+	}
+
+	log.Printf("Saved prediction intent to database for account %s", req.AccountId)
+	return &newPredictionIntent, nil
+}
+
+// CreateOrderIntentRequestWithOutbox saves the order request and its clob_outbox message in one transaction -
+// the relay (NatsService.RelayClobOutbox) publishes it to the CLOB once it is committed
+func (pir *PredictionIntentsRepository) CreateOrderIntentRequestWithOutbox(req *pb_api.PredictionIntentRequest) (*sqlc.PredictionIntent, error) {
+	if pir.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	params, err := getPredictionIntentParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := pir.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	q := sqlc.New(tx)
+	newPredictionIntent, err := q.CreatePredictionIntent(context.Background(), params)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CreatePredictionIntent failed: %v", err)
+	}
+
+	err = q.CreateClobOutboxMessage(context.Background(), sqlc.CreateClobOutboxMessageParams{
+		TxID:    params.TxID,
+		Subject: lib.SUBJECT_CLOB_ORDERS,
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CreateClobOutboxMessage failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("Saved prediction intent (and its clob outbox message) to database for account %s", req.AccountId)
+	return &newPredictionIntent, nil
+}
+
+// CreateOrderIntentRequestsWithOutbox saves a batch of order requests and their clob_outbox messages in one transaction -
+// either every order request is saved or none is
+func (pir *PredictionIntentsRepository) CreateOrderIntentRequestsWithOutbox(reqs []*pb_api.PredictionIntentRequest) ([]sqlc.PredictionIntent, error) {
+	if pir.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	tx, err := pir.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	q := sqlc.New(tx)
+	newPredictionIntents := make([]sqlc.PredictionIntent, 0, len(reqs))
+	for _, req := range reqs {
+		params, err := getPredictionIntentParams(req)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		newPredictionIntent, err := q.CreatePredictionIntent(context.Background(), params)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("CreatePredictionIntent failed (txId=%s): %v", req.TxId, err)
+		}
+
+		err = q.CreateClobOutboxMessage(context.Background(), sqlc.CreateClobOutboxMessageParams{
+			TxID:    params.TxID,
+			Subject: lib.SUBJECT_CLOB_ORDERS,
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("CreateClobOutboxMessage failed (txId=%s): %v", req.TxId, err)
+		}
+
+		newPredictionIntents = append(newPredictionIntents, newPredictionIntent)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("Saved %d prediction intents (and their clob outbox messages) to database", len(newPredictionIntents))
+	return newPredictionIntents, nil
+}
+
+// ReplacePredictionIntent cancels the old prediction intent and inserts the new one (linked via replaces_tx_id) and its clob_outbox message atomically
+func (pir *PredictionIntentsRepository) ReplacePredictionIntent(replacesTxId string, req *pb_api.PredictionIntentRequest) (*sqlc.PredictionIntent, error) {
+	if pir.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	replacesTxUUID, err := uuid.Parse(replacesTxId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid replacesTxId uuid: %v", err)
+	}
+
+	params, err := getPredictionIntentParams(req)
+	if err != nil {
+		return nil, err
+	}
+	params.ReplacesTxID = uuid.NullUUID{UUID: replacesTxUUID, Valid: true}
+
+	tx, err := pir.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	q := sqlc.New(tx)
+	cancelled, err := q.CancelPredictionIntent(context.Background(), replacesTxUUID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CancelPredictionIntent failed: %v", err)
+	}
+	if cancelled == 0 { // matched, evicted or cancelled in the meantime
+		tx.Rollback()
+		return nil, fmt.Errorf("prediction intent %s is no longer open", replacesTxId)
+	}
+
+	newPredictionIntent, err := q.CreatePredictionIntent(context.Background(), params)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CreatePredictionIntent failed: %v", err)
+	}
+
+	err = q.CreateClobOutboxMessage(context.Background(), sqlc.CreateClobOutboxMessageParams{
+		TxID:    params.TxID,
+		Subject: lib.SUBJECT_CLOB_ORDERS,
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("CreateClobOutboxMessage failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("Replaced prediction intent %s with %s in database for account %s", replacesTxId, req.TxId, req.AccountId)
+	return &newPredictionIntent, nil
+}
+
+func getPredictionIntentParams(req *pb_api.PredictionIntentRequest) (sqlc.CreatePredictionIntentParams, error) {
 	txUUID, err := uuid.Parse(req.TxId)
 	if err != nil {
-		return nil, fmt.Errorf("invalid txId uuid: %v", err)
+		return sqlc.CreatePredictionIntentParams{}, fmt.Errorf("invalid txId uuid: %v", err)
 	}
 
 	marketUUID, err := uuid.Parse(req.MarketId)
 	if err != nil {
-		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+		return sqlc.CreatePredictionIntentParams{}, fmt.Errorf("invalid marketId uuid: %v", err)
 	}
 
 	generatedAt, err := time.Parse(time.RFC3339, req.GeneratedAt) // Zulu time (RFC3339)
 	if err != nil {
-		return nil, fmt.Errorf("invalid GeneratedAt timestamp: %v", err)
+		return sqlc.CreatePredictionIntentParams{}, fmt.Errorf("invalid GeneratedAt timestamp: %v", err)
 	}
 	generatedAt = generatedAt.UTC()
 
-	params := sqlc.CreatePredictionIntentParams{
+	timeInForce := strings.ToLower(req.TimeInForce)
+	if timeInForce == "" {
+		timeInForce = lib.TIME_IN_FORCE_GTC
+	}
+
+	expiresAt := sql.NullTime{} // gtd only
+	if req.ExpiresAt != "" {
+		expiresAtTime, err := time.Parse(time.RFC3339, req.ExpiresAt) // Zulu time (RFC3339)
+		if err != nil {
+			return sqlc.CreatePredictionIntentParams{}, fmt.Errorf("invalid ExpiresAt timestamp: %v", err)
+		}
+		expiresAt = sql.NullTime{Time: expiresAtTime.UTC(), Valid: true}
+	}
+
+	return sqlc.CreatePredictionIntentParams{
 		TxID:         txUUID,
 		Net:          req.Net,
 		MarketID:     marketUUID,
@@ -80,16 +256,9 @@ func (pir *PredictionIntentsRepository) CreateOrderIntentRequest(req *pb_api.Pre
 		PublicKeyHex: req.PublicKey,
 		Evmaddress:   req.EvmAddress,
 		Keytype:      int32(req.KeyType),
-	}
-
-	q := sqlc.New(pir.db)
-	newPredictionIntent, err := q.CreatePredictionIntent(context.Background(), params)
-	if err != nil {
-		return nil, fmt.Errorf("CreatePredictionIntent failed: %v", err)
-	}
-
-	log.Printf("Saved prediction intent to database for account %s", req.AccountId)
-	return &newPredictionIntent, nil
+		TimeInForce:  timeInForce,
+		ExpiresAt:    expiresAt,
+	}, nil
 }
 
 func (pir *PredictionIntentsRepository) CancelPredictionIntent(txId string) error {
@@ -103,7 +272,7 @@ func (pir *PredictionIntentsRepository) CancelPredictionIntent(txId string) erro
 	}
 
 	q := sqlc.New(pir.db)
-	err = q.CancelPredictionIntent(context.Background(), txUUID)
+	_, err = q.CancelPredictionIntent(context.Background(), txUUID)
 	if err != nil {
 		return fmt.Errorf("CancelPredictionIntent failed: %v", err)
 	}
@@ -112,6 +281,22 @@ func (pir *PredictionIntentsRepository) CancelPredictionIntent(txId string) erro
 	return nil
 }
 
+// CancelPredictionIntents cancels the still-open intents among txIds in a single statement and returns the ones actually cancelled
+func (pir *PredictionIntentsRepository) CancelPredictionIntents(txIds []uuid.UUID) ([]uuid.UUID, error) {
+	if pir.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(pir.db)
+	cancelled, err := q.CancelPredictionIntents(context.Background(), txIds)
+	if err != nil {
+		return nil, fmt.Errorf("CancelPredictionIntents failed: %v", err)
+	}
+
+	log.Printf("Cancelled %d of %d prediction intents in database", len(cancelled), len(txIds))
+	return cancelled, nil
+}
+
 func (pir *PredictionIntentsRepository) GetAllOpenPredictionIntentsByMarketId(marketId string) (*[]sqlc.PredictionIntent, error) {
 	if pir.db == nil {
 		return nil, fmt.Errorf("database not initialized")
@@ -145,88 +330,227 @@ func (dbRepository *DbRepository) MarkPredictionIntentAsRegenerated(txId string)
 	return nil
 }
 
-func (pir *PredictionIntentsRepository) MarkPredictionIntentAsFullyMatched(marketId string, txId string) error {
+func (pir *PredictionIntentsRepository) GetAllAccountIdsForMarketId(marketId uuid.UUID) ([]string, error) {
 	if pir.db == nil {
-		return fmt.Errorf("database not initialized")
+		return nil, fmt.Errorf("database not initialized")
 	}
 
-	marketUUID, err := uuid.Parse(marketId)
+	q := sqlc.New(pir.db)
+	accountIds, err := q.GetAllAccountIdsForMarketId(context.Background(), marketId)
 	if err != nil {
-		return fmt.Errorf("invalid marketId uuid: %v", err)
+		return nil, fmt.Errorf("GetAllAccountIdsForMarketId failed: %v", err)
 	}
 
-	txUUID, err := uuid.Parse(txId)
-	if err != nil {
-		return fmt.Errorf("invalid txId uuid: %v", err)
+	return accountIds, nil
+}
+
+func (pir *PredictionIntentsRepository) GetAllOpenPredictionIntentsByMarketIdAndAccountId(marketId uuid.UUID, accountId string) ([]sqlc.PredictionIntent, error) {
+	if pir.db == nil {
+		return nil, fmt.Errorf("database not initialized")
 	}
 
 	q := sqlc.New(pir.db)
-	_, err = q.MarkPredictionIntentAsFullyMatched(context.Background(), sqlc.MarkPredictionIntentAsFullyMatchedParams{
-		MarketID: marketUUID,
-		TxID:     txUUID,
+	orderIntents, err := q.GetAllOpenPredictionIntentsByMarketIdAndAccountId(context.Background(), sqlc.GetAllOpenPredictionIntentsByMarketIdAndAccountIdParams{
+		MarketID:  marketId,
+		AccountID: accountId,
 	})
 	if err != nil {
-		return fmt.Errorf("MarkPredictionIntentAsFullyMatched failed: %v", err)
+		return nil, fmt.Errorf("GetAllOpenPredictionIntentsByMarketIdAndAccountId failed: %v", err)
 	}
 
-	log.Printf("Marked prediction intent as fully matched in database for txId: %s", txId)
+	return orderIntents, nil
+}
+
+// MarkPredictionIntentAsEvicted records when and why (e.g. insufficient funds) the intent was evicted
+func (pir *PredictionIntentsRepository) MarkPredictionIntentAsEvicted(txId uuid.UUID, reason string) error {
+	if pir.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(pir.db)
+	err := q.MarkPredictionIntentAsEvicted(context.Background(), sqlc.MarkPredictionIntentAsEvictedParams{
+		TxID:           txId,
+		EvictionReason: reason,
+	})
+	if err != nil {
+		return fmt.Errorf("MarkPredictionIntentAsEvicted failed: %v", err)
+	}
 	return nil
 }
 
-func (pir *PredictionIntentsRepository) GetAllAccountIdsForMarketId(marketId uuid.UUID) ([]string, error) {
+// GetExpiredPredictionIntents returns the open GTD prediction intents past their expires_at
+func (pir *PredictionIntentsRepository) GetExpiredPredictionIntents() ([]sqlc.PredictionIntent, error) {
 	if pir.db == nil {
 		return nil, fmt.Errorf("database not initialized")
 	}
 
 	q := sqlc.New(pir.db)
-	accountIds, err := q.GetAllAccountIdsForMarketId(context.Background(), marketId)
+	predictionIntents, err := q.GetExpiredPredictionIntents(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("GetAllAccountIdsForMarketId failed: %v", err)
+		return nil, fmt.Errorf("GetExpiredPredictionIntents failed: %v", err)
 	}
 
-	return accountIds, nil
+	return predictionIntents, nil
 }
 
-func (pir *PredictionIntentsRepository) GetAllOpenPredictionIntentsByMarketIdAndAccountId(marketId uuid.UUID, accountId string) ([]sqlc.PredictionIntent, error) {
+// MarkPredictionIntentAsExpired returns false if the prediction intent was no longer open (e.g. matched in the meantime)
+func (pir *PredictionIntentsRepository) MarkPredictionIntentAsExpired(txId uuid.UUID) (bool, error) {
+	if pir.db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(pir.db)
+	rows, err := q.MarkPredictionIntentAsExpired(context.Background(), txId)
+	if err != nil {
+		return false, fmt.Errorf("MarkPredictionIntentAsExpired failed: %v", err)
+	}
+
+	log.Printf("Marked prediction intent as expired in database for txId: %s", txId.String())
+	return rows > 0, nil
+}
+
+func (pir *PredictionIntentsRepository) GetAllOpenPredictionIntentsByEvmAddress(evmAddress string) ([]sqlc.PredictionIntent, error) {
 	if pir.db == nil {
 		return nil, fmt.Errorf("database not initialized")
 	}
 
 	q := sqlc.New(pir.db)
-	orderIntents, err := q.GetAllOpenPredictionIntentsByMarketIdAndAccountId(context.Background(), sqlc.GetAllOpenPredictionIntentsByMarketIdAndAccountIdParams{
-		MarketID:  marketId,
+	predictionIntents, err := q.GetAllOpenPredictionIntentsByEvmAddress(context.Background(), evmAddress)
+	if err != nil {
+		return nil, fmt.Errorf("GetAllOpenPredictionIntentsByEvmAddress failed: %v", err)
+	}
+
+	return predictionIntents, nil
+}
+
+// GetPredictionIntents lists prediction intents (newest first) - unset filters match everything
+func (pir *PredictionIntentsRepository) GetPredictionIntents(req *pb_api.ListPredictionIntentsRequest) ([]sqlc.PredictionIntent, error) {
+	if pir.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	params := sqlc.GetPredictionIntentsParams{
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	}
+	if req.AccountId != nil {
+		params.AccountID = sql.NullString{String: *req.AccountId, Valid: true}
+	}
+	if req.MarketId != nil {
+		marketUUID, err := uuid.Parse(*req.MarketId)
+		if err != nil {
+			return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+		}
+		params.MarketID = uuid.NullUUID{UUID: marketUUID, Valid: true}
+	}
+	if req.State != nil {
+		params.State = sql.NullString{String: *req.State, Valid: true}
+	}
+
+	q := sqlc.New(pir.db)
+	predictionIntents, err := q.GetPredictionIntents(context.Background(), params)
+	if err != nil {
+		return nil, fmt.Errorf("GetPredictionIntents failed: %v", err)
+	}
+
+	return predictionIntents, nil
+}
+
+func (pir *PredictionIntentsRepository) GetPredictionIntentByTxId(txId uuid.UUID) (*sqlc.PredictionIntent, error) {
+	if pir.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(pir.db)
+	predictionIntent, err := q.GetPredictionIntentByTxId(context.Background(), txId)
+	if err != nil {
+		return nil, fmt.Errorf("GetPredictionIntentByTxId failed: %v", err)
+	}
+
+	return &predictionIntent, nil
+}
+
+// GetOpenPredictionIntentExposure sums up the account's open intents on the network (and on the market) for the pre-trade risk checks
+func (pir *PredictionIntentsRepository) GetOpenPredictionIntentExposure(accountId string, net string, marketId string) (*sqlc.GetOpenPredictionIntentExposureRow, error) {
+	if pir.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.Parse(marketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	q := sqlc.New(pir.db)
+	exposure, err := q.GetOpenPredictionIntentExposure(context.Background(), sqlc.GetOpenPredictionIntentExposureParams{
+		MarketID:  marketUUID,
 		AccountID: accountId,
+		Net:       net,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("GetAllOpenPredictionIntentsByMarketIdAndAccountId failed: %v", err)
+		return nil, fmt.Errorf("GetOpenPredictionIntentExposure failed: %v", err)
 	}
 
-	return orderIntents, nil
+	return &exposure, nil
 }
 
-func (pir *PredictionIntentsRepository) MarkPredictionIntentAsEvicted(txId uuid.UUID) error {
+func (pir *PredictionIntentsRepository) GetOpenNotionalUsdByNet(net string) (float64, error) {
+	if pir.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(pir.db)
+	notionalUsd, err := q.GetOpenNotionalUsdByNet(context.Background(), net)
+	if err != nil {
+		return 0, fmt.Errorf("GetOpenNotionalUsdByNet failed: %v", err)
+	}
+
+	return notionalUsd, nil
+}
+
+// GetUndeliveredClobOutboxMessages returns (up to limit) messages not yet published to NATS, oldest first
+func (pir *PredictionIntentsRepository) GetUndeliveredClobOutboxMessages(limit int32) ([]sqlc.ClobOutbox, error) {
+	if pir.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(pir.db)
+	messages, err := q.GetUndeliveredClobOutboxMessages(context.Background(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetUndeliveredClobOutboxMessages failed: %v", err)
+	}
+
+	return messages, nil
+}
+
+func (pir *PredictionIntentsRepository) MarkClobOutboxMessageAsDelivered(id int32) error {
 	if pir.db == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
 	q := sqlc.New(pir.db)
-	err := q.MarkPredictionIntentAsEvicted(context.Background(), txId)
+	err := q.MarkClobOutboxMessageAsDelivered(context.Background(), id)
 	if err != nil {
-		return fmt.Errorf("MarkPredictionIntentAsEvicted failed: %v", err)
+		return fmt.Errorf("MarkClobOutboxMessageAsDelivered failed: %v", err)
 	}
+
 	return nil
 }
 
-func (pir *PredictionIntentsRepository) GetAllOpenPredictionIntentsByEvmAddress(evmAddress string) ([]sqlc.PredictionIntent, error) {
+// SetClobOutboxMessageError records a failed publish attempt - the message stays undelivered and is retried
+func (pir *PredictionIntentsRepository) SetClobOutboxMessageError(id int32, lastError string) error {
 	if pir.db == nil {
-		return nil, fmt.Errorf("database not initialized")
+		return fmt.Errorf("database not initialized")
 	}
 
 	q := sqlc.New(pir.db)
-	predictionIntents, err := q.GetAllOpenPredictionIntentsByEvmAddress(context.Background(), evmAddress)
+	err := q.SetClobOutboxMessageError(context.Background(), sqlc.SetClobOutboxMessageErrorParams{
+		ID:        id,
+		LastError: sql.NullString{String: lastError, Valid: true},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("GetAllOpenPredictionIntentsByEvmAddress failed: %v", err)
+		return fmt.Errorf("SetClobOutboxMessageError failed: %v", err)
 	}
 
-	return predictionIntents, nil
+	return nil
 }
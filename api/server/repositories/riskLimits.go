@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	sqlc "api/gen/sqlc"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+
+	pb_api "api/gen"
+)
+
+type RiskLimitsRepository struct {
+	db *sql.DB
+}
+
+func (riskLimitsRepository *RiskLimitsRepository) CloseDb() error {
+	var err = riskLimitsRepository.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close database: %v", err)
+	}
+	return nil
+}
+
+func (riskLimitsRepository *RiskLimitsRepository) InitDb() error {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_UNAME"), os.Getenv("DB_PWORD"), os.Getenv("DB_NAME"))
+
+	var db, err = sql.Open("postgres", connStr)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	riskLimitsRepository.db = db
+
+	// Verify connection
+	if err = db.Ping(); err != nil {
+		return fmt.Errorf("failed to ping database: %v", err)
+	}
+
+	log.Println("DB: RiskLimitsRepository connected successfully")
+	return nil
+}
+
+// SetRiskLimits replaces the risk limits of the scope (net, or net + market) - returns nil if no limit is set (the scope's limits are removed)
+func (riskLimitsRepository *RiskLimitsRepository) SetRiskLimits(req *pb_api.RiskLimits) (*sqlc.RiskLimit, error) {
+	if riskLimitsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID := uuid.NullUUID{}
+	if req.MarketId != nil {
+		parsed, err := uuid.Parse(*req.MarketId)
+		if err != nil {
+			return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+		}
+		marketUUID = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+
+	// OK
+	// Start a transaction
+	tx, err := riskLimitsRepository.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	q := sqlc.New(tx)
+	_, err = q.DeleteRiskLimits(context.Background(), sqlc.DeleteRiskLimitsParams{
+		Net:      req.Net,
+		MarketID: marketUUID,
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("DeleteRiskLimits failed: %v", err)
+	}
+
+	var riskLimit *sqlc.RiskLimit
+	if req.MaxOpenIntentsPerAccount != nil || req.MaxPositionQty != nil || req.MinPriceUsd != nil || req.MaxPriceUsd != nil || req.MaxNotionalUsdPerAccount != nil || req.MaxOpenNotionalUsd != nil {
+		created, err := q.CreateRiskLimits(context.Background(), sqlc.CreateRiskLimitsParams{
+			Net:                      req.Net,
+			MarketID:                 marketUUID,
+			MaxOpenIntentsPerAccount: toNullInt32(req.MaxOpenIntentsPerAccount),
+			MaxPositionQty:           toNullFloat64(req.MaxPositionQty),
+			MinPriceUsd:              toNullFloat64(req.MinPriceUsd),
+			MaxPriceUsd:              toNullFloat64(req.MaxPriceUsd),
+			MaxNotionalUsdPerAccount: toNullFloat64(req.MaxNotionalUsdPerAccount),
+			MaxOpenNotionalUsd:       toNullFloat64(req.MaxOpenNotionalUsd),
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("CreateRiskLimits failed: %v", err)
+		}
+		riskLimit = &created
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("Set risk limits in database for net %s (marketId=%s)", req.Net, req.GetMarketId())
+	return riskLimit, nil
+}
+
+func (riskLimitsRepository *RiskLimitsRepository) GetRiskLimits() ([]sqlc.RiskLimit, error) {
+	if riskLimitsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(riskLimitsRepository.db)
+	riskLimits, err := q.GetRiskLimits(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("GetRiskLimits failed: %v", err)
+	}
+
+	return riskLimits, nil
+}
+
+// GetRiskLimitsForMarket returns the network-wide limits (if any) followed by the market's override (if any)
+func (riskLimitsRepository *RiskLimitsRepository) GetRiskLimitsForMarket(net string, marketId string) ([]sqlc.RiskLimit, error) {
+	if riskLimitsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	marketUUID, err := uuid.Parse(marketId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marketId uuid: %v", err)
+	}
+
+	q := sqlc.New(riskLimitsRepository.db)
+	riskLimits, err := q.GetRiskLimitsForMarket(context.Background(), sqlc.GetRiskLimitsForMarketParams{
+		Net:      net,
+		MarketID: uuid.NullUUID{UUID: marketUUID, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetRiskLimitsForMarket failed: %v", err)
+	}
+
+	return riskLimits, nil
+}
+
+func toNullInt32(v *int32) sql.NullInt32 {
+	if v == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *v, Valid: true}
+}
+
+func toNullFloat64(v *float64) sql.NullFloat64 {
+	if v == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *v, Valid: true}
+}
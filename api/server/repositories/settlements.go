@@ -0,0 +1,264 @@
+package repositories
+
+import (
+	sqlc "api/gen/sqlc"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"api/server/lib"
+
+	"github.com/google/uuid"
+)
+
+type SettlementsRepository struct {
+	db *sql.DB
+}
+
+func (settlementsRepository *SettlementsRepository) CloseDb() error {
+	var err = settlementsRepository.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close database: %v", err)
+	}
+	return nil
+}
+
+func (settlementsRepository *SettlementsRepository) InitDb() error {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_UNAME"), os.Getenv("DB_PWORD"), os.Getenv("DB_NAME"))
+
+	var db, err = sql.Open("postgres", connStr)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	settlementsRepository.db = db
+
+	// Verify connection
+	if err = db.Ping(); err != nil {
+		return fmt.Errorf("failed to ping database: %v", err)
+	}
+
+	log.Println("DB: SettlementsRepository connected successfully")
+	return nil
+}
+
+// GetSettlements returns the settlements of a match, of every match an intent took part in and/or in a status (oldest first) - nil filters are ignored
+func (settlementsRepository *SettlementsRepository) GetSettlements(matchId *int32, txId *string, status *string) ([]sqlc.GetSettlementsRow, error) {
+	if settlementsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	params := sqlc.GetSettlementsParams{}
+	if matchId != nil {
+		params.MatchID = sql.NullInt32{Int32: *matchId, Valid: true}
+	}
+	if txId != nil {
+		txUUID, err := uuid.Parse(*txId)
+		if err != nil {
+			return nil, fmt.Errorf("invalid txId uuid: %v", err)
+		}
+		params.TxID = uuid.NullUUID{UUID: txUUID, Valid: true}
+	}
+	if status != nil {
+		params.Status = sql.NullString{String: *status, Valid: true}
+	}
+
+	q := sqlc.New(settlementsRepository.db)
+	settlements, err := q.GetSettlements(context.Background(), params)
+	if err != nil {
+		return nil, fmt.Errorf("GetSettlements failed: %v", err)
+	}
+
+	return settlements, nil
+}
+
+// GetSettlementsByMatchIds returns the settlements keyed by match id
+func (settlementsRepository *SettlementsRepository) GetSettlementsByMatchIds(matchIds []int32) (map[int32]sqlc.Settlement, error) {
+	if settlementsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(settlementsRepository.db)
+	settlements, err := q.GetSettlementsByMatchIds(context.Background(), matchIds)
+	if err != nil {
+		return nil, fmt.Errorf("GetSettlementsByMatchIds failed: %v", err)
+	}
+
+	byMatchId := make(map[int32]sqlc.Settlement, len(settlements))
+	for _, settlement := range settlements {
+		byMatchId[settlement.MatchID] = settlement
+	}
+	return byMatchId, nil
+}
+
+// MarkSettlementAsSubmitted records the Hedera transaction ID of a settlement attempt
+func (settlementsRepository *SettlementsRepository) MarkSettlementAsSubmitted(matchId int32, hederaTxId string) error {
+	if settlementsRepository.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(settlementsRepository.db)
+	err := q.MarkSettlementAsSubmitted(context.Background(), sqlc.MarkSettlementAsSubmittedParams{
+		MatchID:    matchId,
+		HederaTxID: sql.NullString{String: hederaTxId, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("MarkSettlementAsSubmitted failed: %v", err)
+	}
+
+	log.Printf("Settlement submitted for match id: %d (hederaTxId=%s)", matchId, hederaTxId)
+	return nil
+}
+
+// MarkSettlementAsConfirmed - a nil gasUsed means the transaction record was not available
+func (settlementsRepository *SettlementsRepository) MarkSettlementAsConfirmed(matchId int32, receiptStatus string, gasUsed *uint64) error {
+	if settlementsRepository.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	params := sqlc.MarkSettlementAsConfirmedParams{
+		MatchID:       matchId,
+		ReceiptStatus: sql.NullString{String: receiptStatus, Valid: true},
+	}
+	if gasUsed != nil {
+		params.GasUsed = sql.NullInt64{Int64: int64(*gasUsed), Valid: true}
+	}
+
+	q := sqlc.New(settlementsRepository.db)
+	err := q.MarkSettlementAsConfirmed(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("MarkSettlementAsConfirmed failed: %v", err)
+	}
+
+	log.Printf("Settlement confirmed for match id: %d (status=%s)", matchId, receiptStatus)
+	return nil
+}
+
+// MarkSettlementAsFailed records why a settlement attempt failed - an empty receiptStatus means no receipt was available.
+// A transient failure is scheduled for a retry (with backoff), a permanent one (or the last attempt) is dead-lettered.
+func (settlementsRepository *SettlementsRepository) MarkSettlementAsFailed(matchId int32, receiptStatus string, reason string, isPermanent bool) (*sqlc.Settlement, error) {
+	if settlementsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(settlementsRepository.db)
+	settlement, err := q.MarkSettlementAsFailed(context.Background(), sqlc.MarkSettlementAsFailedParams{
+		MatchID:           matchId,
+		ReceiptStatus:     sql.NullString{String: receiptStatus, Valid: receiptStatus != ""},
+		Error:             reason,
+		IsPermanent:       isPermanent,
+		MaxAttempts:       lib.SETTLEMENT_MAX_ATTEMPTS,
+		BackoffSeconds:    lib.SETTLEMENT_RETRY_BACKOFF_SECONDS,
+		MaxBackoffSeconds: lib.SETTLEMENT_RETRY_MAX_BACKOFF_SECONDS,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("MarkSettlementAsFailed failed: %v", err)
+	}
+
+	log.Printf("Settlement %s for match id: %d (attempts=%d): %s", settlement.Status, matchId, settlement.Attempts, reason)
+	return &settlement, nil
+}
+
+// ClaimSettlementForRetry puts a failed or dead-lettered settlement back to pending - false if it was not (or no longer) either
+func (settlementsRepository *SettlementsRepository) ClaimSettlementForRetry(matchId int32) (bool, error) {
+	if settlementsRepository.db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(settlementsRepository.db)
+	n, err := q.ClaimSettlementForRetry(context.Background(), matchId)
+	if err != nil {
+		return false, fmt.Errorf("ClaimSettlementForRetry failed: %v", err)
+	}
+
+	return n > 0, nil
+}
+
+// FailStaleSettlements marks the settlements of CLOB matches left pending (not held) or submitted for longer than staleSeconds as failed (due now) - returns their match ids
+func (settlementsRepository *SettlementsRepository) FailStaleSettlements(staleSeconds int32) ([]int32, error) {
+	if settlementsRepository.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(settlementsRepository.db)
+	matchIds, err := q.FailStaleSettlements(context.Background(), staleSeconds)
+	if err != nil {
+		return nil, fmt.Errorf("FailStaleSettlements failed: %v", err)
+	}
+
+	return matchIds, nil
+}
+
+// PostponeSettlementRetry puts off the next retry of a failed settlement without counting an attempt
+func (settlementsRepository *SettlementsRepository) PostponeSettlementRetry(matchId int32, delaySeconds int32) error {
+	if settlementsRepository.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	q := sqlc.New(settlementsRepository.db)
+	err := q.PostponeSettlementRetry(context.Background(), sqlc.PostponeSettlementRetryParams{
+		MatchID:      matchId,
+		DelaySeconds: delaySeconds,
+	})
+	if err != nil {
+		return fmt.Errorf("PostponeSettlementRetry failed: %v", err)
+	}
+
+	return nil
+}
+
+/*
+*
+AbandonSettlement gives up on a dead-lettered settlement - false if it was not dead-lettered.
+The matched qty is given back to both intents in the same transaction (see: ReleasePredictionIntentFill), the intents are returned as updated.
+*/
+func (settlementsRepository *SettlementsRepository) AbandonSettlement(matchId int32) (bool, []sqlc.PredictionIntent, error) {
+	if settlementsRepository.db == nil {
+		return false, nil, fmt.Errorf("database not initialized")
+	}
+
+	// Start a transaction
+	tx, err := settlementsRepository.db.Begin()
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	q := sqlc.New(tx)
+	n, err := q.MarkSettlementAsAbandoned(context.Background(), matchId)
+	if err != nil {
+		tx.Rollback()
+		return false, nil, fmt.Errorf("MarkSettlementAsAbandoned failed: %v", err)
+	}
+	if n == 0 {
+		tx.Rollback()
+		return false, nil, nil
+	}
+
+	match, err := q.GetMatchById(context.Background(), matchId)
+	if err != nil {
+		tx.Rollback()
+		return false, nil, fmt.Errorf("GetMatchById failed: %v", err)
+	}
+
+	matchedQty := match.MatchedQty
+	predictionIntents := make([]sqlc.PredictionIntent, 0, 2)
+	for _, txId := range []uuid.UUID{match.TxId1, match.TxId2} {
+		predictionIntent, err := q.ReleasePredictionIntentFill(context.Background(), sqlc.ReleasePredictionIntentFillParams{
+			TxID:       txId,
+			MatchedQty: matchedQty,
+		})
+		if err != nil {
+			tx.Rollback()
+			return false, nil, fmt.Errorf("ReleasePredictionIntentFill failed for txId %s: %v", txId.String(), err)
+		}
+		predictionIntents = append(predictionIntents, predictionIntent)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("Settlement abandoned for match id: %d (released qty %f on txIds: {%s, %s})", matchId, matchedQty, match.TxId1.String(), match.TxId2.String())
+	return true, predictionIntents, nil
+}
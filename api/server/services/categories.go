@@ -0,0 +1,59 @@
+package services
+
+import (
+	pb_api "api/gen"
+	repositories "api/server/repositories"
+	"fmt"
+)
+
+type CategoriesService struct {
+	log                  *LogService
+	categoriesRepository *repositories.CategoriesRepository
+}
+
+func (cs *CategoriesService) Init(log *LogService, categoriesRepository *repositories.CategoriesRepository) error {
+	cs.log = log
+	cs.categoriesRepository = categoriesRepository
+
+	cs.log.Log(INFO, "Service: Categories service initialized successfully")
+	return nil
+}
+
+func (cs *CategoriesService) GetCategories() (*pb_api.CategoriesResponse, error) {
+	categories, err := cs.categoriesRepository.GetActiveCategories()
+	if err != nil {
+		return nil, cs.log.Log(ERROR, "failed to get categories: %v", err)
+	}
+
+	var categoryResponses []*pb_api.Category
+	for _, category := range categories {
+		var description string
+		if category.Description.Valid {
+			description = category.Description.String
+		} else {
+			description = ""
+		}
+
+		categoryResponses = append(categoryResponses, &pb_api.Category{
+			Id:          category.ID,
+			Name:        category.Name,
+			Description: description,
+			SortOrder:   category.SortOrder,
+		})
+	}
+
+	return &pb_api.CategoriesResponse{
+		Categories: categoryResponses,
+	}, nil
+}
+
+func (cs *CategoriesService) SetMarketCategories(req *pb_api.SetMarketCategoriesRequest) (*pb_api.StdResponse, error) {
+	err := cs.categoriesRepository.SetMarketCategories(req.MarketId, req.CategoryIds)
+	if err != nil {
+		return nil, cs.log.Log(ERROR, "failed to set categories on market (marketId=%s): %v", req.MarketId, err)
+	}
+
+	return &pb_api.StdResponse{
+		Message: fmt.Sprintf("Set %d categories on market %s", len(req.CategoryIds), req.MarketId),
+	}, nil
+}
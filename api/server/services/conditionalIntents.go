@@ -0,0 +1,274 @@
+package services
+
+import (
+	pb_api "api/gen"
+	sqlc "api/gen/sqlc"
+	"api/server/lib"
+	repositories "api/server/repositories"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	hiero "github.com/hiero-ledger/hiero-sdk-go/v2/sdk"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type ConditionalIntentsService struct {
+	log                          *LogService
+	conditionalIntentsRepository *repositories.ConditionalIntentsRepository
+	priceService                 *PriceService
+	predictionIntentsService     *PredictionIntentsService
+}
+
+func (cis *ConditionalIntentsService) Init(log *LogService, conditionalIntentsRepository *repositories.ConditionalIntentsRepository, priceService *PriceService, hederaService *HederaService, predictionIntentsService *PredictionIntentsService) error {
+	cis.log = log
+	cis.conditionalIntentsRepository = conditionalIntentsRepository
+	cis.priceService = priceService
+	cis.predictionIntentsService = predictionIntentsService
+
+	// evaluate the pending triggers on every settlement price
+	hederaService.OnPriceTick(cis.EvaluateTriggers)
+
+	cis.log.Log(INFO, "Service: ConditionalIntents service initialized successfully")
+	return nil
+}
+
+// CreateConditionalIntent stores a signed prediction intent until the market's last traded price crosses the trigger.
+// The intent is validated now (signature, timestamp, allowance, ...) and again when the trigger fires.
+// The trigger is signed with the intent's key (see: lib.AssembleTriggerPayloadHexForSigning) - it can't be swapped for another one.
+func (cis *ConditionalIntentsService) CreateConditionalIntent(req *pb_api.ConditionalIntentRequest) (*pb_api.ConditionalIntent, error) {
+	// guards
+	lastPriceUsd, err := cis.priceService.GetLatestPriceByMarket(req.Intent.MarketId)
+	if err != nil {
+		return nil, cis.log.Log(ERROR, "failed to get latest price for market %s: %v", req.Intent.MarketId, err)
+	}
+	if isTriggerHit(req.TriggerCondition, req.TriggerPriceUsd, float64(lastPriceUsd)) {
+		return nil, cis.log.Log(ERROR, "trigger (%s %f) is already hit by the last traded price (%f) - place the prediction intent directly", req.TriggerCondition, req.TriggerPriceUsd, lastPriceUsd)
+	}
+
+	// the intent's public key is checked against the account here
+	_, err = cis.predictionIntentsService.validatePredictionIntent(req.Intent, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cis.verifyTriggerSig(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// OK
+	conditionalIntent, err := cis.conditionalIntentsRepository.CreateConditionalIntent(req)
+	if err != nil {
+		return nil, cis.log.Log(ERROR, "database error: failed to save conditional intent (txId=%s): %v", req.Intent.TxId, err)
+	}
+
+	return cis.mapConditionalIntent(conditionalIntent)
+}
+
+// verifyTriggerSig checks the trigger's signature against the public key the intent was signed with
+func (cis *ConditionalIntentsService) verifyTriggerSig(req *pb_api.ConditionalIntentRequest) error {
+	publicKey, err := hiero.PublicKeyFromString(req.Intent.PublicKey)
+	if err != nil {
+		return cis.log.Log(ERROR, "failed to parse public key from string: %v", err)
+	}
+
+	usdcDecimals, err := strconv.ParseUint(os.Getenv("USDC_DECIMALS"), 10, 64)
+	if err != nil {
+		return cis.log.Log(ERROR, "failed to parse USDC_DECIMALS: %v", err)
+	}
+
+	payloadHex, err := lib.AssembleTriggerPayloadHexForSigning(req, usdcDecimals)
+	if err != nil {
+		return cis.log.Log(ERROR, "failed to extract trigger payload for signing: %v", err)
+	}
+
+	// N.B. treat the hex string as a Utf8 string - same as for prediction intents
+	isValidSig, err := lib.VerifySig(&publicKey, payloadHex, req.TriggerSig)
+	if err != nil {
+		return cis.log.Log(ERROR, "failed to verify trigger signature: %v", err)
+	}
+	if !isValidSig {
+		return cis.log.Log(ERROR, "invalid trigger signature for account %s (txId=%s)", req.Intent.AccountId, req.Intent.TxId)
+	}
+	return nil
+}
+
+// ListConditionalIntents lists the account's pending triggers (newest first)
+func (cis *ConditionalIntentsService) ListConditionalIntents(req *pb_api.ListConditionalIntentsRequest) (*pb_api.ConditionalIntentsResponse, error) {
+	conditionalIntents, err := cis.conditionalIntentsRepository.GetPendingConditionalIntentsByAccountId(req)
+	if err != nil {
+		return nil, cis.log.Log(ERROR, "failed to list conditional intents for account %s: %v", req.AccountId, err)
+	}
+
+	response := &pb_api.ConditionalIntentsResponse{}
+	for _, conditionalIntent := range conditionalIntents {
+		mapped, err := cis.mapConditionalIntent(&conditionalIntent)
+		if err != nil {
+			return nil, err
+		}
+		response.ConditionalIntents = append(response.ConditionalIntents, mapped)
+	}
+	return response, nil
+}
+
+// CancelConditionalIntent cancels a pending trigger on behalf of the account that set it.
+// The request is signed over the same payload as CancelPredictionIntent (see: lib.AssembleCancelPayloadHexForSigning).
+func (cis *ConditionalIntentsService) CancelConditionalIntent(req *pb_api.CancelOrderRequest) (*pb_api.StdResponse, error) {
+	// guards
+	txUUID, err := uuid.Parse(req.TxId)
+	if err != nil {
+		return nil, cis.log.Log(ERROR, "invalid txId uuid: %v", err)
+	}
+
+	conditionalIntent, err := cis.conditionalIntentsRepository.GetConditionalIntent(txUUID)
+	if err != nil {
+		return nil, cis.log.Log(ERROR, "failed to get conditional intent (txId=%s): %v", req.TxId, err)
+	}
+	if !strings.EqualFold(conditionalIntent.MarketID.String(), req.MarketId) {
+		return nil, cis.log.Log(ERROR, "conditional intent (txId=%s) does not belong to market %s", req.TxId, req.MarketId)
+	}
+	if conditionalIntent.AccountID != req.AccountId {
+		return nil, cis.log.Log(ERROR, "conditional intent (txId=%s) does not belong to account %s", req.TxId, req.AccountId)
+	}
+
+	intent := &pb_api.PredictionIntentRequest{}
+	err = protojson.Unmarshal(conditionalIntent.Intent, intent)
+	if err != nil {
+		return nil, cis.log.Log(ERROR, "failed to unmarshal conditional intent (txId=%s): %v", req.TxId, err)
+	}
+
+	// verify the signature against the public key the intent was signed with
+	publicKey, err := hiero.PublicKeyFromString(intent.PublicKey)
+	if err != nil {
+		return nil, cis.log.Log(ERROR, "failed to parse public key from string: %v", err)
+	}
+
+	payloadHex, err := lib.AssembleCancelPayloadHexForSigning(req, intent.EvmAddress)
+	if err != nil {
+		return nil, cis.log.Log(ERROR, "failed to extract cancel payload for signing: %v", err)
+	}
+
+	// N.B. treat the hex string as a Utf8 string - same as for prediction intents
+	isValidSig, err := lib.VerifySig(&publicKey, payloadHex, req.Sig)
+	if err != nil {
+		return nil, cis.log.Log(ERROR, "failed to verify signature: %v", err)
+	}
+	if !isValidSig {
+		return nil, cis.log.Log(ERROR, "invalid signature for account %s", req.AccountId)
+	}
+
+	// OK
+	isCancelled, err := cis.conditionalIntentsRepository.CancelConditionalIntent(txUUID)
+	if err != nil {
+		return nil, cis.log.Log(ERROR, "failed to cancel conditional intent (txId=%s): %v", req.TxId, err)
+	}
+	if !isCancelled {
+		return nil, cis.log.Log(ERROR, "conditional intent (txId=%s) is no longer pending", req.TxId)
+	}
+
+	return &pb_api.StdResponse{
+		Message: fmt.Sprintf("Cancelled conditional intent with txId: %s", req.TxId),
+	}, nil
+}
+
+// EvaluateTriggers places every pending conditional intent of the market that the new traded price triggers
+func (cis *ConditionalIntentsService) EvaluateTriggers(marketId string, priceUsd float64) {
+	conditionalIntents, err := cis.conditionalIntentsRepository.GetPendingConditionalIntentsByMarketId(marketId)
+	if err != nil {
+		cis.log.Log(ERROR, "failed to get pending conditional intents for market %s: %v", marketId, err)
+		return
+	}
+
+	for _, conditionalIntent := range conditionalIntents {
+		if !isTriggerHit(conditionalIntent.TriggerCondition, conditionalIntent.TriggerPriceUsd, priceUsd) {
+			continue
+		}
+
+		txId := conditionalIntent.TxID.String()
+
+		// claim it first - a concurrent price tick (or a cancel) may have beaten us to it
+		isClaimed, err := cis.conditionalIntentsRepository.MarkConditionalIntentAsTriggered(conditionalIntent.TxID)
+		if err != nil {
+			cis.log.Log(ERROR, "failed to mark conditional intent (txId=%s) as triggered: %v", txId, err)
+			continue
+		}
+		if !isClaimed {
+			continue
+		}
+
+		cis.log.Log(INFO, "Conditional intent (txId=%s) triggered: price %f is %s %f", txId, priceUsd, conditionalIntent.TriggerCondition, conditionalIntent.TriggerPriceUsd)
+
+		intent := &pb_api.PredictionIntentRequest{}
+		err = protojson.Unmarshal(conditionalIntent.Intent, intent)
+		if err == nil {
+			var response *pb_api.PredictionIntentResponse
+			response, err = cis.predictionIntentsService.CreateTriggeredPredictionIntent(intent)
+			if err != nil && response != nil && response.Message != "" {
+				err = fmt.Errorf("%s: %v", response.Message, err)
+			}
+		}
+		if err != nil {
+			cis.log.Log(WARN, "triggered conditional intent (txId=%s) was rejected: %v", txId, err)
+			if failErr := cis.conditionalIntentsRepository.MarkConditionalIntentAsFailed(conditionalIntent.TxID, err.Error()); failErr != nil {
+				cis.log.Log(ERROR, "failed to mark conditional intent (txId=%s) as failed: %v", txId, failErr)
+			}
+		}
+	}
+}
+
+// isTriggerHit reports whether priceUsd (last traded YES price) satisfies the trigger
+func isTriggerHit(triggerCondition string, triggerPriceUsd float64, priceUsd float64) bool {
+	switch strings.ToLower(triggerCondition) {
+	case lib.TRIGGER_CONDITION_ABOVE:
+		return priceUsd >= triggerPriceUsd
+	case lib.TRIGGER_CONDITION_BELOW:
+		return priceUsd <= triggerPriceUsd
+	default:
+		return false
+	}
+}
+
+func (cis *ConditionalIntentsService) mapConditionalIntent(conditionalIntent *sqlc.ConditionalIntent) (*pb_api.ConditionalIntent, error) {
+	intent := &pb_api.PredictionIntentRequest{}
+	err := protojson.Unmarshal(conditionalIntent.Intent, intent)
+	if err != nil {
+		return nil, cis.log.Log(ERROR, "failed to unmarshal conditional intent (txId=%s): %v", conditionalIntent.TxID.String(), err)
+	}
+
+	status := "pending"
+	switch {
+	case conditionalIntent.CancelledAt.Valid:
+		status = "cancelled"
+	case conditionalIntent.FailedAt.Valid:
+		status = "failed"
+	case conditionalIntent.TriggeredAt.Valid:
+		status = "triggered"
+	}
+
+	return &pb_api.ConditionalIntent{
+		TxId:             conditionalIntent.TxID.String(),
+		MarketId:         conditionalIntent.MarketID.String(),
+		AccountId:        conditionalIntent.AccountID,
+		TriggerCondition: conditionalIntent.TriggerCondition,
+		TriggerPriceUsd:  conditionalIntent.TriggerPriceUsd,
+		Intent: &pb_api.PredictionIntent{
+			TxId:        intent.TxId,
+			Net:         intent.Net,
+			MarketId:    intent.MarketId,
+			GeneratedAt: intent.GeneratedAt,
+			AccountId:   intent.AccountId,
+			MarketLimit: intent.MarketLimit,
+			PriceUsd:    intent.PriceUsd,
+			Qty:         intent.Qty,
+			TimeInForce: intent.TimeInForce,
+			ExpiresAt:   intent.ExpiresAt,
+		},
+		Status:        status,
+		CreatedAt:     conditionalIntent.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		TriggeredAt:   formatNullTime(conditionalIntent.TriggeredAt),
+		FailureReason: conditionalIntent.FailureReason.String,
+	}, nil
+}
@@ -1,12 +1,14 @@
 package services
 
 import (
+	"api/server/lib"
 	repositories "api/server/repositories"
 	"fmt"
 	"math"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	hiero "github.com/hiero-ledger/hiero-sdk-go/v2/sdk"
 )
@@ -15,18 +17,24 @@ type CronService struct {
 	log                         *LogService
 	priceRepository             *repositories.PriceRepository
 	marketsRepository           *repositories.MarketsRepository
+	marketTemplatesRepository   *repositories.MarketTemplatesRepository
 	predictionIntentsRepository *repositories.PredictionIntentsRepository
 	hederaService               *HederaService
 	predictionIntentsService    *PredictionIntentsService
+	marketsService              *MarketsService
+	marketTemplatesService      *MarketTemplatesService
 }
 
-func (cs *CronService) Init(log *LogService, mr *repositories.MarketsRepository, pir *repositories.PredictionIntentsRepository, hs *HederaService, pis *PredictionIntentsService) error {
+func (cs *CronService) Init(log *LogService, mr *repositories.MarketsRepository, mtr *repositories.MarketTemplatesRepository, pir *repositories.PredictionIntentsRepository, hs *HederaService, pis *PredictionIntentsService, ms *MarketsService, mts *MarketTemplatesService) error {
 	// inject deps
 	cs.log = log
 	cs.marketsRepository = mr
+	cs.marketTemplatesRepository = mtr
 	cs.predictionIntentsRepository = pir
 	cs.hederaService = hs
 	cs.predictionIntentsService = pis
+	cs.marketsService = ms
+	cs.marketTemplatesService = mts
 
 	cs.log.Log(INFO, "Service: Cron service initialized successfully")
 	return nil
@@ -35,11 +43,97 @@ func (cs *CronService) Init(log *LogService, mr *repositories.MarketsRepository,
 func (cs *CronService) CronJob() {
 	cs.log.Log(INFO, "CronService: Running CronJob...")
 
+	cs.ReconcileMarketCreations()
+	cs.CreateScheduledMarkets()
+	cs.CloseExpiredMarkets()
+	cs.ExpirePredictionIntents()
 	cs.KickOutOrderIntentsNotBackedByFunds()
 
 	cs.log.Log(INFO, "CronService: CronJob completed.")
 }
 
+func (cs *CronService) ReconcileMarketCreations() {
+	cs.log.Log(INFO, "ReconcileMarketCreations: Finishing half-created markets...")
+
+	marketCreations, err := cs.marketsRepository.GetStalledMarketCreations()
+	if err != nil {
+		cs.log.Log(ERROR, "Failed to fetch stalled market creations: %v", err)
+		return
+	}
+
+	for _, marketCreation := range marketCreations {
+		if marketCreation.Attempts >= lib.MAX_MARKET_CREATION_ATTEMPTS {
+			err := cs.marketsService.AbandonMarketCreation(&marketCreation)
+			if err != nil {
+				cs.log.Log(ERROR, "Failed to abandon market creation ID %s (will retry on the next run): %v", marketCreation.MarketID, err)
+			}
+			continue
+		}
+
+		err := cs.marketsService.ResumeMarketCreation(&marketCreation)
+		if err != nil {
+			cs.log.Log(ERROR, "Failed to resume market creation ID %s at step %s (will retry on the next run): %v", marketCreation.MarketID, marketCreation.Step, err)
+			continue
+		}
+		cs.log.Log(INFO, "Reconciled market creation ID %s", marketCreation.MarketID)
+	}
+}
+
+func (cs *CronService) CreateScheduledMarkets() {
+	cs.log.Log(INFO, "CreateScheduledMarkets: Creating markets from due market templates...")
+
+	marketTemplates, err := cs.marketTemplatesRepository.GetActiveMarketTemplates()
+	if err != nil {
+		cs.log.Log(ERROR, "Failed to fetch active market templates: %v", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, marketTemplate := range marketTemplates {
+		err := cs.marketTemplatesService.RunMarketTemplate(&marketTemplate, now)
+		if err != nil {
+			cs.log.Log(ERROR, "Failed to run market template ID %s (will retry on the next run): %v", marketTemplate.TemplateID, err)
+			continue
+		}
+	}
+}
+
+func (cs *CronService) CloseExpiredMarkets() {
+	cs.log.Log(INFO, "CloseExpiredMarkets: Closing markets past their closes_at...")
+
+	markets, err := cs.marketsRepository.GetMarketsPastClosesAt()
+	if err != nil {
+		cs.log.Log(ERROR, "Failed to fetch markets past closes_at: %v", err)
+		return
+	}
+
+	for _, market := range markets {
+		err := cs.marketsService.CloseMarket(&market)
+		if err != nil {
+			cs.log.Log(ERROR, "Failed to close market ID %s (will retry on the next run): %v", market.MarketID, err)
+			continue
+		}
+	}
+}
+
+func (cs *CronService) ExpirePredictionIntents() {
+	cs.log.Log(INFO, "ExpirePredictionIntents: Expiring GTD prediction intents past their expires_at...")
+
+	predictionIntents, err := cs.predictionIntentsRepository.GetExpiredPredictionIntents()
+	if err != nil {
+		cs.log.Log(ERROR, "Failed to fetch expired prediction intents: %v", err)
+		return
+	}
+
+	for _, predictionIntent := range predictionIntents {
+		err := cs.predictionIntentsService.ExpirePredictionIntent(&predictionIntent)
+		if err != nil {
+			cs.log.Log(ERROR, "Failed to expire prediction intent txId %s (will retry on the next run): %v", predictionIntent.TxID, err)
+			continue
+		}
+	}
+}
+
 func (cs *CronService) UpdatePositionsWithRealPositions() error {
 	cs.log.Log(INFO, "UpdatePositionsWithRealPositions...")
 	// TODO: implement
@@ -136,12 +230,12 @@ func (cs *CronService) KickOutOrderIntentsNotBackedByFunds() {
 
 			for _, pi := range usersOpenPredictionIntents {
 				cs.log.Log(INFO, "processing txId=%s", pi.TxID.String())
-				sumTotalOfAllPredictionIntents += (math.Abs(pi.PriceUsd) * pi.Qty)
+				sumTotalOfAllPredictionIntents += (math.Abs(pi.PriceUsd) * pi.RemainingQty) // only the qty not yet matched still needs funds
 				cs.log.Log(INFO, "sumTotalOfAllPredictionIntent: %f", sumTotalOfAllPredictionIntents)
 
 				if sumTotalOfAllPredictionIntents > usdcBalance {
 					// cancel this prediction intent
-					_, err := cs.predictionIntentsService.CancelPredictionIntent(market.MarketID.String(), pi.TxID.String())
+					_, err := cs.predictionIntentsService.cancelPredictionIntent(market.MarketID.String(), pi.TxID.String())
 					// err = cs.predictionIntentsRepository.CancelPredictionIntent(pi.TxID.String())
 					if err != nil {
 						cs.log.Log(ERROR, "Failed to cancel prediction intent txId %s for market ID %s and account ID %s: %v", pi.TxID.String(), market.MarketID, accountIdStr, err)
@@ -150,7 +244,8 @@ func (cs *CronService) KickOutOrderIntentsNotBackedByFunds() {
 					cs.log.Log(WARN, "-> Cancelled prediction intent txId %s for market ID %s and account ID %s due to insufficient funds (total required: %f, allowance: %f, balance: %f)", pi.TxID.String(), market.MarketID, accountIdStr, sumTotalOfAllPredictionIntents, allowance, usdcBalance)
 
 					// and mark predictionIntent as evicted:
-					err = cs.predictionIntentsRepository.MarkPredictionIntentAsEvicted(pi.TxID)
+					reason := fmt.Sprintf("insufficient funds for the open intents: $%.2f required (allowance $%.2f, balance $%.2f)", sumTotalOfAllPredictionIntents, allowance, usdcBalance)
+					err = cs.predictionIntentsRepository.MarkPredictionIntentAsEvicted(pi.TxID, reason)
 					if err != nil {
 						cs.log.Log(ERROR, "Failed to mark as evicted prediction intent txId %s for market ID %s and account ID %s: %v", pi.TxID.String(), market.MarketID, accountIdStr, err)
 						continue
@@ -0,0 +1,201 @@
+package services
+
+import (
+	pb_api "api/gen"
+	pb_clob "api/gen/clob"
+	sqlc "api/gen/sqlc"
+	"api/server/lib"
+	repositories "api/server/repositories"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// MatchFees - the trading fees charged on one match, aligned with the (YES, NO) order tuple
+type MatchFees struct {
+	MakerTxId string     // the side that was resting on the book - empty if it could not be determined
+	FeeUsd    [2]float64 // [YES side, NO side]
+}
+
+type FeesService struct {
+	log                         *LogService
+	hederaService               *HederaService
+	feeSchedulesRepository      *repositories.FeeSchedulesRepository
+	predictionIntentsRepository *repositories.PredictionIntentsRepository
+	marketsRepository           *repositories.MarketsRepository
+}
+
+func (fs *FeesService) Init(log *LogService, hederaService *HederaService, feeSchedulesRepository *repositories.FeeSchedulesRepository, predictionIntentsRepository *repositories.PredictionIntentsRepository, marketsRepository *repositories.MarketsRepository) error {
+	fs.log = log
+	fs.hederaService = hederaService
+	fs.feeSchedulesRepository = feeSchedulesRepository
+	fs.predictionIntentsRepository = predictionIntentsRepository
+	fs.marketsRepository = marketsRepository
+
+	fs.log.Log(INFO, "Service: Fees service initialized successfully")
+	return nil
+}
+
+func (fs *FeesService) SetFeeSchedule(req *pb_api.FeeSchedule) (*pb_api.FeeSchedule, error) {
+	// guards
+	err := fs.checkMaxTradingFeeBps(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// OK
+	feeSchedule, err := fs.feeSchedulesRepository.SetFeeSchedule(req)
+	if err != nil {
+		return nil, fs.log.Log(ERROR, "failed to set fee schedule for net %s (marketId=%s): %v", req.Net, req.GetMarketId(), err)
+	}
+	if feeSchedule == nil { // no fee set - the scope's schedule was removed
+		fs.log.Log(INFO, "Removed the fee schedule for net %s (marketId=%s)", req.Net, req.GetMarketId())
+		return &pb_api.FeeSchedule{Net: req.Net, MarketId: req.MarketId}, nil
+	}
+
+	fs.log.Log(INFO, "Set the fee schedule for net %s (marketId=%s): maker %d bps, taker %d bps", req.Net, req.GetMarketId(), feeSchedule.MakerFeeBps, feeSchedule.TakerFeeBps)
+	return mapFeeSchedule(feeSchedule), nil
+}
+
+/*
+*
+A fee above the smart contract's maxTradingFeeBps would make every settlement revert ("fee too high").
+A market's fees are checked against the market's smart contract, network-wide fees against the current X_SMART_CONTRACT_ID
+(the one new markets are created on) - raise it with setMaxTradingFeeBps(...) first.
+*/
+func (fs *FeesService) checkMaxTradingFeeBps(req *pb_api.FeeSchedule) error {
+	if req.GetMakerFeeBps() == 0 && req.GetTakerFeeBps() == 0 {
+		return nil
+	}
+
+	smartContractId := os.Getenv(fmt.Sprintf("%s_SMART_CONTRACT_ID", strings.ToUpper(req.Net)))
+	if req.MarketId != nil {
+		market, err := fs.marketsRepository.GetMarketByIdIncludingSuspended(req.GetMarketId())
+		if err != nil {
+			return fs.log.Log(ERROR, "failed to get market %s: %v", req.GetMarketId(), err)
+		}
+		smartContractId = market.SmartContractID
+	}
+
+	// only the current smart contract charges fees (and has maxTradingFeeBps)
+	contractVersion, err := fs.hederaService.GetPrismContractVersion(strings.ToLower(req.Net), smartContractId)
+	if err != nil {
+		return fs.log.Log(ERROR, "failed to look up the version of smart contract %s - fee schedule not set: %v", smartContractId, err)
+	}
+	if contractVersion == lib.PRISM_CONTRACT_VERSION_LEGACY {
+		return fs.log.Log(ERROR, "smart contract %s is a legacy contract, which does not charge trading fees - fee schedule not set", smartContractId)
+	}
+
+	maxTradingFeeBps, err := fs.hederaService.GetMaxTradingFeeBps(req.Net, smartContractId)
+	if err != nil {
+		return fs.log.Log(ERROR, "failed to get maxTradingFeeBps of smart contract %s - fee schedule not set: %v", smartContractId, err)
+	}
+	if uint64(req.GetMakerFeeBps()) > maxTradingFeeBps || uint64(req.GetTakerFeeBps()) > maxTradingFeeBps {
+		return fs.log.Log(ERROR, "maker %d bps / taker %d bps exceeds maxTradingFeeBps (%d) of smart contract %s", req.GetMakerFeeBps(), req.GetTakerFeeBps(), maxTradingFeeBps, smartContractId)
+	}
+	return nil
+}
+
+func (fs *FeesService) GetFeeSchedules() (*pb_api.FeeSchedulesResponse, error) {
+	feeSchedules, err := fs.feeSchedulesRepository.GetFeeSchedules()
+	if err != nil {
+		return nil, fs.log.Log(ERROR, "failed to get fee schedules: %v", err)
+	}
+
+	response := &pb_api.FeeSchedulesResponse{}
+	for _, feeSchedule := range feeSchedules {
+		response.FeeSchedules = append(response.FeeSchedules, mapFeeSchedule(&feeSchedule))
+	}
+	return response, nil
+}
+
+// GetEffectiveFeeSchedule returns the market's override, else the network-wide schedule, else no fees.
+// A market on a legacy smart contract never has fees - the contract can't charge them.
+func (fs *FeesService) GetEffectiveFeeSchedule(net string, marketId string) (*pb_api.FeeSchedule, error) {
+	net = strings.ToLower(net)
+	market, err := fs.marketsRepository.GetMarketByIdIncludingSuspended(marketId)
+	if err != nil {
+		return nil, fs.log.Log(ERROR, "failed to get market %s: %v", marketId, err)
+	}
+	contractVersion, err := fs.hederaService.GetPrismContractVersion(market.Net, market.SmartContractID)
+	if err != nil {
+		return nil, fs.log.Log(ERROR, "failed to look up the smart contract version of market %s: %v", marketId, err)
+	}
+	if contractVersion == lib.PRISM_CONTRACT_VERSION_LEGACY {
+		return &pb_api.FeeSchedule{Net: net, MakerFeeBps: new(uint32), TakerFeeBps: new(uint32)}, nil
+	}
+
+	feeSchedules, err := fs.feeSchedulesRepository.GetFeeSchedulesForMarket(net, marketId)
+	if err != nil {
+		return nil, fs.log.Log(ERROR, "failed to get fee schedules for net %s (marketId=%s): %v", net, marketId, err)
+	}
+
+	effective := &pb_api.FeeSchedule{Net: net, MakerFeeBps: new(uint32), TakerFeeBps: new(uint32)}
+	for _, feeSchedule := range feeSchedules { // network-wide first - the market's override wins
+		effective = mapFeeSchedule(&feeSchedule)
+	}
+	return effective, nil
+}
+
+/*
+ComputeMatchFees works out the maker/taker fee of each side of a match.
+The maker is the side whose prediction intent reached the book first (prediction_intents.created_at) - the other side crossed the book.
+Each side pays its fee in basis points of its own matched notional: min(qty1, qty2) * |priceUsd|.
+*/
+func (fs *FeesService) ComputeMatchFees(orderRequestClobTuple [2]*pb_clob.CreateOrderRequestClob) (*MatchFees, error) {
+	feeSchedule, err := fs.GetEffectiveFeeSchedule(orderRequestClobTuple[0].Net, orderRequestClobTuple[0].MarketId)
+	if err != nil {
+		return nil, err
+	}
+
+	var predictionIntents [2]*sqlc.PredictionIntent
+	for i, order := range orderRequestClobTuple {
+		txUUID, err := uuid.Parse(order.TxId)
+		if err != nil {
+			return nil, fs.log.Log(ERROR, "invalid txId uuid: %v", err)
+		}
+		predictionIntents[i], err = fs.predictionIntentsRepository.GetPredictionIntentByTxId(txUUID)
+		if err != nil {
+			return nil, fs.log.Log(ERROR, "failed to get prediction intent (txId=%s): %v", order.TxId, err)
+		}
+	}
+
+	// the earlier intent was resting on the book - on a tie fall back to the txIds (UUIDv7 - time ordered)
+	maker := 0
+	if predictionIntents[1].CreatedAt.Before(predictionIntents[0].CreatedAt) ||
+		(predictionIntents[1].CreatedAt.Equal(predictionIntents[0].CreatedAt) && orderRequestClobTuple[1].TxId < orderRequestClobTuple[0].TxId) {
+		maker = 1
+	}
+
+	qty := math.Min(orderRequestClobTuple[0].Qty, orderRequestClobTuple[1].Qty) // every matched YES/NO pair is backed by $1
+	matchFees := &MatchFees{MakerTxId: orderRequestClobTuple[maker].TxId}
+	for i, order := range orderRequestClobTuple {
+		feeBps := feeSchedule.GetTakerFeeBps()
+		if i == maker {
+			feeBps = feeSchedule.GetMakerFeeBps()
+		}
+		matchFees.FeeUsd[i] = float64(feeBps) / lib.FEE_BPS_DENOMINATOR * qty * math.Abs(order.PriceUsd)
+	}
+
+	fs.log.Log(INFO, "Match fees (maker txId=%s): YES $USD%.6f, NO $USD%.6f", matchFees.MakerTxId, matchFees.FeeUsd[0], matchFees.FeeUsd[1])
+	return matchFees, nil
+}
+
+func mapFeeSchedule(feeSchedule *sqlc.FeeSchedule) *pb_api.FeeSchedule {
+	makerFeeBps := uint32(feeSchedule.MakerFeeBps)
+	takerFeeBps := uint32(feeSchedule.TakerFeeBps)
+	mapped := &pb_api.FeeSchedule{
+		Net:         feeSchedule.Net,
+		MakerFeeBps: &makerFeeBps,
+		TakerFeeBps: &takerFeeBps,
+		UpdatedAt:   feeSchedule.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	if feeSchedule.MarketID.Valid {
+		marketId := feeSchedule.MarketID.UUID.String()
+		mapped.MarketId = &marketId
+	}
+	return mapped
+}
@@ -4,16 +4,20 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"math/big"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"os"
 
 	pb_api "api/gen"
 	pb_clob "api/gen/clob"
+	sqlc "api/gen/sqlc"
 	"api/server/lib"
 	repositories "api/server/repositories"
 
@@ -21,23 +25,31 @@ import (
 )
 
 type HederaService struct {
-	log               *LogService
-	hedera_clients    map[string]*hiero.Client // look up based on 'previewnet', 'testnet', 'mainnet'
-	dbRepository      *repositories.DbRepository
-	priceRepository   *repositories.PriceRepository
-	marketsRepository *repositories.MarketsRepository
-	matchesRepository *repositories.MatchesRepository
+	log                   *LogService
+	hedera_clients        map[string]*hiero.Client // look up based on 'previewnet', 'testnet', 'mainnet'
+	dbRepository          *repositories.DbRepository
+	priceRepository       *repositories.PriceRepository
+	marketsRepository     *repositories.MarketsRepository
+	matchesRepository     *repositories.MatchesRepository
+	settlementsRepository *repositories.SettlementsRepository
+
+	priceTickHandlers []func(marketId string, priceUsd float64) // called (in a goroutine) after every recorded settlement price
+
+	contractVersions   map[string]lib.PrismContractVersion // smart contract ID => version (a deployed contract never changes)
+	contractVersionsMu sync.Mutex
 }
 
-func (hs *HederaService) InitHedera(log *LogService, dbRepository *repositories.DbRepository, priceRepository *repositories.PriceRepository, marketsRepository *repositories.MarketsRepository, matchesRepository *repositories.MatchesRepository) error {
+func (hs *HederaService) InitHedera(log *LogService, dbRepository *repositories.DbRepository, priceRepository *repositories.PriceRepository, marketsRepository *repositories.MarketsRepository, matchesRepository *repositories.MatchesRepository, settlementsRepository *repositories.SettlementsRepository) error {
 	hs.log = log
 	hs.dbRepository = dbRepository
 	hs.priceRepository = priceRepository
 	hs.marketsRepository = marketsRepository
 	hs.matchesRepository = matchesRepository
+	hs.settlementsRepository = settlementsRepository
 
 	// First initialize the map to avoid nil map assignment
 	hs.hedera_clients = make(map[string]*hiero.Client)
+	hs.contractVersions = make(map[string]lib.PrismContractVersion)
 
 	var err error
 
@@ -260,14 +272,59 @@ func (hs *HederaService) GetUsdcBalanceUsd(networkSelected hiero.LedgerID, accou
 	return balance, nil
 }
 
+// GetFundsUsd returns the account's USDC allowance to the market's smart contract and its USDC balance (both USD)
+func (hs *HederaService) GetFundsUsd(net string, accountIdStr string, smartContractIdStr string) (allowanceUsd float64, balanceUsd float64, err error) {
+	networkSelected, err := hiero.LedgerIDFromString(strings.ToLower(net))
+	if err != nil {
+		return 0, 0, hs.log.Log(ERROR, "invalid net %s: %v", net, err)
+	}
+	accountId, err := hiero.AccountIDFromString(accountIdStr)
+	if err != nil {
+		return 0, 0, hs.log.Log(ERROR, "invalid account ID %s: %v", accountIdStr, err)
+	}
+	smartContractId, err := hiero.ContractIDFromString(smartContractIdStr)
+	if err != nil {
+		return 0, 0, hs.log.Log(ERROR, "invalid smart contract ID %s: %v", smartContractIdStr, err)
+	}
+
+	usdcAddressStr := os.Getenv(fmt.Sprintf("%s_USDC_ADDRESS", strings.ToUpper(net)))
+	usdcDecimalsStr := os.Getenv("USDC_DECIMALS")
+
+	if usdcAddressStr == "" || usdcDecimalsStr == "" {
+		return 0, 0, hs.log.Log(ERROR, "USDC_ADDRESS or USDC_DECIMALS environment variable is not set")
+	}
+	usdcDecimals, err := strconv.ParseUint(usdcDecimalsStr, 10, 64)
+	if err != nil {
+		return 0, 0, hs.log.Log(ERROR, "invalid USDC_DECIMALS: %v", err)
+	}
+	usdcAddress, err := hiero.ContractIDFromString(usdcAddressStr)
+	if err != nil {
+		return 0, 0, hs.log.Log(ERROR, "invalid USDC address: %v", err)
+	}
+
+	// OK - proceed
+
+	allowanceUsd, err = hs.GetSpenderAllowanceUsd(*networkSelected, accountId, smartContractId, usdcAddress, usdcDecimals)
+	if err != nil {
+		return 0, 0, err
+	}
+	balanceUsd, err = hs.GetUsdcBalanceUsd(*networkSelected, accountId)
+	if err != nil {
+		return 0, 0, err
+	}
+	return allowanceUsd, balanceUsd, nil
+}
+
 /*
 *
 This function takes a number of input parameters from the YES and NO side
 - performs validation
 - determines which side (YES or NO) gets the YES or NO position tokens (the negative USD side gets the NO, the positive side gets the YES)
 - in the event of a partial match, the lower collatoralUsd amount (priceUsd * qty) is used for the collateral
+- charges each side its trading fee (see: FeesService.ComputeMatchFees) on top of the collateral
 - constructs sigObjYes/sigObjNo off-chain. sigObjYes and sigObjNo have key type information embedded in them
 - submits to the buyPositionTokensOnBehalfAtomic(...) function on the Prism smart contract
+- moves the match's settlement from pending to submitted, then to confirmed or failed - failed (transient) or dead_lettered (permanent, see: isTransientSettlementFailure)
 
 * @param marketId - nique market ID for the transaction (UUIDv7 string)
 * @param origQtyYes - quantity of YES position tokens requested by the user when they originally placed the order
@@ -284,11 +341,23 @@ This function takes a number of input parameters from the YES and NO side
 * @param evmNo -
 * @param keyTypeYes -
 * @param keyTypeNo -
+* @param feeUsdYes - trading fee (USD) charged to the sideYes account
+* @param feeUsdNo - trading fee (USD) charged to the sideNo account
+* @param matchId - id of the match being settled (0 => the match was not recorded, so neither is its settlement)
 
 * @return bool - Returns true if the transaction is successful, otherwise false.
 * @return error - Returns an error if the transaction fails or the receipt cannot be retrieved.
 */
-func (hs *HederaService) BuyPositionTokens(sideYes *pb_clob.CreateOrderRequestClob, sideNo *pb_clob.CreateOrderRequestClob) (bool, error) {
+func (hs *HederaService) BuyPositionTokens(sideYes *pb_clob.CreateOrderRequestClob, sideNo *pb_clob.CreateOrderRequestClob, feeUsdYes float64, feeUsdNo float64, matchId int32) (isOK bool, err error) {
+	// a failure before the contract is executed is recorded on the settlement here - from then on, where it happens
+	isExecuted := false
+	isTransientFailure := false
+	defer func() {
+		if err != nil && !isExecuted {
+			hs.markSettlementAsFailed(matchId, "", err.Error(), !isTransientFailure)
+		}
+	}()
+
 	// validate that sideYes.MarketId == sideNo.MarketId and sideYes.MarketId != ""
 	if sideYes.MarketId != sideNo.MarketId || sideYes.MarketId == "" {
 		return false, hs.log.Log(ERROR, "market IDs do not match or invalid: %s vs %s", sideYes.MarketId, sideNo.MarketId)
@@ -313,8 +382,33 @@ func (hs *HederaService) BuyPositionTokens(sideYes *pb_clob.CreateOrderRequestCl
 
 	// sideYes should have the positive priceUsd, sideNo should have the negative priceUsd
 	if sideYes.PriceUsd <= 0 {
-		// flip yes and no sides
+		// flip yes and no sides (and their fees)
 		sideYes, sideNo = sideNo, sideYes
+		feeUsdYes, feeUsdNo = feeUsdNo, feeUsdYes
+	}
+
+	// NO - do not use the current X_SMART_CONTRACT_ID - use the one that is stored in the markets table
+	// contractID, err := hiero.ContractIDFromString(
+	// 	os.Getenv(fmt.Sprintf("%s_SMART_CONTRACT_ID", strings.ToUpper(sideYes.Net))),
+	// )
+	market, err := hs.marketsRepository.GetMarketByIdIncludingSuspended(sideYes.MarketId /* yes or no, doesn't matter*/) // matches made before a suspension still settle
+	if err != nil {
+		isTransientFailure = true // db
+		return false, hs.log.Log(ERROR, "invalid contract ID: %v", err)
+	}
+	contractId, err := hiero.ContractIDFromString(market.SmartContractID)
+	if err != nil {
+		return false, hs.log.Log(ERROR, "invalid contract ID in market record: %v", err)
+	}
+
+	// the payloads and the contract call are those of the smart contract the market was created on
+	contractVersion, err := hs.GetPrismContractVersion(market.Net, market.SmartContractID)
+	if err != nil {
+		isTransientFailure = true // network
+		return false, hs.log.Log(ERROR, "failed to look up the smart contract version of market %s: %v", sideYes.MarketId, err)
+	}
+	if contractVersion == lib.PRISM_CONTRACT_VERSION_LEGACY && (feeUsdYes != 0 || feeUsdNo != 0) {
+		return false, hs.log.Log(ERROR, "market %s is on a legacy smart contract, which does not charge trading fees (feeUsdYes=%f, feeUsdNo=%f)", sideYes.MarketId, feeUsdYes, feeUsdNo)
 	}
 
 	usdcDecimalsStr := os.Getenv("USDC_DECIMALS")
@@ -354,6 +448,16 @@ func (hs *HederaService) BuyPositionTokens(sideYes *pb_clob.CreateOrderRequestCl
 		return false, hs.log.Log(ERROR, "failed to calculate priceUsdAbsScaledNoBig: %v", err)
 	}
 
+	feeScaledYesBig, err := lib.FloatToBigIntScaledDecimals(feeUsdYes, int(usdcDecimals))
+	if err != nil {
+		return false, hs.log.Log(ERROR, "failed to calculate feeScaledYesBig: %v", err)
+	}
+
+	feeScaledNoBig, err := lib.FloatToBigIntScaledDecimals(feeUsdNo, int(usdcDecimals))
+	if err != nil {
+		return false, hs.log.Log(ERROR, "failed to calculate feeScaledNoBig: %v", err)
+	}
+
 	sigYes, err := base64.StdEncoding.DecodeString(sideYes.Sig) // Sig is base64-encoded
 	if err != nil {
 		hs.log.Log(ERROR, "Error decoding sigYes64 from base64: %v", err)
@@ -368,24 +472,28 @@ func (hs *HederaService) BuyPositionTokens(sideYes *pb_clob.CreateOrderRequestCl
 	hs.log.Log(INFO, "sigYes (len=%d): %x", len(sigYes), sigYes)
 	hs.log.Log(INFO, "sigNo (len=%d): %x", len(sigNo), sigNo)
 
-	serializedPayloadYes, err := lib.AssemblePayloadHexForSigning(&pb_api.PredictionIntentRequest{
-		PriceUsd:   sideYes.PriceUsd,
-		Qty:        sideYes.QtyOrig, // N.B. use QtyOrig and not Qty (remaining amount) - digital sig verifies based on original quantity, not current available Qty
-		MarketId:   sideYes.MarketId,
-		EvmAddress: sideYes.EvmAddress,
-		TxId:       sideYes.TxId,
-	}, usdcDecimals)
+	serializedPayloadYes, err := lib.AssemblePayloadHexForSigningForContractVersion(&pb_api.PredictionIntentRequest{
+		PriceUsd:    sideYes.PriceUsd,
+		Qty:         sideYes.QtyOrig, // N.B. use QtyOrig and not Qty (remaining amount) - digital sig verifies based on original quantity, not current available Qty
+		MarketId:    sideYes.MarketId,
+		EvmAddress:  sideYes.EvmAddress,
+		TxId:        sideYes.TxId,
+		TimeInForce: sideYes.TimeInForce,
+		ExpiresAt:   sideYes.ExpiresAt,
+	}, usdcDecimals, contractVersion)
 	if err != nil {
 		return false, hs.log.Log(ERROR, "failed to extract YES payload for signing: %v", err)
 	}
 
-	serializedPayloadNo, err := lib.AssemblePayloadHexForSigning(&pb_api.PredictionIntentRequest{
-		PriceUsd:   sideNo.PriceUsd,
-		Qty:        sideNo.QtyOrig, // N.B. use QtyOrig and not Qty (remaining amount) - digital sig verifies based on original quantity, not current available Qty
-		MarketId:   sideNo.MarketId,
-		EvmAddress: sideNo.EvmAddress,
-		TxId:       sideNo.TxId,
-	}, usdcDecimals)
+	serializedPayloadNo, err := lib.AssemblePayloadHexForSigningForContractVersion(&pb_api.PredictionIntentRequest{
+		PriceUsd:    sideNo.PriceUsd,
+		Qty:         sideNo.QtyOrig, // N.B. use QtyOrig and not Qty (remaining amount) - digital sig verifies based on original quantity, not current available Qty
+		MarketId:    sideNo.MarketId,
+		EvmAddress:  sideNo.EvmAddress,
+		TxId:        sideNo.TxId,
+		TimeInForce: sideNo.TimeInForce,
+		ExpiresAt:   sideNo.ExpiresAt,
+	}, usdcDecimals, contractVersion)
 	if err != nil {
 		return false, hs.log.Log(ERROR, "failed to extract NO payload for signing: %v", err)
 	}
@@ -429,6 +537,24 @@ func (hs *HederaService) BuyPositionTokens(sideYes *pb_clob.CreateOrderRequestCl
 		return false, hs.log.Log(ERROR, "failed to convert txIdUuidNo to bigint: %v", err)
 	}
 
+	// time-in-force and expiry are part of the signed payloads (the smart contract also rejects expired GTD orders)
+	timeInForceYes, err := lib.TimeInForceCode(sideYes.TimeInForce)
+	if err != nil {
+		return false, hs.log.Log(ERROR, "invalid timeInForce on the YES side: %v", err)
+	}
+	timeInForceNo, err := lib.TimeInForceCode(sideNo.TimeInForce)
+	if err != nil {
+		return false, hs.log.Log(ERROR, "invalid timeInForce on the NO side: %v", err)
+	}
+	expiresAtYes, err := lib.ExpiresAtUnix(sideYes.ExpiresAt)
+	if err != nil {
+		return false, hs.log.Log(ERROR, "invalid expiresAt on the YES side: %v", err)
+	}
+	expiresAtNo, err := lib.ExpiresAtUnix(sideNo.ExpiresAt)
+	if err != nil {
+		return false, hs.log.Log(ERROR, "invalid expiresAt on the NO side: %v", err)
+	}
+
 	// sigObjYes and sigObjNo (Hedera format signature objects)
 	sigObjYes, err := lib.BuildSignatureMap(publicKeyYes, sigYes, lib.HederaKeyType(sideYes.KeyType))
 	sigObjNo, err := lib.BuildSignatureMap(publicKeyNo, sigNo, lib.HederaKeyType(sideNo.KeyType))
@@ -448,53 +574,75 @@ func (hs *HederaService) BuyPositionTokens(sideYes *pb_clob.CreateOrderRequestCl
 	params.AddUint256BigInt(qtyScaledNoBig)
 	params.AddUint256BigInt(priceUsdAbsScaledYesBig)
 	params.AddUint256BigInt(priceUsdAbsScaledNoBig)
-	params.AddUint128BigInt(txIdYesBig) // txIdYes
-	params.AddUint128BigInt(txIdNoBig)  // txIdNo
-	params.AddBytes(sigObjYes)          // sigObjYes
-	params.AddBytes(sigObjNo)           // sigObjNo
+	if contractVersion == lib.PRISM_CONTRACT_VERSION_LEGACY {
+		// legacy: buyPositionTokensOnBehalfAtomic(marketId, signerYes, signerNo, qtyScaledYes, qtyScaledNo, priceUsdAbsScaledYes, priceUsdAbsScaledNo, txIdYes, txIdNo, sigObjYes, sigObjNo)
+		params.AddUint128BigInt(txIdYesBig) // txIdYes
+		params.AddUint128BigInt(txIdNoBig)  // txIdNo
+		params.AddBytes(sigObjYes)          // sigObjYes
+		params.AddBytes(sigObjNo)           // sigObjNo
+	} else {
+		params.AddUint256BigInt(feeScaledYesBig) // feeScaledYes
+		params.AddUint256BigInt(feeScaledNoBig)  // feeScaledNo
+		params.AddUint128BigInt(txIdYesBig)      // txIdYes
+		params.AddUint128BigInt(txIdNoBig)       // txIdNo
+		params.AddUint8(timeInForceYes)          // timeInForceYes
+		params.AddUint8(timeInForceNo)           // timeInForceNo
+		params.AddUint64(expiresAtYes)           // expiresAtYes
+		params.AddUint64(expiresAtNo)            // expiresAtNo
+		params.AddBytes(sigObjYes)               // sigObjYes
+		params.AddBytes(sigObjNo)                // sigObjNo
+	}
 
-	hs.log.Log(INFO, "Prepared smart contract parameters for BuyPositionTokens")
+	hs.log.Log(INFO, "Prepared smart contract parameters for BuyPositionTokens (contract version %d)", contractVersion)
 	hs.log.Log(INFO, "marketIdBytes (hex): %s", hex.EncodeToString(marketIdBig.Bytes()))
 	hs.log.Log(INFO, "accountIdYes: %s", sideYes.EvmAddress)
 	hs.log.Log(INFO, "accountIdNo: %s", sideNo.EvmAddress)
 	// hs.log.Log(INFO, "collateralUsdAbsScaledYes: %s", collateralUsdAbsScaledYes.String())
 	// hs.log.Log(INFO, "collateralUsdAbsScaledNo: %s", collateralUsdAbsScaledNo.String())
+	hs.log.Log(INFO, "feeScaledYes: %s, feeScaledNo: %s", feeScaledYesBig.String(), feeScaledNoBig.String())
 	hs.log.Log(INFO, "txIdYesBig (hex): %s", hex.EncodeToString(txIdYesBig.Bytes()))
 	hs.log.Log(INFO, "txIdNoBig (hex): %s", hex.EncodeToString(txIdNoBig.Bytes()))
 	hs.log.Log(INFO, "sigObjYes (len=%d): %x", len(sigObjYes), sigObjYes)
 	hs.log.Log(INFO, "sigObjNo (len=%d): %x", len(sigObjNo), sigObjNo)
-	// NO - do not use the current X_SMART_CONTRACT_ID - use the one that is stored in the markets table
-	// contractID, err := hiero.ContractIDFromString(
-	// 	os.Getenv(fmt.Sprintf("%s_SMART_CONTRACT_ID", strings.ToUpper(sideYes.Net))),
-	// )
-	market, err := hs.marketsRepository.GetMarketById(sideYes.MarketId /* yes or no, doesn't matter*/)
-	if err != nil {
-		return false, hs.log.Log(ERROR, "invalid contract ID: %v", err)
-	}
-	contractId, err := hiero.ContractIDFromString(market.SmartContractID)
+	client := hs.hedera_clients[sideYes.Net] // both sides are guaranteed to be on the same network
+
+	// the Hedera transaction ID is recorded before the transaction is sent: if its outcome is lost (e.g. a receipt timeout),
+	// the settlement is reconciled against it before it is ever retried or abandoned (see: GetSettlementTxOutcome)
+	hederaTxId := hiero.TransactionIDGenerate(client.GetOperatorAccountID())
+	err = hs.markSettlementAsSubmitted(matchId, hederaTxId.String())
 	if err != nil {
-		return false, hs.log.Log(ERROR, "invalid contract ID in market record: %v", err)
+		isTransientFailure = true // db
+		return false, hs.log.Log(ERROR, "not submitting match %d - failed to record its Hedera transaction ID: %v", matchId, err)
 	}
+	isExecuted = true
 
 	tx, err := hiero.NewContractExecuteTransaction().
+		SetTransactionID(hederaTxId).
 		SetContractID(contractId).
 		SetGas(5_000_000). // TODO - can this be lowered? 2M in 4_buy.ts
 		SetFunction("buyPositionTokensOnBehalfAtomic", params).
-		Execute(hs.hedera_clients[sideYes.Net]) // both sides are guaranteed to be on the same network
+		Execute(client)
 	if err != nil {
+		// N.B. the transaction may still have reached a node (e.g. a timeout) - a retry looks its outcome up first
+		hs.markSettlementAsFailed(matchId, "", fmt.Sprintf("failed to execute contract: %v", err), !isTransientSettlementFailure(err))
 		return false, hs.log.Log(ERROR, "failed to execute contract: %v", err)
 	}
 
-	receipt, err := tx.GetReceipt(hs.hedera_clients[sideYes.Net]) // both sides are guaranteed to be on the same network
+	receipt, err := tx.GetReceipt(client)
 	if err != nil {
+		// the receipt is still populated when the transaction reached consensus but did not succeed (e.g. CONTRACT_REVERT_EXECUTED)
+		hs.markSettlementAsFailed(matchId, receipt.Status.String(), fmt.Sprintf("failed to get transaction receipt: %v", err), !isTransientSettlementFailure(err))
 		return false, hs.log.Log(ERROR, "failed to get transaction receipt: %v", err)
 	}
 
 	// the smart contract function returns (nYes, nNo)
-	record, err := tx.GetRecord(hs.hedera_clients[sideYes.Net])
+	record, err := tx.GetRecord(client)
 	if err != nil {
+		// settled on-chain (the receipt succeeded) - never retry it
+		hs.markSettlementAsConfirmed(matchId, receipt.Status.String(), nil)
 		return false, hs.log.Log(ERROR, "failed to get transaction record: %v", err)
 	}
+	hs.markSettlementAsConfirmed(matchId, receipt.Status.String(), &record.CallResult.GasUsed)
 	nYesTokens := new(big.Int).SetBytes(record.CallResult.GetUint256(0))
 	nNoTokens := new(big.Int).SetBytes(record.CallResult.GetUint256(1))
 	nYesTokens2 := new(big.Int).SetBytes(record.CallResult.GetUint256(2))
@@ -528,6 +676,9 @@ func (hs *HederaService) BuyPositionTokens(sideYes *pb_clob.CreateOrderRequestCl
 	if err != nil {
 		return false, hs.log.Log(ERROR, "Error saving price history for market %s: %v", sideYes.MarketId, err)
 	}
+	for _, handler := range hs.priceTickHandlers {
+		go handler(sideYes.MarketId, sideYes.PriceUsd) // e.g. stop/take-profit triggers - don't hold up the settlement
+	}
 	// don't need to save the No side
 	// err = h.dbRepository.SavePriceHistory(sideNo.MarketId, sideNo.PriceUsd)
 	// if err != nil {
@@ -549,7 +700,216 @@ func (hs *HederaService) BuyPositionTokens(sideYes *pb_clob.CreateOrderRequestCl
 	return true, nil
 }
 
-func (hs *HederaService) CreateNewMarket(req *pb_api.CreateMarketRequest) (uint64, error) {
+// markSettlementAsSubmitted is the exception to the bookkeeping below: a transaction whose ID was not recorded must not be sent
+func (hs *HederaService) markSettlementAsSubmitted(matchId int32, hederaTxId string) error {
+	if matchId == 0 || hs.settlementsRepository == nil {
+		return nil
+	}
+	return hs.settlementsRepository.MarkSettlementAsSubmitted(matchId, hederaTxId)
+}
+
+// the settlement state is bookkeeping - failing to record it must not fail (or repeat) an on-chain settlement
+
+func (hs *HederaService) markSettlementAsConfirmed(matchId int32, receiptStatus string, gasUsed *uint64) {
+	if matchId == 0 || hs.settlementsRepository == nil {
+		return
+	}
+	err := hs.settlementsRepository.MarkSettlementAsConfirmed(matchId, receiptStatus, gasUsed)
+	if err != nil {
+		hs.log.Log(ERROR, "Error marking settlement of match %d as confirmed: %v", matchId, err)
+	}
+}
+
+func (hs *HederaService) markSettlementAsFailed(matchId int32, receiptStatus string, reason string, isPermanent bool) {
+	if matchId == 0 || hs.settlementsRepository == nil {
+		return
+	}
+	settlement, err := hs.settlementsRepository.MarkSettlementAsFailed(matchId, receiptStatus, reason, isPermanent)
+	if err != nil {
+		hs.log.Log(ERROR, "Error marking settlement of match %d as failed: %v", matchId, err)
+		return
+	}
+	if settlement.Status == lib.SETTLEMENT_STATUS_DEAD_LETTERED {
+		hs.log.Log(ERROR, "Settlement of match %d dead-lettered after %d attempt(s): %s", matchId, settlement.Attempts, reason)
+	}
+}
+
+// Hedera statuses worth a retry - any other status (e.g. CONTRACT_REVERT_EXECUTED: bad signature, allowance too low) won't change on a retry
+var transientSettlementStatuses = map[hiero.Status]bool{
+	hiero.StatusBusy: true,
+	hiero.StatusPlatformTransactionNotCreated: true,
+	hiero.StatusPlatformNotActive:             true,
+	hiero.StatusTransactionExpired:            true,
+	hiero.StatusUnknown:                       true,
+	hiero.StatusReceiptNotFound:               true,
+	hiero.StatusInsufficientPayerBalance:      true, // the operator account - retried once it's topped up
+}
+
+// isTransientSettlementFailure - an error without a Hedera status (network, timeout, max attempts exceeded) is transient
+func isTransientSettlementFailure(err error) bool {
+	var preCheckErr hiero.ErrHederaPreCheckStatus
+	if errors.As(err, &preCheckErr) {
+		return transientSettlementStatuses[preCheckErr.Status]
+	}
+	var receiptErr hiero.ErrHederaReceiptStatus
+	if errors.As(err, &receiptErr) {
+		return transientSettlementStatuses[receiptErr.Status]
+	}
+	return true
+}
+
+/*
+*
+GetSettlementTxOutcome looks up what became of a settlement transaction whose outcome was lost (e.g. a receipt timeout, a restart):
+its receipt while the network still has it (~3 minutes after consensus), then the mirror node.
+It is NOT_EXECUTED only if it provably did not change any state - it reverted, or it can no longer reach consensus and the mirror node doesn't have it.
+N.B. the smart contract only marks the lower-collateral side's txId as used, so a settlement that succeeded must never be resubmitted.
+*/
+func (hs *HederaService) GetSettlementTxOutcome(net string, hederaTxIdStr string) (outcome string, receiptStatus string, err error) {
+	hederaTxId, err := hiero.TransactionIdFromString(hederaTxIdStr)
+	if err != nil || hederaTxId.AccountID == nil || hederaTxId.ValidStart == nil {
+		return lib.SETTLEMENT_TX_OUTCOME_UNKNOWN, "", hs.log.Log(ERROR, "invalid Hedera transaction ID %s: %v", hederaTxIdStr, err)
+	}
+	client, ok := hs.hedera_clients[net]
+	if !ok {
+		return lib.SETTLEMENT_TX_OUTCOME_UNKNOWN, "", hs.log.Log(ERROR, "invalid net %s", net)
+	}
+
+	// 1. the receipt
+	receipt, err := hiero.NewTransactionReceiptQuery().
+		SetTransactionID(hederaTxId).
+		Execute(client)
+	var receiptErr hiero.ErrHederaReceiptStatus
+	if errors.As(err, &receiptErr) {
+		receipt, err = receiptErr.Receipt, nil
+	}
+	if err == nil && receipt.Status != hiero.StatusUnknown {
+		if receipt.Status == hiero.StatusSuccess {
+			return lib.SETTLEMENT_TX_OUTCOME_SUCCEEDED, receipt.Status.String(), nil
+		}
+		return lib.SETTLEMENT_TX_OUTCOME_NOT_EXECUTED, receipt.Status.String(), nil // reached consensus but reverted (e.g. CONTRACT_REVERT_EXECUTED)
+	}
+	if err != nil {
+		hs.log.Log(INFO, "no receipt for Hedera transaction %s (%v) - looking it up on the mirror node", hederaTxIdStr, err)
+	}
+
+	// 2. the mirror node (format: 0.0.1234-1700000000-123456789)
+	mirrorTxId := fmt.Sprintf("%s-%d-%09d", hederaTxId.AccountID.String(), hederaTxId.ValidStart.Unix(), hederaTxId.ValidStart.Nanosecond())
+	mirrorNodeURL := fmt.Sprintf("https://%s.mirrornode.hedera.com/api/v1/transactions/%s", net, mirrorTxId)
+	resp, err := lib.Fetch(lib.GET, mirrorNodeURL, nil)
+	if err != nil {
+		return lib.SETTLEMENT_TX_OUTCOME_UNKNOWN, "", hs.log.Log(ERROR, "failed to query mirror node: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		// not on the mirror node: it did not execute if it can't reach consensus any more (and the mirror node has caught up)
+		deadline := hederaTxId.ValidStart.Add((lib.SETTLEMENT_TX_VALID_DURATION_SECONDS + lib.SETTLEMENT_TX_MIRROR_LAG_SECONDS) * time.Second)
+		if time.Now().After(deadline) {
+			return lib.SETTLEMENT_TX_OUTCOME_NOT_EXECUTED, "", nil
+		}
+		return lib.SETTLEMENT_TX_OUTCOME_UNKNOWN, "", nil
+	}
+	if resp.StatusCode != 200 {
+		return lib.SETTLEMENT_TX_OUTCOME_UNKNOWN, "", hs.log.Log(ERROR, "network response was not ok: status %d (%s)", resp.StatusCode, mirrorNodeURL)
+	}
+
+	var result struct {
+		Transactions []struct {
+			Result string `json:"result"`
+			Nonce  int    `json:"nonce"`
+		} `json:"transactions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return lib.SETTLEMENT_TX_OUTCOME_UNKNOWN, "", hs.log.Log(ERROR, "failed to parse response: %v", err)
+	}
+
+	// the parent transaction (nonce 0) - child transactions of the contract call have the same ID
+	for _, transaction := range result.Transactions {
+		if transaction.Nonce != 0 {
+			continue
+		}
+		if transaction.Result == hiero.StatusSuccess.String() {
+			return lib.SETTLEMENT_TX_OUTCOME_SUCCEEDED, transaction.Result, nil
+		}
+		return lib.SETTLEMENT_TX_OUTCOME_NOT_EXECUTED, transaction.Result, nil
+	}
+	return lib.SETTLEMENT_TX_OUTCOME_UNKNOWN, "", nil
+}
+
+/*
+*
+ConfirmSettlement records a settlement whose transaction turned out to have succeeded (see: GetSettlementTxOutcome):
+the settlement is confirmed, the match gets its tx hash and both accounts' positions are read back from the smart contract.
+The price is not recorded - it is stale by now.
+*/
+func (hs *HederaService) ConfirmSettlement(sideYes *pb_clob.CreateOrderRequestClob, sideNo *pb_clob.CreateOrderRequestClob, matchId int32, hederaTxId string, receiptStatus string) error {
+	if sideYes.PriceUsd <= 0 {
+		sideYes, sideNo = sideNo, sideYes
+	}
+
+	err := hs.settlementsRepository.MarkSettlementAsConfirmed(matchId, receiptStatus, nil)
+	if err != nil {
+		return hs.log.Log(ERROR, "Error marking settlement of match %d as confirmed: %v", matchId, err)
+	}
+
+	err = hs.matchesRepository.UpdateMatchTxHash(sideYes.MarketId, sideYes.TxId, sideNo.TxId, hederaTxId)
+	if err != nil {
+		return hs.log.Log(ERROR, "Error logging a successful tx to matches table: %v", err)
+	}
+
+	market, err := hs.marketsRepository.GetMarketByIdIncludingSuspended(sideYes.MarketId)
+	if err != nil {
+		return hs.log.Log(ERROR, "failed to get market %s: %v", sideYes.MarketId, err)
+	}
+	for _, side := range []*pb_clob.CreateOrderRequestClob{sideYes, sideNo} {
+		nYesTokens, nNoTokens, err := hs.getUserTokens(market, side.EvmAddress)
+		if err != nil {
+			return err
+		}
+		_, err = hs.dbRepository.UpsertUserPositions(side.EvmAddress, side.MarketId, nYesTokens.Int64(), nNoTokens.Int64())
+		if err != nil {
+			return hs.log.Log(ERROR, "Error upserting user position tokens for %s on market %s: %v", side.EvmAddress, side.MarketId, err)
+		}
+	}
+
+	hs.log.Log(INFO, "Confirmed settlement of match %d from its Hedera transaction %s (status: %s)", matchId, hederaTxId, receiptStatus)
+	return nil
+}
+
+// getUserTokens reads the account's YES and NO position tokens in the market from the smart contract
+func (hs *HederaService) getUserTokens(market *sqlc.Market, evmAddress string) (*big.Int, *big.Int, error) {
+	marketIdBig, err := lib.Uuid7_to_bigint(market.MarketID.String())
+	if err != nil {
+		return nil, nil, hs.log.Log(ERROR, "failed to convert marketId to bigint: %v", err)
+	}
+	params := hiero.NewContractFunctionParameters()
+	params.AddUint128BigInt(marketIdBig) // marketId
+	params.AddAddress(evmAddress)        // user
+
+	contractID, err := hiero.ContractIDFromString(market.SmartContractID)
+	if err != nil {
+		return nil, nil, hs.log.Log(ERROR, "invalid contract ID in market record: %v", err)
+	}
+
+	result, err := hiero.NewContractCallQuery().
+		SetContractID(contractID).
+		SetGas(100_000).
+		SetFunction("getUserTokens", params).
+		Execute(hs.hedera_clients[market.Net])
+	if err != nil {
+		return nil, nil, hs.log.Log(ERROR, "failed to query getUserTokens(%s, %s) on %s: %v", market.MarketID.String(), evmAddress, contractID, err)
+	}
+
+	return new(big.Int).SetBytes(result.GetUint256(0)), new(big.Int).SetBytes(result.GetUint256(1)), nil
+}
+
+// OnPriceTick registers a handler for every new traded price - only call it while the services are being initialized in main()
+func (hs *HederaService) OnPriceTick(handler func(marketId string, priceUsd float64)) {
+	hs.priceTickHandlers = append(hs.priceTickHandlers, handler)
+}
+
+func (hs *HederaService) CreateNewMarket(req *pb_api.CreateMarketRequest, smartContractId string) (uint64, error) {
 	// call the smart contract function createNewMarket(uint128 marketId, string memory _statement)
 	marketIdBig, err := lib.Uuid7_to_bigint(req.MarketId)
 	if err != nil {
@@ -559,10 +919,8 @@ func (hs *HederaService) CreateNewMarket(req *pb_api.CreateMarketRequest) (uint6
 	params.AddUint128BigInt(marketIdBig) // marketId
 	params.AddString(req.Statement)      // statement
 
-	// YES, use the X_SMART_CONTRACT_ID that's loaded in the env var (new market creation always uses the current one)
-	contractID, err := hiero.ContractIDFromString(
-		os.Getenv(fmt.Sprintf("%s_SMART_CONTRACT_ID", strings.ToUpper(req.Net))),
-	)
+	// the caller pins the X_SMART_CONTRACT_ID that was current when the market creation was first requested
+	contractID, err := hiero.ContractIDFromString(smartContractId)
 	if err != nil {
 		return 0, hs.log.Log(ERROR, "invalid smart contract ID: %v", err)
 	}
@@ -595,3 +953,219 @@ func (hs *HederaService) CreateNewMarket(req *pb_api.CreateMarketRequest) (uint6
 
 	return remainingAllowance.Uint64(), nil
 }
+
+// MarketExistsOnChain reads the public statements(marketId) mapping - a non-empty statement means createNewMarket already went through
+func (hs *HederaService) MarketExistsOnChain(net string, smartContractId string, marketId string) (bool, error) {
+	marketIdBig, err := lib.Uuid7_to_bigint(marketId)
+	if err != nil {
+		return false, hs.log.Log(ERROR, "failed to convert marketId to bigint: %v", err)
+	}
+	params := hiero.NewContractFunctionParameters()
+	params.AddUint128BigInt(marketIdBig) // marketId
+
+	contractID, err := hiero.ContractIDFromString(smartContractId)
+	if err != nil {
+		return false, hs.log.Log(ERROR, "invalid smart contract ID: %v", err)
+	}
+
+	result, err := hiero.NewContractCallQuery().
+		SetContractID(contractID).
+		SetGas(50_000).
+		SetFunction("statements", params).
+		Execute(hs.hedera_clients[net])
+	if err != nil {
+		return false, hs.log.Log(ERROR, "failed to query statements(%s) on %s: %v", marketId, contractID, err)
+	}
+
+	return result.GetString(0) != "", nil
+}
+
+// GetMaxTradingFeeBps reads the public maxTradingFeeBps of a Prism smart contract - the highest fee it charges each side of a match
+func (hs *HederaService) GetMaxTradingFeeBps(net string, smartContractId string) (uint64, error) {
+	contractID, err := hiero.ContractIDFromString(smartContractId)
+	if err != nil {
+		return 0, hs.log.Log(ERROR, "invalid smart contract ID: %v", err)
+	}
+
+	result, err := hiero.NewContractCallQuery().
+		SetContractID(contractID).
+		SetGas(50_000).
+		SetFunction("maxTradingFeeBps", hiero.NewContractFunctionParameters()).
+		Execute(hs.hedera_clients[net])
+	if err != nil {
+		return 0, hs.log.Log(ERROR, "failed to query maxTradingFeeBps on %s: %v", contractID, err)
+	}
+
+	return new(big.Int).SetBytes(result.GetUint256(0)).Uint64(), nil
+}
+
+/*
+*
+GetPrismContractVersion tells which version of the Prism smart contract a market was created on (see: lib.PrismContractVersion).
+Markets keep the smart contract they were created on, so their orders must be signed and settled the way that contract expects.
+Only the current contract has the public maxTradingFeeBps: calling it on a legacy contract reverts.
+*/
+func (hs *HederaService) GetPrismContractVersion(net string, smartContractId string) (lib.PrismContractVersion, error) {
+	hs.contractVersionsMu.Lock()
+	contractVersion, ok := hs.contractVersions[smartContractId]
+	hs.contractVersionsMu.Unlock()
+	if ok {
+		return contractVersion, nil
+	}
+
+	contractID, err := hiero.ContractIDFromString(smartContractId)
+	if err != nil {
+		return lib.PRISM_CONTRACT_VERSION_UNKNOWN, hs.log.Log(ERROR, "invalid smart contract ID: %v", err)
+	}
+	client, ok := hs.hedera_clients[net]
+	if !ok {
+		return lib.PRISM_CONTRACT_VERSION_UNKNOWN, hs.log.Log(ERROR, "invalid net %s", net)
+	}
+
+	contractVersion = lib.PRISM_CONTRACT_VERSION_CURRENT
+	_, err = hiero.NewContractCallQuery().
+		SetContractID(contractID).
+		SetGas(50_000).
+		SetFunction("maxTradingFeeBps", hiero.NewContractFunctionParameters()).
+		Execute(client)
+	if err != nil {
+		var preCheckErr hiero.ErrHederaPreCheckStatus
+		if !errors.As(err, &preCheckErr) || preCheckErr.Status != hiero.StatusContractRevertExecuted {
+			return lib.PRISM_CONTRACT_VERSION_UNKNOWN, hs.log.Log(ERROR, "failed to look up the version of smart contract %s: %v", contractID, err)
+		}
+		contractVersion = lib.PRISM_CONTRACT_VERSION_LEGACY
+	}
+
+	hs.contractVersionsMu.Lock()
+	hs.contractVersions[smartContractId] = contractVersion
+	hs.contractVersionsMu.Unlock()
+
+	hs.log.Log(INFO, "smart contract %s (%s) is version %d", smartContractId, net, contractVersion)
+	return contractVersion, nil
+}
+
+// ResolveMarket calls resolveMarket(uint128 marketId, bool noYes).
+// Skips the transaction if the public resolutionTimes(marketId) mapping says it already went through (with the same outcome).
+func (hs *HederaService) ResolveMarket(market *sqlc.Market, outcome bool) error {
+	// call the smart contract function resolveMarket(uint128 marketId, bool noYes)
+	marketIdBig, err := lib.Uuid7_to_bigint(market.MarketID.String())
+	if err != nil {
+		return hs.log.Log(ERROR, "failed to convert marketId to bigint: %v", err)
+	}
+	marketIdParams := hiero.NewContractFunctionParameters()
+	marketIdParams.AddUint128BigInt(marketIdBig) // marketId
+
+	params := hiero.NewContractFunctionParameters()
+	params.AddUint128BigInt(marketIdBig) // marketId
+	params.AddBool(outcome)              // noYes (true => YES wins, false => NO wins)
+
+	// NO - do not use the current X_SMART_CONTRACT_ID - use the one that is stored in the markets table
+	contractID, err := hiero.ContractIDFromString(market.SmartContractID)
+	if err != nil {
+		return hs.log.Log(ERROR, "invalid contract ID in market record: %v", err)
+	}
+
+	// a previous attempt may have resolved it on-chain and failed afterwards (e.g. on the db) - resolveMarket would revert with "Already resolved"
+	resolutionTime, err := hiero.NewContractCallQuery().
+		SetContractID(contractID).
+		SetGas(50_000).
+		SetFunction("resolutionTimes", marketIdParams).
+		Execute(hs.hedera_clients[market.Net])
+	if err != nil {
+		return hs.log.Log(ERROR, "failed to query resolutionTimes(%s) on %s: %v", market.MarketID.String(), contractID, err)
+	}
+	if new(big.Int).SetBytes(resolutionTime.GetUint256(0)).Sign() > 0 {
+		// voidMarket sets resolutionTimes too
+		voided, err := hiero.NewContractCallQuery().
+			SetContractID(contractID).
+			SetGas(50_000).
+			SetFunction("voided", marketIdParams).
+			Execute(hs.hedera_clients[market.Net])
+		if err != nil {
+			return hs.log.Log(ERROR, "failed to query voided(%s) on %s: %v", market.MarketID.String(), contractID, err)
+		}
+		if voided.GetBool(0) {
+			return hs.log.Log(ERROR, "market %s is voided on Prism smart contract (%s) and cannot be resolved", market.MarketID.String(), contractID)
+		}
+
+		resolvedOutcome, err := hiero.NewContractCallQuery().
+			SetContractID(contractID).
+			SetGas(50_000).
+			SetFunction("outcomes", marketIdParams).
+			Execute(hs.hedera_clients[market.Net])
+		if err != nil {
+			return hs.log.Log(ERROR, "failed to query outcomes(%s) on %s: %v", market.MarketID.String(), contractID, err)
+		}
+		if resolvedOutcome.GetBool(0) != outcome {
+			return hs.log.Log(ERROR, "market %s is already resolved on Prism smart contract (%s) with outcome=%t, not %t", market.MarketID.String(), contractID, resolvedOutcome.GetBool(0), outcome)
+		}
+		hs.log.Log(INFO, "Market %s is already resolved on Prism smart contract (%s), outcome=%t", market.MarketID.String(), contractID, outcome)
+		return nil
+	}
+
+	hs.log.Log(INFO, "Resolving market %s on Prism smart contract (%s), outcome=%t", market.MarketID.String(), contractID, outcome)
+	result, err := hiero.NewContractExecuteTransaction().
+		SetContractID(contractID).
+		SetGas(100_000). // 100k in 7_resolveMarket.ts
+		SetFunction("resolveMarket", params).
+		Execute(hs.hedera_clients[market.Net])
+	if err != nil {
+		return hs.log.Log(ERROR, "failed to execute contract: %v", err)
+	}
+
+	receipt, err := result.GetReceipt(hs.hedera_clients[market.Net])
+	if err != nil {
+		return hs.log.Log(ERROR, "ResolveMarket - tx failed (could not get transaction receipt). Hedera txId = %s. %v", result.TransactionID.String(), err)
+	}
+
+	hs.log.Log(INFO, "ResolveMarket - tx successful (status: %s). Hedera txId = %s", receipt.Status.String(), result.TransactionID.String())
+	return nil
+}
+
+// VoidMarket calls voidMarket(uint128 marketId) - afterwards redeem() pays 50% of the collateral for every YES and NO token.
+// Skips the transaction if the public voided(marketId) mapping says it already went through.
+func (hs *HederaService) VoidMarket(market *sqlc.Market) error {
+	marketIdBig, err := lib.Uuid7_to_bigint(market.MarketID.String())
+	if err != nil {
+		return hs.log.Log(ERROR, "failed to convert marketId to bigint: %v", err)
+	}
+	params := hiero.NewContractFunctionParameters()
+	params.AddUint128BigInt(marketIdBig) // marketId
+
+	// NO - do not use the current X_SMART_CONTRACT_ID - use the one that is stored in the markets table
+	contractID, err := hiero.ContractIDFromString(market.SmartContractID)
+	if err != nil {
+		return hs.log.Log(ERROR, "invalid contract ID in market record: %v", err)
+	}
+
+	voided, err := hiero.NewContractCallQuery().
+		SetContractID(contractID).
+		SetGas(50_000).
+		SetFunction("voided", params).
+		Execute(hs.hedera_clients[market.Net])
+	if err != nil {
+		return hs.log.Log(ERROR, "failed to query voided(%s) on %s: %v", market.MarketID.String(), contractID, err)
+	}
+	if voided.GetBool(0) {
+		hs.log.Log(INFO, "Market %s is already voided on Prism smart contract (%s)", market.MarketID.String(), contractID)
+		return nil
+	}
+
+	hs.log.Log(INFO, "Voiding market %s on Prism smart contract (%s)", market.MarketID.String(), contractID)
+	result, err := hiero.NewContractExecuteTransaction().
+		SetContractID(contractID).
+		SetGas(100_000). // 100k in 8_voidMarket.ts
+		SetFunction("voidMarket", params).
+		Execute(hs.hedera_clients[market.Net])
+	if err != nil {
+		return hs.log.Log(ERROR, "failed to execute contract: %v", err)
+	}
+
+	receipt, err := result.GetReceipt(hs.hedera_clients[market.Net])
+	if err != nil {
+		return hs.log.Log(ERROR, "VoidMarket - tx failed (could not get transaction receipt). Hedera txId = %s. %v", result.TransactionID.String(), err)
+	}
+
+	hs.log.Log(INFO, "VoidMarket - tx successful (status: %s). Hedera txId = %s", receipt.Status.String(), result.TransactionID.String())
+	return nil
+}
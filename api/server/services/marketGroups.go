@@ -0,0 +1,174 @@
+package services
+
+import (
+	pb_api "api/gen"
+	sqlc "api/gen/sqlc"
+	repositories "api/server/repositories"
+	"strings"
+)
+
+type MarketGroupsService struct {
+	log                    *LogService
+	marketGroupsRepository *repositories.MarketGroupsRepository
+	marketsService         *MarketsService
+}
+
+func (mgs *MarketGroupsService) Init(log *LogService, marketGroupsRepository *repositories.MarketGroupsRepository, marketsService *MarketsService) error {
+	mgs.log = log
+	mgs.marketGroupsRepository = marketGroupsRepository
+	mgs.marketsService = marketsService
+
+	mgs.log.Log(INFO, "Service: MarketGroups service initialized successfully")
+	return nil
+}
+
+// CreateMarketGroup creates the group and one binary market per leg through MarketsService.CreateMarket.
+// Safe to retry with the same request: existing legs are skipped by the market creation saga.
+func (mgs *MarketGroupsService) CreateMarketGroup(req *pb_api.CreateMarketGroupRequest) (*pb_api.MarketGroupResponse, error) {
+	// guards
+	seen := make(map[string]bool)
+	for _, leg := range req.Legs {
+		marketId := strings.ToLower(leg.MarketId)
+		if seen[marketId] {
+			return nil, mgs.log.Log(ERROR, "duplicate leg marketId %s in market group %s", leg.MarketId, req.GroupId)
+		}
+		seen[marketId] = true
+	}
+
+	marketGroup, err := mgs.marketGroupsRepository.CreateMarketGroup(req)
+	if err != nil {
+		return nil, mgs.log.Log(ERROR, "failed to create market group (groupId=%s): %v", req.GroupId, err)
+	}
+	if marketGroup.Net != strings.ToLower(req.Net) || marketGroup.Statement != strings.TrimSpace(req.Statement) {
+		return nil, mgs.log.Log(ERROR, "groupId %s is already used by a different market group", req.GroupId)
+	}
+
+	// OK - create every leg (each one goes through contract => CLOB => db)
+	for i, leg := range req.Legs {
+		_, err := mgs.marketsService.CreateMarket(&pb_api.CreateMarketRequest{
+			MarketId:    leg.MarketId,
+			Net:         req.Net,
+			Statement:   leg.Statement,
+			ImageUrl:    req.ImageUrl,
+			ClosesAt:    req.ClosesAt,
+			Description: req.Description,
+			CategoryIds: req.CategoryIds,
+			GroupId:     &req.GroupId,
+		})
+		if err != nil {
+			return nil, mgs.log.Log(ERROR, "failed to create leg %d/%d (marketId=%s) of market group %s (retry to resume): %v", i+1, len(req.Legs), leg.MarketId, req.GroupId, err)
+		}
+	}
+
+	return mgs.GetMarketGroup(req.GroupId)
+}
+
+func (mgs *MarketGroupsService) GetMarketGroup(groupId string) (*pb_api.MarketGroupResponse, error) {
+	marketGroup, err := mgs.marketGroupsRepository.GetMarketGroup(groupId)
+	if err != nil {
+		return nil, mgs.log.Log(ERROR, "failed to get market group: %v", err)
+	}
+
+	legs, err := mgs.marketGroupsRepository.GetMarketsByGroupId(marketGroup.GroupID)
+	if err != nil {
+		return nil, mgs.log.Log(ERROR, "failed to get legs of market group %s: %v", groupId, err)
+	}
+
+	return mgs.mapMarketGroupToResponse(marketGroup, legs)
+}
+
+// ResolveMarketGroup resolves the winning leg YES and every other leg NO.
+// The winner is recorded on the group first, so a retry after a partial failure can only finish the same resolution.
+func (mgs *MarketGroupsService) ResolveMarketGroup(req *pb_api.ResolveMarketGroupRequest) (*pb_api.MarketGroupResponse, error) {
+	// guards
+	marketGroup, err := mgs.marketGroupsRepository.GetMarketGroup(req.GroupId)
+	if err != nil {
+		return nil, mgs.log.Log(ERROR, "failed to get market group: %v", err)
+	}
+
+	legs, err := mgs.marketGroupsRepository.GetMarketsByGroupId(marketGroup.GroupID)
+	if err != nil {
+		return nil, mgs.log.Log(ERROR, "failed to get legs of market group %s: %v", req.GroupId, err)
+	}
+
+	winnerFound := false
+	for _, leg := range legs {
+		isWinner := strings.EqualFold(leg.MarketID.String(), req.WinningMarketId)
+		if isWinner {
+			winnerFound = true
+		}
+		// a leg resolved by hand must agree with this resolution
+		if leg.Outcome.Valid && leg.Outcome.Bool != isWinner {
+			return nil, mgs.log.Log(ERROR, "leg %s of market group %s is already resolved with outcome=%t", leg.MarketID, req.GroupId, leg.Outcome.Bool)
+		}
+		// a paused leg still has held settlements (see: MarketsService.resolveMarket) - don't lock in a winner that can't be resolved
+		if leg.IsPaused && !leg.ResolvedAt.Valid {
+			return nil, mgs.log.Log(ERROR, "leg %s of market group %s is paused - resume or suspend it first", leg.MarketID, req.GroupId)
+		}
+	}
+	if !winnerFound {
+		return nil, mgs.log.Log(ERROR, "market %s is not a leg of market group %s", req.WinningMarketId, req.GroupId)
+	}
+
+	/////
+	// OK - 2 steps to resolve a market group
+	/////
+
+	// Step 1:
+	// lock in the winner on the **db**
+	marketGroup, err = mgs.marketGroupsRepository.SetMarketGroupWinner(marketGroup.GroupID, req.WinningMarketId)
+	if err != nil {
+		return nil, mgs.log.Log(ERROR, "failed to set the winner of market group %s: %v", req.GroupId, err)
+	}
+
+	// Step 2:
+	// resolve every leg (**smart contract**, **db** and **CLOB**) - keep going on failure so one bad leg doesn't block the others
+	var failed []string
+	for i := range legs {
+		leg := &legs[i]
+		isWinner := strings.EqualFold(leg.MarketID.String(), req.WinningMarketId)
+
+		resolvedLeg, err := mgs.marketsService.resolveMarket(leg, isWinner)
+		if err != nil {
+			failed = append(failed, leg.MarketID.String())
+			continue
+		}
+		legs[i] = *resolvedLeg
+	}
+	if len(failed) > 0 {
+		return nil, mgs.log.Log(ERROR, "failed to resolve %d/%d legs of market group %s (retry to resume): %v", len(failed), len(legs), req.GroupId, failed)
+	}
+
+	return mgs.mapMarketGroupToResponse(marketGroup, legs)
+}
+
+func (mgs *MarketGroupsService) mapMarketGroupToResponse(marketGroup *sqlc.MarketGroup, legs []sqlc.Market) (*pb_api.MarketGroupResponse, error) {
+	var legResponses []*pb_api.MarketResponse
+	for i := range legs {
+		legResponse, err := mgs.marketsService.mapMarketToMarketResponse(&legs[i])
+		if err != nil {
+			return nil, mgs.log.Log(ERROR, "failed to map market to market response: %v", err)
+		}
+		legResponses = append(legResponses, legResponse)
+	}
+
+	response := &pb_api.MarketGroupResponse{
+		GroupId:     marketGroup.GroupID.String(),
+		Net:         marketGroup.Net,
+		Statement:   marketGroup.Statement,
+		Description: marketGroup.Description.String,
+		ImageUrl:    marketGroup.ImageUrl.String,
+		CreatedAt:   marketGroup.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		Legs:        legResponses,
+	}
+	if marketGroup.ClosesAt.Valid {
+		response.ClosesAt = marketGroup.ClosesAt.Time.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	if marketGroup.ResolvedAt.Valid {
+		response.ResolvedAt = marketGroup.ResolvedAt.Time.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	if marketGroup.WinningMarketID.Valid {
+		response.WinningMarketId = marketGroup.WinningMarketID.UUID.String()
+	}
+	return response, nil
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	pb_api "api/gen"
+	sqlc "api/gen/sqlc"
+	"api/server/lib"
+	repositories "api/server/repositories"
+	"strings"
+
+	hiero "github.com/hiero-ledger/hiero-sdk-go/v2/sdk"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type MarketProposalsService struct {
+	log                       *LogService
+	marketProposalsRepository *repositories.MarketProposalsRepository
+	marketsRepository         *repositories.MarketsRepository
+	marketsService            *MarketsService
+	hederaService             *HederaService
+}
+
+func (mps *MarketProposalsService) Init(log *LogService, marketProposalsRepository *repositories.MarketProposalsRepository, marketsRepository *repositories.MarketsRepository, marketsService *MarketsService, hederaService *HederaService) error {
+	mps.log = log
+	mps.marketProposalsRepository = marketProposalsRepository
+	mps.marketsRepository = marketsRepository
+	mps.marketsService = marketsService
+	mps.hederaService = hederaService
+
+	mps.log.Log(INFO, "Service: MarketProposals service initialized successfully")
+	return nil
+}
+
+// ProposeMarket queues a signed market proposal for moderation - nothing goes on-chain and no fee is charged until an admin approves it
+func (mps *MarketProposalsService) ProposeMarket(req *pb_api.ProposeMarketRequest) (*pb_api.MarketProposal, error) {
+	// guards
+	net := strings.ToLower(req.Market.Net)
+	if !lib.IsValidNetwork(net) {
+		return nil, mps.log.Log(ERROR, "invalid network: %s", req.Market.Net)
+	}
+
+	if !lib.IsValidKeyType(req.KeyType) {
+		return nil, mps.log.Log(ERROR, "unsupported key type: %d", req.KeyType)
+	}
+
+	accountId, err := hiero.AccountIDFromString(req.AccountId)
+	if err != nil {
+		return nil, mps.log.Log(ERROR, "invalid account ID: %v", err)
+	}
+
+	// the proposed marketId must be new
+	if _, err := mps.marketsRepository.GetMarketById(req.Market.MarketId); err == nil {
+		return nil, mps.log.Log(ERROR, "market %s already exists", req.Market.MarketId)
+	}
+
+	// public key sent from the front-end must match the public key looked up on the mirror node
+	publicKeyLookedUp, _, err := mps.hederaService.GetPublicKey(accountId, net)
+	if err != nil {
+		return nil, mps.log.Log(ERROR, "failed to get public key: %v", err)
+	}
+	publicKey, err := hiero.PublicKeyFromString(req.PublicKey)
+	if err != nil {
+		return nil, mps.log.Log(ERROR, "failed to parse public key from string: %v", err)
+	}
+	if publicKeyLookedUp.String() != publicKey.String() || publicKey.String() == "" {
+		return nil, mps.log.Log(ERROR, "public key mismatch: expected %s, got %s", publicKeyLookedUp.String(), publicKey.String())
+	}
+
+	// now verify signature
+	payloadHex, err := lib.AssembleMarketProposalPayloadHexForSigning(req.Market)
+	if err != nil {
+		return nil, mps.log.Log(ERROR, "failed to assemble the market proposal payload for signing: %v", err)
+	}
+	isValidSig, err := lib.VerifySig(&publicKey, payloadHex, req.Sig)
+	if err != nil {
+		return nil, mps.log.Log(ERROR, "failed to verify signature: %v", err)
+	}
+	if !isValidSig {
+		return nil, mps.log.Log(ERROR, "invalid signature for account %s", req.AccountId)
+	}
+
+	// OK
+	marketProposal, err := mps.marketProposalsRepository.CreateMarketProposal(req)
+	if err != nil {
+		return nil, mps.log.Log(ERROR, "failed to create market proposal (marketId=%s): %v", req.Market.MarketId, err)
+	}
+
+	return mps.mapMarketProposalToResponse(marketProposal)
+}
+
+func (mps *MarketProposalsService) GetMarketProposals(req *pb_api.GetMarketProposalsRequest) (*pb_api.MarketProposalsResponse, error) {
+	marketProposals, err := mps.marketProposalsRepository.GetMarketProposals(req.Status, req.Limit, req.Offset)
+	if err != nil {
+		return nil, mps.log.Log(ERROR, "failed to get market proposals: %v", err)
+	}
+
+	var proposals []*pb_api.MarketProposal
+	for _, marketProposal := range marketProposals {
+		proposal, err := mps.mapMarketProposalToResponse(&marketProposal)
+		if err != nil {
+			return nil, err
+		}
+		proposals = append(proposals, proposal)
+	}
+
+	return &pb_api.MarketProposalsResponse{
+		Proposals: proposals,
+	}, nil
+}
+
+// ApproveMarketProposal runs the regular CreateMarket path (contract => CLOB => db) on the proposed request.
+// The operator account pays the market creation fee at this point.
+// Safe to re-run: CreateMarket picks up where a failed attempt stopped.
+func (mps *MarketProposalsService) ApproveMarketProposal(marketId string) (*pb_api.CreateMarketResponse, error) {
+	// guards
+	marketProposal, err := mps.marketProposalsRepository.GetMarketProposal(marketId)
+	if err != nil {
+		return nil, mps.log.Log(ERROR, "failed to get market proposal: %v", err)
+	}
+	if marketProposal.Status != "pending" {
+		return nil, mps.log.Log(ERROR, "market proposal (marketId=%s) is already %s", marketId, marketProposal.Status)
+	}
+
+	createMarketRequest := &pb_api.CreateMarketRequest{}
+	err = protojson.Unmarshal(marketProposal.Request, createMarketRequest)
+	if err != nil {
+		return nil, mps.log.Log(ERROR, "failed to unmarshal proposed market (marketId=%s): %v", marketId, err)
+	}
+
+	/////
+	// OK - 2 steps to approve a proposal
+	/////
+
+	// Step 1:
+	// create the market (**smart contract**, **CLOB** and **db**)
+	response, err := mps.marketsService.CreateMarket(createMarketRequest)
+	if err != nil {
+		return nil, mps.log.Log(ERROR, "failed to create proposed market (marketId=%s): %v", marketId, err)
+	}
+
+	// Step 2:
+	// mark the proposal as approved (fee charged) on the **db**
+	_, err = mps.marketProposalsRepository.ApproveMarketProposal(marketId)
+	if err != nil {
+		return nil, mps.log.Log(ERROR, "market (marketId=%s) is live but failed to mark its proposal as approved: %v", marketId, err)
+	}
+
+	return response, nil
+}
+
+// RejectMarketProposal records the reason - the proposal never reached the smart contract, so no fee was charged
+func (mps *MarketProposalsService) RejectMarketProposal(req *pb_api.RejectMarketProposalRequest) (*pb_api.MarketProposal, error) {
+	marketProposal, err := mps.marketProposalsRepository.RejectMarketProposal(req.MarketId, strings.TrimSpace(req.Reason))
+	if err != nil {
+		return nil, mps.log.Log(ERROR, "failed to reject market proposal (marketId=%s): %v", req.MarketId, err)
+	}
+
+	return mps.mapMarketProposalToResponse(marketProposal)
+}
+
+func (mps *MarketProposalsService) mapMarketProposalToResponse(marketProposal *sqlc.MarketProposal) (*pb_api.MarketProposal, error) {
+	market := &pb_api.CreateMarketRequest{}
+	err := protojson.Unmarshal(marketProposal.Request, market)
+	if err != nil {
+		return nil, mps.log.Log(ERROR, "failed to unmarshal proposed market (marketId=%s): %v", marketProposal.MarketID, err)
+	}
+
+	reviewedAt := ""
+	if marketProposal.ReviewedAt.Valid {
+		reviewedAt = marketProposal.ReviewedAt.Time.UTC().Format("2006-01-02T15:04:05Z")
+	}
+
+	return &pb_api.MarketProposal{
+		MarketId:     marketProposal.MarketID.String(),
+		Status:       marketProposal.Status,
+		AccountId:    marketProposal.AccountID,
+		Market:       market,
+		RejectReason: marketProposal.RejectReason.String,
+		FeeCharged:   marketProposal.FeeCharged,
+		CreatedAt:    marketProposal.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		ReviewedAt:   reviewedAt,
+	}, nil
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	pb_api "api/gen"
+	sqlc "api/gen/sqlc"
+	repositories "api/server/repositories"
+	"strings"
+	"text/template"
+	"time"
+
+	cron "github.com/robfig/cron/v3"
+)
+
+type MarketTemplatesService struct {
+	log                       *LogService
+	marketTemplatesRepository *repositories.MarketTemplatesRepository
+	marketsService            *MarketsService
+}
+
+// marketTemplateData is what a statement pattern can refer to, e.g. {{.ClosesAt.Format "Mon Jan 2"}}
+type marketTemplateData struct {
+	ScheduledAt time.Time
+	ClosesAt    time.Time
+}
+
+func (mts *MarketTemplatesService) Init(log *LogService, marketTemplatesRepository *repositories.MarketTemplatesRepository, marketsService *MarketsService) error {
+	mts.log = log
+	mts.marketTemplatesRepository = marketTemplatesRepository
+	mts.marketsService = marketsService
+
+	mts.log.Log(INFO, "Service: MarketTemplates service initialized successfully")
+	return nil
+}
+
+func (mts *MarketTemplatesService) CreateMarketTemplate(req *pb_api.MarketTemplateRequest) (*pb_api.MarketTemplate, error) {
+	// guards
+	err := mts.validateMarketTemplate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// OK
+	marketTemplate, err := mts.marketTemplatesRepository.CreateMarketTemplate(req)
+	if err != nil {
+		return nil, mts.log.Log(ERROR, "failed to create market template (templateId=%s): %v", req.TemplateId, err)
+	}
+
+	return mts.mapMarketTemplateToResponse(marketTemplate)
+}
+
+// UpdateMarketTemplate replaces every field - markets already created from the template are not touched
+func (mts *MarketTemplatesService) UpdateMarketTemplate(req *pb_api.MarketTemplateRequest) (*pb_api.MarketTemplate, error) {
+	// guards
+	err := mts.validateMarketTemplate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// OK
+	marketTemplate, err := mts.marketTemplatesRepository.UpdateMarketTemplate(req)
+	if err != nil {
+		return nil, mts.log.Log(ERROR, "failed to update market template (templateId=%s): %v", req.TemplateId, err)
+	}
+
+	return mts.mapMarketTemplateToResponse(marketTemplate)
+}
+
+func (mts *MarketTemplatesService) GetMarketTemplates() (*pb_api.MarketTemplatesResponse, error) {
+	marketTemplates, err := mts.marketTemplatesRepository.GetMarketTemplates()
+	if err != nil {
+		return nil, mts.log.Log(ERROR, "failed to get market templates: %v", err)
+	}
+
+	var templates []*pb_api.MarketTemplate
+	for i := range marketTemplates {
+		marketTemplateResponse, err := mts.mapMarketTemplateToResponse(&marketTemplates[i])
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, marketTemplateResponse)
+	}
+
+	return &pb_api.MarketTemplatesResponse{
+		Templates: templates,
+	}, nil
+}
+
+// PreviewMarketTemplate lists the next N markets the template will create, starting now (or from the last run if it is later)
+func (mts *MarketTemplatesService) PreviewMarketTemplate(req *pb_api.PreviewMarketTemplateRequest) (*pb_api.PreviewMarketTemplateResponse, error) {
+	marketTemplate, err := mts.marketTemplatesRepository.GetMarketTemplate(req.TemplateId)
+	if err != nil {
+		return nil, mts.log.Log(ERROR, "failed to get market template: %v", err)
+	}
+
+	schedule, err := cron.ParseStandard(marketTemplate.Schedule)
+	if err != nil {
+		return nil, mts.log.Log(ERROR, "invalid schedule %q on market template %s: %v", marketTemplate.Schedule, req.TemplateId, err)
+	}
+
+	from := time.Now().UTC()
+	if marketTemplate.LastRunAt.Valid && marketTemplate.LastRunAt.Time.After(from) {
+		from = marketTemplate.LastRunAt.Time.UTC()
+	}
+
+	var markets []*pb_api.MarketTemplatePreview
+	scheduledAt := from
+	for i := int32(0); i < req.N; i++ {
+		scheduledAt = schedule.Next(scheduledAt)
+		if scheduledAt.IsZero() { // the schedule never fires again
+			break
+		}
+
+		statement, closesAt, err := renderMarketTemplate(marketTemplate.StatementPattern, marketTemplate.ClosesAfterSeconds, scheduledAt)
+		if err != nil {
+			return nil, mts.log.Log(ERROR, "failed to render market template %s: %v", req.TemplateId, err)
+		}
+
+		markets = append(markets, &pb_api.MarketTemplatePreview{
+			ScheduledAt: scheduledAt.Format("2006-01-02T15:04:05Z"),
+			Statement:   statement,
+			ClosesAt:    closesAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+
+	return &pb_api.PreviewMarketTemplateResponse{
+		Markets: markets,
+	}, nil
+}
+
+// RunMarketTemplate creates the market for the latest scheduled time that is due, if any.
+// Missed older runs are skipped - they would close too soon (or in the past).
+// Safe to re-run: the marketId is pinned per scheduled time, so a retry resumes the same market creation.
+func (mts *MarketTemplatesService) RunMarketTemplate(marketTemplate *sqlc.MarketTemplate, now time.Time) error {
+	templateId := marketTemplate.TemplateID.String()
+
+	// guards
+	schedule, err := cron.ParseStandard(marketTemplate.Schedule)
+	if err != nil {
+		return mts.log.Log(ERROR, "invalid schedule %q on market template %s: %v", marketTemplate.Schedule, templateId, err)
+	}
+
+	from := marketTemplate.CreatedAt.UTC()
+	if marketTemplate.LastRunAt.Valid {
+		from = marketTemplate.LastRunAt.Time.UTC()
+	}
+
+	var scheduledAt time.Time
+	for next := schedule.Next(from); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+		scheduledAt = next
+	}
+	if scheduledAt.IsZero() {
+		return nil // not due yet
+	}
+
+	statement, closesAt, err := renderMarketTemplate(marketTemplate.StatementPattern, marketTemplate.ClosesAfterSeconds, scheduledAt)
+	if err != nil {
+		return mts.log.Log(ERROR, "failed to render market template %s: %v", templateId, err)
+	}
+	if !closesAt.After(now) {
+		mts.log.Log(WARN, "market template %s: market scheduled at %s would already be closed, skipping it", templateId, scheduledAt.Format(time.RFC3339))
+		return mts.marketTemplatesRepository.SetMarketTemplateLastRunAt(marketTemplate.TemplateID, scheduledAt)
+	}
+
+	/////
+	// OK - 3 steps to run a market template
+	/////
+
+	// Step 1:
+	// pin the marketId for this scheduled time on the **db**
+	marketTemplateRun, err := mts.marketTemplatesRepository.CreateMarketTemplateRun(marketTemplate.TemplateID, scheduledAt)
+	if err != nil {
+		return mts.log.Log(ERROR, "failed to create run of market template %s: %v", templateId, err)
+	}
+
+	closesAtStr := closesAt.Format("2006-01-02T15:04:05.000Z")
+	req := &pb_api.CreateMarketRequest{
+		MarketId:    marketTemplateRun.MarketID.String(),
+		Net:         marketTemplate.Net,
+		Statement:   statement,
+		ImageUrl:    marketTemplate.ImageUrl.String,
+		ClosesAt:    &closesAtStr,
+		Description: marketTemplate.Description.String,
+	}
+	if marketTemplate.CategoryID.Valid {
+		req.CategoryIds = []int32{marketTemplate.CategoryID.Int32}
+	}
+	if err := req.ValidateAll(); err != nil { // PGV validation - a rendered statement may be out of bounds
+		return mts.log.Log(ERROR, "market template %s generated an invalid market: %v", templateId, err)
+	}
+
+	// Step 2:
+	// create the market (**smart contract**, **CLOB** and **db**)
+	_, err = mts.marketsService.CreateMarket(req)
+	if err != nil {
+		return mts.log.Log(ERROR, "failed to create market %s from market template %s (will retry on the next run): %v", req.MarketId, templateId, err)
+	}
+
+	// Step 3:
+	// move the template on to the next scheduled time on the **db**
+	err = mts.marketTemplatesRepository.SetMarketTemplateLastRunAt(marketTemplate.TemplateID, scheduledAt)
+	if err != nil {
+		return mts.log.Log(ERROR, "market %s created but failed to set last_run_at on market template %s: %v", req.MarketId, templateId, err)
+	}
+
+	mts.log.Log(INFO, "Created market %s from market template %s (scheduled at %s): %s", req.MarketId, templateId, scheduledAt.Format(time.RFC3339), statement)
+	return nil
+}
+
+func (mts *MarketTemplatesService) validateMarketTemplate(req *pb_api.MarketTemplateRequest) error {
+	if _, err := cron.ParseStandard(strings.TrimSpace(req.Schedule)); err != nil {
+		return mts.log.Log(ERROR, "invalid schedule %q: %v", req.Schedule, err)
+	}
+
+	// render once so a broken pattern is rejected now rather than by the cron job
+	if _, _, err := renderMarketTemplate(req.StatementPattern, req.ClosesAfterSeconds, time.Now().UTC()); err != nil {
+		return mts.log.Log(ERROR, "invalid statement pattern: %v", err)
+	}
+	return nil
+}
+
+func renderMarketTemplate(statementPattern string, closesAfterSeconds int64, scheduledAt time.Time) (string, time.Time, error) {
+	closesAt := scheduledAt.Add(time.Duration(closesAfterSeconds) * time.Second)
+
+	tmpl, err := template.New("statement").Option("missingkey=error").Parse(statementPattern)
+	if err != nil {
+		return "", closesAt, err
+	}
+
+	var statement strings.Builder
+	err = tmpl.Execute(&statement, marketTemplateData{ScheduledAt: scheduledAt, ClosesAt: closesAt})
+	if err != nil {
+		return "", closesAt, err
+	}
+
+	return strings.TrimSpace(statement.String()), closesAt, nil
+}
+
+func (mts *MarketTemplatesService) mapMarketTemplateToResponse(marketTemplate *sqlc.MarketTemplate) (*pb_api.MarketTemplate, error) {
+	response := &pb_api.MarketTemplate{
+		TemplateId:         marketTemplate.TemplateID.String(),
+		Name:               marketTemplate.Name,
+		Net:                marketTemplate.Net,
+		StatementPattern:   marketTemplate.StatementPattern,
+		Description:        marketTemplate.Description.String,
+		ImageUrl:           marketTemplate.ImageUrl.String,
+		CategoryId:         marketTemplate.CategoryID.Int32,
+		ClosesAfterSeconds: marketTemplate.ClosesAfterSeconds,
+		Schedule:           marketTemplate.Schedule,
+		IsActive:           marketTemplate.IsActive,
+		CreatedAt:          marketTemplate.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	if marketTemplate.LastRunAt.Valid {
+		response.LastRunAt = marketTemplate.LastRunAt.Time.UTC().Format("2006-01-02T15:04:05Z")
+	}
+
+	if marketTemplate.IsActive {
+		schedule, err := cron.ParseStandard(marketTemplate.Schedule)
+		if err != nil {
+			return nil, mts.log.Log(ERROR, "invalid schedule %q on market template %s: %v", marketTemplate.Schedule, marketTemplate.TemplateID, err)
+		}
+		from := time.Now().UTC()
+		if marketTemplate.LastRunAt.Valid && marketTemplate.LastRunAt.Time.After(from) {
+			from = marketTemplate.LastRunAt.Time.UTC()
+		}
+		if next := schedule.Next(from); !next.IsZero() {
+			response.NextRunAt = next.Format("2006-01-02T15:04:05Z")
+		}
+	}
+	return response, nil
+}
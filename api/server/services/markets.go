@@ -12,6 +12,8 @@ import (
 	"time"
 
 	hiero "github.com/hiero-ledger/hiero-sdk-go/v2/sdk"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 type MarketsService struct {
@@ -20,14 +22,16 @@ type MarketsService struct {
 	hederaService     *HederaService
 	priceService      *PriceService
 	priceRepository   *repositories.PriceRepository
+	natsService       *NatsService
 }
 
-func (ms *MarketsService) Init(log *LogService, marketsRepository *repositories.MarketsRepository, hederaService *HederaService, priceService *PriceService) error {
+func (ms *MarketsService) Init(log *LogService, marketsRepository *repositories.MarketsRepository, hederaService *HederaService, priceService *PriceService, natsService *NatsService) error {
 	ms.log = log
 	ms.marketsRepository = marketsRepository
 	ms.hederaService = hederaService
 	ms.priceService = priceService
 	ms.priceRepository = priceService.priceRepository
+	ms.natsService = natsService
 
 	ms.log.Log(INFO, "Service: Market service initialized successfully")
 	return nil
@@ -46,7 +50,9 @@ func (ms *MarketsService) GetMarketById(marketId string) (*pb_api.MarketResponse
 	return response, nil
 }
 
-func (ms *MarketsService) GetMarkets(limit int32, offset int32) (*pb_api.MarketsResponse, error) {
+func (ms *MarketsService) GetMarkets(req *pb_api.GetMarketsRequest) (*pb_api.MarketsResponse, error) {
+	limit := req.GetLimit()
+
 	result := os.Getenv("DB_MAX_ROWS")
 	DB_MAX_ROWS, err := strconv.Atoi(result)
 	if err != nil {
@@ -57,11 +63,16 @@ func (ms *MarketsService) GetMarkets(limit int32, offset int32) (*pb_api.Markets
 		limit = int32(DB_MAX_ROWS)
 	}
 
-	markets, err := ms.marketsRepository.GetMarkets(limit, offset)
+	markets, err := ms.marketsRepository.GetMarkets(req, limit)
 	if err != nil {
 		return nil, ms.log.Log(ERROR, "failed to get markets: %v", err)
 	}
 
+	total, err := ms.marketsRepository.CountMarkets(req)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to count markets: %v", err)
+	}
+
 	var marketResponses []*pb_api.MarketResponse
 	for _, market := range markets {
 		marketResponse, err := ms.mapMarketToMarketResponse(&market)
@@ -73,42 +84,47 @@ func (ms *MarketsService) GetMarkets(limit int32, offset int32) (*pb_api.Markets
 
 	response := &pb_api.MarketsResponse{
 		Markets: marketResponses,
+		Total:   total,
 	}
 	return response, nil
 }
 
-// TODO - idempotent?
+// CreateMarket is idempotent per marketId: the intent is persisted first and every step is recorded,
+// so a retry (or the cron reconciler) picks up where the last attempt stopped
 func (ms *MarketsService) CreateMarket(req *pb_api.CreateMarketRequest) (*pb_api.CreateMarketResponse, error) {
 	// guards
 	// protobuf validation does a great job sofar ;)
 
-	/////
-	// OK - 3 steps to create a new market
-	/////
+	// YES, use the current X_SMART_CONTRACT_ID loaded from env vars - we're creating a new market
+	contractID, err := hiero.ContractIDFromString(
+		os.Getenv(fmt.Sprintf("%s_SMART_CONTRACT_ID", strings.ToUpper(req.Net))),
+	)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "invalid smart contract ID: %v", err)
+	}
 
-	// Step 1:
-	// create a market on the **smart contract** - return with error if it fails
-	remainingAllowance, err := ms.hederaService.CreateNewMarket(req)
+	// record the intent before doing anything that costs money
+	marketCreation, err := ms.marketsRepository.CreateMarketCreation(req, contractID.String())
 	if err != nil {
-		return nil, ms.log.Log(ERROR, "failed to create new market (marketId=%s) on Hedera: %v", req.MarketId, err)
+		return nil, ms.log.Log(ERROR, "failed to record market creation (marketId=%s) on the db: %v", req.MarketId, err)
 	}
 
-	// Step 2:
-	// create market on the **CLOB**
-	err = lib.CreateMarketOnClob(req.MarketId)
+	// a retry must be the same request - the marketId can't be reused for a different market
+	storedReq := &pb_api.CreateMarketRequest{}
+	err = protojson.Unmarshal(marketCreation.Request, storedReq)
 	if err != nil {
-		return nil, ms.log.Log(ERROR, "failed to create new market (marketId=%s) on CLOB: %v", req.MarketId, err)
+		return nil, ms.log.Log(ERROR, "failed to unmarshal stored market creation request (marketId=%s): %v", req.MarketId, err)
+	}
+	if !proto.Equal(storedReq, req) {
+		return nil, ms.log.Log(ERROR, "marketId %s is already used by a different CreateMarket request", req.MarketId)
+	}
+	if marketCreation.FlaggedAt.Valid {
+		return nil, ms.log.Log(ERROR, "market creation (marketId=%s) was abandoned at step %s and needs manual attention", req.MarketId, marketCreation.Step)
 	}
 
-	// Step 3:
-	// now record the tx on the **db**
-	contractID, err := hiero.ContractIDFromString(
-		// YES, use the current X_SMART_CONTRACT_ID loaded from env vars - we're creating a new market
-		os.Getenv(fmt.Sprintf("%s_SMART_CONTRACT_ID", strings.ToUpper(req.Net))),
-	)
-	market, err := ms.marketsRepository.CreateMarket(req, contractID.String())
+	market, remainingAllowance, err := ms.runMarketCreation(marketCreation, req)
 	if err != nil {
-		return nil, ms.log.Log(ERROR, "failed to create a new market row (marketId=%s) on the db: %v", req.MarketId, err)
+		return nil, err
 	}
 
 	/////
@@ -124,6 +140,395 @@ func (ms *MarketsService) CreateMarket(req *pb_api.CreateMarketRequest) (*pb_api
 	}, nil
 }
 
+// ResumeMarketCreation replays a stalled market creation from its stored request (used by the cron reconciler)
+func (ms *MarketsService) ResumeMarketCreation(marketCreation *sqlc.MarketCreation) error {
+	req := &pb_api.CreateMarketRequest{}
+	err := protojson.Unmarshal(marketCreation.Request, req)
+	if err != nil {
+		return ms.log.Log(ERROR, "failed to unmarshal stored market creation request (marketId=%s): %v", marketCreation.MarketID, err)
+	}
+
+	_, _, err = ms.runMarketCreation(marketCreation, req)
+	return err
+}
+
+// AbandonMarketCreation compensates what can be undone (the CLOB book) and flags the rest for a human.
+// Note: the on-chain market and its creation fee can't be rolled back.
+func (ms *MarketsService) AbandonMarketCreation(marketCreation *sqlc.MarketCreation) error {
+	step := lib.MarketCreationStep(marketCreation.Step)
+
+	if step == lib.MARKET_CREATION_CLOB_CREATED {
+		err := lib.DeleteMarketOnClob(marketCreation.MarketID.String())
+		if err != nil {
+			return ms.log.Log(ERROR, "failed to remove the book of abandoned market creation (marketId=%s) from the CLOB: %v", marketCreation.MarketID, err)
+		}
+	}
+
+	err := ms.marketsRepository.FlagMarketCreation(marketCreation.MarketID)
+	if err != nil {
+		return ms.log.Log(ERROR, "failed to flag market creation (marketId=%s): %v", marketCreation.MarketID, err)
+	}
+
+	ms.log.Log(ERROR, "abandoned market creation (marketId=%s) at step %s after %d attempts (last error: %s)", marketCreation.MarketID, step, marketCreation.Attempts, marketCreation.LastError.String)
+	return nil
+}
+
+func (ms *MarketsService) runMarketCreation(marketCreation *sqlc.MarketCreation, req *pb_api.CreateMarketRequest) (*sqlc.Market, uint64, error) {
+	marketCreation, err := ms.marketsRepository.StartMarketCreationAttempt(marketCreation.MarketID)
+	if err != nil {
+		return nil, 0, ms.log.Log(ERROR, "failed to start market creation attempt (marketId=%s): %v", req.MarketId, err)
+	}
+
+	market, remainingAllowance, err := ms.advanceMarketCreation(marketCreation, req)
+	if err != nil {
+		if dbErr := ms.marketsRepository.SetMarketCreationError(marketCreation.MarketID, err.Error()); dbErr != nil {
+			ms.log.Log(ERROR, "failed to record market creation error (marketId=%s): %v", req.MarketId, dbErr)
+		}
+		return nil, 0, err
+	}
+
+	return market, remainingAllowance, nil
+}
+
+func (ms *MarketsService) advanceMarketCreation(marketCreation *sqlc.MarketCreation, req *pb_api.CreateMarketRequest) (*sqlc.Market, uint64, error) {
+	step := lib.MarketCreationStep(marketCreation.Step)
+	remainingAllowance := uint64(marketCreation.RemainingAllowance.Int64)
+
+	/////
+	// OK - 3 steps to create a new market (each one is skipped if a previous attempt already did it)
+	/////
+
+	// Step 1:
+	// create a market on the **smart contract**
+	if step == lib.MARKET_CREATION_PENDING || step == lib.MARKET_CREATION_CONTRACT_CREATING {
+		// claim the step first - a concurrent CreateMarket (or the reconciler) would otherwise create the market on-chain too
+		isClaimed, err := ms.marketsRepository.ClaimMarketCreationContractStep(marketCreation.MarketID, lib.MARKET_CREATION_CLAIM_STALE_SECONDS)
+		if err != nil {
+			return nil, 0, ms.log.Log(ERROR, "failed to claim the smart contract step of market creation (marketId=%s): %v", req.MarketId, err)
+		}
+		if !isClaimed {
+			return nil, 0, ms.log.Log(WARN, "market creation (marketId=%s) is already in progress - try again later", req.MarketId)
+		}
+
+		// a previous attempt may have crashed after the tx went through - don't pay the creation fee twice
+		exists, err := ms.hederaService.MarketExistsOnChain(req.Net, marketCreation.SmartContractID, req.MarketId)
+		if err != nil {
+			return nil, 0, ms.log.Log(ERROR, "failed to check whether market (marketId=%s) exists on Hedera: %v", req.MarketId, err)
+		}
+
+		var allowance *uint64 // nil => unknown (keep whatever is stored)
+		if !exists {
+			remainingAllowance, err = ms.hederaService.CreateNewMarket(req, marketCreation.SmartContractID)
+			if err != nil {
+				return nil, 0, ms.log.Log(ERROR, "failed to create new market (marketId=%s) on Hedera: %v", req.MarketId, err)
+			}
+			allowance = &remainingAllowance
+		} else {
+			ms.log.Log(WARN, "market (marketId=%s) already exists on Hedera, skipping the smart contract step", req.MarketId)
+		}
+
+		err = ms.marketsRepository.SetMarketCreationStep(marketCreation.MarketID, lib.MARKET_CREATION_CONTRACT_CREATED, allowance)
+		if err != nil {
+			return nil, 0, ms.log.Log(ERROR, "market (marketId=%s) created on Hedera but failed to record the step: %v", req.MarketId, err)
+		}
+		step = lib.MARKET_CREATION_CONTRACT_CREATED
+	}
+
+	// Step 2:
+	// create market on the **CLOB** (an existing book is fine)
+	if step == lib.MARKET_CREATION_CONTRACT_CREATED {
+		err := lib.CreateMarketOnClob(req.MarketId)
+		if err != nil {
+			return nil, 0, ms.log.Log(ERROR, "failed to create new market (marketId=%s) on CLOB: %v", req.MarketId, err)
+		}
+
+		err = ms.marketsRepository.SetMarketCreationStep(marketCreation.MarketID, lib.MARKET_CREATION_CLOB_CREATED, nil)
+		if err != nil {
+			return nil, 0, ms.log.Log(ERROR, "market (marketId=%s) created on CLOB but failed to record the step: %v", req.MarketId, err)
+		}
+		step = lib.MARKET_CREATION_CLOB_CREATED
+	}
+
+	// Step 3:
+	// now record the market on the **db** (completes the saga in the same transaction)
+	if step == lib.MARKET_CREATION_CLOB_CREATED {
+		market, err := ms.marketsRepository.CreateMarket(req, marketCreation.SmartContractID)
+		if err != nil {
+			return nil, 0, ms.log.Log(ERROR, "failed to create a new market row (marketId=%s) on the db: %v", req.MarketId, err)
+		}
+		return market, remainingAllowance, nil
+	}
+
+	// already completed - plain retry
+	market, err := ms.marketsRepository.GetMarketById(req.MarketId)
+	if err != nil {
+		return nil, 0, ms.log.Log(ERROR, "failed to get market by id: %v", err)
+	}
+	return market, remainingAllowance, nil
+}
+
+func (ms *MarketsService) ResolveMarket(req *pb_api.ResolveMarketRequest) (*pb_api.MarketResponse, error) {
+	// guards
+	market, err := ms.marketsRepository.GetMarketByIdIncludingSuspended(req.MarketId) // a suspended market still has to be resolved
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to get market by id: %v", err)
+	}
+	if market.GroupID.Valid { // exactly one leg of a group may resolve YES
+		return nil, ms.log.Log(ERROR, "market (marketId=%s) is a leg of market group %s - use ResolveMarketGroup", req.MarketId, market.GroupID.UUID.String())
+	}
+
+	market, err = ms.resolveMarket(market, req.Outcome)
+	if err != nil {
+		return nil, err
+	}
+
+	/////
+	// Output: map the result to MarketResponse
+	/////
+	marketResponse, err := ms.mapMarketToMarketResponse(market)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to map market to market response: %v", err)
+	}
+	return marketResponse, nil
+}
+
+func (ms *MarketsService) resolveMarket(market *sqlc.Market, outcome bool) (*sqlc.Market, error) {
+	marketId := market.MarketID.String()
+	if market.VoidedAt.Valid {
+		return nil, ms.log.Log(ERROR, "market (marketId=%s) was voided at %s and cannot be resolved", marketId, market.VoidedAt.Time.Format(time.RFC3339))
+	}
+	// the matches held while a market is paused must settle before it resolves (they would revert with "Market resolved")
+	if market.IsPaused && !market.ResolvedAt.Valid {
+		return nil, ms.log.Log(ERROR, "market (marketId=%s) is paused - resume or suspend it first so its held settlements go through", marketId)
+	}
+
+	/////
+	// OK - 4 steps to resolve a market
+	/////
+
+	// Step 1:
+	// remove the book from the **CLOB** first so nothing else can match in the meantime
+	// (a match settled after the on-chain resolve reverts with "Market resolved")
+	err := lib.DeleteMarketOnClob(marketId)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to delete market (marketId=%s) on CLOB: %v", marketId, err)
+	}
+
+	// an already resolved market is done (safe to re-run if the CLOB step failed last time)
+	if !market.ResolvedAt.Valid {
+		// Step 2:
+		// submit any settlements still held to the **smart contract** (e.g. matched while a resume was releasing them)
+		nReleased, err := ms.natsService.ReleaseHeldSettlements(marketId)
+		if err != nil {
+			return nil, ms.log.Log(ERROR, "failed to release held settlements of market (marketId=%s) - not resolved: %v", marketId, err)
+		}
+		if nReleased > 0 {
+			ms.log.Log(INFO, "released %d held settlements of market (marketId=%s) before resolving it", nReleased, marketId)
+		}
+
+		// Step 3:
+		// resolve the market on the **smart contract** - skipped if a previous attempt already went through
+		err = ms.hederaService.ResolveMarket(market, outcome)
+		if err != nil {
+			return nil, ms.log.Log(ERROR, "failed to resolve market (marketId=%s) on Hedera: %v", marketId, err)
+		}
+
+		// Step 4:
+		// set resolved_at + outcome and cancel all open prediction intents on the **db**
+		market, err = ms.marketsRepository.ResolveMarket(marketId, outcome)
+		if err != nil {
+			return nil, ms.log.Log(ERROR, "market (marketId=%s) resolved on Hedera but failed to update the db (safe to retry): %v", marketId, err)
+		}
+	} else {
+		ms.log.Log(WARN, "market (marketId=%s) already resolved at %s, removed it from the CLOB only", marketId, market.ResolvedAt.Time.Format(time.RFC3339))
+	}
+
+	return market, nil
+}
+
+// VoidMarket annuls a market that cannot be resolved fairly: every YES and NO token is refunded at 50% on-chain.
+// Safe to re-run: each step is skipped or idempotent once it went through.
+func (ms *MarketsService) VoidMarket(req *pb_api.VoidMarketRequest) (*pb_api.MarketResponse, error) {
+	// guards
+	market, err := ms.marketsRepository.GetMarketByIdIncludingSuspended(req.MarketId) // a suspended market still has to be voided
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to get market by id: %v", err)
+	}
+	if market.ResolvedAt.Valid {
+		return nil, ms.log.Log(ERROR, "market (marketId=%s) is already resolved and cannot be voided", req.MarketId)
+	}
+	// only the current smart contract has voidMarket - don't pull the book of a market that can't be voided
+	contractVersion, err := ms.hederaService.GetPrismContractVersion(market.Net, market.SmartContractID)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to look up the smart contract version of market (marketId=%s): %v", req.MarketId, err)
+	}
+	if contractVersion == lib.PRISM_CONTRACT_VERSION_LEGACY {
+		return nil, ms.log.Log(ERROR, "market (marketId=%s) is on a legacy smart contract (%s), which cannot void markets - resolve it instead", req.MarketId, market.SmartContractID)
+	}
+	// the matches held while a market is paused must settle before it is voided (the refund is worked out from the positions)
+	if market.IsPaused && !market.VoidedAt.Valid {
+		return nil, ms.log.Log(ERROR, "market (marketId=%s) is paused - resume or suspend it first so its held settlements go through", req.MarketId)
+	}
+
+	/////
+	// OK - 4 steps to void a market
+	/////
+
+	// Step 1:
+	// remove the book from the **CLOB** first so nothing else can match in the meantime
+	err = lib.DeleteMarketOnClob(req.MarketId)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to delete market (marketId=%s) on CLOB: %v", req.MarketId, err)
+	}
+
+	// an already voided market is done (safe to re-run if the CLOB step failed last time)
+	if !market.VoidedAt.Valid {
+		// Step 2:
+		// submit any settlements still held to the **smart contract** (e.g. matched while a resume was releasing them)
+		nReleased, err := ms.natsService.ReleaseHeldSettlements(req.MarketId)
+		if err != nil {
+			return nil, ms.log.Log(ERROR, "failed to release held settlements of market (marketId=%s) - not voided: %v", req.MarketId, err)
+		}
+		if nReleased > 0 {
+			ms.log.Log(INFO, "released %d held settlements of market (marketId=%s) before voiding it", nReleased, req.MarketId)
+		}
+
+		// Step 3:
+		// void the market on the **smart contract** - redeem() then pays 50% per token
+		err = ms.hederaService.VoidMarket(market)
+		if err != nil {
+			return nil, ms.log.Log(ERROR, "failed to void market (marketId=%s) on Hedera: %v", req.MarketId, err)
+		}
+
+		// Step 4:
+		// set voided_at + void_reason, cancel all open prediction intents and record position refunds on the **db**
+		market, err = ms.marketsRepository.VoidMarket(market.MarketID, strings.TrimSpace(req.Reason))
+		if err != nil {
+			return nil, ms.log.Log(ERROR, "market (marketId=%s) voided on Hedera but failed to update the db: %v", req.MarketId, err)
+		}
+		ms.log.Log(INFO, "Voided market (marketId=%s): %s", req.MarketId, req.Reason)
+	} else {
+		ms.log.Log(WARN, "market (marketId=%s) already voided at %s, removed it from the CLOB only", req.MarketId, market.VoidedAt.Time.Format(time.RFC3339))
+	}
+
+	/////
+	// Output: map the result to MarketResponse
+	/////
+	marketResponse, err := ms.mapMarketToMarketResponse(market)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to map market to market response: %v", err)
+	}
+	return marketResponse, nil
+}
+
+func (ms *MarketsService) CloseMarket(market *sqlc.Market) error {
+	/////
+	// OK - 3 steps to close a market
+	/////
+
+	// Step 1:
+	// remove the book from the **CLOB** first so nothing else can match in the meantime
+	err := lib.DeleteMarketOnClob(market.MarketID.String())
+	if err != nil {
+		return ms.log.Log(ERROR, "failed to delete market (marketId=%s) on CLOB: %v", market.MarketID.String(), err)
+	}
+
+	// Step 2:
+	// submit any settlements still held to the **smart contract** - unless the market is paused: they stay held until it is resumed
+	// (or suspended), and it can't be resolved or voided until then
+	if !market.IsPaused {
+		nReleased, err := ms.natsService.ReleaseHeldSettlements(market.MarketID.String())
+		if err != nil {
+			return ms.log.Log(ERROR, "failed to release held settlements of market (marketId=%s) - not closed: %v", market.MarketID.String(), err)
+		}
+		if nReleased > 0 {
+			ms.log.Log(INFO, "released %d held settlements of market (marketId=%s) before closing it", nReleased, market.MarketID.String())
+		}
+	}
+
+	// Step 3:
+	// set closed_at and cancel all open prediction intents on the **db**
+	_, err = ms.marketsRepository.CloseMarket(market.MarketID)
+	if err != nil {
+		return ms.log.Log(ERROR, "failed to close market (marketId=%s) on the db: %v", market.MarketID.String(), err)
+	}
+
+	ms.log.Log(INFO, "Closed market (marketId=%s), closesAt=%s", market.MarketID.String(), market.ClosesAt.Format(time.RFC3339))
+	return nil
+}
+
+func (ms *MarketsService) PauseMarket(marketId string) (*pb_api.MarketResponse, error) {
+	// new prediction intents are rejected and matches are held (not settled) while a market is paused
+	market, err := ms.marketsRepository.SetMarketPaused(marketId, true)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to pause market (marketId=%s): %v", marketId, err)
+	}
+	ms.log.Log(INFO, "Paused market (marketId=%s)", marketId)
+
+	marketResponse, err := ms.mapMarketToMarketResponse(market)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to map market to market response: %v", err)
+	}
+	return marketResponse, nil
+}
+
+func (ms *MarketsService) ResumeMarket(marketId string) (*pb_api.MarketResponse, error) {
+	// Step 1:
+	// un-pause on the **db** first so new matches settle straight away
+	market, err := ms.marketsRepository.SetMarketPaused(marketId, false)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to resume market (marketId=%s): %v", marketId, err)
+	}
+
+	// Step 2:
+	// submit the settlements held while the market was paused to the **smart contract**
+	nReleased, err := ms.natsService.ReleaseHeldSettlements(marketId)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "market (marketId=%s) resumed but failed to release held settlements: %v", marketId, err)
+	}
+	ms.log.Log(INFO, "Resumed market (marketId=%s), released %d held settlements", marketId, nReleased)
+
+	marketResponse, err := ms.mapMarketToMarketResponse(market)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to map market to market response: %v", err)
+	}
+	return marketResponse, nil
+}
+
+func (ms *MarketsService) SuspendMarket(marketId string) (*pb_api.MarketResponse, error) {
+	/////
+	// OK - 3 steps to suspend a market
+	/////
+
+	// Step 1:
+	// remove the book from the **CLOB** first so nothing else can match in the meantime
+	err := lib.DeleteMarketOnClob(marketId)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to delete market (marketId=%s) on CLOB: %v", marketId, err)
+	}
+
+	// Step 2:
+	// set is_suspended (and clear is_paused) and cancel all open prediction intents on the **db**
+	market, err := ms.marketsRepository.SuspendMarket(marketId)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to suspend market (marketId=%s) on the db: %v", marketId, err)
+	}
+
+	// Step 3:
+	// a suspended market can't be resumed - submit the settlements held while it was paused to the **smart contract**
+	nReleased, err := ms.natsService.ReleaseHeldSettlements(marketId)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "market (marketId=%s) suspended but failed to release held settlements: %v", marketId, err)
+	}
+	ms.log.Log(INFO, "Suspended market (marketId=%s), released %d held settlements", marketId, nReleased)
+
+	marketResponse, err := ms.mapMarketToMarketResponse(market)
+	if err != nil {
+		return nil, ms.log.Log(ERROR, "failed to map market to market response: %v", err)
+	}
+	return marketResponse, nil
+}
+
 func (ms *MarketsService) mapMarketToMarketResponse(market *sqlc.Market) (*pb_api.MarketResponse, error) {
 	var createdAt string
 	var resolvedAt string
@@ -155,6 +560,13 @@ func (ms *MarketsService) mapMarketToMarketResponse(market *sqlc.Market) (*pb_ap
 		description = ""
 	}
 
+	var closedAt string
+	if market.ClosedAt.Valid {
+		closedAt = market.ClosedAt.Time.UTC().Format("2006-01-02T15:04:05Z")
+	} else {
+		closedAt = "" // market may not yet be closed
+	}
+
 	marketResponse := &pb_api.MarketResponse{
 		MarketId:    market.MarketID.String(),
 		Net:         market.Net,
@@ -166,7 +578,25 @@ func (ms *MarketsService) mapMarketToMarketResponse(market *sqlc.Market) (*pb_ap
 		ImageUrl:    imageUrl,
 		PriceUsd:    priceUsd,
 		Description: description,
+		ClosesAt:    market.ClosesAt.UTC().Format("2006-01-02T15:04:05Z"),
+		ClosedAt:    closedAt,
+	}
+	if market.GroupID.Valid {
+		marketResponse.GroupId = market.GroupID.UUID.String()
+	}
+	if market.Outcome.Valid {
+		marketResponse.Outcome = &market.Outcome.Bool
+	}
+	if market.VoidedAt.Valid {
+		marketResponse.VoidedAt = market.VoidedAt.Time.UTC().Format("2006-01-02T15:04:05Z")
+		marketResponse.VoidReason = market.VoidReason.String
+	}
+	// tells the front-end which payload to sign (0 if the smart contract could not be reached)
+	contractVersion, err := ms.hederaService.GetPrismContractVersion(market.Net, market.SmartContractID)
+	if err != nil {
+		ms.log.Log(WARN, "failed to look up the smart contract version of market %s: %v", market.MarketID.String(), err)
 	}
+	marketResponse.ContractVersion = uint32(contractVersion)
 	return marketResponse, nil
 }
 
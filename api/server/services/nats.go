@@ -2,26 +2,38 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
 	"os"
+	"strings"
+	"time"
 
 	pb_clob "api/gen/clob"
+	"api/gen/sqlc"
 	"api/server/lib"
 	repositories "api/server/repositories"
 
+	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
 )
 
 type NatsService struct {
 	log               *LogService
 	nats              *nats.Conn
+	jetStream         nats.JetStreamContext // nil if the NATS server runs without JetStream
+	clobOutboxWakeup  chan struct{}
 	hederaService     *HederaService
+	feesService       *FeesService
 	dbRepository      *repositories.DbRepository
 	matchesRepository *repositories.MatchesRepository
 	predictionIntents *repositories.PredictionIntentsRepository
+	marketsRepository *repositories.MarketsRepository
+
+	settlementsRepository *repositories.SettlementsRepository
 }
 
-func (ns *NatsService) InitNATS(log *LogService, h *HederaService, d *repositories.DbRepository, m *repositories.MatchesRepository, p *repositories.PredictionIntentsRepository) error {
+func (ns *NatsService) InitNATS(log *LogService, h *HederaService, f *FeesService, d *repositories.DbRepository, m *repositories.MatchesRepository, p *repositories.PredictionIntentsRepository, mr *repositories.MarketsRepository, s *repositories.SettlementsRepository) error {
 	ns.log = log
 
 	// connect to NATS
@@ -35,14 +47,41 @@ func (ns *NatsService) InitNATS(log *LogService, h *HederaService, d *repositori
 	}
 	ns.nats = natsConn
 
+	// publish the CLOB orders via JetStream (acked, de-duplicated by txId) if the server supports it
+	jetStream, err := natsConn.JetStream()
+	if err == nil {
+		_, err = jetStream.StreamInfo(lib.NATS_STREAM_CLOB_ORDERS)
+		if errors.Is(err, nats.ErrStreamNotFound) {
+			_, err = jetStream.AddStream(&nats.StreamConfig{
+				Name:       lib.NATS_STREAM_CLOB_ORDERS,
+				Subjects:   []string{lib.SUBJECT_CLOB_ORDERS},
+				Storage:    nats.FileStorage,
+				MaxAge:     24 * time.Hour,
+				Duplicates: 2 * time.Minute,
+			})
+		}
+	}
+	if err != nil {
+		ns.log.Log(WARN, "JetStream not available (%v) - publishing the CLOB orders with core NATS (flushed)", err)
+	} else {
+		ns.jetStream = jetStream
+	}
+	ns.clobOutboxWakeup = make(chan struct{}, 1)
+
 	// and inject the HederaService:
 	ns.hederaService = h
+	// and inject the FeesService:
+	ns.feesService = f
 	// and inject the DbService:
 	ns.dbRepository = d
 	// and inject the MatchesRepository:
 	ns.matchesRepository = m
 	// and inject the PredictionIntentsRepository:
 	ns.predictionIntents = p
+	// and inject the MarketsRepository:
+	ns.marketsRepository = mr
+	// and inject the SettlementsRepository:
+	ns.settlementsRepository = s
 
 	ns.log.Log(INFO, "Service: NATS service initialized successfully")
 	return nil
@@ -65,6 +104,22 @@ func (ns *NatsService) Publish(subject string, data []byte) error {
 	return nil
 }
 
+// PublishWithAck returns once the server has the message: acked by the JetStream stream (de-duplicated by msgId),
+// or - without JetStream - flushed to the server
+func (ns *NatsService) PublishWithAck(subject string, msgId string, data []byte) error {
+	if ns.nats == nil {
+		return ns.log.Log(ERROR, "NATS connection not initialized")
+	}
+	if ns.jetStream != nil {
+		_, err := ns.jetStream.Publish(subject, data, nats.MsgId(msgId))
+		return err
+	}
+	if err := ns.nats.Publish(subject, data); err != nil {
+		return err
+	}
+	return ns.nats.FlushTimeout(5 * time.Second)
+}
+
 func (ns *NatsService) Subscribe(subject string, handler nats.MsgHandler) (*nats.Subscription, error) {
 	if ns.nats == nil {
 		return nil, ns.log.Log(ERROR, "NATS connection not initialized")
@@ -119,25 +174,16 @@ func (ns *NatsService) HandleOrderMatches() error {
 			return
 		}
 
-		// TODO - assert that the user's allowance >= the size of the matched order
-		// ensure user has provided enough of an allowance to the smart contract:
-		// spenderAllowanceUsd, err := ns.hederaService.GetSpenderAllowanceUsd(*_networkSelected, accountId, _smartContractId, usdcAddress, usdcDecimals)
-		// if err != nil {
-		// 	return "", ns.log.Log(ERROR, "failed to get spender allowance: %v", err)
-		// }
-		// ns.log.Log(INFO, "Spender allowance for account %s on contract %s: $%.2f", accountId.String(), _smartContractId.String(), spenderAllowanceUsd)
-		// if spenderAllowanceUsd < math.Abs(req.GetPriceUsd()*req.GetQty()) {
-		// 	return "", ns.log.Log(ERROR, "Spender allowance ($USD%.2f USD token = %s) too low for this predictionIntent ($USD%.2f)", spenderAllowanceUsd, usdcAddress.String(), req.GetPriceUsd()*req.GetQty())
-		// }
-
-		// // ensure the spenderAllowanceUsd is >= usdc balance currently in the user's wallet
-		// currentUserBalanceUsdc, err := ns.hederaService.GetUsdcBalanceUsd(*_networkSelected, accountId)
-		// if err != nil {
-		// 	return "", ns.log.Log(ERROR, "failed to get user's USDC balance: %v", err)
-		// }
-		// if currentUserBalanceUsdc < spenderAllowanceUsd {
-		// 	return "", ns.log.Log(ERROR, "User's USDC balance ($USD%.2f) is less than the allowance ($USD%.2f)", currentUserBalanceUsdc, spenderAllowanceUsd)
-		// }
+		// a redelivered (or republished) match was recorded - or rejected - the first time round
+		if orderRequestClobTuple[0].MatchId != "" {
+			isProcessed, err := ns.matchesRepository.IsClobMatchIdProcessed(orderRequestClobTuple[0].MatchId)
+			if err != nil {
+				ns.log.Log(ERROR, "Error checking match %s in database: %v", orderRequestClobTuple[0].MatchId, err) // CreateMatch is idempotent anyway
+			} else if isProcessed {
+				ns.log.Log(WARN, "match %s was already processed - ignoring the redelivery (txId=%s, txId=%s)", orderRequestClobTuple[0].MatchId, orderRequestClobTuple[0].TxId, orderRequestClobTuple[1].TxId)
+				return
+			}
+		}
 
 		// OK
 
@@ -156,105 +202,94 @@ func (ns *NatsService) HandleOrderMatches() error {
 		// 	return
 		// }
 
-		_, err := ns.matchesRepository.CreateMatch(
+		// maker/taker fees - stored with the match and charged on-chain with the settlement
+		// N.B. never settled without them: a match whose fees could not be computed is recorded and retried (see: settleMatch)
+		isFeesPending := false
+		matchFees, err := ns.feesService.ComputeMatchFees(orderRequestClobTuple)
+		if err != nil {
+			ns.log.Log(ERROR, "Error computing fees for match (txId=%s, txId=%s) - not settling it yet: %v", orderRequestClobTuple[0].TxId, orderRequestClobTuple[1].TxId, err)
+			matchFees = &MatchFees{}
+			isFeesPending = true
+		}
+
+		/////
+		// funds
+		// re-check both sides' allowance and balance before settling (funds can drop between order entry and match) - not while the market is paused
+		/////
+		marketId := orderRequestClobTuple[0].MarketId
+		market, marketErr := ns.marketsRepository.GetMarketByIdIncludingSuspended(marketId) // matches made before a suspension still settle
+		if marketErr == nil && !market.IsPaused {
+			if ns.rejectUnderfundedMatch(market, orderRequestClobTuple, matchFees.FeeUsd) {
+				return
+			}
+		}
+
+		// idempotent on the CLOB match ID - a redelivered (or republished) match was recorded and settled (or held) the first time round
+		match, isDuplicate, err := ns.matchesRepository.CreateMatch(
 			// note: orderRequestClobTuple[0] is YES side (positive priceUsd)
 			//			 orderRequestClobTuple[1] is NO side (negative priceUsd)
 			[2]*pb_clob.CreateOrderRequestClob{orderRequestClobTuple[0], orderRequestClobTuple[1]},
-			"notYetAvailable",
+			lib.MATCH_TX_HASH_NOT_YET_AVAILABLE,
+			matchFees.MakerTxId,
+			matchFees.FeeUsd,
+			isFeesPending,
 		)
 		if err != nil {
-			ns.log.Log(ERROR, "Error recording match in database: %v", err)
+			// N.B. not settled: an unrecorded settlement could not be told apart from a redelivery of this match
+			ns.log.Log(ERROR, "PROBLEM: Error recording match %s in database - not settling it (txId=%s, txId=%s): %v", orderRequestClobTuple[0].MatchId, orderRequestClobTuple[0].TxId, orderRequestClobTuple[1].TxId, err)
+			return
+		}
+		if isDuplicate {
+			ns.log.Log(WARN, "match %s was already recorded - ignoring the redelivery (txId=%s, txId=%s)", orderRequestClobTuple[0].MatchId, orderRequestClobTuple[0].TxId, orderRequestClobTuple[1].TxId)
+			return
+		}
+		if orderRequestClobTuple[0].MatchId == "" {
+			ns.log.Log(WARN, "match %d has no CLOB match ID - a redelivery of it would not be detected", match.ID)
 		}
 
-		/////
-		// Now, for every match (doesn't matter if partial or full), if the qty remaining is <=0; mark the relevant prediction intent (timestamp) as "fully matched" in the db
-		// find out if it's tx1 or tx2 that is fully matched
-		var amountUsdTx0 float64 = orderRequestClobTuple[0].Qty / orderRequestClobTuple[0].PriceUsd
-		var amountUsdTx1 float64 = math.Abs(orderRequestClobTuple[1].Qty / orderRequestClobTuple[1].PriceUsd)
+		// filled/remaining qty (and fully matched) are recorded per intent with the match (see: MatchesRepository.CreateMatch)
 
-		markAsMatched := [2]bool{false, false}
-		if amountUsdTx0 < amountUsdTx1 {
-			markAsMatched[0] = true // mark tx0 for deletion
-		} else {
-			markAsMatched[1] = true // mark tx1 for deletion
-		}
-		if amountUsdTx0 == amountUsdTx1 {
-			markAsMatched[0] = true // mark both for deletion
-			markAsMatched[1] = true
+		// market lookup failed (e.g. db) - not a pause: the retry worker picks the settlement up
+		if marketErr != nil {
+			reason := fmt.Sprintf("failed to get market %s: %v", marketId, marketErr)
+			if _, err = ns.settlementsRepository.MarkSettlementAsFailed(match.ID, "", reason, false); err != nil {
+				ns.log.Log(ERROR, "Error marking settlement of match %d as failed: %v", match.ID, err)
+			}
+			ns.log.Log(ERROR, "%s - settlement of match %d will be retried", reason, match.ID)
+			return
 		}
 
-		marketId := orderRequestClobTuple[0].MarketId
-		if markAsMatched[0] == true { // mark tx0 for deletion
-			ns.log.Log(INFO, "marking tx0 (%s) as fully matched with tx1 (%s)", orderRequestClobTuple[0].TxId, orderRequestClobTuple[1].TxId)
-			err = ns.predictionIntents.MarkPredictionIntentAsFullyMatched(marketId, orderRequestClobTuple[0].TxId)
-			if err != nil {
-				ns.log.Log(ERROR, "Error marking prediction intent as fully matched in database: %v", err)
+		// fees not computed - the retry worker computes them and settles the match (or holds it while the market is paused)
+		if isFeesPending {
+			reason := "failed to compute the match fees"
+			if _, err = ns.settlementsRepository.MarkSettlementAsFailed(match.ID, "", reason, false); err != nil {
+				ns.log.Log(ERROR, "Error marking settlement of match %d as failed: %v", match.ID, err)
 			}
+			ns.log.Log(ERROR, "%s - settlement of match %d will be retried", reason, match.ID)
+			return
 		}
-		if markAsMatched[1] == true { // mark tx1 for deletion
-			ns.log.Log(INFO, "marking tx1 (%s) as fully matched with tx0 (%s)", orderRequestClobTuple[1].TxId, orderRequestClobTuple[0].TxId)
-			err = ns.predictionIntents.MarkPredictionIntentAsFullyMatched(marketId, orderRequestClobTuple[0].TxId)
+
+		/////
+		// paused market
+		// hold the settlement - it is submitted to the smart contract by ReleaseHeldSettlements when the market is resumed
+		/////
+		if market.IsPaused {
+			err = ns.matchesRepository.HoldMatch(match.ID)
 			if err != nil {
-				ns.log.Log(ERROR, "Error marking prediction intent as fully matched in database: %v", err)
+				ns.log.Log(ERROR, "Error holding settlement for match %d: %v", match.ID, err)
+				return
 			}
+			ns.log.Log(WARN, "market %s is paused - holding settlement for txId=%s, txId=%s", marketId, orderRequestClobTuple[0].TxId, orderRequestClobTuple[1].TxId)
+			return
 		}
 
-		// if amountUsdTx0-amountUsdTx1 <= 0 {
-		// 	// check if one side if wiped out:
-		// 	// Only mark as fully matched if the difference is <= 0
-		// 	ns.log.Log(INFO, "Marking txId %s as fully matched in database (amountUsdTx0 - amountUsdTx1 <= 0)", orderRequestClobTuple[0].TxId)
-		// 	err = ns.predictionIntents.MarkPredictionIntentAsFullyMatched(orderRequestClobTuple[0].MarketId, orderRequestClobTuple[0].TxId)
-		// 	if err != nil {
-		// 		ns.log.Log(ERROR, "Error marking prediction intent as fully matched in database: %v", err)
-		// 	}
-		// } else if amountUsdTx1-amountUsdTx0 <= 0 {
-		// 	// also must check if the other side is wiped out:
-		// 	// Only mark as fully matched if the difference is <= 0
-		// 	ns.log.Log(INFO, "Marking txId %s as fully matched in database (amountUsdTx1 - amountUsdTx0 <= 0)", orderRequestClobTuple[1].TxId)
-		// 	err = ns.predictionIntents.MarkPredictionIntentAsFullyMatched(orderRequestClobTuple[0].MarketId, orderRequestClobTuple[1].TxId)
-		// 	if err != nil {
-		// 		ns.log.Log(ERROR, "Error marking prediction intent as fully matched in database: %v", err)
-		// 	}
-		// } else if amountUsdTx1 == amountUsdTx0 { // full match
-		// 	// also much check if there's an exact match:
-		// 	// exact match - both are fully matched
-		// 	ns.log.Log(INFO, "Marking BOTH txIds %s and %s as fully matched in database (amountUsdTx1 == amountUsdTx0)", orderRequestClobTuple[0].TxId, orderRequestClobTuple[1].TxId)
-		// 	err = ns.predictionIntents.MarkPredictionIntentAsFullyMatched(orderRequestClobTuple[0].MarketId, orderRequestClobTuple[0].TxId)
-		// 	if err != nil {
-		// 		ns.log.Log(ERROR, "Error marking prediction intent as fully matched in database: %v", err)
-		// 	}
-		// 	err = ns.predictionIntents.MarkPredictionIntentAsFullyMatched(orderRequestClobTuple[0].MarketId, orderRequestClobTuple[1].TxId)
-		// 	if err != nil {
-		// 		ns.log.Log(ERROR, "Error marking prediction intent as fully matched in database: %v", err)
-		// 	}
-		// }
-
-		// // if it's a full match, log the relevant txId as "fully_match_at" on prediction_intents table...
-		// // this fully_matched_at timestamp is useful for the cron job to avoid scanning over too large a set of order requests
-		// if !isPartial { // a full match
-		// 	// find out if it's tx1 or tx2 that is fully matched
-		// 	var fullyMatchedTxId string
-		// 	if orderRequestClobTuple[0].Qty-orderRequestClobTuple[1].Qty <= 0 {
-		// 		fullyMatchedTxId = orderRequestClobTuple[0].TxId
-		// 	} else if orderRequestClobTuple[1].Qty-orderRequestClobTuple[0].Qty <= 0 {
-		// 		fullyMatchedTxId = orderRequestClobTuple[1].TxId
-		// 	} else {
-		// 		ns.log.Log(ERROR, "invalid fullyMatchTxId")
-		// 	}
-
-		// 	// err := ns.predictionIntents.MarkPredictionIntentAsFullyMatched(orderRequestClobTuple[0].MarketId, fullyMatchedTxId)
-		// 	// if err != nil {
-		// 	// 	ns.log.Log(ERROR, "Error marking prediction intent as fully matched in database: %v", err)
-		// 	// }
-		// }
-
 		/////
 		// smart contract
 		// Now submit BOTH matches to the smart contract
 		// BuyPositionTokens determines which account recieves the YES and which account receives the NO (price_usd < 0 => NO)
 		/////
 
-		isOK, err := ns.hederaService.BuyPositionTokens(orderRequestClobTuple[0], orderRequestClobTuple[1])
+		isOK, err := ns.hederaService.BuyPositionTokens(orderRequestClobTuple[0], orderRequestClobTuple[1], matchFees.FeeUsd[0], matchFees.FeeUsd[1], match.ID)
 		if err != nil {
 			ns.log.Log(ERROR, "Error submitting match to smart contract: %v ", err)
 		}
@@ -267,3 +302,508 @@ func (ns *NatsService) HandleOrderMatches() error {
 	}
 	return nil
 }
+
+/*
+*
+Reject a match before settlement if either side can't cover it (allowance and USDC balance): true if rejected.
+The underfunded intents are evicted and the CLOB is told to pull them and to put the matched qty of the other side back.
+A side whose funds can't be looked up is given the benefit of the doubt (a failed settlement is retried).
+*/
+func (ns *NatsService) rejectUnderfundedMatch(market *sqlc.Market, orderRequestClobTuple [2]*pb_clob.CreateOrderRequestClob, feeUsdTuple [2]float64) bool {
+	matchId := orderRequestClobTuple[0].MatchId
+	matchedQty := math.Min(orderRequestClobTuple[0].Qty, orderRequestClobTuple[1].Qty)
+
+	isUnderfunded, reasons := ns.getUnderfundedSides(market, orderRequestClobTuple, feeUsdTuple, matchId)
+	if !isUnderfunded[0] && !isUnderfunded[1] {
+		return false
+	}
+
+	// OK - reject: evict the underfunded side(s), reinstate the matched qty of the other side if it is still open
+	// (ioc/fok never rest on the book - their unsettled qty is simply not filled)
+	rejection := &pb_clob.MatchRejection{MatchId: matchId, MarketId: market.MarketID.String()}
+	var evictedTxIds, reinstatedTxIds []uuid.UUID
+	var rejectionReasons []string
+	for i, side := range orderRequestClobTuple {
+		txId, err := uuid.Parse(side.TxId)
+		if err != nil {
+			ns.log.Log(ERROR, "invalid txId %s in match %s: %v", side.TxId, matchId, err)
+			continue
+		}
+		if isUnderfunded[i] {
+			evictedTxIds = append(evictedTxIds, txId)
+			rejection.EvictedTxIds = append(rejection.EvictedTxIds, side.TxId)
+			rejectionReasons = append(rejectionReasons, fmt.Sprintf("txId %s: %s", side.TxId, reasons[i]))
+			continue
+		}
+
+		predictionIntent, err := ns.predictionIntents.GetPredictionIntentByTxId(txId)
+		if err != nil {
+			ns.log.Log(ERROR, "failed to get prediction intent (txId=%s) to reinstate: %v", side.TxId, err)
+			continue
+		}
+		if predictionIntent.TimeInForce == lib.TIME_IN_FORCE_IOC || predictionIntent.TimeInForce == lib.TIME_IN_FORCE_FOK {
+			continue
+		}
+		if getPredictionIntentState(predictionIntent) != lib.PREDICTION_INTENT_STATE_OPEN {
+			continue
+		}
+		reinstatedTxIds = append(reinstatedTxIds, txId)
+		rejection.ReinstatedOrders = append(rejection.ReinstatedOrders, clobOrderFromPredictionIntentRow(predictionIntent, matchedQty))
+	}
+	reason := strings.Join(rejectionReasons, "; ")
+
+	// recorded once per CLOB match ID - the first delivery does the rest
+	isRecorded, err := ns.matchesRepository.CreateMatchRejection(orderRequestClobTuple, evictedTxIds, reinstatedTxIds, reason)
+	if err != nil {
+		ns.log.Log(ERROR, "Error recording rejection of match %s in database: %v", matchId, err)
+	} else if !isRecorded {
+		ns.log.Log(WARN, "match %s was already rejected - ignoring the redelivery", matchId)
+		return true
+	}
+
+	for i, side := range orderRequestClobTuple {
+		if !isUnderfunded[i] {
+			continue
+		}
+		err = ns.predictionIntents.CancelPredictionIntent(side.TxId)
+		if err != nil {
+			ns.log.Log(ERROR, "Failed to cancel underfunded prediction intent txId %s: %v", side.TxId, err)
+			continue
+		}
+		err = ns.predictionIntents.MarkPredictionIntentAsEvicted(uuid.MustParse(side.TxId), reasons[i])
+		if err != nil {
+			ns.log.Log(ERROR, "Failed to mark as evicted prediction intent txId %s: %v", side.TxId, err)
+			continue
+		}
+		ns.log.Log(WARN, "-> Evicted prediction intent txId %s (account %s) of match %s: %s", side.TxId, side.AccountId, matchId, reasons[i])
+	}
+
+	rejectionJSON, err := json.Marshal(rejection)
+	if err != nil {
+		ns.log.Log(ERROR, "failed to marshal rejection of match %s: %v", matchId, err)
+		return true
+	}
+	err = ns.Publish(lib.SUBJECT_CLOB_MATCH_REJECTIONS, rejectionJSON)
+	if err != nil {
+		ns.log.Log(ERROR, "PROBLEM: failed to publish rejection of match %s to the CLOB - evicted %v, not reinstated %v: %v", matchId, rejection.EvictedTxIds, reinstatedTxIds, err)
+		return true
+	}
+
+	ns.log.Log(WARN, "Rejected match %s before settlement (%s) - evicted %d, reinstated %d on the CLOB", matchId, reason, len(evictedTxIds), len(reinstatedTxIds))
+	return true
+}
+
+// getUnderfundedSides re-checks both sides' allowance and USDC balance against |priceUsd| * matched qty + fee - a failed lookup is not underfunded
+func (ns *NatsService) getUnderfundedSides(market *sqlc.Market, orderRequestClobTuple [2]*pb_clob.CreateOrderRequestClob, feeUsdTuple [2]float64, matchId string) (isUnderfunded [2]bool, reasons [2]string) {
+	matchedQty := math.Min(orderRequestClobTuple[0].Qty, orderRequestClobTuple[1].Qty)
+
+	for i, side := range orderRequestClobTuple {
+		requiredUsd := math.Abs(side.PriceUsd)*matchedQty + feeUsdTuple[i]
+		allowanceUsd, balanceUsd, err := ns.hederaService.GetFundsUsd(side.Net, side.AccountId, market.SmartContractID)
+		if err != nil {
+			ns.log.Log(WARN, "could not re-check the funds of account %s (txId=%s) for match %s - settling anyway: %v", side.AccountId, side.TxId, matchId, err)
+			continue
+		}
+		if allowanceUsd < requiredUsd || balanceUsd < requiredUsd {
+			isUnderfunded[i] = true
+			reasons[i] = fmt.Sprintf("insufficient funds at settlement: $%.2f required (allowance $%.2f, balance $%.2f)", requiredUsd, allowanceUsd, balanceUsd)
+		}
+	}
+	return isUnderfunded, reasons
+}
+
+// NotifyClobOutbox wakes the outbox relay up - call it once a clob outbox message is committed
+func (ns *NatsService) NotifyClobOutbox() {
+	select {
+	case ns.clobOutboxWakeup <- struct{}{}:
+	default: // a relay run is already pending
+	}
+}
+
+// RelayClobOutbox publishes the clob outbox to NATS - on every tick and whenever NotifyClobOutbox is called
+func (ns *NatsService) RelayClobOutbox() {
+	ns.log.Log(INFO, "RelayClobOutbox starting...")
+	go func() {
+		ticker := time.NewTicker(lib.CLOB_OUTBOX_RELAY_INTERVAL_MS * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ns.clobOutboxWakeup:
+			}
+			ns.relayClobOutbox()
+		}
+	}()
+}
+
+// relayClobOutbox publishes the undelivered messages in order and stops at the first failed publish (retried on the next run)
+func (ns *NatsService) relayClobOutbox() {
+	messages, err := ns.predictionIntents.GetUndeliveredClobOutboxMessages(lib.CLOB_OUTBOX_BATCH_SIZE)
+	if err != nil {
+		ns.log.Log(ERROR, "failed to get undelivered clob outbox messages: %v", err)
+		return
+	}
+
+	for _, message := range messages {
+		txId := message.TxID.String()
+
+		predictionIntent, err := ns.predictionIntents.GetPredictionIntentByTxId(message.TxID)
+		if err != nil {
+			ns.log.Log(ERROR, "failed to get prediction intent for clob outbox message %d (txId=%s): %v", message.ID, txId, err)
+			continue
+		}
+
+		// closed before it was relayed (e.g. republished after a crash) - don't put it back on the CLOB
+		if state := getPredictionIntentState(predictionIntent); state != lib.PREDICTION_INTENT_STATE_OPEN {
+			ns.log.Log(WARN, "prediction intent (txId=%s) is %s - not publishing clob outbox message %d", txId, state, message.ID)
+			err = ns.predictionIntents.MarkClobOutboxMessageAsDelivered(message.ID)
+			if err != nil {
+				ns.log.Log(ERROR, "failed to mark clob outbox message %d as delivered: %v", message.ID, err)
+				return
+			}
+			continue
+		}
+
+		clobRequestJSON, err := json.Marshal(clobOrderFromPredictionIntentRow(predictionIntent, predictionIntent.RemainingQty))
+		if err != nil {
+			ns.log.Log(ERROR, "failed to marshal CLOB request (txId=%s): %v", txId, err)
+			continue
+		}
+
+		err = ns.PublishWithAck(message.Subject, txId, clobRequestJSON)
+		if err != nil {
+			ns.log.Log(ERROR, "failed to publish clob outbox message %d (txId=%s) to NATS - will retry: %v", message.ID, txId, err)
+			if setErr := ns.predictionIntents.SetClobOutboxMessageError(message.ID, err.Error()); setErr != nil {
+				ns.log.Log(ERROR, "failed to record the error on clob outbox message %d: %v", message.ID, setErr)
+			}
+			return // keep the order - the rest go out after this one
+		}
+
+		// N.B. if this fails the message is published again - the CLOB ignores a duplicate txId
+		err = ns.predictionIntents.MarkClobOutboxMessageAsDelivered(message.ID)
+		if err != nil {
+			ns.log.Log(ERROR, "published clob outbox message %d (txId=%s) but failed to mark it as delivered: %v", message.ID, txId, err)
+			return
+		}
+
+		ns.log.Log(INFO, "Published order to NATS subject '%s': %s", message.Subject, string(clobRequestJSON))
+	}
+}
+
+/*
+*
+Submit every settlement held while the market was paused to the smart contract.
+The order tuples are rebuilt from the prediction_intents table (the same way TriggerRecreateClob rebuilds the CLOB).
+*/
+func (ns *NatsService) ReleaseHeldSettlements(marketId string) (int, error) {
+	marketUUID, err := uuid.Parse(marketId)
+	if err != nil {
+		return 0, ns.log.Log(ERROR, "invalid marketId uuid: %v", err)
+	}
+
+	heldMatches, err := ns.matchesRepository.GetHeldMatchesByMarketId(marketUUID)
+	if err != nil {
+		return 0, ns.log.Log(ERROR, "failed to get held matches for marketId %s: %v", marketId, err)
+	}
+
+	n := 0
+	for _, match := range heldMatches {
+		err = ns.matchesRepository.ReleaseHeldMatch(match.ID)
+		if err != nil {
+			ns.log.Log(ERROR, "failed to release held match %d on the db - still held: %v", match.ID, err)
+			continue
+		}
+		n = n + 1
+
+		// a failed settlement is picked up by the retry worker (see: RetryFailedSettlements)
+		err = ns.settleMatch(&match)
+		if err != nil {
+			ns.log.Log(ERROR, "failed to settle released match %d: %v", match.ID, err)
+		}
+	}
+
+	ns.log.Log(INFO, "Released %d of %d held settlements for marketId %s", n, len(heldMatches), marketId)
+	return n, nil
+}
+
+// RetryFailedSettlements resubmits the failed settlements once they are due (exponential backoff) - every SETTLEMENT_RETRY_INTERVAL_MS.
+// Settlements interrupted mid-way (e.g. by a restart) are swept into the retries first.
+func (ns *NatsService) RetryFailedSettlements() {
+	ns.log.Log(INFO, "RetryFailedSettlements starting...")
+	go func() {
+		ticker := time.NewTicker(lib.SETTLEMENT_RETRY_INTERVAL_MS * time.Millisecond)
+		defer ticker.Stop()
+		for range ticker.C {
+			ns.failStaleSettlements()
+			ns.retryFailedSettlements()
+		}
+	}()
+}
+
+/*
+*
+A match is recorded with a pending settlement - if the API stops before it is submitted (or held),
+or before a submitted transaction's outcome is recorded, nothing else picks it up: its redelivery is ignored as a duplicate.
+Only matches recorded with a CLOB match ID are swept - a match without one was not de-duplicated either (nor was a legacy one backfilled).
+*/
+func (ns *NatsService) failStaleSettlements() {
+	matchIds, err := ns.settlementsRepository.FailStaleSettlements(lib.SETTLEMENT_STALE_SECONDS)
+	if err != nil {
+		ns.log.Log(ERROR, "failed to sweep stale settlements: %v", err)
+		return
+	}
+	if len(matchIds) > 0 {
+		ns.log.Log(WARN, "Swept %d stale (interrupted) settlements into the retries: matches %v", len(matchIds), matchIds)
+	}
+}
+
+func (ns *NatsService) retryFailedSettlements() {
+	matches, err := ns.matchesRepository.GetMatchesDueForSettlementRetry(lib.SETTLEMENT_RETRY_BATCH_SIZE)
+	if err != nil {
+		ns.log.Log(ERROR, "failed to get matches due for a settlement retry: %v", err)
+		return
+	}
+
+	for i := range matches {
+		ns.RetrySettlement(&matches[i]) // logged
+	}
+}
+
+/*
+*
+Claim a failed (or dead-lettered) settlement and submit it to the smart contract again - a paused market holds it instead.
+Returns an error only if the settlement could not be attempted: the outcome of the attempt is recorded on the settlement.
+*/
+func (ns *NatsService) RetrySettlement(match *sqlc.Match) error {
+	// never resubmit a transaction that may have gone through
+	isResubmittable, err := ns.ReconcileSettlementTx(match)
+	if err != nil {
+		return err
+	}
+	if !isResubmittable {
+		return nil
+	}
+
+	isClaimed, err := ns.settlementsRepository.ClaimSettlementForRetry(match.ID)
+	if err != nil {
+		return ns.log.Log(ERROR, "failed to claim the settlement of match %d for a retry: %v", match.ID, err)
+	}
+	if !isClaimed {
+		return ns.log.Log(WARN, "settlement of match %d is neither failed nor dead-lettered - not retrying it", match.ID)
+	}
+
+	market, err := ns.marketsRepository.GetMarketByIdIncludingSuspended(match.MarketID.String())
+	if err != nil {
+		// not a pause - back to failed (transient), so the retry worker picks it up again
+		reason := fmt.Sprintf("failed to get market %s: %v", match.MarketID.String(), err)
+		if _, markErr := ns.settlementsRepository.MarkSettlementAsFailed(match.ID, "", reason, false); markErr != nil {
+			ns.log.Log(ERROR, "Error marking settlement of match %d as failed: %v", match.ID, markErr)
+		}
+		return ns.log.Log(ERROR, "%s - not retrying the settlement of match %d yet", reason, match.ID)
+	}
+	if market.IsPaused {
+		err = ns.matchesRepository.HoldMatch(match.ID)
+		if err != nil {
+			return ns.log.Log(ERROR, "Error holding settlement for match %d: %v", match.ID, err)
+		}
+		ns.log.Log(WARN, "market %s is paused - holding settlement for match %d", match.MarketID.String(), match.ID)
+		return nil
+	}
+
+	ns.log.Log(INFO, "Retrying settlement of match %d (txId=%s, txId=%s)", match.ID, match.TxId1.String(), match.TxId2.String())
+	err = ns.settleMatch(match)
+	if err != nil {
+		ns.log.Log(ERROR, "retry of the settlement of match %d failed: %v", match.ID, err)
+	}
+	return nil
+}
+
+/*
+*
+ReconcileSettlementTx looks up the outcome of the last transaction submitted for a settlement - before it is retried or abandoned.
+Returns true if the settlement may be (re)submitted: nothing was submitted, or the last transaction provably did not execute.
+A transaction that succeeded confirms the settlement. One whose outcome is not known yet leaves the settlement as it is
+(a failed one is looked at again after SETTLEMENT_TX_RECHECK_SECONDS).
+*/
+func (ns *NatsService) ReconcileSettlementTx(match *sqlc.Match) (bool, error) {
+	settlements, err := ns.settlementsRepository.GetSettlementsByMatchIds([]int32{match.ID})
+	if err != nil {
+		return false, ns.log.Log(ERROR, "failed to get the settlement of match %d: %v", match.ID, err)
+	}
+	settlement, ok := settlements[match.ID]
+	if !ok {
+		return false, ns.log.Log(ERROR, "match %d has no settlement", match.ID)
+	}
+	if !settlement.HederaTxID.Valid || settlement.HederaTxID.String == "" {
+		return true, nil
+	}
+	hederaTxId := settlement.HederaTxID.String
+
+	// note: TxId1 is YES side (positive priceUsd)
+	//			 TxId2 is NO side (negative priceUsd)
+	sideYes, err := ns.clobOrderFromPredictionIntent(match.TxId1, match.Qty1)
+	if err != nil {
+		return false, ns.log.Log(ERROR, "failed to rebuild YES side of match %d: %v", match.ID, err)
+	}
+	sideNo, err := ns.clobOrderFromPredictionIntent(match.TxId2, match.Qty2)
+	if err != nil {
+		return false, ns.log.Log(ERROR, "failed to rebuild NO side of match %d: %v", match.ID, err)
+	}
+
+	outcome, receiptStatus, err := ns.hederaService.GetSettlementTxOutcome(sideYes.Net, hederaTxId)
+	if err != nil {
+		ns.log.Log(WARN, "could not look up Hedera transaction %s of match %d: %v", hederaTxId, match.ID, err)
+	}
+
+	switch outcome {
+	case lib.SETTLEMENT_TX_OUTCOME_NOT_EXECUTED:
+		return true, nil
+	case lib.SETTLEMENT_TX_OUTCOME_SUCCEEDED:
+		err = ns.hederaService.ConfirmSettlement(sideYes, sideNo, match.ID, hederaTxId, receiptStatus)
+		if err != nil {
+			return false, err
+		}
+		ns.log.Log(WARN, "settlement of match %d already went through (Hedera transaction %s) - confirmed it instead of resubmitting it", match.ID, hederaTxId)
+		return false, nil
+	default:
+		if postponeErr := ns.settlementsRepository.PostponeSettlementRetry(match.ID, lib.SETTLEMENT_TX_RECHECK_SECONDS); postponeErr != nil {
+			ns.log.Log(ERROR, "failed to postpone the retry of the settlement of match %d: %v", match.ID, postponeErr)
+		}
+		return false, ns.log.Log(WARN, "outcome of Hedera transaction %s of match %d is not known yet - not resubmitting (or abandoning) it", hederaTxId, match.ID)
+	}
+}
+
+/*
+*
+settleMatch rebuilds the order tuple of a recorded match from the prediction_intents table and submits it to the smart contract.
+Funds can drop while a settlement is held or waits for a retry: an underfunded side fails the settlement (transient) instead -
+retried with backoff in case it is topped up, dead-lettered (for an admin to abandon) once the retries are exhausted.
+*/
+func (ns *NatsService) settleMatch(match *sqlc.Match) error {
+	// note: TxId1 is YES side (positive priceUsd)
+	//			 TxId2 is NO side (negative priceUsd)
+	sideYes, err := ns.clobOrderFromPredictionIntent(match.TxId1, match.Qty1)
+	if err != nil {
+		return ns.failUnbuiltSettlement(match.ID, "YES", err)
+	}
+	sideNo, err := ns.clobOrderFromPredictionIntent(match.TxId2, match.Qty2)
+	if err != nil {
+		return ns.failUnbuiltSettlement(match.ID, "NO", err)
+	}
+
+	// the fees could not be computed when the match was recorded - compute (and record) them now
+	if match.IsFeesPending {
+		matchFees, err := ns.feesService.ComputeMatchFees([2]*pb_clob.CreateOrderRequestClob{sideYes, sideNo})
+		if err == nil {
+			err = ns.matchesRepository.SetMatchFees(match.ID, matchFees.MakerTxId, matchFees.FeeUsd)
+		}
+		if err != nil {
+			reason := fmt.Sprintf("failed to compute the fees of match %d: %v", match.ID, err)
+			if _, markErr := ns.settlementsRepository.MarkSettlementAsFailed(match.ID, "", reason, false); markErr != nil {
+				ns.log.Log(ERROR, "Error marking settlement of match %d as failed: %v", match.ID, markErr)
+			}
+			return errors.New(reason)
+		}
+		match.FeeUsd1, match.FeeUsd2 = matchFees.FeeUsd[0], matchFees.FeeUsd[1]
+		match.IsFeesPending = false
+	}
+
+	// re-check both sides' funds (as HandleOrderMatches did when the match came in)
+	market, err := ns.marketsRepository.GetMarketByIdIncludingSuspended(match.MarketID.String())
+	if err != nil {
+		reason := fmt.Sprintf("failed to get market %s: %v", match.MarketID.String(), err)
+		if _, markErr := ns.settlementsRepository.MarkSettlementAsFailed(match.ID, "", reason, false); markErr != nil {
+			ns.log.Log(ERROR, "Error marking settlement of match %d as failed: %v", match.ID, markErr)
+		}
+		return errors.New(reason)
+	}
+	isUnderfunded, reasons := ns.getUnderfundedSides(market, [2]*pb_clob.CreateOrderRequestClob{sideYes, sideNo}, [2]float64{match.FeeUsd1, match.FeeUsd2}, fmt.Sprintf("%d", match.ID))
+	if isUnderfunded[0] || isUnderfunded[1] {
+		var underfundedReasons []string
+		for i, side := range []*pb_clob.CreateOrderRequestClob{sideYes, sideNo} {
+			if isUnderfunded[i] {
+				underfundedReasons = append(underfundedReasons, fmt.Sprintf("txId %s: %s", side.TxId, reasons[i]))
+			}
+		}
+		reason := strings.Join(underfundedReasons, "; ")
+		if _, markErr := ns.settlementsRepository.MarkSettlementAsFailed(match.ID, "", reason, false); markErr != nil {
+			ns.log.Log(ERROR, "Error marking settlement of match %d as failed: %v", match.ID, markErr)
+		}
+		return fmt.Errorf("not settling match %d: %s", match.ID, reason)
+	}
+
+	isOK, err := ns.hederaService.BuyPositionTokens(sideYes, sideNo, match.FeeUsd1, match.FeeUsd2, match.ID)
+	if err != nil {
+		return err
+	}
+	if !isOK {
+		return fmt.Errorf("BuyPositionTokens returned !isOK for txId=%s, txId=%s", sideYes.TxId, sideNo.TxId)
+	}
+	return nil
+}
+
+// failUnbuiltSettlement - BuyPositionTokens was not called to record the failure, so record it here (transient: db)
+func (ns *NatsService) failUnbuiltSettlement(matchId int32, side string, err error) error {
+	reason := fmt.Sprintf("failed to rebuild %s side of match %d: %v", side, matchId, err)
+	if _, markErr := ns.settlementsRepository.MarkSettlementAsFailed(matchId, "", reason, false); markErr != nil {
+		ns.log.Log(ERROR, "Error marking settlement of match %d as failed: %v", matchId, markErr)
+	}
+	return errors.New(reason)
+}
+
+// PublishRequeuedOrder puts an intent back on the CLOB with qty after the settlement of match matchId was abandoned
+func (ns *NatsService) PublishRequeuedOrder(predictionIntent *sqlc.PredictionIntent, qty float64, matchId int32) error {
+	return ns.publishRequeuedOrder(predictionIntent, qty, fmt.Sprintf("%s:requeue:%d", predictionIntent.TxID.String(), matchId))
+}
+
+// PublishRestoredOrder puts an intent pulled from the CLOB back on it with its remaining qty (e.g. a replace that failed on the db)
+func (ns *NatsService) PublishRestoredOrder(predictionIntent *sqlc.PredictionIntent) error {
+	return ns.publishRequeuedOrder(predictionIntent, predictionIntent.RemainingQty, fmt.Sprintf("%s:restore:%d", predictionIntent.TxID.String(), time.Now().UnixNano()))
+}
+
+func (ns *NatsService) publishRequeuedOrder(predictionIntent *sqlc.PredictionIntent, qty float64, msgId string) error {
+	clobRequest := clobOrderFromPredictionIntentRow(predictionIntent, qty)
+	clobRequest.IsRequeue = true // the CLOB has seen the txId before
+
+	clobRequestJSON, err := json.Marshal(clobRequest)
+	if err != nil {
+		return ns.log.Log(ERROR, "failed to marshal CLOB request (txId=%s): %v", clobRequest.TxId, err)
+	}
+
+	// not de-duplicated against the original publish (msgId = txId)
+	err = ns.PublishWithAck(lib.SUBJECT_CLOB_ORDERS, msgId, clobRequestJSON)
+	if err != nil {
+		return ns.log.Log(ERROR, "failed to publish requeued order (txId=%s) to NATS: %v", clobRequest.TxId, err)
+	}
+
+	ns.log.Log(INFO, "Published requeued order to NATS subject '%s': %s", lib.SUBJECT_CLOB_ORDERS, string(clobRequestJSON))
+	return nil
+}
+
+func (ns *NatsService) clobOrderFromPredictionIntent(txId uuid.UUID, qty float64) (*pb_clob.CreateOrderRequestClob, error) {
+	predictionIntent, err := ns.predictionIntents.GetPredictionIntentByTxId(txId)
+	if err != nil {
+		return nil, err
+	}
+
+	return clobOrderFromPredictionIntentRow(predictionIntent, qty), nil
+}
+
+func clobOrderFromPredictionIntentRow(predictionIntent *sqlc.PredictionIntent, qty float64) *pb_clob.CreateOrderRequestClob {
+	return &pb_clob.CreateOrderRequestClob{
+		TxId:        predictionIntent.TxID.String(),
+		Net:         predictionIntent.Net,
+		MarketId:    predictionIntent.MarketID.String(),
+		AccountId:   predictionIntent.AccountID,
+		MarketLimit: predictionIntent.MarketLimit,
+		PriceUsd:    predictionIntent.PriceUsd,
+		Qty:         qty,
+		QtyOrig:     predictionIntent.Qty, // need to keep track of the original qty for on/off-chain signature validation
+		Sig:         predictionIntent.Sig,
+		PublicKey:   predictionIntent.PublicKeyHex,
+		EvmAddress:  predictionIntent.Evmaddress,
+		KeyType:     int32(predictionIntent.Keytype),
+		TimeInForce: predictionIntent.TimeInForce,
+		ExpiresAt:   lib.FormatExpiresAt(predictionIntent.ExpiresAt),
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	pb_api "api/gen"
 	sqlc "api/gen/sqlc"
+	"api/server/lib"
 	repositories "api/server/repositories"
 )
 
@@ -65,6 +66,10 @@ func (ps *PositionsService) GetUserPortfolio(req *pb_api.UserPortfolioRequest) (
 			IsPaused:   market.IsPaused,
 			ResolvedAt: market.ResolvedAt.Time.String(),
 		}
+		if market.VoidedAt.Valid { // voided market: the refund replaces the resolved outcome
+			position.IsVoided = true
+			position.Refund = uint64(userPosition.Refund.Int64)
+		}
 
 		response.Positions[userPosition.MarketID.String()] = position
 	}
@@ -78,14 +83,18 @@ func (ps *PositionsService) GetUserPortfolio(req *pb_api.UserPortfolioRequest) (
 	// loop through each predictionIntents and add to OrderbookPositions
 	for _, pi := range predictionIntents {
 		orderbookPosition := &pb_api.PredictionIntent{
-			TxId:        pi.TxID.String(),
-			Net:         pi.Net,
-			MarketId:    pi.MarketID.String(),
-			GeneratedAt: pi.GeneratedAt.String(),
-			AccountId:   pi.AccountID,
-			MarketLimit: pi.MarketLimit,
-			PriceUsd:    pi.PriceUsd,
-			Qty:         pi.Qty,
+			TxId:         pi.TxID.String(),
+			Net:          pi.Net,
+			MarketId:     pi.MarketID.String(),
+			GeneratedAt:  pi.GeneratedAt.String(),
+			AccountId:    pi.AccountID,
+			MarketLimit:  pi.MarketLimit,
+			PriceUsd:     pi.PriceUsd,
+			Qty:          pi.Qty,
+			TimeInForce:  pi.TimeInForce,
+			ExpiresAt:    lib.FormatExpiresAt(pi.ExpiresAt),
+			QtyFilled:    pi.FilledQty,
+			QtyRemaining: pi.RemainingQty,
 		}
 		if _, ok := response.OpenPredictionIntents[pi.MarketID.String()]; !ok {
 			response.OpenPredictionIntents[pi.MarketID.String()] = &pb_api.PredictionIntents{}
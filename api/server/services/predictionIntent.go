@@ -2,7 +2,8 @@ package services
 
 import (
 	"context"
-	"encoding/json"
+	"database/sql"
+	"errors"
 	"fmt"
 	"math"
 	"os"
@@ -19,7 +20,9 @@ import (
 	"github.com/google/uuid"
 	hiero "github.com/hiero-ledger/hiero-sdk-go/v2/sdk"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 type PredictionIntentsService struct {
@@ -27,18 +30,24 @@ type PredictionIntentsService struct {
 	dbRepository                *repositories.DbRepository
 	marketsRepository           *repositories.MarketsRepository
 	predictionIntentsRepository *repositories.PredictionIntentsRepository
+	matchesRepository           *repositories.MatchesRepository
+	settlementsRepository       *repositories.SettlementsRepository
 
 	natsService   *NatsService
 	hederaService *HederaService
+	riskService   *RiskService
 }
 
-func (pis *PredictionIntentsService) Init(logService *LogService, dbRepository *repositories.DbRepository, marketsRepository *repositories.MarketsRepository, natsService *NatsService, hederaService *HederaService, predictionIntentRepository *repositories.PredictionIntentsRepository) error {
+func (pis *PredictionIntentsService) Init(logService *LogService, dbRepository *repositories.DbRepository, marketsRepository *repositories.MarketsRepository, natsService *NatsService, hederaService *HederaService, predictionIntentRepository *repositories.PredictionIntentsRepository, matchesRepository *repositories.MatchesRepository, settlementsRepository *repositories.SettlementsRepository, riskService *RiskService) error {
 	pis.dbRepository = dbRepository
 	pis.marketsRepository = marketsRepository
 	pis.predictionIntentsRepository = predictionIntentRepository
+	pis.matchesRepository = matchesRepository
+	pis.settlementsRepository = settlementsRepository
 
 	pis.natsService = natsService
 	pis.hederaService = hederaService
+	pis.riskService = riskService
 	pis.log = logService
 
 	pis.log.Log(INFO, "Service: PredictionIntents service initialized successfully, %p", pis)
@@ -46,90 +55,393 @@ func (pis *PredictionIntentsService) Init(logService *LogService, dbRepository *
 	return nil
 }
 
-func (pis *PredictionIntentsService) CreatePredictionIntent(req *pb_api.PredictionIntentRequest) (string, error) {
-	/////
-	// validations
-	/////
-	// Validate account ID format and minimum account number
-	accountId, err := hiero.AccountIDFromString(req.AccountId)
+func (pis *PredictionIntentsService) CreatePredictionIntent(req *pb_api.PredictionIntentRequest) (*pb_api.PredictionIntentResponse, error) {
+	message, err := pis.validatePredictionIntent(req, nil)
 	if err != nil {
-		return "Invalid accountId format", err
+		return rejectedPredictionIntentResponse(req, message, err), err
 	}
 
-	// Validate timestamp is within the last TIMESTAMP_ALLOWED_PAST_SECONDS seconds
-	timestamp, err := time.Parse(time.RFC3339, req.GeneratedAt)
+	return pis.placePredictionIntent(req)
+}
+
+// CreateTriggeredPredictionIntent places a conditional intent whose trigger fired - it runs every CreatePredictionIntent
+// check except the generatedAt window (the intent was signed, and its timestamp checked, when the trigger was set)
+func (pis *PredictionIntentsService) CreateTriggeredPredictionIntent(req *pb_api.PredictionIntentRequest) (*pb_api.PredictionIntentResponse, error) {
+	message, err := pis.checkPredictionIntent(req, time.Now().UTC(), nil)
 	if err != nil {
-		return "", pis.log.Log(ERROR, "invalid timestamp format: %v", err)
+		return rejectedPredictionIntentResponse(req, message, err), err
+	}
+
+	return pis.placePredictionIntent(req)
+}
+
+/*
+CreatePredictionIntents places a batch of signed prediction intents (e.g. a quote ladder) of one account in one market.
+The account, its key and the market are checked once, then every intent on its own (timestamp, txId, signature and risk limits).
+The allowance and balance are checked once against the accepted intents' combined notional, and the accepted intents are
+stored and published to the CLOB together. Returns a result per intent (in request order) - or an error if the whole batch is rejected.
+*/
+func (pis *PredictionIntentsService) CreatePredictionIntents(req *pb_api.PredictionIntentsRequest) (*pb_api.PredictionIntentsResponse, error) {
+	// guards
+	intents := req.PredictionIntents
+	if len(intents) == 0 {
+		return nil, pis.log.Log(ERROR, "empty batch of prediction intents")
+	}
+	first := intents[0]
+	txIds := make(map[string]bool, len(intents))
+	for _, intent := range intents {
+		if intent.AccountId != first.AccountId || intent.PublicKey != first.PublicKey || intent.KeyType != first.KeyType || !strings.EqualFold(intent.EvmAddress, first.EvmAddress) ||
+			!strings.EqualFold(intent.Net, first.Net) || !strings.EqualFold(intent.MarketId, first.MarketId) {
+			return nil, pis.log.Log(ERROR, "every prediction intent of a batch must be for the same account, key, network and market (txId=%s)", intent.TxId)
+		}
+		timeInForce := getTimeInForce(intent)
+		if timeInForce == lib.TIME_IN_FORCE_IOC || timeInForce == lib.TIME_IN_FORCE_FOK {
+			return nil, pis.log.Log(ERROR, "%s prediction intents are matched on arrival and can't be batched (txId=%s) - use CreatePredictionIntent", timeInForce, intent.TxId)
+		}
+		txId := strings.ToLower(intent.TxId)
+		if txIds[txId] {
+			return nil, pis.log.Log(ERROR, "duplicate txId in batch: %s", intent.TxId)
+		}
+		txIds[txId] = true
 	}
 
+	// the account, its key and the market - once for the whole batch
 	now := time.Now().UTC()
-	allowedPastSeconds, err := strconv.Atoi(os.Getenv("TIMESTAMP_ALLOWED_PAST_SECONDS"))
+	account, message, err := pis.checkPredictionIntentAccount(first, now)
 	if err != nil {
-		return "", pis.log.Log(ERROR, "invalid TIMESTAMP_ALLOWED_PAST_SECONDS environment variable: %v", err)
+		if message != "" {
+			return nil, fmt.Errorf("%s: %v", message, err)
+		}
+		return nil, err
 	}
-	allowedFutureSeconds, err := strconv.Atoi(os.Getenv("TIMESTAMP_ALLOWED_FUTURE_SECONDS"))
+
+	// every intent on its own
+	results := make([]*pb_api.PredictionIntentResponse, len(intents))
+	var checked []*pb_api.PredictionIntentRequest
+	var checkedIdx []int
+	for i, intent := range intents {
+		message := ""
+		err := pis.validateGeneratedAt(intent.GeneratedAt, now)
+		if err == nil {
+			message, err = pis.checkPredictionIntentOrder(intent, account, now)
+		}
+		if err != nil {
+			results[i] = rejectedBatchPredictionIntentResponse(intent, message, err)
+			continue
+		}
+		checked = append(checked, intent)
+		checkedIdx = append(checkedIdx, i)
+	}
+
+	// pre-trade risk checks: the limits per intent, then the allowance and balance against the combined notional
+	rejections, err := pis.riskService.CheckPredictionIntents(checked, account.market, account.accountId, account.usdcDecimals)
 	if err != nil {
-		return "", pis.log.Log(ERROR, "invalid TIMESTAMP_ALLOWED_FUTURE_SECONDS environment variable: %v", err)
+		return nil, err
 	}
-	pastDelta := now.Add(-1 * time.Duration(allowedPastSeconds) * time.Second)
-	futureDelta := now.Add(time.Duration(allowedFutureSeconds) * time.Second)
 
-	if timestamp.Before(pastDelta) {
-		return "", pis.log.Log(ERROR, "timestamp is too old: %s", req.GeneratedAt)
+	var accepted []*pb_api.PredictionIntentRequest
+	var acceptedIdx []int
+	for j, intent := range checked {
+		if rejections[j] != nil {
+			results[checkedIdx[j]] = rejectedBatchPredictionIntentResponse(intent, "", rejections[j])
+			continue
+		}
+		accepted = append(accepted, intent)
+		acceptedIdx = append(acceptedIdx, checkedIdx[j])
 	}
 
-	if timestamp.After(futureDelta) {
-		return "", pis.log.Log(ERROR, "timestamp is too far in the future: %s. Now: %s", req.GeneratedAt, now)
+	/// OK
+	if len(accepted) > 0 {
+		// store every accepted intent and its clob outbox message in one db transaction, then wake the relay up once
+		_, err = pis.predictionIntentsRepository.CreateOrderIntentRequestsWithOutbox(accepted)
+		if err != nil {
+			return nil, pis.log.Log(ERROR, "database error: failed to save the batch of %d prediction intents: %v", len(accepted), err)
+		}
+		pis.natsService.NotifyClobOutbox()
 	}
 
-	// check we haven't received this txid previously
-	txUUID, err := uuid.Parse(req.TxId)
+	for j, intent := range accepted {
+		results[acceptedIdx[j]] = &pb_api.PredictionIntentResponse{
+			Message:      fmt.Sprintf("Processed input for user %s", intent.AccountId),
+			TxId:         intent.TxId,
+			TimeInForce:  getTimeInForce(intent),
+			Status:       "accepted", // matching happens asynchronously
+			QtyRemaining: intent.Qty,
+		}
+	}
+
+	pis.log.Log(INFO, "Batch of prediction intents for account %s in market %s: %d of %d accepted", first.AccountId, first.MarketId, len(accepted), len(intents))
+	return &pb_api.PredictionIntentsResponse{Results: results}, nil
+}
+
+// rejectedBatchPredictionIntentResponse - a rejected intent of a batch reports why in its own result (there is no error per intent)
+func rejectedBatchPredictionIntentResponse(req *pb_api.PredictionIntentRequest, message string, err error) *pb_api.PredictionIntentResponse {
+	response := rejectedPredictionIntentResponse(req, message, err)
+	response.Status = "rejected"
+	if response.Message == "" {
+		response.Message = err.Error()
+	}
+	return response
+}
+
+// rejectedPredictionIntentResponse carries the rule code if a pre-trade risk check rejected the intent
+func rejectedPredictionIntentResponse(req *pb_api.PredictionIntentRequest, message string, err error) *pb_api.PredictionIntentResponse {
+	response := &pb_api.PredictionIntentResponse{Message: message, TxId: req.TxId}
+	var rejection *RiskRejection
+	if errors.As(err, &rejection) {
+		response.RuleCode = rejection.RuleCode
+		if response.Message == "" {
+			response.Message = rejection.Message
+		}
+	}
+	return response
+}
+
+// placePredictionIntent puts a validated intent on the CLOB and the db
+func (pis *PredictionIntentsService) placePredictionIntent(req *pb_api.PredictionIntentRequest) (*pb_api.PredictionIntentResponse, error) {
+	// IOC/FOK intents are matched synchronously so the outcome can be reported back
+	timeInForce := getTimeInForce(req)
+	if timeInForce == lib.TIME_IN_FORCE_IOC || timeInForce == lib.TIME_IN_FORCE_FOK {
+		return pis.executePredictionIntent(req, timeInForce)
+	}
+
+	/// OK - All validations passed
+	/// Now you can (attempt to) put the order on the CLOB (subject to on-chain sig verification)
+
+	// store the OrderRequest and its clob outbox message in one db transaction - the txid must be unique or this fails
+	// (and nothing reaches the CLOB)
+	_, err := pis.predictionIntentsRepository.CreateOrderIntentRequestWithOutbox(req)
 	if err != nil {
-		return "", pis.log.Log(ERROR, "invalid txId uuid: %v", err)
+		return &pb_api.PredictionIntentResponse{TxId: req.TxId}, pis.log.Log(ERROR, "database error: failed to save order request: %v", err)
 	}
-	exists, err := pis.dbRepository.IsDuplicateTxId(txUUID)
+
+	/////
+	// notify the CLOB via NATS: the outbox relay publishes it (and retries until NATS acks it)
+	/////
+	pis.natsService.NotifyClobOutbox()
+
+	return &pb_api.PredictionIntentResponse{
+		Message:      fmt.Sprintf("Processed input for user %s", req.AccountId),
+		TxId:         req.TxId,
+		TimeInForce:  timeInForce,
+		Status:       "accepted", // matching happens asynchronously
+		QtyRemaining: req.Qty,
+	}, nil
+}
+
+// executePredictionIntent matches an IOC/FOK intent on the CLOB and reports the outcome - it never rests on the book
+func (pis *PredictionIntentsService) executePredictionIntent(req *pb_api.PredictionIntentRequest, timeInForce string) (*pb_api.PredictionIntentResponse, error) {
+	/////
+	// OK - 3 steps to execute an IOC/FOK prediction intent
+	/////
+
+	// Step 1:
+	// store the intent on the **db** first - matches are settled (asynchronously) from the stored intent
+	_, err := pis.predictionIntentsRepository.CreateOrderIntentRequest(req)
 	if err != nil {
-		return "", pis.log.Log(ERROR, "failed to check existing txId: %v", err)
+		return &pb_api.PredictionIntentResponse{TxId: req.TxId}, pis.log.Log(ERROR, "database error: failed to save order request: %v", err)
 	}
-	if exists {
-		pis.log.Log(WARN, "DUPLICATE txId: %s", req.TxId)
-		return "", fmt.Errorf("duplicate txId: %s", req.TxId)
+
+	// Step 2:
+	// match on the **CLOB** (synchronous)
+	outcome, err := pis.createOrderOnClob(req)
+	if err != nil {
+		switch status.Code(err) {
+		case codes.InvalidArgument, codes.AlreadyExists:
+			// the CLOB rejected it without matching anything - and it can't be resting on the book (IOC/FOK never rest): just close it on the db
+			if cancelErr := pis.predictionIntentsRepository.CancelPredictionIntent(req.TxId); cancelErr != nil {
+				pis.log.Log(ERROR, "failed to cancel prediction intent (txId=%s) after the CLOB rejected it: %v", req.TxId, cancelErr)
+			}
+			return &pb_api.PredictionIntentResponse{TxId: req.TxId, TimeInForce: timeInForce}, err
+		default:
+			// e.g. a timeout or a dropped connection: the CLOB may have matched it before the call failed - leave it open,
+			// its matches (if any) are still recorded and settled from clob.matches.*
+			pis.log.Log(WARN, "%s prediction intent (txId=%s): outcome unknown, left open: %v", strings.ToUpper(timeInForce), req.TxId, err)
+			return &pb_api.PredictionIntentResponse{
+				Message:     fmt.Sprintf("Processed input for user %s: outcome unknown - check GetPredictionIntent", req.AccountId),
+				TxId:        req.TxId,
+				TimeInForce: timeInForce,
+				Status:      "unknown",
+			}, nil
+		}
+	}
+
+	// Step 3:
+	// cancel the unfilled remainder on the **db**
+	if outcome.QtyRemaining > 0 {
+		err = pis.predictionIntentsRepository.CancelPredictionIntent(req.TxId)
+		if err != nil {
+			return &pb_api.PredictionIntentResponse{TxId: req.TxId, TimeInForce: timeInForce}, pis.log.Log(ERROR, "database error: failed to cancel the remainder of %s prediction intent (txId=%s): %v", timeInForce, req.TxId, err)
+		}
+	}
+
+	pis.log.Log(INFO, "%s prediction intent (txId=%s): %s (filled %f of %f)", strings.ToUpper(timeInForce), req.TxId, outcome.Status, outcome.QtyFilled, req.Qty)
+	return &pb_api.PredictionIntentResponse{
+		Message:      fmt.Sprintf("Processed input for user %s: %s", req.AccountId, outcome.Status),
+		TxId:         req.TxId,
+		TimeInForce:  timeInForce,
+		Status:       outcome.Status,
+		QtyFilled:    outcome.QtyFilled,
+		QtyRemaining: outcome.QtyRemaining,
+	}, nil
+}
+
+// getTimeInForce returns the (lowercase) time-in-force of the intent - an empty time-in-force is GTC
+func getTimeInForce(req *pb_api.PredictionIntentRequest) string {
+	timeInForce := strings.ToLower(req.TimeInForce)
+	if timeInForce == "" {
+		return lib.TIME_IN_FORCE_GTC
+	}
+	return timeInForce
+}
+
+// validatePredictionIntent runs every check a new prediction intent must pass (timestamp, txId, market, signature and the pre-trade risk checks)
+// returns a user-facing message (possibly empty) and an error if the intent is rejected.
+// replaced is the open intent the new one replaces (nil if none) - the risk checks don't count it as open.
+func (pis *PredictionIntentsService) validatePredictionIntent(req *pb_api.PredictionIntentRequest, replaced *sqlc.PredictionIntent) (string, error) {
+	// Validate timestamp is within the last TIMESTAMP_ALLOWED_PAST_SECONDS seconds
+	now := time.Now().UTC()
+	err := pis.validateGeneratedAt(req.GeneratedAt, now)
+	if err != nil {
+		return "", err
+	}
+
+	return pis.checkPredictionIntent(req, now, replaced)
+}
+
+// checkPredictionIntent runs every validatePredictionIntent check except the generatedAt window
+func (pis *PredictionIntentsService) checkPredictionIntent(req *pb_api.PredictionIntentRequest, now time.Time, replaced *sqlc.PredictionIntent) (string, error) {
+	account, message, err := pis.checkPredictionIntentAccount(req, now)
+	if err != nil {
+		return message, err
+	}
+
+	message, err = pis.checkPredictionIntentOrder(req, account, now)
+	if err != nil {
+		return message, err
+	}
+
+	// pre-trade risk checks: configurable limits (see: SetRiskLimits), allowance and balance
+	err = pis.riskService.CheckPredictionIntent(req, account.market, account.accountId, account.usdcDecimals, replaced)
+	if err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+// predictionIntentAccount is what checkPredictionIntentAccount looks up (once per account and market)
+type predictionIntentAccount struct {
+	accountId       hiero.AccountID
+	market          *sqlc.Market
+	contractVersion lib.PrismContractVersion // of the market's smart contract - decides the payload the intent is signed over
+	publicKey       hiero.PublicKey
+	usdcDecimals    uint64
+}
+
+// checkPredictionIntentAccount validates the account, network and market of the intent and the public key it was signed with
+func (pis *PredictionIntentsService) checkPredictionIntentAccount(req *pb_api.PredictionIntentRequest, now time.Time) (*predictionIntentAccount, string, error) {
+	/////
+	// validations
+	/////
+	// Validate account ID format and minimum account number
+	accountId, err := hiero.AccountIDFromString(req.AccountId)
+	if err != nil {
+		return nil, "Invalid accountId format", err
 	}
 
 	// validate that the network sent is valid
 	netSelectedByUser := strings.ToLower(req.Net)
 	if !lib.IsValidNetwork(netSelectedByUser) {
-		return "", pis.log.Log(ERROR, "invalid network: %s", req.Net)
+		return nil, "", pis.log.Log(ERROR, "invalid network: %s", req.Net)
+	}
+
+	// look up the market - reject early if it's no longer accepting orders
+	market, err := pis.marketsRepository.GetMarketByIdIncludingSuspended(req.MarketId) // a suspended market gets its own message
+	if err != nil {
+		return nil, "", pis.log.Log(ERROR, "failed to get market by id %s: %v", req.MarketId, err)
+	}
+	if message, isOpen := isMarketOpenForPredictionIntents(market, now); !isOpen {
+		pis.log.Log(WARN, "rejected prediction intent (txId=%s): %s", req.TxId, message)
+		return nil, message, fmt.Errorf("%s", message)
 	}
 
 	// First look up the Hedera accountId against the mirror node
 	publicKeyLookedUp, keyTypeLookedUp, err := pis.hederaService.GetPublicKey(accountId, netSelectedByUser)
 	if err != nil {
-		return "", pis.log.Log(ERROR, "failed to get public key: %v", err)
+		return nil, "", pis.log.Log(ERROR, "failed to get public key: %v", err)
 	}
 	pis.log.Log(INFO, "Mirror node response for account %s on network %s: %s", accountId, netSelectedByUser, publicKeyLookedUp.String())
 
 	// keyType sent from the front-end (no 0x prefix) must match the keyType looked up on the mirror node
 	if !lib.IsValidKeyType(req.KeyType) {
-		return "", pis.log.Log(ERROR, "keyType mismatch: expected %d, got %d", keyTypeLookedUp, req.KeyType)
+		return nil, "", pis.log.Log(ERROR, "keyType mismatch: expected %d, got %d", keyTypeLookedUp, req.KeyType)
 	}
 
 	// public key sent from the front-end (no 0x prefix) must match the public key looked up on the mirror node
 	publicKey, err := hiero.PublicKeyFromString(req.PublicKey)
 	if err != nil {
-		return "", pis.log.Log(ERROR, "failed to parse public key from string: %v", err)
+		return nil, "", pis.log.Log(ERROR, "failed to parse public key from string: %v", err)
 	}
 	if publicKeyLookedUp.String() != publicKey.String() || publicKey.String() == "" {
-		return "", pis.log.Log(ERROR, "public key mismatch: expected %s, got %s", publicKeyLookedUp.String(), publicKey.String())
+		return nil, "", pis.log.Log(ERROR, "public key mismatch: expected %s, got %s", publicKeyLookedUp.String(), publicKey.String())
 	}
 
 	// Now it's safe to proceed with the publicKey passed from the frontend...
 	usdcDecimals, err := strconv.ParseUint(os.Getenv("USDC_DECIMALS"), 10, 64)
 	if err != nil {
-		return "", pis.log.Log(ERROR, "failed to parse USDC_DECIMALS: %v", err)
+		return nil, "", pis.log.Log(ERROR, "failed to parse USDC_DECIMALS: %v", err)
+	}
+
+	contractVersion, err := pis.hederaService.GetPrismContractVersion(market.Net, market.SmartContractID)
+	if err != nil {
+		return nil, "", pis.log.Log(ERROR, "failed to look up the smart contract version of market %s: %v", req.MarketId, err)
+	}
+
+	return &predictionIntentAccount{
+		accountId:       accountId,
+		market:          market,
+		contractVersion: contractVersion,
+		publicKey:       publicKey,
+		usdcDecimals:    usdcDecimals,
+	}, "", nil
+}
+
+// checkPredictionIntentOrder validates the intent itself (time-in-force, txId and signature) against an already checked account
+func (pis *PredictionIntentsService) checkPredictionIntentOrder(req *pb_api.PredictionIntentRequest, account *predictionIntentAccount, now time.Time) (string, error) {
+	// expiresAt is required for GTD (and must be in the future), and not allowed otherwise
+	if getTimeInForce(req) == lib.TIME_IN_FORCE_GTD {
+		expiresAt, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return "A gtd prediction intent needs a valid expiresAt", pis.log.Log(ERROR, "invalid expiresAt for a gtd prediction intent: %v", err)
+		}
+		if !expiresAt.After(now) {
+			return "expiresAt must be in the future", pis.log.Log(ERROR, "expiresAt is in the past: %s. Now: %s", req.ExpiresAt, now)
+		}
+	} else if req.ExpiresAt != "" {
+		return "expiresAt is only allowed for gtd prediction intents", pis.log.Log(ERROR, "expiresAt set on a %s prediction intent (txId=%s)", getTimeInForce(req), req.TxId)
+	}
+
+	// a market on a legacy smart contract can only settle GTC intents (its signed payload has no time-in-force or expiry)
+	if account.contractVersion == lib.PRISM_CONTRACT_VERSION_LEGACY && getTimeInForce(req) != lib.TIME_IN_FORCE_GTC {
+		return "This market only accepts gtc prediction intents", pis.log.Log(WARN, "rejected a %s prediction intent (txId=%s) on market %s - it is on a legacy smart contract", getTimeInForce(req), req.TxId, req.MarketId)
+	}
+
+	// check we haven't received this txid previously
+	txUUID, err := uuid.Parse(req.TxId)
+	if err != nil {
+		return "", pis.log.Log(ERROR, "invalid txId uuid: %v", err)
+	}
+	exists, err := pis.dbRepository.IsDuplicateTxId(txUUID)
+	if err != nil {
+		return "", pis.log.Log(ERROR, "failed to check existing txId: %v", err)
+	}
+	if exists {
+		pis.log.Log(WARN, "DUPLICATE txId: %s", req.TxId)
+		return "", fmt.Errorf("duplicate txId: %s", req.TxId)
 	}
 
-	payloadHex, err := lib.AssemblePayloadHexForSigning(req, usdcDecimals)
+	payloadHex, err := lib.AssemblePayloadHexForSigningForContractVersion(req, account.usdcDecimals, account.contractVersion)
 	if err != nil {
 		return "", pis.log.Log(ERROR, "failed to extract payload for signing: %v", err)
 	}
@@ -137,7 +449,7 @@ func (pis *PredictionIntentsService) CreatePredictionIntent(req *pb_api.Predicti
 	payloadUtf8 := payloadHex // Yes, this is intentional
 	pis.log.Log(INFO, "payloadUtf8: %s", payloadUtf8)
 
-	isValidSig, err := lib.VerifySig(&publicKey, payloadUtf8, req.Sig)
+	isValidSig, err := lib.VerifySig(&account.publicKey, payloadUtf8, req.Sig)
 	if err != nil {
 		return "", pis.log.Log(ERROR, "failed to verify signature: %v", err)
 	}
@@ -147,70 +459,61 @@ func (pis *PredictionIntentsService) CreatePredictionIntent(req *pb_api.Predicti
 	// if we get here, the sig is valid
 	pis.log.Log(INFO, "**Signature is valid for account %s**", req.AccountId)
 
-	// Ensure user has provided enough of an allowance
-	_networkSelected, err := hiero.LedgerIDFromString(netSelectedByUser)
+	return "", nil
+}
+
+// validateGeneratedAt checks that a signed request's generatedAt lies within [now - TIMESTAMP_ALLOWED_PAST_SECONDS, now + TIMESTAMP_ALLOWED_FUTURE_SECONDS]
+func (pis *PredictionIntentsService) validateGeneratedAt(generatedAt string, now time.Time) error {
+	timestamp, err := time.Parse(time.RFC3339, generatedAt)
 	if err != nil {
-		return "", pis.log.Log(ERROR, "failed to get network selected: %v", err)
+		return pis.log.Log(ERROR, "invalid timestamp format: %v", err)
 	}
 
-	// NO, don't use the current X_SMART_CONTRACT_ID loaded from env vars
-	// _smartContractId, err := hiero.ContractIDFromString(os.Getenv(fmt.Sprintf("%s_SMART_CONTRACT_ID", strings.ToUpper(netSelectedByUser))))
-	// if err != nil {
-	// 	return "", pis.log.Log(ERROR, "failed to validate %s_SMART_CONTRACT_ID: %v", strings.ToUpper(netSelectedByUser), err)
-	// }
-	// look up this market's smartContractID in the database
-	market, err := pis.marketsRepository.GetMarketById(req.MarketId)
+	allowedPastSeconds, err := strconv.Atoi(os.Getenv("TIMESTAMP_ALLOWED_PAST_SECONDS"))
 	if err != nil {
-		return "", pis.log.Log(ERROR, "failed to get market by id %s: %v", req.MarketId, err)
+		return pis.log.Log(ERROR, "invalid TIMESTAMP_ALLOWED_PAST_SECONDS environment variable: %v", err)
 	}
-	_smartContractId, err := hiero.ContractIDFromString(market.SmartContractID)
+	allowedFutureSeconds, err := strconv.Atoi(os.Getenv("TIMESTAMP_ALLOWED_FUTURE_SECONDS"))
 	if err != nil {
-		return "", pis.log.Log(ERROR, "failed to validate smart contract ID from market %s: %v", req.MarketId, err)
+		return pis.log.Log(ERROR, "invalid TIMESTAMP_ALLOWED_FUTURE_SECONDS environment variable: %v", err)
 	}
+	pastDelta := now.Add(-1 * time.Duration(allowedPastSeconds) * time.Second)
+	futureDelta := now.Add(time.Duration(allowedFutureSeconds) * time.Second)
 
-	// read USDC address from env var
-	usdcAddress, err := hiero.ContractIDFromString(os.Getenv(fmt.Sprintf("%s_USDC_ADDRESS", strings.ToUpper(netSelectedByUser))))
-	if err != nil {
-		return "", pis.log.Log(ERROR, "failed to validate %s_USDC_ADDRESS: %v", strings.ToUpper(netSelectedByUser), err)
+	if timestamp.Before(pastDelta) {
+		return newRiskRejection(lib.RISK_RULE_TIMESTAMP_WINDOW, pis.log.Log(ERROR, "timestamp is too old: %s", generatedAt))
 	}
 
-	// ensure user has provided enough of an allowance to the smart contract:
-	spenderAllowanceUsd, err := pis.hederaService.GetSpenderAllowanceUsd(*_networkSelected, accountId, _smartContractId, usdcAddress, usdcDecimals)
-	if err != nil {
-		return "", pis.log.Log(ERROR, "failed to get spender allowance: %v", err)
+	if timestamp.After(futureDelta) {
+		return newRiskRejection(lib.RISK_RULE_TIMESTAMP_WINDOW, pis.log.Log(ERROR, "timestamp is too far in the future: %s. Now: %s", generatedAt, now))
 	}
-	pis.log.Log(INFO, "Spender allowance for account %s on contract %s: $%.2f", accountId.String(), _smartContractId.String(), spenderAllowanceUsd)
 
-	if spenderAllowanceUsd < math.Abs(req.GetPriceUsd()*req.GetQty()) {
-		return "", pis.log.Log(ERROR, "Spender allowance ($USD%.2f USD token = %s) too low for this predictionIntent ($USD%.2f)", spenderAllowanceUsd, usdcAddress.String(), req.GetPriceUsd()*req.GetQty())
-	}
+	return nil
+}
+
+// createOrderOnClob places the order on the CLOB via gRPC and returns how it was matched (used for IOC/FOK)
+func (pis *PredictionIntentsService) createOrderOnClob(req *pb_api.PredictionIntentRequest) (*pb_clob.CreateOrderResponse, error) {
+	clobAddr := os.Getenv("CLOB_HOST") + ":" + os.Getenv("CLOB_PORT")
 
-	// ensure the spenderAllowanceUsd is <= usdc balance currently in the user's wallet
-	currentUserBalanceUsdc, err := pis.hederaService.GetUsdcBalanceUsd(*_networkSelected, accountId)
+	conn, err := grpc.NewClient(clobAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
-		return "", pis.log.Log(ERROR, "failed to get user's USDC balance: %v", err)
-	}
-	pis.log.Log(INFO, "Current USDC balance for account %s: $%.2f", accountId.String(), currentUserBalanceUsdc)
-	pis.log.Log(INFO, "Spender allowance for account %s: $%.2f", accountId.String(), spenderAllowanceUsd)
-	if spenderAllowanceUsd <= currentUserBalanceUsdc {
-		// OK
-	} else {
-		if math.Abs(req.PriceUsd)*req.Qty <= currentUserBalanceUsdc {
-			// this is also OK - let's not warn the user that their allowance is higher than their balance
-		} else {
-			return "", pis.log.Log(ERROR, "Spender allowance ($USD%.2f) is greater than than the user's balance ($USD%.2f)", spenderAllowanceUsd, currentUserBalanceUsdc)
-		}
+		// nothing was sent to the CLOB
+		pis.log.Log(ERROR, "failed to create order (marketId=%s, txId=%s) - connect to CLOB gRPC server failed: %v", req.MarketId, req.TxId, err)
+		return nil, status.Errorf(codes.InvalidArgument, "failed to connect to the CLOB: %v", err)
 	}
+	defer conn.Close()
 
-	/// OK - All validations passed
-	/// Now you can (attempt to) put the order on the CLOB (subject to on-chain sig verification)
-
-	/////
-	// notify the CLOB via NATS:
-	/////
+	clobClient := pb_clob.NewClobInternalClient(conn)
+	outcome, err := clobClient.CreateOrder(context.Background(), clobOrderFromPredictionIntentRequest(req))
+	if err != nil {
+		pis.log.Log(ERROR, "failed to create order (marketId=%s, txId=%s) on the CLOB (%s): %v", req.MarketId, req.TxId, clobAddr, err)
+		return nil, err // keep the gRPC status - it tells a rejected order from one whose outcome is unknown
+	}
+	return outcome, nil
+}
 
-	// Marshal the CLOB req: *pb_api.PredictionIntentRequest to JSON
-	clobRequestObj := &pb_clob.CreateOrderRequestClob{
+func clobOrderFromPredictionIntentRequest(req *pb_api.PredictionIntentRequest) *pb_clob.CreateOrderRequestClob {
+	return &pb_clob.CreateOrderRequestClob{
 		TxId:        req.TxId,
 		Net:         req.Net,
 		MarketId:    req.MarketId,
@@ -223,34 +526,636 @@ func (pis *PredictionIntentsService) CreatePredictionIntent(req *pb_api.Predicti
 		PublicKey:   req.PublicKey, // passing extra key info - i) avoid lookups ii) handle situation where user has changed their key
 		EvmAddress:  req.EvmAddress,
 		KeyType:     int32(req.KeyType),
+		TimeInForce: getTimeInForce(req),
+		ExpiresAt:   req.ExpiresAt, // signed - needed for signature validation
+	}
+}
+
+// isMarketOpenForPredictionIntents returns a user-facing reason and false if the market can no longer take new orders
+func isMarketOpenForPredictionIntents(market *sqlc.Market, now time.Time) (string, bool) {
+	if market.IsSuspended {
+		return fmt.Sprintf("market %s is suspended - no new predictions are accepted", market.MarketID.String()), false
+	}
+	if market.ResolvedAt.Valid {
+		return fmt.Sprintf("market %s has been resolved - no new predictions are accepted", market.MarketID.String()), false
+	}
+	if market.VoidedAt.Valid {
+		return fmt.Sprintf("market %s has been voided - no new predictions are accepted", market.MarketID.String()), false
+	}
+	if market.ClosedAt.Valid || !now.Before(market.ClosesAt) {
+		return fmt.Sprintf("market %s closed at %s - no new predictions are accepted", market.MarketID.String(), market.ClosesAt.UTC().Format(time.RFC3339)), false
 	}
-	clobRequestJSON, err := json.Marshal(clobRequestObj)
+	if market.IsPaused {
+		return fmt.Sprintf("market %s is paused - no new predictions are accepted until it is resumed", market.MarketID.String()), false
+	}
+	return "", true
+}
+
+// CancelPredictionIntent cancels a prediction intent on behalf of the account that placed it.
+// The request must be signed (see: lib.AssembleCancelPayloadHexForSigning) with the key the intent was placed with.
+func (pis *PredictionIntentsService) CancelPredictionIntent(req *pb_api.CancelOrderRequest) (*pb_api.StdResponse, error) {
+	// guards
+	predictionIntent, err := pis.getOpenPredictionIntent(req.TxId, req.MarketId, req.AccountId)
 	if err != nil {
-		return "", pis.log.Log(ERROR, "failed to marshal CLOB request: %v", err)
+		return nil, err
 	}
 
-	// Publish the message to NATS:
-	err = pis.natsService.Publish(lib.SUBJECT_CLOB_ORDERS, clobRequestJSON)
+	err = pis.verifyCancelSig(predictionIntent, req)
 	if err != nil {
-		return "", pis.log.Log(ERROR, "failed to publish to NATS: %v", err)
+		return nil, err
 	}
 
-	pis.log.Log(INFO, "Published order to NATS subject '%s': %s", lib.SUBJECT_CLOB_ORDERS, string(clobRequestJSON))
+	// OK
+	return pis.cancelPredictionIntent(req.MarketId, req.TxId)
+}
 
-	// now store the OrderRequest in the database - the txid must be unique or this fails
-	_, err = pis.predictionIntentsRepository.CreateOrderIntentRequest(req)
+// verifyCancelSig verifies a cancel signature (see: lib.AssembleCancelPayloadHexForSigning) against the public key the intent was placed with
+func (pis *PredictionIntentsService) verifyCancelSig(predictionIntent *sqlc.PredictionIntent, req *pb_api.CancelOrderRequest) error {
+	publicKey, err := hiero.PublicKeyFromString(predictionIntent.PublicKeyHex)
 	if err != nil {
-		return "", pis.log.Log(ERROR, "database error: failed to save order request: %v", err)
+		return pis.log.Log(ERROR, "failed to parse public key from string: %v", err)
 	}
 
-	return fmt.Sprintf("Processed input for user %s", req.AccountId), nil
+	payloadHex, err := lib.AssembleCancelPayloadHexForSigning(req, predictionIntent.Evmaddress)
+	if err != nil {
+		return pis.log.Log(ERROR, "failed to extract cancel payload for signing: %v", err)
+	}
+
+	// N.B. treat the hex string as a Utf8 string - same as for prediction intents
+	isValidSig, err := lib.VerifySig(&publicKey, payloadHex, req.Sig)
+	if err != nil {
+		return pis.log.Log(ERROR, "failed to verify signature: %v", err)
+	}
+	if !isValidSig {
+		return pis.log.Log(ERROR, "invalid signature for account %s", req.AccountId)
+	}
+	return nil
 }
 
-func (pis *PredictionIntentsService) CancelPredictionIntent(marketId string, txId string) (*pb_api.StdResponse, error) {
+// CancelAllPredictionIntents cancels every open prediction intent of an account, optionally scoped to a market and/or a side.
+// The request must be signed (see: lib.AssembleCancelAllPayloadHexForSigning) with the key the intents were placed with;
+// intents placed with a different key are reported as not cancelled. The CLOB is hit once per market, not once per order.
+func (pis *PredictionIntentsService) CancelAllPredictionIntents(req *pb_api.CancelAllPredictionIntentsRequest) (*pb_api.CancelAllPredictionIntentsResponse, error) {
 	// guards
+	err := pis.validateGeneratedAt(req.GeneratedAt, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	payloadHex, err := lib.AssembleCancelAllPayloadHexForSigning(req)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to extract cancel all payload for signing: %v", err)
+	}
+
+	predictionIntents, err := pis.predictionIntentsRepository.GetAllOpenPredictionIntentsByEvmAddress(req.EvmAddress)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to get open prediction intents for evm address %s: %v", req.EvmAddress, err)
+	}
+
+	response := &pb_api.CancelAllPredictionIntentsResponse{}
+	txIdsByMarket := make(map[string][]uuid.UUID)
+	isValidSigByKey := make(map[string]bool) // the same key usually signed all of an account's intents - verify once per key
+
+	for _, pi := range predictionIntents {
+		marketId := pi.MarketID.String()
+		if pi.AccountID != req.AccountId {
+			continue
+		}
+		if req.MarketId != "" && !strings.EqualFold(marketId, req.MarketId) {
+			continue
+		}
+		if (req.Side == "buy" && pi.PriceUsd < 0) || (req.Side == "sell" && pi.PriceUsd >= 0) {
+			continue
+		}
+
+		// verify the signature against the public key the intent was placed with
+		isValidSig, ok := isValidSigByKey[pi.PublicKeyHex]
+		if !ok {
+			publicKey, err := hiero.PublicKeyFromString(pi.PublicKeyHex)
+			if err == nil {
+				// N.B. treat the hex string as a Utf8 string - same as for prediction intents
+				isValidSig, err = lib.VerifySig(&publicKey, payloadHex, req.Sig)
+			}
+			if err != nil {
+				pis.log.Log(WARN, "failed to verify cancel all signature against public key %s: %v", pi.PublicKeyHex, err)
+			}
+			isValidSigByKey[pi.PublicKeyHex] = isValidSig
+		}
+		if !isValidSig {
+			response.Results = append(response.Results, &pb_api.CancelResult{
+				TxId:     pi.TxID.String(),
+				MarketId: marketId,
+				Message:  "invalid signature for the key this prediction intent was placed with",
+			})
+			continue
+		}
+
+		txIdsByMarket[marketId] = append(txIdsByMarket[marketId], pi.TxID)
+	}
+
+	if len(txIdsByMarket) == 0 {
+		return response, nil
+	}
 
 	// OK
+	clobAddr := os.Getenv("CLOB_HOST") + ":" + os.Getenv("CLOB_PORT")
+	conn, err := grpc.NewClient(clobAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to cancel all prediction intents - connect to CLOB gRPC server failed: %v", err)
+	}
+	defer conn.Close()
+	clobClient := pb_clob.NewClobInternalClient(conn)
+
+	for marketId, txIds := range txIdsByMarket {
+		/////
+		// OK - 2 steps to cancel a market's worth of prediction intents
+		/////
+
+		// Step 1:
+		// pull the orders from the **CLOB** in one round-trip
+		clobTxIds := make([]string, len(txIds))
+		for i, txId := range txIds {
+			clobTxIds[i] = txId.String()
+		}
+		_, err := clobClient.CancelOrders(
+			context.Background(),
+			&pb_clob.CancelOrdersRequest{
+				MarketId: marketId,
+				TxIds:    clobTxIds,
+			},
+		)
+		if err != nil {
+			pis.log.Log(ERROR, "failed to cancel %d orders (marketId=%s) on the CLOB (%s): %v", len(txIds), marketId, clobAddr, err)
+			for _, txId := range clobTxIds {
+				response.Results = append(response.Results, &pb_api.CancelResult{
+					TxId:     txId,
+					MarketId: marketId,
+					Message:  "failed to cancel the order on the CLOB",
+				})
+			}
+			continue
+		}
+
+		// Step 2:
+		// mark them as cancelled on the **db** - an intent matched in the meantime is no longer open and stays as it is
+		cancelledTxIds, err := pis.predictionIntentsRepository.CancelPredictionIntents(txIds)
+		if err != nil {
+			pis.log.Log(ERROR, "database error: failed to cancel %d prediction intents (marketId=%s): %v", len(txIds), marketId, err)
+		}
+		isCancelled := make(map[uuid.UUID]bool, len(cancelledTxIds))
+		for _, txId := range cancelledTxIds {
+			isCancelled[txId] = true
+		}
+
+		for _, txId := range txIds {
+			result := &pb_api.CancelResult{
+				TxId:        txId.String(),
+				MarketId:    marketId,
+				IsCancelled: isCancelled[txId],
+			}
+			if err != nil {
+				result.Message = "pulled from the CLOB but failed to cancel in the database"
+			} else if !result.IsCancelled {
+				result.Message = "no longer open"
+			} else {
+				response.NCancelled++
+			}
+			response.Results = append(response.Results, result)
+		}
+	}
+
+	return response, nil
+}
+
+// ReplacePredictionIntent atomically amends an open prediction intent: the new intent is validated once (the old one no longer counting as open),
+// the old order is pulled from the CLOB, both rows (and the new order's clob outbox message) are updated in one db transaction
+// and the outbox relay publishes the new order. If the db step fails, the old order goes back on the CLOB.
+// The old intent's cancellation must be signed (see: lib.AssembleCancelPayloadHexForSigning) with the key it was placed with -
+// the new intent's signature does not cover replaces_tx_id, so on its own it could be attached to any open intent of the account.
+func (pis *PredictionIntentsService) ReplacePredictionIntent(req *pb_api.ReplacePredictionIntentRequest) (string, error) {
+	// guards
+	replaced, err := pis.getOpenPredictionIntent(req.ReplacesTxId, req.Intent.MarketId, req.Intent.AccountId)
+	if err != nil {
+		return "", err
+	}
+
+	err = pis.verifyCancelSig(replaced, &pb_api.CancelOrderRequest{
+		MarketId:  req.Intent.MarketId,
+		TxId:      req.ReplacesTxId,
+		AccountId: req.Intent.AccountId,
+		Sig:       req.ReplacesSig,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if timeInForce := getTimeInForce(req.Intent); timeInForce != lib.TIME_IN_FORCE_GTC && timeInForce != lib.TIME_IN_FORCE_GTD {
+		return "", pis.log.Log(ERROR, "a %s prediction intent cannot replace an open prediction intent (gtc or gtd only)", timeInForce)
+	}
+
+	message, err := pis.validatePredictionIntent(req.Intent, replaced)
+	if err != nil {
+		return message, err
+	}
+
+	/////
+	// OK - 3 steps to replace a prediction intent
+	/////
+
+	// Step 1:
+	// pull the old order from the **CLOB**
+	err = pis.cancelOrderOnClob(req.Intent.MarketId, req.ReplacesTxId)
+	if err != nil {
+		return "", err
+	}
+
+	// Step 2:
+	// cancel the old row and insert the new one (linked via replaces_tx_id) and its clob outbox message on the **db**
+	_, err = pis.predictionIntentsRepository.ReplacePredictionIntent(req.ReplacesTxId, req.Intent)
+	if err != nil {
+		// the old intent is still open on the db - put it back on the CLOB (with whatever it has left)
+		if restoreErr := pis.restoreOrderOnClob(replaced.TxID); restoreErr != nil {
+			pis.log.Log(ERROR, "PROBLEM: old order (txId=%s) is open on the db but not on the CLOB: %v", req.ReplacesTxId, restoreErr)
+		}
+		return "", pis.log.Log(ERROR, "failed to replace order (txId=%s) by txId=%s on the db - the old order stays: %v", req.ReplacesTxId, req.Intent.TxId, err)
+	}
+
+	// Step 3:
+	// put the new order on the **CLOB** straight away (via the outbox relay) to keep the quote gap as short as possible
+	pis.natsService.NotifyClobOutbox()
+
+	return fmt.Sprintf("Replaced order intent with txId: %s by txId: %s", req.ReplacesTxId, req.Intent.TxId), nil
+}
+
+// restoreOrderOnClob puts an intent pulled from the CLOB back on it if it is still open on the db (re-read: it may have changed since)
+func (pis *PredictionIntentsService) restoreOrderOnClob(txId uuid.UUID) error {
+	predictionIntent, err := pis.predictionIntentsRepository.GetPredictionIntentByTxId(txId)
+	if err != nil {
+		return pis.log.Log(ERROR, "failed to get prediction intent (txId=%s): %v", txId.String(), err)
+	}
+	if getPredictionIntentState(predictionIntent) != lib.PREDICTION_INTENT_STATE_OPEN || predictionIntent.RemainingQty <= 0 {
+		pis.log.Log(INFO, "prediction intent (txId=%s) is no longer open - not restoring it on the CLOB", txId.String())
+		return nil
+	}
+
+	err = pis.natsService.PublishRestoredOrder(predictionIntent)
+	if err != nil {
+		return err
+	}
+	pis.log.Log(INFO, "Restored order (txId=%s) on the CLOB with qty %f", txId.String(), predictionIntent.RemainingQty)
+	return nil
+}
+
+// GetPredictionIntent returns the lifecycle state and fill history of a prediction intent
+func (pis *PredictionIntentsService) GetPredictionIntent(req *pb_api.GetPredictionIntentRequest) (*pb_api.PredictionIntentStatus, error) {
+	txUUID, err := uuid.Parse(req.TxId)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "invalid txId uuid: %v", err)
+	}
 
+	predictionIntent, err := pis.predictionIntentsRepository.GetPredictionIntentByTxId(txUUID)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to get prediction intent (txId=%s): %v", req.TxId, err)
+	}
+
+	statuses, err := pis.getPredictionIntentStatuses([]sqlc.PredictionIntent{*predictionIntent})
+	if err != nil {
+		return nil, err
+	}
+	return statuses[0], nil
+}
+
+// ListPredictionIntents lists prediction intents (newest first), optionally filtered by account, market and state
+func (pis *PredictionIntentsService) ListPredictionIntents(req *pb_api.ListPredictionIntentsRequest) (*pb_api.PredictionIntentStatusesResponse, error) {
+	predictionIntents, err := pis.predictionIntentsRepository.GetPredictionIntents(req)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to list prediction intents: %v", err)
+	}
+
+	statuses, err := pis.getPredictionIntentStatuses(predictionIntents)
+	if err != nil {
+		return nil, err
+	}
+	return &pb_api.PredictionIntentStatusesResponse{PredictionIntents: statuses}, nil
+}
+
+// GetSettlements returns the on-chain settlement state of a match and/or of every match an intent took part in
+func (pis *PredictionIntentsService) GetSettlements(req *pb_api.GetSettlementsRequest) (*pb_api.SettlementsResponse, error) {
+	// guards
+	if req.MatchId == nil && req.TxId == nil {
+		return nil, pis.log.Log(WARN, "either matchId or txId is required")
+	}
+
+	settlements, err := pis.settlementsRepository.GetSettlements(req.MatchId, req.TxId, nil)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to get settlements: %v", err)
+	}
+
+	// OK
+	response := &pb_api.SettlementsResponse{Settlements: []*pb_api.Settlement{}}
+	for _, settlement := range settlements {
+		response.Settlements = append(response.Settlements, mapSettlement(&settlement))
+	}
+	return response, nil
+}
+
+// ListDeadLetteredSettlements returns the settlements waiting for an admin to retry or abandon them (oldest first)
+func (pis *PredictionIntentsService) ListDeadLetteredSettlements() (*pb_api.SettlementsResponse, error) {
+	status := lib.SETTLEMENT_STATUS_DEAD_LETTERED
+	settlements, err := pis.settlementsRepository.GetSettlements(nil, nil, &status)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to get dead-lettered settlements: %v", err)
+	}
+
+	response := &pb_api.SettlementsResponse{Settlements: []*pb_api.Settlement{}}
+	for _, settlement := range settlements {
+		response.Settlements = append(response.Settlements, mapSettlement(&settlement))
+	}
+	return response, nil
+}
+
+// RetrySettlement makes one more attempt at a failed or dead-lettered settlement - the outcome is on the settlement returned
+func (pis *PredictionIntentsService) RetrySettlement(req *pb_api.SettlementRequest) (*pb_api.Settlement, error) {
+	match, err := pis.matchesRepository.GetMatchById(req.MatchId)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to get match %d: %v", req.MatchId, err)
+	}
+
+	err = pis.natsService.RetrySettlement(match)
+	if err != nil {
+		return nil, err
+	}
+
+	return pis.getSettlement(req.MatchId)
+}
+
+/*
+*
+Give up on a dead-lettered settlement: its matched qty is given back to both intents,
+so each intent still live is put back on the CLOB with the qty it now has left.
+Refused unless its last transaction (if any) provably did not execute (see: ReconcileSettlementTx).
+*/
+func (pis *PredictionIntentsService) AbandonSettlement(req *pb_api.SettlementRequest) (*pb_api.AbandonSettlementResponse, error) {
+	match, err := pis.matchesRepository.GetMatchById(req.MatchId)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to get match %d: %v", req.MatchId, err)
+	}
+
+	// its last transaction may have settled it after all - then its qty must not be re-queued
+	isResubmittable, err := pis.natsService.ReconcileSettlementTx(match)
+	if err != nil {
+		return nil, err
+	}
+	if !isResubmittable {
+		return nil, pis.log.Log(WARN, "settlement of match %d went through on-chain - confirmed it instead of abandoning it", req.MatchId)
+	}
+
+	isAbandoned, predictionIntents, err := pis.settlementsRepository.AbandonSettlement(req.MatchId)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to abandon the settlement of match %d: %v", req.MatchId, err)
+	}
+	if !isAbandoned {
+		return nil, pis.log.Log(WARN, "settlement of match %d is not dead-lettered - not abandoning it", req.MatchId)
+	}
+
+	// OK
+	response := &pb_api.AbandonSettlementResponse{RequeuedTxIds: []string{}}
+	for i := range predictionIntents {
+		txId := predictionIntents[i].TxID.String()
+		isRequeued, err := pis.requeuePredictionIntent(&predictionIntents[i], req.MatchId)
+		if err != nil {
+			pis.log.Log(ERROR, "failed to requeue prediction intent (txId=%s) of abandoned match %d: %v", txId, req.MatchId, err)
+			continue
+		}
+		if isRequeued {
+			response.RequeuedTxIds = append(response.RequeuedTxIds, txId)
+		}
+	}
+
+	response.Settlement, err = pis.getSettlement(req.MatchId)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// requeuePredictionIntent puts a gtc/gtd intent back on the CLOB with its remaining qty - false if it is closed or has nothing left
+func (pis *PredictionIntentsService) requeuePredictionIntent(predictionIntent *sqlc.PredictionIntent, matchId int32) (bool, error) {
+	txId := predictionIntent.TxID.String()
+
+	// ioc/fok never rest on the book
+	if predictionIntent.TimeInForce == lib.TIME_IN_FORCE_IOC || predictionIntent.TimeInForce == lib.TIME_IN_FORCE_FOK {
+		return false, nil
+	}
+	if predictionIntent.ExpiresAt.Valid && !predictionIntent.ExpiresAt.Time.After(time.Now()) {
+		return false, nil
+	}
+	if predictionIntent.RemainingQty <= 0 || getPredictionIntentState(predictionIntent) != lib.PREDICTION_INTENT_STATE_OPEN {
+		return false, nil
+	}
+
+	// a partially filled intent is still resting on the book with less than it now has left
+	err := pis.cancelOrderOnClob(predictionIntent.MarketID.String(), txId)
+	if err != nil {
+		pis.log.Log(INFO, "prediction intent (txId=%s) was not resting on the CLOB - requeueing it", txId)
+	}
+
+	err = pis.natsService.PublishRequeuedOrder(predictionIntent, predictionIntent.RemainingQty, matchId)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (pis *PredictionIntentsService) getSettlement(matchId int32) (*pb_api.Settlement, error) {
+	settlements, err := pis.settlementsRepository.GetSettlements(&matchId, nil, nil)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to get the settlement of match %d: %v", matchId, err)
+	}
+	if len(settlements) == 0 {
+		return nil, pis.log.Log(ERROR, "no settlement for match %d", matchId)
+	}
+	return mapSettlement(&settlements[0]), nil
+}
+
+func mapSettlement(settlement *sqlc.GetSettlementsRow) *pb_api.Settlement {
+	return &pb_api.Settlement{
+		MatchId:        settlement.MatchID,
+		MarketId:       settlement.MarketID.String(),
+		TxId1:          settlement.TxId1.String(),
+		TxId2:          settlement.TxId2.String(),
+		Status:         settlement.Status,
+		HederaTxId:     settlement.HederaTxID.String,
+		ReceiptStatus:  settlement.ReceiptStatus.String,
+		GasUsed:        uint64(settlement.GasUsed.Int64),
+		Error:          settlement.Error.String,
+		Attempts:       settlement.Attempts,
+		IsHeld:         settlement.HeldAt.Valid,
+		CreatedAt:      settlement.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:      settlement.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		NextAttemptAt:  formatNullTime(settlement.NextAttemptAt),
+		DeadLetteredAt: formatNullTime(settlement.DeadLetteredAt),
+		AbandonedAt:    formatNullTime(settlement.AbandonedAt),
+	}
+}
+
+// getPredictionIntentStatuses attaches the fills (one matches query and one settlements query for all of them) to each prediction intent
+func (pis *PredictionIntentsService) getPredictionIntentStatuses(predictionIntents []sqlc.PredictionIntent) ([]*pb_api.PredictionIntentStatus, error) {
+	statuses := []*pb_api.PredictionIntentStatus{}
+	if len(predictionIntents) == 0 {
+		return statuses, nil
+	}
+
+	txIds := make([]uuid.UUID, len(predictionIntents))
+	for i, pi := range predictionIntents {
+		txIds[i] = pi.TxID
+	}
+	matches, err := pis.matchesRepository.GetMatchesByTxIds(txIds)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to get matches for %d prediction intents: %v", len(txIds), err)
+	}
+	matchIds := make([]int32, len(matches))
+	for i, match := range matches {
+		matchIds[i] = match.ID
+	}
+	settlements, err := pis.settlementsRepository.GetSettlementsByMatchIds(matchIds)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to get settlements for %d matches: %v", len(matchIds), err)
+	}
+
+	for _, pi := range predictionIntents {
+		status := &pb_api.PredictionIntentStatus{
+			TxId:           pi.TxID.String(),
+			MarketId:       pi.MarketID.String(),
+			AccountId:      pi.AccountID,
+			Net:            pi.Net,
+			State:          getPredictionIntentState(&pi),
+			PriceUsd:       pi.PriceUsd,
+			Qty:            pi.Qty,
+			TimeInForce:    pi.TimeInForce,
+			ExpiresAt:      lib.FormatExpiresAt(pi.ExpiresAt),
+			CreatedAt:      pi.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			CancelledAt:    formatNullTime(pi.CancelledAt),
+			FullyMatchedAt: formatNullTime(pi.FullyMatchedAt),
+			EvictedAt:      formatNullTime(pi.EvictedAt),
+			ExpiredAt:      formatNullTime(pi.ExpiredAt),
+			RegeneratedAt:  formatNullTime(pi.RegeneratedAt),
+		}
+		if pi.ReplacesTxID.Valid {
+			status.ReplacesTxId = pi.ReplacesTxID.UUID.String()
+		}
+		if pi.EvictedAt.Valid {
+			status.EvictionReason = pi.EvictionReason.String
+		}
+
+		// each side settles at the price it signed, so every fill is at |price_usd|
+		priceUsdAbs := math.Abs(pi.PriceUsd)
+		var qtySettled, notionalUsd float64
+		for _, match := range matches {
+			var counterpartyTxId uuid.UUID
+			var feeUsd float64
+			switch pi.TxID {
+			case match.TxId1:
+				counterpartyTxId = match.TxId2
+				feeUsd = match.FeeUsd1
+			case match.TxId2:
+				counterpartyTxId = match.TxId1
+				feeUsd = match.FeeUsd2
+			default:
+				continue
+			}
+
+			fill := &pb_api.Fill{
+				CounterpartyTxId: counterpartyTxId.String(),
+				Qty:              match.MatchedQty, // every matched YES/NO pair is backed by $1 (same as the volume_24h sort)
+				PriceUsd:         priceUsdAbs,
+				IsHeld:           match.HeldAt.Valid,
+				CreatedAt:        match.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+				FeeUsd:           feeUsd,
+				IsMaker:          match.MakerTxID.Valid && match.MakerTxID.UUID == pi.TxID,
+				MatchId:          match.ID,
+			}
+			if settlement, ok := settlements[match.ID]; ok {
+				fill.SettlementStatus = settlement.Status
+			}
+			if match.TxHash != lib.MATCH_TX_HASH_NOT_YET_AVAILABLE {
+				fill.TxHash = match.TxHash
+			}
+
+			status.Fills = append(status.Fills, fill)
+			if fill.SettlementStatus == lib.SETTLEMENT_STATUS_ABANDONED {
+				continue // never settled - its qty was requeued
+			}
+			qtySettled += fill.Qty
+			status.FeesUsd += fill.FeeUsd
+			notionalUsd += fill.Qty * fill.PriceUsd
+		}
+
+		if qtySettled > 0 {
+			status.AvgFillPriceUsd = notionalUsd / qtySettled
+		}
+		// filled/remaining qty are kept on the intent with every match (see: MatchesRepository.CreateMatch)
+		status.QtyFilled = pi.FilledQty
+		if status.State == lib.PREDICTION_INTENT_STATE_OPEN {
+			status.QtyRemaining = pi.RemainingQty
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// getPredictionIntentState - keep the precedence in sync with the GetPredictionIntents query (an evicted intent is also cancelled)
+func getPredictionIntentState(pi *sqlc.PredictionIntent) string {
+	switch {
+	case pi.FullyMatchedAt.Valid:
+		return lib.PREDICTION_INTENT_STATE_FILLED
+	case pi.EvictedAt.Valid:
+		return lib.PREDICTION_INTENT_STATE_EVICTED
+	case pi.ExpiredAt.Valid:
+		return lib.PREDICTION_INTENT_STATE_EXPIRED
+	case pi.CancelledAt.Valid:
+		return lib.PREDICTION_INTENT_STATE_CANCELLED
+	default:
+		return lib.PREDICTION_INTENT_STATE_OPEN
+	}
+}
+
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// getOpenPredictionIntent returns the prediction intent if it belongs to the account and market given and is still open
+func (pis *PredictionIntentsService) getOpenPredictionIntent(txId string, marketId string, accountId string) (*sqlc.PredictionIntent, error) {
+	txUUID, err := uuid.Parse(txId)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "invalid txId uuid: %v", err)
+	}
+
+	predictionIntent, err := pis.predictionIntentsRepository.GetPredictionIntentByTxId(txUUID)
+	if err != nil {
+		return nil, pis.log.Log(ERROR, "failed to get prediction intent (txId=%s): %v", txId, err)
+	}
+
+	if !strings.EqualFold(predictionIntent.MarketID.String(), marketId) {
+		return nil, pis.log.Log(ERROR, "prediction intent (txId=%s) does not belong to market %s", txId, marketId)
+	}
+	if predictionIntent.AccountID != accountId {
+		return nil, pis.log.Log(ERROR, "prediction intent (txId=%s) does not belong to account %s", txId, accountId)
+	}
+	if predictionIntent.CancelledAt.Valid || predictionIntent.FullyMatchedAt.Valid || predictionIntent.EvictedAt.Valid || predictionIntent.ExpiredAt.Valid {
+		return nil, pis.log.Log(ERROR, "prediction intent (txId=%s) is no longer open", txId)
+	}
+
+	return predictionIntent, nil
+}
+
+// cancelPredictionIntent cancels without any signature check - internal callers only (e.g. the cron job evicting unfunded intents)
+func (pis *PredictionIntentsService) cancelPredictionIntent(marketId string, txId string) (*pb_api.StdResponse, error) {
 	// 1. Mark the position as cancelled in the database
 	// - prediction_intents: set the cancelled_at timestamp
 	// 2. Remove the order from the CLOB
@@ -280,12 +1185,55 @@ func (pis *PredictionIntentsService) CancelPredictionIntent(marketId string, txI
 
 	// log.Printf("Published cancel order to NATS subject '%s': %s", lib.NATS_CLOB_CANCEL_ORDERS, string(cancelRequestJSON))
 
+	err = pis.cancelOrderOnClob(marketId, txId)
+	if err != nil {
+		return nil, err
+	}
+
+	// OK if we got here:
+	response := &pb_api.StdResponse{
+		Message: fmt.Sprintf("Cancelled order intent with txId: %s", txId),
+	}
+	return response, nil
+}
+
+// ExpirePredictionIntent expires a GTD prediction intent past its expires_at (called by the cron job)
+func (pis *PredictionIntentsService) ExpirePredictionIntent(predictionIntent *sqlc.PredictionIntent) error {
+	txId := predictionIntent.TxID.String()
+	marketId := predictionIntent.MarketID.String()
+
+	/////
+	// OK - 2 steps to expire a prediction intent
+	/////
+
+	// Step 1:
+	// mark it as expired on the **db**
+	isExpired, err := pis.predictionIntentsRepository.MarkPredictionIntentAsExpired(predictionIntent.TxID)
+	if err != nil {
+		return pis.log.Log(ERROR, "failed to mark prediction intent (txId=%s) as expired: %v", txId, err)
+	}
+	if !isExpired {
+		return nil // matched, cancelled or evicted in the meantime
+	}
+
+	// Step 2:
+	// pull it from the **CLOB** - best effort: the CLOB never matches an expired order and drops it on the next match anyway
+	err = pis.cancelOrderOnClob(marketId, txId)
+	if err != nil {
+		pis.log.Log(WARN, "expired prediction intent (txId=%s) could not be pulled from the CLOB (already gone?): %v", txId, err)
+	}
+
+	pis.log.Log(INFO, "Expired prediction intent (txId=%s, expiresAt=%s)", txId, predictionIntent.ExpiresAt.Time.UTC().Format(time.RFC3339))
+	return nil
+}
+
+func (pis *PredictionIntentsService) cancelOrderOnClob(marketId string, txId string) error {
 	// TODO - use NATS
 	clobAddr := os.Getenv("CLOB_HOST") + ":" + os.Getenv("CLOB_PORT")
 
 	conn, err := grpc.NewClient(clobAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
-		return nil, pis.log.Log(ERROR, "failed to cancel order (marketId=%s, txId=%s) - connect to CLOB gRPC server failed: %v", marketId, txId, err)
+		return pis.log.Log(ERROR, "failed to cancel order (marketId=%s, txId=%s) - connect to CLOB gRPC server failed: %v", marketId, txId, err)
 	}
 	defer conn.Close()
 
@@ -298,14 +1246,9 @@ func (pis *PredictionIntentsService) CancelPredictionIntent(marketId string, txI
 		},
 	)
 	if err != nil {
-		return nil, pis.log.Log(ERROR, "failed to cancel order (marketId=%s, txId=%s) on the CLOB (%s): %v", marketId, txId, clobAddr, err)
-	}
-
-	// OK if we got here:
-	response := &pb_api.StdResponse{
-		Message: fmt.Sprintf("Cancelled order intent with txId: %s", txId),
+		return pis.log.Log(ERROR, "failed to cancel order (marketId=%s, txId=%s) on the CLOB (%s): %v", marketId, txId, clobAddr, err)
 	}
-	return response, nil
+	return nil
 }
 
 func (pis *PredictionIntentsService) GetAllOpenPredictionIntentsByMarketId(marketId string) (*[]sqlc.PredictionIntent, error) {
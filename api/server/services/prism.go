@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	pb_api "api/gen"
 	pb_clob "api/gen/clob"
@@ -103,6 +104,16 @@ func (p *Prism) MacroMetadata() (*pb_api.MacroMetadataResponse, error) {
 		totalVolumeUsd[period] = volume
 	}
 
+	totalFeesUsd := make(map[string]float64)
+	feePeriods := map[string]time.Duration{"1h": time.Hour, "24h": 24 * time.Hour, "7d": 7 * 24 * time.Hour, "30d": 30 * 24 * time.Hour}
+	for period, duration := range feePeriods {
+		fees, err := p.matchesRepository.GetTotalFeesUsdSince(time.Now().Add(-duration))
+		if err != nil {
+			return nil, p.log.Log(ERROR, "failed to get total fees USD for period %s: %v", period, err)
+		}
+		totalFeesUsd[period] = fees
+	}
+
 	nActiveTraders, err := p.dbRepository.GetNumActiveTraders()
 	if err != nil {
 		return nil, p.log.Log(ERROR, "failed to get number of active traders: %v", err)
@@ -120,6 +131,7 @@ func (p *Prism) MacroMetadata() (*pb_api.MacroMetadataResponse, error) {
 		TvlUsd:                      1234567.89,     // TODO - implement real TVL calculation
 		TotalVolumeUsd:              totalVolumeUsd, // TODO - implement a real total volume
 		ActiveTraders:               nActiveTraders,
+		TotalFeesUsd:                totalFeesUsd,
 	}
 
 	return response, nil
@@ -166,33 +178,8 @@ func (p *Prism) TriggerRecreateClob() (bool, error) {
 		for _, predictionIntent := range *allPredictionIntents {
 			p.log.Log(INFO, "\t - txId: %s", predictionIntent.TxID.String())
 
-			// calculate "qtyRemaining" to be placed on CLOB (may not exist)
-			var qtyRemaining float64 = predictionIntent.Qty // set to Qty by default
-
-			allMatches, err := p.matchesRepository.GetAllMatchesForMarketIdTxId(predictionIntent.MarketID, predictionIntent.TxID)
-			p.log.Log(INFO, "\t - allMatches for txId %s on marketId %s: %v", predictionIntent.TxID.String(), predictionIntent.MarketID.String(), allMatches)
-			if err != nil || len(allMatches) == 0 {
-				// no matches for this predictionIntent qty found: qtyRemaining = req.Qty (default)
-				// qtyRemaining is predictionIntent.Qty - OK
-			} else {
-				// we must find the latest qtyRemaining for this txId
-
-				// loop through allMatches
-				// calculate the Qty for predictionIntent.TxID
-				// subtract this Qty from qtyRemaining
-				// at the end of the loop, if qtyRemaining is > 0, continue to add the order to the CLOB
-				// otherwise, don't add anything to the clob
-				for _, match := range allMatches {
-					p.log.Log(INFO, "\t row on 'match': %v", match)
-					// Each match has a Qty field that represents the amount matched for this TxID
-					if match.TxId1 == predictionIntent.TxID {
-						// log.Print("%s", match.Qty1)
-						qtyRemaining -= match.Qty2
-					} else if match.TxId2 == predictionIntent.TxID {
-						qtyRemaining -= match.Qty1
-					}
-				}
-			}
+			// qtyRemaining to be placed on the CLOB - kept on the intent with every match (see: MatchesRepository.CreateMatch)
+			qtyRemaining := predictionIntent.RemainingQty
 
 			if qtyRemaining <= 0 {
 				// All qty has been matched, nothing to restore to CLOB for this predictionIntent
@@ -216,6 +203,8 @@ func (p *Prism) TriggerRecreateClob() (bool, error) {
 				PublicKey:   predictionIntent.PublicKeyHex, // passing extra key info - i) avoid lookups ii) handle situation where user has changed their key
 				EvmAddress:  predictionIntent.Evmaddress,
 				KeyType:     int32(predictionIntent.Keytype),
+				TimeInForce: predictionIntent.TimeInForce,                    // signed - needed for signature validation
+				ExpiresAt:   lib.FormatExpiresAt(predictionIntent.ExpiresAt), // the CLOB rejects it if it expired while the CLOB was down
 			}
 			clobRequestJSON, err := json.Marshal(clobRequestObj)
 			if err != nil {
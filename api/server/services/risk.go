@@ -0,0 +1,508 @@
+package services
+
+import (
+	pb_api "api/gen"
+	sqlc "api/gen/sqlc"
+	"api/server/lib"
+	repositories "api/server/repositories"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	hiero "github.com/hiero-ledger/hiero-sdk-go/v2/sdk"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RiskRejection is returned when a pre-trade risk check rejects a prediction intent.
+// gRPC sends it as FAILED_PRECONDITION with the rule code (lib.RISK_RULE_*) as the reason of an ErrorInfo detail.
+type RiskRejection struct {
+	RuleCode string
+	Message  string
+}
+
+func (rr *RiskRejection) Error() string {
+	return fmt.Sprintf("%s: %s", rr.RuleCode, rr.Message)
+}
+
+func (rr *RiskRejection) GRPCStatus() *status.Status {
+	st := status.New(codes.FailedPrecondition, rr.Error())
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: rr.RuleCode, Domain: "risk"})
+	if err != nil {
+		return st
+	}
+	return detailed
+}
+
+// newRiskRejection tags an error (typically the one returned by LogService.Log) with the rule that rejected the intent
+func newRiskRejection(ruleCode string, err error) *RiskRejection {
+	return &RiskRejection{RuleCode: ruleCode, Message: err.Error()}
+}
+
+// RiskCheckInput is what every risk check sees - the intent has passed the signature check by now
+type RiskCheckInput struct {
+	Req          *pb_api.PredictionIntentRequest
+	Market       *sqlc.Market
+	AccountId    hiero.AccountID
+	Net          string                 // lowercase
+	UsdcDecimals uint64                 // USDC_DECIMALS
+	Limits       *pb_api.RiskLimits     // effective limits: the market's override over the network-wide ones
+	NotionalUsd  float64                // what the funds checks must cover: |priceUsd * qty|, or a batch's combined notional
+	Replaced     *sqlc.PredictionIntent // the open intent this one replaces (see: ReplacePredictionIntent) - not counted as open, nil if none
+
+	exposure            *sqlc.GetOpenPredictionIntentExposureRow // loaded on first use (see: getExposure) - includes a batch's earlier intents
+	batchNotionalUsd    float64                                  // notional of a batch's earlier intents (see: CheckPredictionIntents)
+	spenderAllowanceUsd float64                                  // set by checkAllowance
+}
+
+// RiskCheck is one rule of the pre-trade pipeline: return a *RiskRejection to reject the intent,
+// or any other error if the rule could not be evaluated
+type RiskCheck func(in *RiskCheckInput) error
+
+type RiskService struct {
+	log                         *LogService
+	riskLimitsRepository        *repositories.RiskLimitsRepository
+	predictionIntentsRepository *repositories.PredictionIntentsRepository
+	positionsRepository         *repositories.PositionsRepository
+	hederaService               *HederaService
+	feesService                 *FeesService
+	checks                      []RiskCheck // per intent
+	fundsChecks                 []RiskCheck // once per CheckPredictionIntent(s) call, on the (combined) notional
+}
+
+func (rs *RiskService) Init(log *LogService, riskLimitsRepository *repositories.RiskLimitsRepository, predictionIntentsRepository *repositories.PredictionIntentsRepository, positionsRepository *repositories.PositionsRepository, hederaService *HederaService, feesService *FeesService) error {
+	rs.log = log
+	rs.riskLimitsRepository = riskLimitsRepository
+	rs.predictionIntentsRepository = predictionIntentsRepository
+	rs.positionsRepository = positionsRepository
+	rs.hederaService = hederaService
+	rs.feesService = feesService
+
+	// the pipeline runs in this order - the db-only rules first, the mirror node lookups last
+	rs.checks = []RiskCheck{
+		rs.checkPriceBand,
+		rs.checkMaxOpenIntents,
+		rs.checkMaxPosition,
+		rs.checkMaxNotional,
+		rs.checkNetworkLimit,
+	}
+	rs.fundsChecks = []RiskCheck{
+		rs.checkAllowance,
+		rs.checkBalance, // needs the allowance looked up by checkAllowance
+	}
+
+	rs.log.Log(INFO, "Service: Risk service initialized successfully")
+	return nil
+}
+
+// AddCheck appends a (per intent) rule to the pipeline - only call it while the services are being initialized in main()
+func (rs *RiskService) AddCheck(check RiskCheck) {
+	rs.checks = append(rs.checks, check)
+}
+
+// CheckPredictionIntent runs the pre-trade risk pipeline and stops at the first rule that rejects the intent.
+// replaced is the open intent it replaces (nil if none) - a replace is net-neutral at the limits.
+func (rs *RiskService) CheckPredictionIntent(req *pb_api.PredictionIntentRequest, market *sqlc.Market, accountId hiero.AccountID, usdcDecimals uint64, replaced *sqlc.PredictionIntent) error {
+	net := strings.ToLower(req.Net)
+	riskLimits, err := rs.riskLimitsRepository.GetRiskLimitsForMarket(net, req.MarketId)
+	if err != nil {
+		return rs.log.Log(ERROR, "failed to get risk limits for market %s: %v", req.MarketId, err)
+	}
+
+	in := &RiskCheckInput{
+		Req:          req,
+		Market:       market,
+		AccountId:    accountId,
+		Net:          net,
+		UsdcDecimals: usdcDecimals,
+		Limits:       mergeRiskLimits(net, riskLimits),
+		NotionalUsd:  math.Abs(req.PriceUsd * req.Qty),
+		Replaced:     replaced,
+	}
+	for _, check := range append(rs.checks, rs.fundsChecks...) {
+		err = check(in)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+CheckPredictionIntents runs the pipeline over a batch of one account's intents in one market.
+The per intent rules see the batch's earlier accepted intents as already open; the funds checks run once, on the accepted intents' combined notional.
+Returns a rejection per intent (nil => accepted), or an error if the batch as a whole is rejected.
+*/
+func (rs *RiskService) CheckPredictionIntents(reqs []*pb_api.PredictionIntentRequest, market *sqlc.Market, accountId hiero.AccountID, usdcDecimals uint64) ([]error, error) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	net := strings.ToLower(reqs[0].Net)
+	riskLimits, err := rs.riskLimitsRepository.GetRiskLimitsForMarket(net, reqs[0].MarketId)
+	if err != nil {
+		return nil, rs.log.Log(ERROR, "failed to get risk limits for market %s: %v", reqs[0].MarketId, err)
+	}
+	limits := mergeRiskLimits(net, riskLimits)
+
+	// load the exposure once - it is bumped by every accepted intent
+	exposure, err := rs.predictionIntentsRepository.GetOpenPredictionIntentExposure(reqs[0].AccountId, net, reqs[0].MarketId)
+	if err != nil {
+		return nil, rs.log.Log(ERROR, "failed to get the open prediction intents of account %s: %v", reqs[0].AccountId, err)
+	}
+
+	rejections := make([]error, len(reqs))
+	var accepted *pb_api.PredictionIntentRequest
+	var batchNotionalUsd float64
+	for i, req := range reqs {
+		in := &RiskCheckInput{
+			Req:              req,
+			Market:           market,
+			AccountId:        accountId,
+			Net:              net,
+			UsdcDecimals:     usdcDecimals,
+			Limits:           limits,
+			NotionalUsd:      math.Abs(req.PriceUsd * req.Qty),
+			exposure:         exposure,
+			batchNotionalUsd: batchNotionalUsd,
+		}
+		for _, check := range rs.checks {
+			rejections[i] = check(in)
+			if rejections[i] != nil {
+				break
+			}
+		}
+		if rejections[i] != nil {
+			continue
+		}
+
+		exposure.NOpenInMarket++
+		if req.PriceUsd < 0 {
+			exposure.QtyNoInMarket += req.Qty
+		} else {
+			exposure.QtyYesInMarket += req.Qty
+		}
+		exposure.NotionalUsd += in.NotionalUsd
+		batchNotionalUsd += in.NotionalUsd
+		accepted = req
+	}
+	if accepted == nil { // every intent was rejected - no funds needed
+		return rejections, nil
+	}
+
+	in := &RiskCheckInput{
+		Req:          accepted, // any accepted intent - the funds checks only use its account and market
+		Market:       market,
+		AccountId:    accountId,
+		Net:          net,
+		UsdcDecimals: usdcDecimals,
+		Limits:       limits,
+		NotionalUsd:  batchNotionalUsd,
+	}
+	for _, check := range rs.fundsChecks {
+		err = check(in)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rejections, nil
+}
+
+// SetRiskLimits replaces the risk limits of a network (market_id unset) or of a market
+func (rs *RiskService) SetRiskLimits(req *pb_api.RiskLimits) (*pb_api.RiskLimits, error) {
+	// guards
+	if req.MarketId != nil && (req.MaxNotionalUsdPerAccount != nil || req.MaxOpenNotionalUsd != nil) {
+		return nil, rs.log.Log(ERROR, "maxNotionalUsdPerAccount and maxOpenNotionalUsd are network-wide limits - unset marketId")
+	}
+	if req.MinPriceUsd != nil && req.MaxPriceUsd != nil && *req.MinPriceUsd > *req.MaxPriceUsd {
+		return nil, rs.log.Log(ERROR, "minPriceUsd (%f) is greater than maxPriceUsd (%f)", *req.MinPriceUsd, *req.MaxPriceUsd)
+	}
+
+	// OK
+	riskLimit, err := rs.riskLimitsRepository.SetRiskLimits(req)
+	if err != nil {
+		return nil, rs.log.Log(ERROR, "failed to set risk limits for net %s (marketId=%s): %v", req.Net, req.GetMarketId(), err)
+	}
+	if riskLimit == nil { // no limit set - the scope's limits were removed
+		rs.log.Log(INFO, "Removed the risk limits for net %s (marketId=%s)", req.Net, req.GetMarketId())
+		return &pb_api.RiskLimits{Net: req.Net, MarketId: req.MarketId}, nil
+	}
+
+	rs.log.Log(INFO, "Set the risk limits for net %s (marketId=%s)", req.Net, req.GetMarketId())
+	return mapRiskLimit(riskLimit), nil
+}
+
+func (rs *RiskService) GetRiskLimits() (*pb_api.RiskLimitsResponse, error) {
+	riskLimits, err := rs.riskLimitsRepository.GetRiskLimits()
+	if err != nil {
+		return nil, rs.log.Log(ERROR, "failed to get risk limits: %v", err)
+	}
+
+	response := &pb_api.RiskLimitsResponse{}
+	for _, riskLimit := range riskLimits {
+		response.RiskLimits = append(response.RiskLimits, mapRiskLimit(&riskLimit))
+	}
+	return response, nil
+}
+
+/////
+// rules
+/////
+
+func (rs *RiskService) checkPriceBand(in *RiskCheckInput) error {
+	priceUsdAbs := math.Abs(in.Req.PriceUsd)
+	if in.Limits.MinPriceUsd != nil && priceUsdAbs < *in.Limits.MinPriceUsd {
+		return newRiskRejection(lib.RISK_RULE_PRICE_BAND, rs.log.Log(WARN, "price $USD%.4f is below the market's minimum price ($USD%.4f)", priceUsdAbs, *in.Limits.MinPriceUsd))
+	}
+	if in.Limits.MaxPriceUsd != nil && priceUsdAbs > *in.Limits.MaxPriceUsd {
+		return newRiskRejection(lib.RISK_RULE_PRICE_BAND, rs.log.Log(WARN, "price $USD%.4f is above the market's maximum price ($USD%.4f)", priceUsdAbs, *in.Limits.MaxPriceUsd))
+	}
+	return nil
+}
+
+func (rs *RiskService) checkMaxOpenIntents(in *RiskCheckInput) error {
+	if in.Limits.MaxOpenIntentsPerAccount == nil {
+		return nil
+	}
+
+	exposure, err := rs.getExposure(in)
+	if err != nil {
+		return err
+	}
+	if exposure.NOpenInMarket+1 > *in.Limits.MaxOpenIntentsPerAccount {
+		return newRiskRejection(lib.RISK_RULE_MAX_OPEN_INTENTS, rs.log.Log(WARN, "account %s already has %d open prediction intents in market %s (max %d)", in.Req.AccountId, exposure.NOpenInMarket, in.Req.MarketId, *in.Limits.MaxOpenIntentsPerAccount))
+	}
+	return nil
+}
+
+// checkMaxPosition - a buy adds to the YES side, a sell to the NO side (position tokens are scaled by USDC_DECIMALS)
+func (rs *RiskService) checkMaxPosition(in *RiskCheckInput) error {
+	if in.Limits.MaxPositionQty == nil {
+		return nil
+	}
+
+	exposure, err := rs.getExposure(in)
+	if err != nil {
+		return err
+	}
+
+	positions, err := rs.positionsRepository.GetUserPositionsByMarketId(in.Req.EvmAddress, in.Req.MarketId)
+	if err != nil {
+		return rs.log.Log(ERROR, "failed to get positions of %s in market %s: %v", in.Req.EvmAddress, in.Req.MarketId, err)
+	}
+
+	side := "YES"
+	var nTokens int64
+	openQty := exposure.QtyYesInMarket
+	if in.Req.PriceUsd < 0 {
+		side = "NO"
+		openQty = exposure.QtyNoInMarket
+	}
+	for _, position := range positions {
+		if side == "YES" {
+			nTokens += position.NYes
+		} else {
+			nTokens += position.NNo
+		}
+	}
+	positionQty := float64(nTokens) / math.Pow10(int(in.UsdcDecimals))
+
+	if positionQty+openQty+in.Req.Qty > *in.Limits.MaxPositionQty {
+		return newRiskRejection(lib.RISK_RULE_MAX_POSITION, rs.log.Log(WARN, "%s position of account %s in market %s would be %f (position %f + open %f + new %f) - max %f", side, in.Req.AccountId, in.Req.MarketId, positionQty+openQty+in.Req.Qty, positionQty, openQty, in.Req.Qty, *in.Limits.MaxPositionQty))
+	}
+	return nil
+}
+
+func (rs *RiskService) checkMaxNotional(in *RiskCheckInput) error {
+	if in.Limits.MaxNotionalUsdPerAccount == nil {
+		return nil
+	}
+
+	exposure, err := rs.getExposure(in)
+	if err != nil {
+		return err
+	}
+	notionalUsd := math.Abs(in.Req.PriceUsd * in.Req.Qty)
+	if exposure.NotionalUsd+notionalUsd > *in.Limits.MaxNotionalUsdPerAccount {
+		return newRiskRejection(lib.RISK_RULE_MAX_NOTIONAL, rs.log.Log(WARN, "open notional of account %s on %s would be $USD%.2f (open $USD%.2f + new $USD%.2f) - max $USD%.2f", in.Req.AccountId, in.Net, exposure.NotionalUsd+notionalUsd, exposure.NotionalUsd, notionalUsd, *in.Limits.MaxNotionalUsdPerAccount))
+	}
+	return nil
+}
+
+func (rs *RiskService) checkNetworkLimit(in *RiskCheckInput) error {
+	if in.Limits.MaxOpenNotionalUsd == nil {
+		return nil
+	}
+
+	openNotionalUsd, err := rs.predictionIntentsRepository.GetOpenNotionalUsdByNet(in.Net)
+	if err != nil {
+		return rs.log.Log(ERROR, "failed to get the open notional on %s: %v", in.Net, err)
+	}
+	notionalUsd := math.Abs(in.Req.PriceUsd * in.Req.Qty)
+	openNotionalUsd += in.batchNotionalUsd
+	if in.Replaced != nil {
+		openNotionalUsd -= math.Abs(in.Replaced.PriceUsd) * in.Replaced.RemainingQty
+	}
+	if openNotionalUsd+notionalUsd > *in.Limits.MaxOpenNotionalUsd {
+		return newRiskRejection(lib.RISK_RULE_NETWORK_LIMIT, rs.log.Log(WARN, "open notional on %s would be $USD%.2f - max $USD%.2f", in.Net, openNotionalUsd+notionalUsd, *in.Limits.MaxOpenNotionalUsd))
+	}
+	return nil
+}
+
+// checkAllowance ensures the user has provided enough of an allowance to the market's smart contract
+func (rs *RiskService) checkAllowance(in *RiskCheckInput) error {
+	_networkSelected, err := hiero.LedgerIDFromString(in.Net)
+	if err != nil {
+		return rs.log.Log(ERROR, "failed to get network selected: %v", err)
+	}
+
+	// NO, don't use the current X_SMART_CONTRACT_ID loaded from env vars
+	// use this market's smartContractID from the database
+	_smartContractId, err := hiero.ContractIDFromString(in.Market.SmartContractID)
+	if err != nil {
+		return rs.log.Log(ERROR, "failed to validate smart contract ID from market %s: %v", in.Req.MarketId, err)
+	}
+
+	// read USDC address from env var
+	usdcAddress, err := hiero.ContractIDFromString(os.Getenv(fmt.Sprintf("%s_USDC_ADDRESS", strings.ToUpper(in.Net))))
+	if err != nil {
+		return rs.log.Log(ERROR, "failed to validate %s_USDC_ADDRESS: %v", strings.ToUpper(in.Net), err)
+	}
+
+	spenderAllowanceUsd, err := rs.hederaService.GetSpenderAllowanceUsd(*_networkSelected, in.AccountId, _smartContractId, usdcAddress, in.UsdcDecimals)
+	if err != nil {
+		return rs.log.Log(ERROR, "failed to get spender allowance: %v", err)
+	}
+	rs.log.Log(INFO, "Spender allowance for account %s on contract %s: $%.2f", in.AccountId.String(), _smartContractId.String(), spenderAllowanceUsd)
+	in.spenderAllowanceUsd = spenderAllowanceUsd
+
+	// the settlement also charges the trading fee - allow for the higher of the maker and taker fees
+	feeSchedule, err := rs.feesService.GetEffectiveFeeSchedule(in.Net, in.Req.MarketId)
+	if err != nil {
+		return err
+	}
+	feeBps := math.Max(float64(feeSchedule.GetMakerFeeBps()), float64(feeSchedule.GetTakerFeeBps()))
+	requiredUsd := in.NotionalUsd * (1 + feeBps/lib.FEE_BPS_DENOMINATOR)
+
+	if spenderAllowanceUsd < requiredUsd {
+		return newRiskRejection(lib.RISK_RULE_ALLOWANCE, rs.log.Log(ERROR, "Spender allowance ($USD%.2f USD token = %s) too low for the prediction intent(s) ($USD%.2f including fees)", spenderAllowanceUsd, usdcAddress.String(), requiredUsd))
+	}
+	return nil
+}
+
+// checkBalance ensures the spenderAllowanceUsd is <= usdc balance currently in the user's wallet
+func (rs *RiskService) checkBalance(in *RiskCheckInput) error {
+	_networkSelected, err := hiero.LedgerIDFromString(in.Net)
+	if err != nil {
+		return rs.log.Log(ERROR, "failed to get network selected: %v", err)
+	}
+
+	currentUserBalanceUsdc, err := rs.hederaService.GetUsdcBalanceUsd(*_networkSelected, in.AccountId)
+	if err != nil {
+		return rs.log.Log(ERROR, "failed to get user's USDC balance: %v", err)
+	}
+	rs.log.Log(INFO, "Current USDC balance for account %s: $%.2f", in.AccountId.String(), currentUserBalanceUsdc)
+	rs.log.Log(INFO, "Spender allowance for account %s: $%.2f", in.AccountId.String(), in.spenderAllowanceUsd)
+	if in.spenderAllowanceUsd <= currentUserBalanceUsdc {
+		// OK
+	} else {
+		if in.NotionalUsd <= currentUserBalanceUsdc {
+			// this is also OK - let's not warn the user that their allowance is higher than their balance
+		} else {
+			return newRiskRejection(lib.RISK_RULE_BALANCE, rs.log.Log(ERROR, "Spender allowance ($USD%.2f) is greater than than the user's balance ($USD%.2f)", in.spenderAllowanceUsd, currentUserBalanceUsdc))
+		}
+	}
+	return nil
+}
+
+/////
+// helpers
+/////
+
+// getExposure loads the account's open intents (once per CheckPredictionIntent)
+func (rs *RiskService) getExposure(in *RiskCheckInput) (*sqlc.GetOpenPredictionIntentExposureRow, error) {
+	if in.exposure != nil {
+		return in.exposure, nil
+	}
+
+	exposure, err := rs.predictionIntentsRepository.GetOpenPredictionIntentExposure(in.Req.AccountId, in.Net, in.Req.MarketId)
+	if err != nil {
+		return nil, rs.log.Log(ERROR, "failed to get the open prediction intents of account %s: %v", in.Req.AccountId, err)
+	}
+
+	// the intent being replaced is still open on the db - take it out
+	if in.Replaced != nil {
+		exposure.NOpenInMarket--
+		if in.Replaced.PriceUsd < 0 {
+			exposure.QtyNoInMarket -= in.Replaced.RemainingQty
+		} else {
+			exposure.QtyYesInMarket -= in.Replaced.RemainingQty
+		}
+		exposure.NotionalUsd -= math.Abs(in.Replaced.PriceUsd) * in.Replaced.RemainingQty
+	}
+
+	in.exposure = exposure
+	return exposure, nil
+}
+
+// mergeRiskLimits - the rows come network-wide first, so the market's limits override them
+func mergeRiskLimits(net string, riskLimits []sqlc.RiskLimit) *pb_api.RiskLimits {
+	merged := &pb_api.RiskLimits{Net: net}
+	for _, riskLimit := range riskLimits {
+		mapped := mapRiskLimit(&riskLimit)
+		if mapped.MarketId != nil {
+			merged.MarketId = mapped.MarketId
+		}
+		if mapped.MaxOpenIntentsPerAccount != nil {
+			merged.MaxOpenIntentsPerAccount = mapped.MaxOpenIntentsPerAccount
+		}
+		if mapped.MaxPositionQty != nil {
+			merged.MaxPositionQty = mapped.MaxPositionQty
+		}
+		if mapped.MinPriceUsd != nil {
+			merged.MinPriceUsd = mapped.MinPriceUsd
+		}
+		if mapped.MaxPriceUsd != nil {
+			merged.MaxPriceUsd = mapped.MaxPriceUsd
+		}
+		if mapped.MaxNotionalUsdPerAccount != nil {
+			merged.MaxNotionalUsdPerAccount = mapped.MaxNotionalUsdPerAccount
+		}
+		if mapped.MaxOpenNotionalUsd != nil {
+			merged.MaxOpenNotionalUsd = mapped.MaxOpenNotionalUsd
+		}
+	}
+	return merged
+}
+
+func mapRiskLimit(riskLimit *sqlc.RiskLimit) *pb_api.RiskLimits {
+	mapped := &pb_api.RiskLimits{
+		Net:       riskLimit.Net,
+		UpdatedAt: riskLimit.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	if riskLimit.MarketID.Valid {
+		marketId := riskLimit.MarketID.UUID.String()
+		mapped.MarketId = &marketId
+	}
+	if riskLimit.MaxOpenIntentsPerAccount.Valid {
+		mapped.MaxOpenIntentsPerAccount = &riskLimit.MaxOpenIntentsPerAccount.Int32
+	}
+	if riskLimit.MaxPositionQty.Valid {
+		mapped.MaxPositionQty = &riskLimit.MaxPositionQty.Float64
+	}
+	if riskLimit.MinPriceUsd.Valid {
+		mapped.MinPriceUsd = &riskLimit.MinPriceUsd.Float64
+	}
+	if riskLimit.MaxPriceUsd.Valid {
+		mapped.MaxPriceUsd = &riskLimit.MaxPriceUsd.Float64
+	}
+	if riskLimit.MaxNotionalUsdPerAccount.Valid {
+		mapped.MaxNotionalUsdPerAccount = &riskLimit.MaxNotionalUsdPerAccount.Float64
+	}
+	if riskLimit.MaxOpenNotionalUsd.Valid {
+		mapped.MaxOpenNotionalUsd = &riskLimit.MaxOpenNotionalUsd.Float64
+	}
+	return mapped
+}
@@ -2,6 +2,16 @@ fn main() -> Result<(), Box<dyn std::error::Error>> {
    tonic_prost_build::configure()
         .build_server(true)
         .type_attribute(".", "#[derive(serde::Serialize, serde::Deserialize)]")
+        // the API omits empty fields when publishing orders (e.g. GTC orders have no expires_at)
+        .field_attribute("clob.CreateOrderRequestClob.time_in_force", "#[serde(default)]")
+        .field_attribute("clob.CreateOrderRequestClob.expires_at", "#[serde(default)]")
+        .field_attribute("clob.CreateOrderRequestClob.is_requeue", "#[serde(default)]")
+        .field_attribute("clob.CreateOrderRequestClob.match_seq", "#[serde(default)]")
+        .field_attribute("clob.CreateOrderRequestClob.match_id", "#[serde(default)]")
+        // the API omits empty lists (e.g. nothing to reinstate)
+        .field_attribute("clob.MatchRejection.match_id", "#[serde(default)]")
+        .field_attribute("clob.MatchRejection.evicted_tx_ids", "#[serde(default)]")
+        .field_attribute("clob.MatchRejection.reinstated_orders", "#[serde(default)]")
         // .out_dir("src/gen")
         .compile_protos(
             &["proto/api.proto", "proto/clob.proto"],
@@ -1,3 +1,4 @@
 pub const CLOB_ORDERS: &str = "clob.orders";
 pub const CLOB_MATCHES_FULL: &str = "clob.matches.full";
 pub const CLOB_MATCHES_PARTIAL: &str = "clob.matches.partial";
+pub const CLOB_MATCH_REJECTIONS: &str = "clob.match_rejections";
@@ -6,7 +6,7 @@ use tokio::sync::oneshot;
 use crate::orderbook::OrderBookService;
 use crate::orderbook::proto::clob_internal_server::ClobInternalServer;
 use crate::orderbook::proto::clob_public_server::ClobPublicServer;
-use crate::orderbook::proto::{CreateOrderRequestClob, StdResponse, BookRequest, BookSnapshot, clob_public_server::{ClobPublic}, clob_internal_server::{ClobInternal}};
+use crate::orderbook::proto::{CreateOrderRequestClob, CreateOrderResponse, StdResponse, BookRequest, BookSnapshot, clob_public_server::{ClobPublic}, clob_internal_server::{ClobInternal}};
 // use tonic_reflection::server::Builder as ReflectionBuilder;
 
 #[derive(Debug, Clone)]
@@ -32,20 +32,23 @@ impl ClobInternal for ClobService {
     async fn create_order(
         &self,
         request: Request<CreateOrderRequestClob>,
-    ) -> Result<Response<StdResponse>, Status> {
+    ) -> Result<Response<CreateOrderResponse>, Status> {
         let order = request.into_inner();
-        
-        let result = self.order_book_service.place_order(order).await;
-        if let Err(e) = result {
-            log::error!("Failed to place order: {}", e);
-            return Err(Status::internal(e.to_string()));
+
+        // Check if the order with the same txId already exists
+        if self.order_book_service.order_exists(&order.tx_id).await {
+            log::warn!("Duplicate order txId detected: {}. Order not entered into the orderbook.", order.tx_id);
+            return Err(Status::already_exists(format!("order {} already exists", order.tx_id)));
         }
-        let response = StdResponse {
-            message: "success".to_string(),
-            error_code: 0,
-        };
 
-        Ok(Response::new(response))
+        let result = self.order_book_service.place_order(order).await;
+        match result {
+            Ok(response) => Ok(Response::new(response)),
+            Err(e) => {
+                log::error!("Failed to place order: {}", e);
+                Err(Status::internal(e.to_string()))
+            }
+        }
     }
 
     async fn create_market(
@@ -60,9 +63,36 @@ impl ClobInternal for ClobService {
         
         match result {
             Ok(success) if success => (),
-            _ => {
+            Ok(_) => {
+                // lets the API retry market creation safely
+                return Err(Status::already_exists(format!("market {} already exists", inner.market_id)));
+            }
+            Err(_) => {
                 log::error!("Failed to add market");
-                return Err(Status::internal(format!("WARN: could not add market {}. Does the market already exist?", inner.market_id)));
+                return Err(Status::internal(format!("WARN: could not add market {}", inner.market_id)));
+            }
+        }
+        let response = crate::orderbook::proto::StdResponse {
+            message: "success".to_string(),
+            error_code: 0,
+        };
+
+        Ok(Response::new(response))
+    }
+
+    async fn delete_market(
+        &self,
+        request: Request<crate::orderbook::proto::MarketIdRequest>,
+    ) -> Result<Response<crate::orderbook::proto::StdResponse>, Status> {
+        let inner = request.into_inner();
+
+        let result = self.order_book_service.remove_market(&inner.market_id).await;
+
+        match result {
+            Ok(success) if success => (),
+            _ => {
+                log::error!("Failed to remove market");
+                return Err(Status::not_found(format!("WARN: could not remove market {}. Does the market exist?", inner.market_id)));
             }
         }
         let response = crate::orderbook::proto::StdResponse {
@@ -96,6 +126,26 @@ impl ClobInternal for ClobService {
         Ok(Response::new(response))
     }
 
+    async fn cancel_orders(
+        &self,
+        request: Request<crate::orderbook::proto::CancelOrdersRequest>,
+    ) -> Result<Response<crate::orderbook::proto::CancelOrdersResponse>, Status> {
+        let inner = request.into_inner();
+
+        match self.order_book_service.cancel_orders(&inner.market_id, &inner.tx_ids).await {
+            Ok(cancelled_tx_ids) => {
+                let response = crate::orderbook::proto::CancelOrdersResponse {
+                    cancelled_tx_ids,
+                };
+                Ok(Response::new(response))
+            }
+            Err(e) => {
+                log::error!("Failed to cancel orders: {}", e);
+                Err(Status::not_found(format!("WARN: could not cancel orders for market {}", inner.market_id)))
+            }
+        }
+    }
+
     async fn get_orders_for_user(
         &self,
         request: Request<crate::orderbook::proto::UserRequest>,
@@ -46,6 +46,7 @@ async fn main() -> Result<(), Box<dyn std::error::Error>> {
     // });
 
     // NATS: listen for new orders to add to orderbook
+    let nats_client_for_rejections = nats_service.nats_client.clone(); // nats_service is moved into the task below
     let order_book_service_for_nats = order_book_service.clone();
     let nats_task = tokio::spawn(async move {
         let result = nats::NatsService::subscribe_and_place_orders(&nats_service.nats_client, order_book_service_for_nats).await;
@@ -54,6 +55,15 @@ async fn main() -> Result<(), Box<dyn std::error::Error>> {
         }
     });
 
+    // NATS: listen for matches rejected by the API before settlement
+    let order_book_service_for_rejections = order_book_service.clone();
+    let nats_rejections_task = tokio::spawn(async move {
+        let result = nats::NatsService::subscribe_and_reject_matches(&nats_client_for_rejections, order_book_service_for_rejections).await;
+        if let Err(e) = result {
+            log::error!("NATS subscription (match rejections) failed: {}", e);
+        }
+    });
+
     /////
     // Finally, start the gRPC server
     /////
@@ -85,7 +95,7 @@ async fn main() -> Result<(), Box<dyn std::error::Error>> {
     }
     
     // Run all tasks concurrently
-    let _ = tokio::try_join!(/*orderbook_scan_task,*/ grpc_server_task, nats_task);
+    let _ = tokio::try_join!(/*orderbook_scan_task,*/ grpc_server_task, nats_task, nats_rejections_task);
 
     Ok(())
 }
@@ -1,4 +1,4 @@
-use crate::{constants, orderbook::{OrderBookService, proto::CreateOrderRequestClob}};
+use crate::{constants, orderbook::{OrderBookService, proto::{CreateOrderRequestClob, MatchRejection}}};
 use async_nats::ServerAddr;
 use futures_util::StreamExt;
 
@@ -26,8 +26,8 @@ impl NatsService {
         while let Some(message) = subscriber.next().await {
             match serde_json::from_slice::<CreateOrderRequestClob>(&message.payload) {
                 Ok(order) => {
-                    // Check if the order with the same txId already exists
-                    if order_book_service.order_exists(&order.tx_id).await {
+                    // Check if the order with the same txId already exists (a requeued order has been taken off the book by the API)
+                    if !order.is_requeue && order_book_service.order_exists(&order.tx_id).await {
                         log::warn!("Duplicate order txId detected: {}. Order not entered into the orderbook.", order.tx_id);
                     } else {
                         let _ = order_book_service.place_order(order).await;
@@ -45,6 +45,43 @@ impl NatsService {
         Ok(())
     }
 
+    // matches the API rejected before settlement (e.g. an underfunded side): pull the evicted orders, put the other side's qty back
+    pub async fn subscribe_and_reject_matches(
+        nats: &async_nats::Client,
+        order_book_service: OrderBookService,
+    ) -> Result<(), Box<dyn std::error::Error + Send + Sync>> {
+        log::info!("NATS \t Listening on: \"{}\"", constants::CLOB_MATCH_REJECTIONS);
+
+        let mut subscriber = nats.subscribe(constants::CLOB_MATCH_REJECTIONS.to_string()).await?;
+
+        while let Some(message) = subscriber.next().await {
+            match serde_json::from_slice::<MatchRejection>(&message.payload) {
+                Ok(rejection) => {
+                    log::warn!("REJECTED \t Match {} rejected by the API - evicting {:?}", rejection.match_id, rejection.evicted_tx_ids);
+                    if !rejection.evicted_tx_ids.is_empty() {
+                        if let Err(e) = order_book_service.cancel_orders(&rejection.market_id, &rejection.evicted_tx_ids).await {
+                            log::error!("Failed to pull the evicted orders of match {}: {}", rejection.match_id, e);
+                        }
+                    }
+                    for order in rejection.reinstated_orders {
+                        let tx_id = order.tx_id.clone();
+                        if let Err(e) = order_book_service.reinstate_order(order).await {
+                            log::error!("Failed to reinstate order {} of match {}: {}", tx_id, rejection.match_id, e);
+                        }
+                    }
+                }
+                Err(err) => {
+                    log::error!(
+                        "Failed to deserialize message payload: {:?}, error: {}",
+                        message.payload, err
+                    );
+                }
+            }
+        }
+
+        Ok(())
+    }
+
     // TODO - should we be using NATS rather than a direct call?
     // pub async fn subscribe_and_cancel_orders(
     //     nats: &async_nats::Client,
@@ -72,11 +109,17 @@ impl NatsService {
     //     Ok(())
     // }
 
-    pub async fn publish_match(&self, is_partial_match: bool, yes_side_pos_price_usd: &CreateOrderRequestClob, no_side_neg_price_usd: &CreateOrderRequestClob) -> Result<(), Box<dyn std::error::Error + Send + Sync>> {
-        let orders: Vec<CreateOrderRequestClob> = vec![yes_side_pos_price_usd.clone(), no_side_neg_price_usd.clone()]; // Create a vector of CreateOrderRequestClob
+    pub async fn publish_match(&self, is_partial_match: bool, match_seq: u64, yes_side_pos_price_usd: &CreateOrderRequestClob, no_side_neg_price_usd: &CreateOrderRequestClob) -> Result<(), Box<dyn std::error::Error + Send + Sync>> {
+        let mut orders: Vec<CreateOrderRequestClob> = vec![yes_side_pos_price_usd.clone(), no_side_neg_price_usd.clone()]; // Create a vector of CreateOrderRequestClob
+        // deterministic match ID - the API records a match once per match_id (a republished match is ignored)
+        let match_id = format!("{}:{}:{}", yes_side_pos_price_usd.tx_id, no_side_neg_price_usd.tx_id, match_seq);
+        for order in orders.iter_mut() {
+            order.match_seq = match_seq;
+            order.match_id = match_id.clone();
+        }
         let payload = serde_json::to_vec(&orders).unwrap();
         let _ = self.nats_client.publish(if is_partial_match { constants::CLOB_MATCHES_PARTIAL } else { constants::CLOB_MATCHES_FULL }, payload.into()).await;
-        log::info!("NATS \t Published MATCH - YES: {:?} NO: {:?}", yes_side_pos_price_usd, no_side_neg_price_usd);
+        log::info!("NATS \t Published MATCH {} - YES: {:?} NO: {:?}", match_id, yes_side_pos_price_usd, no_side_neg_price_usd);
         Ok(())
     }
 }
@@ -2,15 +2,19 @@ use tokio::sync::RwLock;
 use std::{sync::Arc, collections::{HashMap, HashSet}};
 use once_cell::sync::Lazy;
 use std::sync::Mutex;
+use std::sync::atomic::{AtomicU64, Ordering};
 use log;
 
 // Global LUT to ensure unique tx_id's
 static TX_ID_LUT: Lazy<Mutex<HashSet<String>>> = Lazy::new(|| Mutex::new(HashSet::new()));
 
+// Match sequence number (part of every published match's match_id) - seeded with the start time (µs) so a restarted CLOB never reuses one
+static MATCH_SEQ: Lazy<AtomicU64> = Lazy::new(|| AtomicU64::new(chrono::Utc::now().timestamp_micros() as u64));
+
 pub mod proto {
     tonic::include_proto!("clob");
 }
-use proto::{CreateOrderRequestClob, BookSnapshot, OrderDetail};
+use proto::{CreateOrderRequestClob, CreateOrderResponse, BookSnapshot, OrderDetail};
 
 use crate::{nats};
 
@@ -46,35 +50,38 @@ impl OrderBookService {
         return Ok(true);
     }
 
-    // pub async fn remove_market(&self, market_id: &str) {
-    // // Guards
-    // if !is_valid_market_id(market_id.clone()) {
-    //     log::error!("Invalid market_id format: {}", market_id);
-    //     return;
-    // }
-    //     let mut order_books = self.order_books.write().await;
-    //     order_books.remove(market_id);
-    // }
+    pub async fn remove_market(&self, market_id: &str) -> Result<bool, Box<dyn std::error::Error>> {
+        // No guards for performance - assume validated upstream
+
+        let mut order_books = self.order_books.write().await;
+        if order_books.remove(&market_id.to_lowercase()).is_none() {
+            log::warn!("WARN: Attempt to remove a market ({}) which does not exist in OrderBookService", market_id.to_lowercase());
+            return Ok(false);
+        }
+
+        log::info!("Market \"{}\" removed from OrderBookService", market_id.to_lowercase());
+        return Ok(true);
+    }
 
     pub async fn order_exists(&self, tx_id: &str) -> bool {
         let lut = TX_ID_LUT.lock().unwrap();
         lut.contains(tx_id)
     }
 
-    pub async fn place_order(&self, order: CreateOrderRequestClob) -> Result<(), Box<dyn std::error::Error>> {
+    pub async fn place_order(&self, order: CreateOrderRequestClob) -> Result<CreateOrderResponse, Box<dyn std::error::Error>> {
         // No guards for performance - assume validated upstream
         let order_books = self.order_books.read().await;
         if let Some(order_book) = order_books.get(&order.market_id.to_lowercase()) {
             let tx_id = order.tx_id.clone();
             let mut book = order_book.write().await;
-            book.add_order(order).await;
+            let outcome = book.add_order(order).await;
 
             // Add tx_id to the LUT to avoid duplicate tx_ids
             let mut lut = TX_ID_LUT.lock().unwrap();
             lut.insert(tx_id);
 
             // return OK
-            Ok(())
+            Ok(outcome)
         } else {
             Err("Market not found".into())
         }
@@ -176,6 +183,67 @@ impl OrderBookService {
         }
     }
 
+    // puts qty back on a resting order (keeps its place in the queue) - or back on the book if it is not resting any more
+    pub async fn reinstate_order(&self, order: CreateOrderRequestClob) -> Result<bool, Box<dyn std::error::Error>> {
+        // No guards for performance - assume validated upstream
+
+        let order_books = self.order_books.read().await;
+        if let Some(order_book) = order_books.get(&order.market_id.to_lowercase()) {
+            let mut book = order_book.write().await;
+
+            let resting_order = if order.price_usd < 0.0 {
+                book.sell_orders.iter_mut().find(|o| o.tx_id == order.tx_id)
+            } else {
+                book.buy_orders.iter_mut().find(|o| o.tx_id == order.tx_id)
+            };
+            if let Some(resting_order) = resting_order {
+                resting_order.qty += order.qty;
+                log::info!("REINSTATE \t Added qty {} back to order {} (qty={})", order.qty, order.tx_id, resting_order.qty);
+                return Ok(true);
+            }
+
+            log::info!("REINSTATE \t Order {} is not resting - placing qty {} back on the book", order.tx_id, order.qty);
+            let _ = book.add_order(order).await; // may match again
+            Ok(false)
+        } else {
+            Err("Market not found".into())
+        }
+    }
+
+    pub async fn cancel_orders(&self, market_id: &str, tx_ids: &[String]) -> Result<Vec<String>, Box<dyn std::error::Error>> {
+        // No guards for performance - assume validated upstream
+
+        let order_books = self.order_books.read().await;
+        if let Some(order_book) = order_books.get(&market_id.to_lowercase()) {
+            // one write lock for the whole batch - the book can't match a half-cancelled set
+            let mut book = order_book.write().await;
+            let wanted: HashSet<&str> = tx_ids.iter().map(|tx_id| tx_id.as_str()).collect();
+            let mut cancelled: Vec<String> = Vec::new();
+
+            book.buy_orders.retain(|o| {
+                if wanted.contains(o.tx_id.as_str()) {
+                    cancelled.push(o.tx_id.clone());
+                    false
+                } else {
+                    true
+                }
+            });
+            book.sell_orders.retain(|o| {
+                if wanted.contains(o.tx_id.as_str()) {
+                    cancelled.push(o.tx_id.clone());
+                    false
+                } else {
+                    true
+                }
+            });
+
+            log::info!("Cancelled {} of {} orders in market {}", cancelled.len(), tx_ids.len(), market_id);
+            Ok(cancelled)
+        } else {
+            Err("Market not found".into())
+        }
+    }
+
     pub async fn get_orders_for_user(&self, evm_address: &str) -> Result<Vec<CreateOrderRequestClob>, Box<dyn std::error::Error>> {
         // No guards for performance - assume validated upstream
 
@@ -204,6 +272,14 @@ impl OrderBookService {
     }
 }
 
+// a match made by OrderBook::match_book - both sides with their qty before the match
+#[derive(Debug, Clone)]
+struct BookMatch {
+    is_partial_match: bool,
+    incoming_order: CreateOrderRequestClob,
+    existing_order: CreateOrderRequestClob,
+}
+
 #[derive(Debug)]
 pub struct OrderBook {
     buy_orders: Vec<CreateOrderRequestClob>,
@@ -220,29 +296,100 @@ impl OrderBook {
         }
     }
 
-    pub async fn add_order(&mut self, order: CreateOrderRequestClob) {
+    pub async fn add_order(&mut self, order: CreateOrderRequestClob) -> CreateOrderResponse {
         // No guards for performance - assume validated upstream
 
         log::info!("CREATE \t CreateOrderRequestClob: {:?}", order); // Log the incoming order
 
+        let now = chrono::Utc::now();
         if order.price_usd < 0.0 {
-            Self::match_order(&self.nats_service, order, &mut self.buy_orders, &mut self.sell_orders).await;
+            Self::match_order(&self.nats_service, order, &mut self.buy_orders, &mut self.sell_orders, now).await
         } else {
-            Self::match_order(&self.nats_service, order, &mut self.sell_orders, &mut self.buy_orders).await;
+            Self::match_order(&self.nats_service, order, &mut self.sell_orders, &mut self.buy_orders, now).await
+        }
+    }
+
+    // GTD orders carry a signed expires_at - an expired order must never be matched (the smart contract would reject it)
+    fn is_expired(order: &CreateOrderRequestClob, now: chrono::DateTime<chrono::Utc>) -> bool {
+        if order.expires_at.is_empty() {
+            return false;
+        }
+        match chrono::DateTime::parse_from_rfc3339(&order.expires_at) {
+            Ok(expires_at) => expires_at.with_timezone(&chrono::Utc) <= now,
+            Err(_) => false, // validated upstream
         }
     }
 
-    async fn match_order(nats_service: &nats::NatsService, mut incoming_order: CreateOrderRequestClob, opposite_orders: &mut Vec<CreateOrderRequestClob>, same_side_orders: &mut Vec<CreateOrderRequestClob>) {
+    fn prices_cross(incoming_order: &CreateOrderRequestClob, existing_order: &CreateOrderRequestClob) -> bool {
+        (incoming_order.price_usd > 0.0 && incoming_order.price_usd >= existing_order.price_usd) ||
+        (incoming_order.price_usd < 0.0 && existing_order.price_usd >= incoming_order.price_usd.abs())
+    }
+
+    async fn match_order(nats_service: &nats::NatsService, incoming_order: CreateOrderRequestClob, opposite_orders: &mut Vec<CreateOrderRequestClob>, same_side_orders: &mut Vec<CreateOrderRequestClob>, now: chrono::DateTime<chrono::Utc>) -> CreateOrderResponse {
+        let (response, book_matches) = Self::match_book(incoming_order, opposite_orders, same_side_orders, now);
+
+        for book_match in book_matches {
+            // Notify NATS of the match - spawn to fire/forget
+            let match_seq = MATCH_SEQ.fetch_add(1, Ordering::SeqCst); // taken in match order
+            let nats_clone = nats_service.clone();
+            tokio::spawn(async move {
+                // ensure the positive price order is always first!
+                let (orc_yes, orc_no) = if book_match.incoming_order.price_usd < 0.0 {
+                    (&book_match.existing_order, &book_match.incoming_order)
+                } else {
+                    (&book_match.incoming_order, &book_match.existing_order)
+                };
+                if let Err(e) = nats_clone.publish_match(book_match.is_partial_match, match_seq, orc_yes, orc_no).await {
+                    log::error!("NATS\tFailed to publish match (partial={}): {}", book_match.is_partial_match, e);
+                }
+            });
+        }
+
+        response
+    }
+
+    // matches the incoming order against the book - the matches it made are returned (in match order) to be published
+    fn match_book(mut incoming_order: CreateOrderRequestClob, opposite_orders: &mut Vec<CreateOrderRequestClob>, same_side_orders: &mut Vec<CreateOrderRequestClob>, now: chrono::DateTime<chrono::Utc>) -> (CreateOrderResponse, Vec<BookMatch>) {
         // No guards for performance - assume validated upstream
 
+        let tx_id = incoming_order.tx_id.clone();
+        let qty_on_arrival = incoming_order.qty;
+        let time_in_force = incoming_order.time_in_force.to_lowercase();
+        let mut book_matches: Vec<BookMatch> = Vec::new();
+
+        if Self::is_expired(&incoming_order, now) {
+            log::warn!("EXPIRED \t Order {} expired at {} - not entered into the orderbook", tx_id, incoming_order.expires_at);
+            return (CreateOrderResponse { tx_id, status: "expired".to_string(), qty_filled: 0.0, qty_remaining: qty_on_arrival }, book_matches);
+        }
+
+        // drop resting GTD orders which expired since the last match (the API's cron job expires them on the db)
+        opposite_orders.retain(|o| {
+            let is_expired = Self::is_expired(o, now);
+            if is_expired {
+                log::info!("EXPIRED \t Removed order {} (expired at {}) from the orderbook", o.tx_id, o.expires_at);
+            }
+            !is_expired
+        });
+
         opposite_orders.sort_by(|a, b| b.price_usd.partial_cmp(&a.price_usd).unwrap());
 
+        // FOK - all or nothing: make sure the book can fill the whole qty before matching anything
+        if time_in_force == "fok" {
+            let qty_available: f64 = opposite_orders.iter()
+                .take_while(|o| Self::prices_cross(&incoming_order, o))
+                .map(|o| o.qty)
+                .sum();
+            if qty_available < incoming_order.qty {
+                log::info!("KILL \t FOK order {} cannot be fully filled (qty={}, available={})", tx_id, incoming_order.qty, qty_available);
+                return (CreateOrderResponse { tx_id, status: "killed".to_string(), qty_filled: 0.0, qty_remaining: qty_on_arrival }, book_matches);
+            }
+        }
+
         let i = 0;
         while i < opposite_orders.len() {
             let existing_order = &mut opposite_orders[i];
             // Match based on price constraints
-            if (incoming_order.price_usd > 0.0 && incoming_order.price_usd >= existing_order.price_usd) ||
-               (incoming_order.price_usd < 0.0 && existing_order.price_usd >= incoming_order.price_usd.abs()) {
+            if Self::prices_cross(&incoming_order, existing_order) {
 
                 let orc2= existing_order.clone();
                 if incoming_order.qty <= existing_order.qty {
@@ -253,46 +400,18 @@ impl OrderBook {
                     }
 
                     log::info!("MATCH \t OrderRequestClob: {:?}", incoming_order);
-                    
-                    // Notify NATS of full match - spawn to fire/forget
-                    let orc1 = incoming_order.clone();
-                    let nats_clone = nats_service.clone();
-                    tokio::spawn(async move {
-                        // ensure the positive price order is always first!
-                        if orc1.price_usd < 0.0 {
-                            if let Err(e) = nats_clone.publish_match(false, &orc2, &orc1).await {
-                                log::error!("NATS\tFailed to publish match: {}", e);
-                            }
-                        } else {
-                            if let Err(e) = nats_clone.publish_match(false, &orc1, &orc2).await {
-                                log::error!("NATS\tFailed to publish match: {}", e);
-                            }
-                        }
-                    });
-                    
-                    return;
+                    book_matches.push(BookMatch { is_partial_match: false, incoming_order: incoming_order.clone(), existing_order: orc2 });
+
+                    return (CreateOrderResponse { tx_id, status: "filled".to_string(), qty_filled: qty_on_arrival, qty_remaining: 0.0 }, book_matches);
                 } else {
                     // PARTIAL match
+                    // publish both sides with their qty before the match (as for a full match) - the matched qty is the smaller of the two
+                    let orc1 = incoming_order.clone();
                     incoming_order.qty -= existing_order.qty;
                     opposite_orders.remove(i);
 
                     log::info!("MATCH_PARTIAL \t Remaining incoming order quantity: {}", incoming_order.qty);
-                    
-                     // Notify NATS of partial match - spawn to fire/forget
-                    let orc1 = incoming_order.clone();
-                    let nats_clone = nats_service.clone();
-                    tokio::spawn(async move {
-                        // ensure the positive price order is always first!
-                        if orc1.price_usd < 0.0 {
-                            if let Err(e) = nats_clone.publish_match(true, &orc2, &orc1).await {
-                                log::error!("NATS\tFailed to publish (partial) match: {}", e);
-                            }
-                        } else {
-                            if let Err(e) = nats_clone.publish_match(true, &orc1, &orc2).await {
-                                log::error!("NATS\tFailed to publish (partial) match: {}", e);
-                            }
-                        }
-                    });
+                    book_matches.push(BookMatch { is_partial_match: true, incoming_order: orc1, existing_order: orc2 });
                     continue; // Continue searching for additional matches (partial match)
                 }
             } else {
@@ -301,8 +420,19 @@ impl OrderBook {
             }
         }
 
+        let qty_remaining = incoming_order.qty;
+        let qty_filled = qty_on_arrival - qty_remaining;
+
+        // IOC (and FOK) orders never rest on the book - the remainder is cancelled
+        if time_in_force == "ioc" || time_in_force == "fok" {
+            log::info!("CANCEL \t {} order {}: remaining qty {} cancelled (filled {})", time_in_force.to_uppercase(), tx_id, qty_remaining, qty_filled);
+            let status = if qty_filled > 0.0 { "partially_filled" } else { "cancelled" };
+            return (CreateOrderResponse { tx_id, status: status.to_string(), qty_filled, qty_remaining }, book_matches);
+        }
+
         // If no match, add to the respective order book // log::info!("No match found, adding to same side orders: {:?}", incoming_order);
         same_side_orders.push(incoming_order);
+        (CreateOrderResponse { tx_id, status: "open".to_string(), qty_filled, qty_remaining }, book_matches)
     }
 
     pub fn snapshot(&self, depth: usize) -> BookSnapshot {
@@ -373,3 +503,153 @@ impl OrderBook {
     //     log::info!("SCAN \t Complete.");
     // }
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn now() -> chrono::DateTime<chrono::Utc> {
+        chrono::DateTime::parse_from_rfc3339("2026-01-01T00:00:00Z").unwrap().with_timezone(&chrono::Utc)
+    }
+
+    fn order(tx_id: &str, price_usd: f64, qty: f64, time_in_force: &str, expires_at: &str) -> CreateOrderRequestClob {
+        CreateOrderRequestClob {
+            tx_id: tx_id.to_string(),
+            market_id: "01989c0a-87e8-77e8-8000-000000000003".to_string(),
+            price_usd,
+            qty,
+            qty_orig: qty,
+            time_in_force: time_in_force.to_string(),
+            expires_at: expires_at.to_string(),
+            ..Default::default()
+        }
+    }
+
+    // resting buy orders - sorted best price first
+    fn buy_orders() -> Vec<CreateOrderRequestClob> {
+        vec![order("buy-1", 0.6, 3.0, "gtc", ""), order("buy-2", 0.3, 10.0, "gtc", "")]
+    }
+
+    #[test]
+    fn fok_is_killed_without_enough_liquidity() {
+        let mut buys = buy_orders();
+        let mut sells = Vec::new();
+
+        let (response, book_matches) = OrderBook::match_book(order("sell-1", -0.5, 5.0, "fok", ""), &mut buys, &mut sells, now());
+
+        assert_eq!(response.status, "killed");
+        assert_eq!(response.qty_filled, 0.0);
+        assert_eq!(response.qty_remaining, 5.0);
+        assert!(book_matches.is_empty());
+        assert_eq!(buys.iter().map(|o| o.qty).collect::<Vec<_>>(), vec![3.0, 10.0]);
+        assert!(sells.is_empty());
+    }
+
+    #[test]
+    fn fok_is_filled_with_enough_liquidity() {
+        let mut buys = buy_orders();
+        let mut sells = Vec::new();
+
+        let (response, book_matches) = OrderBook::match_book(order("sell-1", -0.25, 5.0, "fok", ""), &mut buys, &mut sells, now());
+
+        assert_eq!(response.status, "filled");
+        assert_eq!(response.qty_filled, 5.0);
+        assert_eq!(book_matches.len(), 2);
+        assert_eq!(buys.len(), 1);
+        assert_eq!(buys[0].qty, 8.0);
+        assert!(sells.is_empty());
+    }
+
+    #[test]
+    fn ioc_remainder_is_cancelled() {
+        let mut buys = buy_orders();
+        let mut sells = Vec::new();
+
+        let (response, book_matches) = OrderBook::match_book(order("sell-1", -0.5, 5.0, "ioc", ""), &mut buys, &mut sells, now());
+
+        assert_eq!(response.status, "partially_filled");
+        assert_eq!(response.qty_filled, 3.0);
+        assert_eq!(response.qty_remaining, 2.0);
+        assert_eq!(book_matches.len(), 1);
+        assert_eq!(buys.len(), 1);
+        assert!(sells.is_empty()); // the remainder never rests on the book
+    }
+
+    #[test]
+    fn ioc_without_a_match_is_cancelled() {
+        let mut buys = buy_orders();
+        let mut sells = Vec::new();
+
+        let (response, book_matches) = OrderBook::match_book(order("sell-1", -0.9, 5.0, "ioc", ""), &mut buys, &mut sells, now());
+
+        assert_eq!(response.status, "cancelled");
+        assert_eq!(response.qty_filled, 0.0);
+        assert_eq!(response.qty_remaining, 5.0);
+        assert!(book_matches.is_empty());
+        assert_eq!(buys.len(), 2);
+        assert!(sells.is_empty());
+    }
+
+    #[test]
+    fn gtd_is_expired() {
+        assert!(OrderBook::is_expired(&order("buy-1", 0.5, 1.0, "gtd", "2025-12-31T23:59:59.000Z"), now()));
+        assert!(OrderBook::is_expired(&order("buy-1", 0.5, 1.0, "gtd", "2026-01-01T00:00:00.000Z"), now()));
+        assert!(!OrderBook::is_expired(&order("buy-1", 0.5, 1.0, "gtd", "2026-01-01T00:00:01.000Z"), now()));
+        assert!(!OrderBook::is_expired(&order("buy-1", 0.5, 1.0, "gtc", ""), now()));
+    }
+
+    #[test]
+    fn expired_gtd_order_is_not_entered() {
+        let mut buys = buy_orders();
+        let mut sells = Vec::new();
+
+        let (response, book_matches) = OrderBook::match_book(order("sell-1", -0.5, 2.0, "gtd", "2025-12-31T00:00:00.000Z"), &mut buys, &mut sells, now());
+
+        assert_eq!(response.status, "expired");
+        assert_eq!(response.qty_remaining, 2.0);
+        assert!(book_matches.is_empty());
+        assert_eq!(buys.len(), 2);
+        assert!(sells.is_empty());
+    }
+
+    #[test]
+    fn expired_resting_gtd_order_is_dropped() {
+        let mut buys = vec![order("buy-1", 0.6, 3.0, "gtd", "2025-12-31T00:00:00.000Z"), order("buy-2", 0.3, 10.0, "gtc", "")];
+        let mut sells = Vec::new();
+
+        let (response, book_matches) = OrderBook::match_book(order("sell-1", -0.5, 2.0, "gtc", ""), &mut buys, &mut sells, now());
+
+        assert_eq!(response.status, "open");
+        assert!(book_matches.is_empty());
+        assert_eq!(buys.len(), 1);
+        assert_eq!(buys[0].tx_id, "buy-2");
+        assert_eq!(sells.len(), 1);
+        assert_eq!(sells[0].tx_id, "sell-1");
+    }
+
+    #[test]
+    fn partial_matches_carry_the_pre_match_qty() {
+        let mut buys = vec![order("buy-1", 0.6, 8.0, "gtc", ""), order("buy-2", 0.55, 5.0, "gtc", "")];
+        let mut sells = Vec::new();
+
+        let (response, book_matches) = OrderBook::match_book(order("sell-1", -0.5, 10.0, "gtc", ""), &mut buys, &mut sells, now());
+
+        assert_eq!(response.status, "filled");
+        assert_eq!(response.qty_filled, 10.0);
+        assert_eq!(response.qty_remaining, 0.0);
+
+        assert_eq!(book_matches.len(), 2);
+        assert!(book_matches[0].is_partial_match);
+        assert_eq!(book_matches[0].existing_order.tx_id, "buy-1");
+        assert_eq!(book_matches[0].incoming_order.qty, 10.0);
+        assert_eq!(book_matches[0].existing_order.qty, 8.0);
+        assert!(!book_matches[1].is_partial_match);
+        assert_eq!(book_matches[1].existing_order.tx_id, "buy-2");
+        assert_eq!(book_matches[1].incoming_order.qty, 2.0);
+        assert_eq!(book_matches[1].existing_order.qty, 5.0);
+
+        assert_eq!(buys.len(), 1);
+        assert_eq!(buys[0].qty, 3.0);
+        assert!(sells.is_empty());
+    }
+}
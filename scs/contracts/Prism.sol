@@ -40,16 +40,21 @@ contract Prism {
   mapping(uint128 => string) public statements;
   mapping(uint128 => bool) public outcomes;               // true = YES wins, false = NO wins
   mapping(uint128 => uint256) public resolutionTimes;
+  mapping(uint128 => bool) public voided;                 // voided (annulled) markets refund every position token at 50%
   mapping(uint128 => uint256) public totalCollateralUsd;
+  mapping(uint128 => uint256) public totalFeesUsd;        // trading fees charged per market (paid to the owner)
   
   mapping(uint128 => mapping(address => uint256)) public yesTokens;
   mapping(uint128 => mapping(address => uint256)) public noTokens;
 
   uint256 public marketCreationFeeUsdc;
   uint256 public collateralTokenNdecimals;
+  uint256 public maxTradingFeeBps;                        // caps the trading fee the owner can charge each side of a match (basis points of the signed collateral)
   
   event PositionTokensPurchased(uint128 marketId, address indexed buyer, uint256 collateralUsd, uint256 priceUsdAbsScaled);
+  event TradingFeeCharged(uint128 marketId, address indexed payer, uint256 feeUsd);
   event MarketResolved(uint128 marketId, bool outcome);
+  event MarketVoided(uint128 marketId);
   event WinningsRedeemed(uint128 marketId, address indexed user, uint256 amount);
   event TokenAssociated(address indexed token);
   event AccountAuthorizationResponse(int64 responseCode, address account, bool response);
@@ -65,6 +70,7 @@ contract Prism {
 
     marketCreationFeeUsdc = 100000; // defaults to 0.10 USDC (6 decimals)
     collateralTokenNdecimals = 6;   // defaults to 6
+    maxTradingFeeBps = 100;         // defaults to 1%
   }
 
   /**
@@ -92,6 +98,11 @@ contract Prism {
     marketCreationFeeUsdc = _marketCreationFeeUsdc; // including nDecimals
   }
 
+  function setMaxTradingFeeBps(uint256 _maxTradingFeeBps) external onlyOwner {
+    require(_maxTradingFeeBps <= 1000, "Max trading fee too high"); // same 10% ceiling as the fee_schedules table
+    maxTradingFeeBps = _maxTradingFeeBps;
+  }
+
   // function setCollateralTokenNdecimals(uint256 _collateralTokenNdecimals) external onlyOwner {
   //   collateralTokenNdecimals = _collateralTokenNdecimals;
   // }
@@ -109,8 +120,14 @@ contract Prism {
   @param qtyScaledNo The quantity of NO position tokens to buy (scaled up to the number of collateral token decimal places)
   @param priceUsdAbsScaledYes The price (in USDC) per YES position token (scaled up to the number of collateral token decimal places)
   @param priceUsdAbsScaledNo The price (in USDC) per NO position token (scaled up to the number of collateral token decimal places)
+  @param feeScaledYes The maker/taker trading fee (in USDC) charged to signerYes on top of the collateral (scaled up to the number of collateral token decimal places)
+  @param feeScaledNo The maker/taker trading fee (in USDC) charged to signerNo on top of the collateral (scaled up to the number of collateral token decimal places)
   @param txIdYes txId of the Yes side 
   @param txIdNo txId of the No side
+  @param timeInForceYes The time-in-force code signed with the YES order (0 = GTC, 1 = GTD, 2 = IOC, 3 = FOK)
+  @param timeInForceNo The time-in-force code signed with the NO order
+  @param expiresAtYes The expiry (unix seconds) signed with a GTD YES order (0 = never expires)
+  @param expiresAtNo The expiry (unix seconds) signed with a GTD NO order (0 = never expires)
   @param sigObjYes The signatureObject (includes the key type) of the YES transaction
   @param sigObjNo The signatureObject (includes the key type) of the NO transaction
 
@@ -127,17 +144,29 @@ contract Prism {
     uint256 qtyScaledNo,
     uint256 priceUsdAbsScaledYes,
     uint256 priceUsdAbsScaledNo,
+    uint256 feeScaledYes,
+    uint256 feeScaledNo,
     uint128 txIdYes,
     uint128 txIdNo,
+    uint8 timeInForceYes,
+    uint8 timeInForceNo,
+    uint64 expiresAtYes,
+    uint64 expiresAtNo,
     bytes calldata sigObjYes,
     bytes calldata sigObjNo
   ) external onlyOwner returns (uint256 yes, uint256 no, uint256 yes2, uint256 no2) {
     require(resolutionTimes[marketId] == 0, "Market resolved");
     require(bytes(statements[marketId]).length > 0, "No market statement has been set");
+    require(expiresAtYes == 0 || block.timestamp <= expiresAtYes, "YES order expired");
+    require(expiresAtNo == 0 || block.timestamp <= expiresAtNo, "NO order expired");
 
     uint256 collateralUsdAbsScaledYes = (qtyScaledYes * priceUsdAbsScaledYes) / (10 ** collateralTokenNdecimals);
     uint256 collateralUsdAbsScaledNo  = (qtyScaledNo  * priceUsdAbsScaledNo)  / (10 ** collateralTokenNdecimals);
 
+    // the fees are not signed by the users - cap them against what each user did sign
+    require(feeScaledYes * 10000 <= collateralUsdAbsScaledYes * maxTradingFeeBps, "YES fee too high");
+    require(feeScaledNo  * 10000 <= collateralUsdAbsScaledNo  * maxTradingFeeBps, "NO fee too high");
+
     // prevent replay attacks by ensuring unique txIds // TODO - storage size ;(
     require(!usedTxIds[txIdYes], "Duplicate txIdYes");
     require(!usedTxIds[txIdNo], "Duplicate txIdNo");
@@ -163,8 +192,8 @@ contract Prism {
     }
 
     // on-chain signature verifiaction using an on-chain assembled payload (check the original values at order entry):
-    require(isAuthorized(signerYes, assemblePayload(0xf0 /* YES MUST have this prefix */, collateralUsdAbsScaledYes, signerYes, marketId, txIdYes), sigObjYes), "isAuthorized YES failed");
-    require(isAuthorized(signerNo,  assemblePayload(0xf1 /* NO MUST have this prefix */,  collateralUsdAbsScaledNo,  signerNo,  marketId, txIdNo),  sigObjNo),  "isAuthorized NO failed");
+    require(isAuthorized(signerYes, assemblePayload(0xf0 /* YES MUST have this prefix */, collateralUsdAbsScaledYes, signerYes, marketId, txIdYes, timeInForceYes, expiresAtYes), sigObjYes), "isAuthorized YES failed");
+    require(isAuthorized(signerNo,  assemblePayload(0xf1 /* NO MUST have this prefix */,  collateralUsdAbsScaledNo,  signerNo,  marketId, txIdNo,  timeInForceNo,  expiresAtNo),  sigObjNo),  "isAuthorized NO failed");
 
     // Transfer 2 collaterals (lower amount) from the buyer to the Prism smart contract using the buyer's allowance
     require(collateralToken.transferFrom(signerYes, address(this), collateralUsdAbsScaled_lower), "Transfer failed");
@@ -172,6 +201,17 @@ contract Prism {
     // update totalCollateralUsd for this marketId:
     totalCollateralUsd[marketId] += (2 * collateralUsdAbsScaled_lower);
 
+    // Transfer the trading fees (if any) from the buyers to the owner - the fees are not part of the market's collateral
+    if (feeScaledYes > 0) {
+      require(collateralToken.transferFrom(signerYes, owner, feeScaledYes), "Fee transfer failed");
+      emit TradingFeeCharged(marketId, signerYes, feeScaledYes);
+    }
+    if (feeScaledNo > 0) {
+      require(collateralToken.transferFrom(signerNo, owner, feeScaledNo), "Fee transfer failed");
+      emit TradingFeeCharged(marketId, signerNo, feeScaledNo);
+    }
+    totalFeesUsd[marketId] += (feeScaledYes + feeScaledNo);
+
     // Now set the position token quantities for the two buyers:
     // uint256 nPositionTokens = (collateralUsdAbsScaled_lower * (10 ** collateralTokenNdecimals)) / qty_lower;   // number of position tokens to mint for each side (YES and NO)
     yesTokens[marketId][signerYes] += qty_lower;                    // 1:1 mapping of collateral qty to position tokens
@@ -185,6 +225,7 @@ contract Prism {
   
   /**
   This function allows users to redeem their winning position tokens for collateral after the market has been resolved.
+  If the market was voided, every YES and NO token is refunded at 50% (a YES/NO pair was backed by 1 USDC).
   A user (msg.sender) can only access their own winnings after the market is resolved
   @param marketId The ID of the market for which the user wants to redeem their winnings.
   @return amountUSDC The amount of collateral (in USDC) redeemed by the user
@@ -192,7 +233,12 @@ contract Prism {
   function redeem(uint128 marketId) external returns (uint256 amountUSDC) {
     require(resolutionTimes[marketId] > 0, "Not resolved yet");
     
-    uint256 nTokens = outcomes[marketId] ? yesTokens[marketId][msg.sender] : noTokens[marketId][msg.sender];
+    uint256 nTokens;
+    if (voided[marketId]) {
+      nTokens = (yesTokens[marketId][msg.sender] + noTokens[marketId][msg.sender]) / 2; // 50/50 refund
+    } else {
+      nTokens = outcomes[marketId] ? yesTokens[marketId][msg.sender] : noTokens[marketId][msg.sender];
+    }
     require(nTokens > 0, "No winning tokens");
 
     // TODO - 2% profit redeem fee...
@@ -245,7 +291,20 @@ contract Prism {
 
   // TODO - implement storage pruning...
 
-  // TODO - implement an admin function close a market and return funds 50/50 to YES and NO token holders respectively
+  /**
+  This function allows the oracle to void (annul) a market instead of resolving it.
+  Trading stops and redeem() refunds every YES and NO position token at 50% (0.5 USDC each).
+  @param marketId The ID of the market to be voided.
+  */
+  function voidMarket(uint128 marketId) external onlyOracle {
+    require(bytes(statements[marketId]).length > 0, "No market statement has been set");
+    require(resolutionTimes[marketId] == 0, "Already resolved");
+
+    voided[marketId] = true;
+    resolutionTimes[marketId] = block.timestamp; // blocks buyPositionTokensOnBehalfAtomic and unlocks redeem
+
+    emit MarketVoided(marketId);
+  }
 
     
 
@@ -323,14 +382,14 @@ contract Prism {
   }
 
   /**
-  This internal-only function takes the USDC collateral amount, market ID, and transaction ID and assembles them together
+  This internal-only function takes the USDC collateral amount, market ID, transaction ID, time-in-force and expiry and assembles them together
   Then calculates the keccak256 hash of the assembled payload
   Then it converts the keccak hash to a base64-encoded string (which will have a fixed length of 44 characters)
   Finally, it prefixes the base64-encoded string with the Hedera Signed Message header (using a hard-coded input string length of 44 characters)
   */
-  function assemblePayload(uint8 buySell, uint256 collateralUsd, address evmAddr, uint128 marketId, uint128 txId) internal pure returns (bytes memory) {
+  function assemblePayload(uint8 buySell, uint256 collateralUsd, address evmAddr, uint128 marketId, uint128 txId, uint8 timeInForce, uint64 expiresAt) internal pure returns (bytes memory) {
     // note: when using encodePacked, a bool gets encoded to 0x00 or 0x01 - this zero prefix prevents an odd register length
-    bytes memory assembled = abi.encodePacked(buySell, collateralUsd, evmAddr, marketId, txId);
+    bytes memory assembled = abi.encodePacked(buySell, collateralUsd, evmAddr, marketId, txId, timeInForce, expiresAt);
     bytes32 keccak = keccak256(assembled);
 
     string memory base64 = Base64.encode(abi.encodePacked(keccak));
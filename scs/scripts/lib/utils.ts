@@ -90,23 +90,28 @@ const getEvmAddress = async (hederaNetwork: string, accountId: string | AccountI
   return data.evm_address
 }
 
-const payloadHex2components = (payloadHex: string): [boolean, bigint, EvmAddress, bigint, bigint] => {
+const payloadHex2components = (payloadHex: string): [boolean, bigint, EvmAddress, bigint, bigint, number, bigint] => {
   const buySell = payloadHex.substring(0, 2).toLowerCase() === 'f1' ? true : false
   const collateralUsdAbsScaled = BigInt('0x' + payloadHex.substring(2, 66))
   const evmAddr = EvmAddress.fromString('0x' + payloadHex.substring(66, 106))
   const marketId = BigInt('0x' + payloadHex.substring(106, 138))
   const txId = BigInt('0x' + payloadHex.substring(138, 170))
+  const timeInForce = parseInt(payloadHex.substring(170, 172), 16)
+  const expiresAt = BigInt('0x' + payloadHex.substring(172, 188))
 
-  return [buySell, collateralUsdAbsScaled, evmAddr, marketId, txId]
+  return [buySell, collateralUsdAbsScaled, evmAddr, marketId, txId, timeInForce, expiresAt]
 }
 
-const assemblePayloadHexForSigning = (priceUsd: number, qty: number, evmAddress: string, marketId: string, txId: string, usdcDecimals: number): string => {
+// timeInForce: 0 = GTC, 1 = GTD, 2 = IOC, 3 = FOK. expiresAt: unix seconds (GTD only, otherwise 0)
+const assemblePayloadHexForSigning = (priceUsd: number, qty: number, evmAddress: string, marketId: string, txId: string, usdcDecimals: number, timeInForce = 0, expiresAt = 0): string => {
   const packedHex = [
     priceUsd < 0 ? 'f1': 'f0', // 1 => sell, 0 => buy (uint8 = 8 bits = 2 hex chars)
     floatToBigIntScaledDecimals(Math.abs(priceUsd * qty), usdcDecimals).toString(16).padStart(64, '0'),
     evmAddress.replace(/^0x/, '').toLowerCase().padStart(40, '0'), // note: an evm address is exactly 20 bytes = 40 hex chars
     uuidToBigInt(marketId).toString(16).padStart(32, '0'),
-    uuidToBigInt(txId).toString(16).padStart(32, '0')
+    uuidToBigInt(txId).toString(16).padStart(32, '0'),
+    timeInForce.toString(16).padStart(2, '0'),
+    expiresAt.toString(16).padStart(16, '0') // uint64
   ].join('')
   return packedHex
 }
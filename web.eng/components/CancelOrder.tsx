@@ -1,10 +1,30 @@
 import { apiClient } from '../grpcClient'
+import { useAppContext } from '../AppProvider'
+import { assembleCancelPayloadHexForSigning } from '../lib/utils'
+import { keccak256 } from 'ethers'
 
 const CancelOrder = ({marketId, txId}: {marketId: string, txId: string}) => {
+  const { signerZero, userAccountInfo } = useAppContext()
+
   return (
     <button onClick={async () => {
       console.log(`cancel txid = ${txId}`)
-      const result = await apiClient.cancelPredictionIntent({marketId, txId})
+      if (!signerZero || !userAccountInfo) {
+        console.warn('CancelOrder: signerZero is undefined')
+        return
+      }
+
+      // only the account that placed the order can cancel it - sign the cancel payload (see: README.md)
+      const packedHex = assembleCancelPayloadHexForSigning({marketId, txId}, userAccountInfo.evm_address)
+      const packedKeccakHex = keccak256(Buffer.from(packedHex, 'hex')).slice(2)
+      const sig = (await signerZero.sign([Buffer.from(packedKeccakHex, 'hex')], { encoding: 'base64' }))[0].signature
+
+      const result = await apiClient.cancelPredictionIntent({
+        marketId,
+        txId,
+        accountId: signerZero.getAccountId().toString(),
+        sig: Buffer.from(sig).toString('base64')
+      })
       console.log('CancelOrder result:', result.response)
     }}>X</button>
   )
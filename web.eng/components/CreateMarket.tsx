@@ -2,15 +2,14 @@ import { useState, useEffect } from 'react'
 import { v7 as uuidv7 } from 'uuid'
 import { apiClient } from '../grpcClient'
 import { useAppContext } from '../AppProvider'
-import { bigIntScaledDecimalsToFloat, delay } from '../lib/utils'
+import { assembleMarketProposalPayloadHexForSigning, keyTypeToInt } from '../lib/utils'
+import { keccak256 } from 'ethers'
 import toast from 'react-hot-toast'
-import { useNavigate } from 'react-router'
 
 const CreateMarket = () => {
-  const navigate = useNavigate()
   // const location = useLocation()
 
-  const { signerZero, spenderAllowanceUsd, marketCreationFeeScaledUsdc, usdcNdecimals, setShowPopupAllowance, setSpenderAllowanceUsd } = useAppContext()
+  const { signerZero, userAccountInfo } = useAppContext()
   const [statement, setStatement] = useState('')
   const [imageUrl, setImageUrl] = useState(window.location.origin + '/640_480.png')
   const [ isSubmitDisabled, setIsSubmitDisabled ] = useState(true)
@@ -32,8 +31,8 @@ const CreateMarket = () => {
       {
         /*
         A form to create a new market
-        calls api.CreateMarket 
-        message NewMarketRequest {market_id, net, statement, image_url }
+        calls api.ProposeMarket (the market goes live once an admin approves the proposal)
+        message ProposeMarketRequest { market: CreateMarketRequest {market_id, net, statement, image_url, ...}, account_id, sig, public_key, key_type }
 
         I want a nicely styled form with labels and placeholders to do this
         use tailwindccss for styling
@@ -47,16 +46,7 @@ const CreateMarket = () => {
         <h2>To create a new market, first connect your wallet.</h2>
       )}
 
-      { (spenderAllowanceUsd < marketCreationFeeScaledUsdc / (10 ** usdcNdecimals)) && (
-        <>
-          <h2>To create a new market, you must deposit at least {(marketCreationFeeScaledUsdc / (10 ** usdcNdecimals)).toFixed(2)} USDC into your account. </h2>
-
-          {/* TODO */}
-          <span className="text-blue-600 underline cursor-pointer" title="Click here to deposit" onClick={() => { setShowPopupAllowance(true) }}>Deposit</span>
-        </>
-      )}
-
-      <div className={`max-w-2xl mx-auto p-6 bg-card rounded-lg shadow-md ${(spenderAllowanceUsd < marketCreationFeeScaledUsdc / (10 ** usdcNdecimals)) || typeof signerZero === 'undefined' ? 'opacity-50 pointer-events-none select-none' : ''}`}>
+      <div className={`max-w-2xl mx-auto p-6 bg-card rounded-lg shadow-md ${typeof signerZero === 'undefined' ? 'opacity-50 pointer-events-none select-none' : ''}`}>
         To create a new market, simply enter a <span className="text-blue-600 underline cursor-pointer" title="Your statement should be clear, concise and specific. Please make a statement and don't ask a question. Importantly, the statement MUST be publicly verifiable. Ambiguous or unverifiable statements can cause resolution problems and could result in the market resolving in an unexpected way.">publicly verifiable market statement</span> below. 
         
         <br/>
@@ -66,7 +56,7 @@ const CreateMarket = () => {
         
         <br/>
         <br/>
-        As soon as you submit this form, your market will be created and made available for trading without any censorship.
+        Your market is submitted as a proposal and made available for trading as soon as it is approved. Proposing a market is free - the market creation fee is paid on approval.
         
         <br/>
         <br/>
@@ -118,33 +108,43 @@ const CreateMarket = () => {
           onClick={async () => {
             try {
               setIsSubmitDisabled(true)
-              const result = await apiClient.createMarket({
+              if (!signerZero || !userAccountInfo) {
+                console.warn('CreateMarket: signerZero is undefined')
+                return
+              }
+              const market = {
                 marketId: uuidv7(),
                 net: 'testnet',
                 statement,
                 imageUrl: imageUrl,
-                description: statement // todo - its own description
+                description: statement, // todo - its own description
+                categoryIds: []
+              }
+
+              // the proposer signs the proposed market (see: README.md)
+              const packedHex = assembleMarketProposalPayloadHexForSigning(market)
+              const packedKeccakHex = keccak256(Buffer.from(packedHex, 'hex')).slice(2)
+              const sig = (await signerZero.sign([Buffer.from(packedKeccakHex, 'hex')], { encoding: 'base64' }))[0].signature
+
+              const result = await apiClient.proposeMarket({
+                market,
+                accountId: signerZero.getAccountId().toString(),
+                sig: Buffer.from(sig).toString('base64'),
+                publicKey: userAccountInfo.key.key,
+                keyType: keyTypeToInt(userAccountInfo.key._type)
               })
               const response = result.response
-              console.log('CreateMarketResponse', response)
-
-              // CreateMarketResponse includes the remaining allowance in the smart contract response
-              // relying on the mirrornode network has too much latency to reflect the new allowance immediately after market creation
-              setSpenderAllowanceUsd(bigIntScaledDecimalsToFloat(response.remainingAllowance, usdcNdecimals))
+              console.log('MarketProposal', response)
 
-              toast.success(`Market created successfully with marketId: ${response.marketResponse.marketId}`)
-              
-              await delay(1000)
-              navigate(`/market/${response.marketResponse.marketId}`)
-              
+              toast.success(`Market proposed successfully with marketId: ${response.marketId} - it goes live once approved`)
             } catch (error) {
-              console.error('Error creating market:', error)
+              console.error('Error proposing market:', error)
             } finally {
               setIsSubmitDisabled(false)
             }
           }}
         >
-            { isSubmitDisabled ? 'Creating Market...' : 'Create Market' }
+            { isSubmitDisabled ? 'Proposing Market...' : 'Propose Market' }
             { isSubmitDisabled && <span className="ml-2 spinner-border spinner-border-sm inline-block w-4 h-4 border-2 rounded-full border-white border-t-transparent animate-spin"></span> }
         </button>
       </div>
@@ -6,7 +6,7 @@ import { apiClient, clobClient } from '../grpcClient'
 import { PriceUpdate } from '../gen/clob'
 import { PredictionIntentRequest } from '../gen/api'
 import { defaultPredictionIntentRequest } from '../constants'
-import { assemblePayloadHexForSigning, keyTypeToInt } from '../lib/utils'
+import { assemblePayloadHexForSigning, assembleLegacyPayloadHexForSigning, keyTypeToInt } from '../lib/utils'
 import { v7 as uuidv7 } from 'uuid'
 import { keccak256 } from 'ethers'
 import toast from 'react-hot-toast'
@@ -15,7 +15,7 @@ import { getSpenderAllowanceUsd } from '../lib/hedera'
 const TIMESTAMP_ALLOWED_PAST_SECONDS = 300
 
 const PopupTradePanel = () => {
-  const { showPopupTradePanel, setShowPopupTradePanel, marketId, signerZero, userAccountInfo, networkSelected, usdcNdecimals, spenderAllowanceUsd, setSpenderAllowanceUsd, minOrderSizeUsd, usdcTokenIds, smartContractIds } = useAppContext()
+  const { showPopupTradePanel, setShowPopupTradePanel, marketId, markets, signerZero, userAccountInfo, networkSelected, usdcNdecimals, spenderAllowanceUsd, setSpenderAllowanceUsd, minOrderSizeUsd, usdcTokenIds, smartContractIds } = useAppContext()
 
   const [thingerSign, setThingerSign] = useState(false)
   const [thingerSubmit, setThingerSubmit] = useState(false)
@@ -327,7 +327,9 @@ const PopupTradePanel = () => {
               const _predictionIntentRequest = generatePredictionIntentObject(predictionIntentRequest)
               // setPredictionIntentRequest(_predictionIntentRequest)
 
-              const packedHex = assemblePayloadHexForSigning(_predictionIntentRequest, usdcNdecimals)
+              // a market on a legacy smart contract (contractVersion 1) signs the payload without timeInForce/expiresAt
+              const isLegacyContract = markets.find((m) => m.marketId === marketId)?.contractVersion === 1
+              const packedHex = isLegacyContract ? assembleLegacyPayloadHexForSigning(_predictionIntentRequest, usdcNdecimals) : assemblePayloadHexForSigning(_predictionIntentRequest, usdcNdecimals)
               console.log(`packedHex: ${packedHex}`)
               const packedKeccakHex = keccak256(Buffer.from(packedHex, 'hex')).slice(2)
               console.log(`packedKeccakHex (len=${Buffer.from(packedKeccakHex, 'hex').length}): ${packedKeccakHex}`)
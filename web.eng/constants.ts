@@ -27,7 +27,9 @@ const defaultPredictionIntentRequest = (): PredictionIntentRequest => {
     sig: '',
     publicKey: '',
     evmAddress: '0123456789012345678901234567890123456789',
-    keyType: 0 // 1 = ed25519, 2 = ecdsa_secp256k1, 0 would be rejected
+    keyType: 0, // 1 = ed25519, 2 = ecdsa_secp256k1, 0 would be rejected
+    timeInForce: 'gtc', // gtc, gtd (+ expiresAt), ioc, fok
+    expiresAt: ''
   }
 }
 
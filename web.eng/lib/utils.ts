@@ -1,4 +1,4 @@
-import { PredictionIntentRequest } from '../gen/api'
+import { CancelAllPredictionIntentsRequest, CancelOrderRequest, ConditionalIntentRequest, CreateMarketRequest, PredictionIntentRequest } from '../gen/api'
 import { BookSnapshot } from '../gen/clob'
 
 const uint8ToBase64 = (bytes: Uint8Array): string => {
@@ -61,6 +61,9 @@ const isValidUUIDv7 = (uuid: string): boolean => {
   return uuidv7Regex.test(uuid)
 }
 
+// time-in-force codes signed in the payload - must match TimeInForceCode in ./api/server/lib/sign.go (and Prism.sol)
+const timeInForceCodes: Record<string, number> = { '': 0, gtc: 0, gtd: 1, ioc: 2, fok: 3 }
+
 /**
  * Assembles a payload hex string for signing from the PredictionIntentRequest object
  * See: prism/README.md for format definition details
@@ -75,7 +78,103 @@ const assemblePayloadHexForSigning = (predictionIntentRequest: PredictionIntentR
     floatToBigIntScaledDecimals(Math.abs(predictionIntentRequest.priceUsd * predictionIntentRequest.qty), usdcDecimals).toString(16).padStart(64, '0'),
     predictionIntentRequest.evmAddress.replace(/^0x/, '').toLowerCase().padStart(40, '0'), // note: an evm address is exactly 20 bytes = 40 hex chars
     uuidToBigInt(predictionIntentRequest.marketId).toString(16).padStart(32, '0'),
-    uuidToBigInt(predictionIntentRequest.txId).toString(16).padStart(32, '0')
+    uuidToBigInt(predictionIntentRequest.txId).toString(16).padStart(32, '0'),
+    (timeInForceCodes[predictionIntentRequest.timeInForce.toLowerCase()] ?? 0).toString(16).padStart(2, '0'), // uint8
+    (predictionIntentRequest.expiresAt ? Math.floor(new Date(predictionIntentRequest.expiresAt).getTime() / 1000) : 0).toString(16).padStart(16, '0') // uint64 unix seconds (gtd only)
+  ].join('')
+  return packedHex
+}
+
+/**
+ * Assembles the payload hex string signed for a market on a legacy smart contract (MarketResponse.contractVersion === 1),
+ * which predates time-in-force and expiry: the same fields as assemblePayloadHexForSigning without the last two (gtc only)
+ * See: prism/README.md for format definition details
+ * Also see: ./api/server/lib/sign.go
+ */
+const assembleLegacyPayloadHexForSigning = (predictionIntentRequest: PredictionIntentRequest, usdcDecimals: number): string => {
+  return assemblePayloadHexForSigning(predictionIntentRequest, usdcDecimals).slice(0, 2 + 64 + 40 + 32 + 32)
+}
+
+/**
+ * Assembles a payload hex string for signing a cancellation of a prediction intent
+ * See: prism/README.md for format definition details
+ * Also see: ./api/server/lib/sign.go
+ * @param cancelOrderRequest CancelOrderRequest object (the sig is not part of the payload)
+ * @param evmAddress the evm address the prediction intent was placed with
+ * @returns a string conforming to the format
+ */
+const assembleCancelPayloadHexForSigning = (cancelOrderRequest: Pick<CancelOrderRequest, 'marketId' | 'txId'>, evmAddress: string): string => {
+  const packedHex = [
+    'fc', // cancel (uint8 = 8 bits = 2 hex chars)
+    evmAddress.replace(/^0x/, '').toLowerCase().padStart(40, '0'), // note: an evm address is exactly 20 bytes = 40 hex chars
+    uuidToBigInt(cancelOrderRequest.marketId).toString(16).padStart(32, '0'),
+    uuidToBigInt(cancelOrderRequest.txId).toString(16).padStart(32, '0')
+  ].join('')
+  return packedHex
+}
+
+/**
+ * Assembles a payload hex string for signing the trigger of a conditional intent (signed with the same key as the intent)
+ * See: prism/README.md for format definition details
+ * Also see: ./api/server/lib/sign.go
+ * @param conditionalIntentRequest ConditionalIntentRequest object (the triggerSig is not part of the payload)
+ * @param predictionIntentRequest the wrapped prediction intent
+ * @param usdcDecimals number of decimals for USDC
+ * @returns a string conforming to the format
+ */
+const assembleTriggerPayloadHexForSigning = (conditionalIntentRequest: Pick<ConditionalIntentRequest, 'triggerCondition' | 'triggerPriceUsd'>, predictionIntentRequest: Pick<PredictionIntentRequest, 'evmAddress' | 'marketId' | 'txId'>, usdcDecimals: number): string => {
+  const packedHex = [
+    'fd', // trigger (uint8 = 8 bits = 2 hex chars)
+    predictionIntentRequest.evmAddress.replace(/^0x/, '').toLowerCase().padStart(40, '0'), // note: an evm address is exactly 20 bytes = 40 hex chars
+    uuidToBigInt(predictionIntentRequest.marketId).toString(16).padStart(32, '0'),
+    uuidToBigInt(predictionIntentRequest.txId).toString(16).padStart(32, '0'),
+    conditionalIntentRequest.triggerCondition.toLowerCase() === 'above' ? '01' : '02', // uint8: 1 = above, 2 = below
+    floatToBigIntScaledDecimals(conditionalIntentRequest.triggerPriceUsd, usdcDecimals).toString(16).padStart(64, '0')
+  ].join('')
+  return packedHex
+}
+
+/**
+ * Assembles a payload hex string for signing a bulk cancellation of prediction intents
+ * See: prism/README.md for format definition details
+ * Also see: ./api/server/lib/sign.go
+ * @param cancelAllRequest CancelAllPredictionIntentsRequest object (the sig is not part of the payload)
+ * @returns a string conforming to the format
+ */
+const assembleCancelAllPayloadHexForSigning = (cancelAllRequest: Pick<CancelAllPredictionIntentsRequest, 'evmAddress' | 'marketId' | 'side' | 'generatedAt'>): string => {
+  const side = cancelAllRequest.side === 'buy' ? 'f0' : cancelAllRequest.side === 'sell' ? 'f1' : '00'
+  const packedHex = [
+    'fa', // cancel all (uint8 = 8 bits = 2 hex chars)
+    cancelAllRequest.evmAddress.replace(/^0x/, '').toLowerCase().padStart(40, '0'), // note: an evm address is exactly 20 bytes = 40 hex chars
+    (cancelAllRequest.marketId ? uuidToBigInt(cancelAllRequest.marketId) : 0n).toString(16).padStart(32, '0'),
+    side,
+    BigInt(Date.parse(cancelAllRequest.generatedAt)).toString(16).padStart(16, '0')
+  ].join('')
+  return packedHex
+}
+
+/**
+ * Assembles a payload hex string for signing a market proposal (ProposeMarket) - every free-text field is length-prefixed
+ * See: prism/README.md for format definition details
+ * Also see: ./api/server/lib/sign.go
+ * @param createMarketRequest the proposed market
+ * @returns a string conforming to the format
+ */
+const assembleMarketProposalPayloadHexForSigning = (createMarketRequest: Pick<CreateMarketRequest, 'marketId' | 'net' | 'statement' | 'imageUrl' | 'closesAt' | 'description' | 'categoryIds'>): string => {
+  const lengthPrefixedHex = (field: string): string => {
+    const bytes = new TextEncoder().encode(field) // utf8
+    return bytes.length.toString(16).padStart(8, '0') + Array.from(bytes, (b) => b.toString(16).padStart(2, '0')).join('') // uint32 byte length, then the bytes
+  }
+  const packedHex = [
+    'fb', // market proposal (uint8 = 8 bits = 2 hex chars)
+    uuidToBigInt(createMarketRequest.marketId).toString(16).padStart(32, '0'),
+    lengthPrefixedHex(createMarketRequest.net),
+    lengthPrefixedHex(createMarketRequest.statement),
+    lengthPrefixedHex(createMarketRequest.imageUrl),
+    lengthPrefixedHex(createMarketRequest.closesAt ?? ''),
+    lengthPrefixedHex(createMarketRequest.description),
+    createMarketRequest.categoryIds.length.toString(16).padStart(2, '0'), // uint8 count, then a uint32 per category
+    ...createMarketRequest.categoryIds.map((categoryId) => categoryId.toString(16).padStart(8, '0'))
   ].join('')
   return packedHex
 }
@@ -116,6 +215,11 @@ export {
   uuidToBigInt,
   isValidUUIDv7,
   assemblePayloadHexForSigning,
+  assembleLegacyPayloadHexForSigning,
+  assembleCancelPayloadHexForSigning,
+  assembleTriggerPayloadHexForSigning,
+  assembleCancelAllPayloadHexForSigning,
+  assembleMarketProposalPayloadHexForSigning,
   keyTypeToInt,
   delay,
   formatNumberShort
@@ -12,6 +12,8 @@ import type {
   MarketResponse,
   MarketsResponse,
   NewMarketRequest,
+  ProposeMarketRequest,
+  MarketProposalResponse,
   PriceHistoryRequest,
   PriceHistoryResponse,
   GetCommentsRequest,
@@ -125,13 +127,13 @@ export const apiServiceClient = {
   },
 
   /**
-   * Create a new market
+   * Propose a new market (it goes live once an admin approves it)
    */
-  createMarket: async (request: NewMarketRequest): Promise<MarketResponse> => {
-    return unaryCall<NewMarketRequest, MarketResponse>(
-      'CreateMarket',
+  proposeMarket: async (request: ProposeMarketRequest): Promise<MarketProposalResponse> => {
+    return unaryCall<ProposeMarketRequest, MarketProposalResponse>(
+      'ProposeMarket',
       request,
-      (data) => data as MarketResponse
+      (data) => data as MarketProposalResponse
     );
   },
 
@@ -186,6 +188,8 @@ export type {
   MarketResponse,
   MarketsResponse,
   NewMarketRequest,
+  ProposeMarketRequest,
+  MarketProposalResponse,
   PriceHistoryRequest,
   PriceHistoryResponse,
   GetCommentsRequest,
@@ -13,6 +13,8 @@ import type {
   MarketResponse,
   MarketsResponse,
   NewMarketRequest,
+  ProposeMarketRequest,
+  MarketProposalResponse,
   PriceHistoryRequest,
   PriceHistoryResponse,
   GetCommentsRequest,
@@ -51,6 +53,10 @@ export const Serializers = {
     return new TextEncoder().encode(JSON.stringify(data));
   },
 
+  proposeMarketRequest: (data: ProposeMarketRequest): Uint8Array => {
+    return new TextEncoder().encode(JSON.stringify(data));
+  },
+
   priceHistoryRequest: (data: PriceHistoryRequest): Uint8Array => {
     return new TextEncoder().encode(JSON.stringify(data));
   },
@@ -95,4 +101,9 @@ export const Deserializers = {
     const json = new TextDecoder().decode(bytes);
     return JSON.parse(json) as CreateCommentResponse;
   },
+
+  marketProposalResponse: (bytes: Uint8Array): MarketProposalResponse => {
+    const json = new TextDecoder().decode(bytes);
+    return JSON.parse(json) as MarketProposalResponse;
+  },
 };
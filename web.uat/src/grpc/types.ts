@@ -63,6 +63,29 @@ export interface NewMarketRequest {
   net: HederaNetwork;
   statement: string;
   imageUrl?: string;
+  closesAt?: string;
+  description?: string;
+  categoryIds?: number[];
+}
+
+// markets go live only once an admin approves the proposal
+export interface ProposeMarketRequest {
+  market: NewMarketRequest;
+  accountId: string;
+  sig: string; // over the length-prefixed market fields (see: assembleMarketProposalPayloadHexForSigning in web.eng/lib/utils.ts)
+  publicKey: string;
+  keyType: KeyType;
+}
+
+export interface MarketProposalResponse {
+  marketId: string;
+  status: 'pending' | 'approved' | 'rejected';
+  accountId: string;
+  market: NewMarketRequest;
+  rejectReason: string;
+  feeCharged: boolean;
+  createdAt: string;
+  reviewedAt: string;
 }
 
 export interface PriceHistoryRequest {